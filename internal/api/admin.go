@@ -0,0 +1,199 @@
+// internal/api/admin.go
+
+package api
+
+import (
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+type adminCommentsResponse struct {
+    Comments []commentResponse        `json:"comments" xml:"comments>comment"`
+    Deleted  []deletedCommentResponse `json:"deleted" xml:"deleted>comment"`
+    Total    int                      `json:"total" xml:"total"`
+    Limit    int                      `json:"limit" xml:"limit"`
+    Offset   int                      `json:"offset" xml:"offset"`
+}
+
+// deletedCommentResponse is a comment deleted within the tombstone window,
+// of which only its owner and deletion time are kept.
+type deletedCommentResponse struct {
+    ID        string    `json:"id" xml:"id"`
+    UserID    string    `json:"user_id" xml:"user_id"`
+    DeletedAt timestamp `json:"deleted_at" xml:"deleted_at"`
+}
+
+// Admin comment list handler. Lists every user's comments, newest first,
+// optionally filtered by user, author and creation time (before and after,
+// in RFC 3339). Comments deleted within the tombstone window are listed
+// separately under deleted, matched by user and deletion time; they have no
+// author, so an author filter leaves them out.
+func handleAdminListComments(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        l := logger.With("user_id", userID)
+
+        params := newQueryParams(r)
+        limit, offset := params.page(defaultListLimit, maxListLimit)
+        before := params.time("before")
+        after := params.time("after")
+        if problems := params.Problems(); problems != nil {
+            encodeProblems(w, r, problems)
+            return
+        }
+        user := strings.TrimSpace(r.URL.Query().Get("user"))
+        author := strings.TrimSpace(r.URL.Query().Get("author"))
+
+        var filters []storage.Filter
+        if user != "" {
+            filters = append(filters, storage.Filter{Op: storage.FilterUser, Value: user})
+        }
+        if author != "" {
+            filters = append(filters, storage.Filter{Op: storage.FilterAuthor, Value: author})
+        }
+        if !before.IsZero() {
+            filters = append(filters, storage.Filter{Op: storage.FilterBefore, Time: before})
+        }
+        if !after.IsZero() {
+            filters = append(filters, storage.Filter{Op: storage.FilterAfter, Time: after})
+        }
+        filter := storage.Filter{}
+        if len(filters) > 0 {
+            filter = storage.Filter{Op: storage.FilterAnd, Children: filters}
+        }
+
+        comments, total, err := store.Search(ctx, filter, offset, limit)
+        if err != nil {
+            l.Error(ctx, "failed to list comments",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        tombstones, err := store.Tombstones(ctx)
+        if err != nil {
+            l.Error(ctx, "failed to list deleted comments",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        reacted, err := store.ReactedTo(ctx, userID)
+        if err != nil {
+            l.Error(ctx, "failed to load reactions",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        resp := adminCommentsResponse{
+            Comments: make([]commentResponse, len(comments)),
+            Deleted:  []deletedCommentResponse{},
+            Total:    total,
+            Limit:    limit,
+            Offset:   offset,
+        }
+        for i, c := range comments {
+            resp.Comments[i] = toCommentResponse(c, reacted[c.ID])
+        }
+        if err := addFlagCounts(ctx, store, resp.Comments); err != nil {
+            l.Error(ctx, "failed to load flag counts",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+        if author == "" {
+            for id, t := range tombstones {
+                if (user != "" && t.UserID != user) ||
+                    (!before.IsZero() && !t.DeletedAt.Before(before)) ||
+                    (!after.IsZero() && t.DeletedAt.Before(after)) {
+                    continue
+                }
+                resp.Deleted = append(resp.Deleted, deletedCommentResponse{ID: id, UserID: t.UserID, DeletedAt: timestamp(t.DeletedAt)})
+            }
+            sort.Slice(resp.Deleted, func(i, j int) bool {
+                a, b := time.Time(resp.Deleted[i].DeletedAt), time.Time(resp.Deleted[j].DeletedAt)
+                if a.Equal(b) {
+                    return resp.Deleted[i].ID < resp.Deleted[j].ID
+                }
+                return a.After(b)
+            })
+        }
+
+        setPageHeaders(w, r, total, limit, offset)
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            l.Error(ctx, "failed to encode response",
+                "error", err,
+            )
+        }
+    })
+}
+
+type deleteUserCommentsResponse struct {
+    UserID  string `json:"user_id" xml:"user_id"`
+    Deleted int    `json:"deleted" xml:"deleted"`
+    DryRun  bool   `json:"dry_run" xml:"dry_run"`
+}
+
+// Delete user comments handler. Removes every comment by the user in the
+// path and returns how many there were; with dry_run=true they are only
+// counted. Unlike erasure, the user's reactions and tombstones are kept.
+func handleDeleteUserComments(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        adminID := UserIDFromContext(ctx)
+        userID := r.PathValue("user_id")
+
+        params := newQueryParams(r)
+        dryRun := params.boolean("dry_run")
+        if problems := params.Problems(); problems != nil {
+            encodeProblems(w, r, problems)
+            return
+        }
+
+        var deleted int
+        var err error
+        if dryRun {
+            deleted, err = store.CountByUser(ctx, userID)
+        } else {
+            deleted, err = store.DeleteByUser(ctx, userID)
+        }
+        if err != nil {
+            logger.Error(ctx, "failed to delete user comments",
+                "error", err,
+                "user_id", userID,
+                "admin_id", adminID,
+                "dry_run", dryRun,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        msg := "deleted user comments"
+        if dryRun {
+            msg = "dry run of deleting user comments"
+        }
+        logger.Warn(ctx, msg,
+            "user_id", userID,
+            "admin_id", adminID,
+            "deleted", deleted,
+            "dry_run", dryRun,
+        )
+        resp := deleteUserCommentsResponse{UserID: userID, Deleted: deleted, DryRun: dryRun}
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            logger.Error(ctx, "failed to encode response",
+                "error", err,
+                "user_id", userID,
+            )
+        }
+    })
+}
@@ -0,0 +1,64 @@
+// internal/api/caching.go
+
+package api
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// setLastModified sets Last-Modified to modified and reports whether it
+// did. It is left out while the second modified falls in hasn't ended, as
+// the header's one-second resolution couldn't tell a later change in that
+// second from modified itself.
+func setLastModified(w http.ResponseWriter, modified, now time.Time) bool {
+    if modified.IsZero() || !modified.Truncate(time.Second).Before(now.Truncate(time.Second)) {
+        return false
+    }
+    w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+    return true
+}
+
+// notModifiedSince reports whether r's If-Modified-Since covers modified,
+// so the client's copy is current and it can be answered with 304.
+func notModifiedSince(r *http.Request, modified time.Time) bool {
+    v := r.Header.Get("If-Modified-Since")
+    if v == "" {
+        return false
+    }
+    since, err := http.ParseTime(v)
+    if err != nil {
+        return false
+    }
+    return !modified.Truncate(time.Second).After(since)
+}
+
+// setETag sets ETag to a comment's version, which clients can send back in
+// If-Match to update only the version they read.
+func setETag(w http.ResponseWriter, version int64) {
+    w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// errMalformedIfMatch is returned for an If-Match that isn't one version.
+var errMalformedIfMatch = errors.New("If-Match must be a single comment version, as given in ETag")
+
+// ifMatchVersion returns the version r's If-Match requires, or 0 if it
+// has none or is "*", which any existing comment matches. The quotes
+// around the version may be left out.
+func ifMatchVersion(r *http.Request) (int64, error) {
+    v := strings.TrimSpace(r.Header.Get("If-Match"))
+    if v == "" || v == "*" {
+        return 0, nil
+    }
+    if unquoted, err := strconv.Unquote(v); err == nil {
+        v = unquoted
+    }
+    version, err := strconv.ParseInt(v, 10, 64)
+    if err != nil || version < 1 {
+        return 0, errMalformedIfMatch
+    }
+    return version, nil
+}
@@ -0,0 +1,99 @@
+// internal/api/consistency.go
+
+package api
+
+import (
+    "encoding/base64"
+    "encoding/binary"
+    "errors"
+    "net/http"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// consistencyTokenHeader carries the store position of a write back to the
+// client, which may echo it on later reads to ask that they reflect at
+// least that write.
+const consistencyTokenHeader = "X-Consistency-Token"
+
+// encodeConsistencyToken returns the opaque token for a write position.
+func encodeConsistencyToken(pos uint64) string {
+    var b [8]byte
+    binary.BigEndian.PutUint64(b[:], pos)
+    return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// parseConsistencyToken returns the write position a token refers to.
+func parseConsistencyToken(token string) (uint64, error) {
+    b, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil || len(b) != 8 {
+        return 0, errors.New("malformed consistency token")
+    }
+    return binary.BigEndian.Uint64(b), nil
+}
+
+// isReadMethod reports whether method leaves the store unchanged.
+func isReadMethod(method string) bool {
+    return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// newConsistencyMiddleware stamps successful writes with a consistency token
+// and checks tokens echoed on reads. The in-memory store serves every read
+// from the data its writes went to, so a valid token needs no further
+// action; a backend with replicas or caches would wait for, or route to, a
+// copy at the token's position here.
+func newConsistencyMiddleware(logger *logging.Logger, store storage.CommentRepository) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if !isReadMethod(r.Method) {
+                next.ServeHTTP(&consistencyWriter{ResponseWriter: w, r: r, logger: logger, store: store}, r)
+                return
+            }
+
+            if token := r.Header.Get(consistencyTokenHeader); token != "" {
+                if _, err := parseConsistencyToken(token); err != nil {
+                    encodeError(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+                    return
+                }
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// consistencyWriter adds the store's write position to successful responses
+// as they are written, after the handler's mutation has been applied.
+type consistencyWriter struct {
+    http.ResponseWriter
+    r           *http.Request
+    logger      *logging.Logger
+    store       storage.CommentRepository
+    wroteHeader bool
+}
+
+func (cw *consistencyWriter) WriteHeader(code int) {
+    if !cw.wroteHeader {
+        cw.wroteHeader = true
+        if code < http.StatusBadRequest {
+            pos, err := cw.store.Position(cw.r.Context())
+            if err != nil {
+                cw.logger.Error(cw.r.Context(), "failed to read store position", "error", err)
+            } else {
+                cw.Header().Set(consistencyTokenHeader, encodeConsistencyToken(pos))
+            }
+        }
+    }
+    cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *consistencyWriter) Write(b []byte) (int, error) {
+    if !cw.wroteHeader {
+        cw.WriteHeader(http.StatusOK)
+    }
+    return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *consistencyWriter) Unwrap() http.ResponseWriter {
+    return cw.ResponseWriter
+}
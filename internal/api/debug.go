@@ -0,0 +1,123 @@
+// internal/api/debug.go
+
+package api
+
+import (
+    "encoding/xml"
+    "net/http"
+    "net/http/pprof"
+    "runtime"
+    "time"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// addDebugRoutes mounts the pprof handlers and runtime stats under /debug
+// for admins, each wrapped in admin. Profiles and traces run for as long as
+// the caller asks, so they are exempt from the request timeout.
+func addDebugRoutes(mux *http.ServeMux, admin []Middleware, logger *logging.Logger, store storage.CommentRepository, metrics *storage.Metrics) {
+    mux.Handle("GET /debug/vars", Chain(handleRuntimeStats(logger, store, metrics, time.Now()), admin...))
+    mux.Handle("GET /debug/pprof/", Chain(http.HandlerFunc(pprof.Index), admin...))
+    mux.Handle("GET /debug/pprof/cmdline", Chain(http.HandlerFunc(pprof.Cmdline), admin...))
+    mux.Handle("GET /debug/pprof/profile", Chain(withoutTimeout(http.HandlerFunc(pprof.Profile)), admin...))
+    mux.Handle("GET /debug/pprof/symbol", Chain(http.HandlerFunc(pprof.Symbol), admin...))
+    mux.Handle("POST /debug/pprof/symbol", Chain(http.HandlerFunc(pprof.Symbol), admin...))
+    mux.Handle("GET /debug/pprof/trace", Chain(withoutTimeout(http.HandlerFunc(pprof.Trace)), admin...))
+}
+
+type runtimeStatsResponse struct {
+    XMLName           xml.Name   `json:"-" xml:"runtime"`
+    UptimeSeconds     int64      `json:"uptime_seconds" xml:"uptime_seconds"`
+    Goroutines        int        `json:"goroutines" xml:"goroutines"`
+    HeapAllocBytes    uint64     `json:"heap_alloc_bytes" xml:"heap_alloc_bytes"`
+    HeapInuseBytes    uint64     `json:"heap_inuse_bytes" xml:"heap_inuse_bytes"`
+    HeapObjects       uint64     `json:"heap_objects" xml:"heap_objects"`
+    SysBytes          uint64     `json:"sys_bytes" xml:"sys_bytes"`
+    GCCycles          uint32     `json:"gc_cycles" xml:"gc_cycles"`
+    GCPauseTotalMS    float64    `json:"gc_pause_total_ms" xml:"gc_pause_total_ms"`
+    LastGC            *time.Time `json:"last_gc,omitempty" xml:"last_gc,omitempty"`
+    Comments          int        `json:"comments" xml:"comments"`
+    StoreMemoryBytes  int64      `json:"store_memory_bytes" xml:"store_memory_bytes"`
+    LogEntriesDropped uint64     `json:"log_entries_dropped" xml:"log_entries_dropped"`
+
+    StoreOperations storeOperations `json:"store_operations" xml:"store_operations"`
+}
+
+// storeOperations reports the calls made to each store method, keyed by
+// method name.
+type storeOperations map[string]storeOperationResponse
+
+func (m storeOperations) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+    return marshalXMLEntries(e, start, m)
+}
+
+type storeOperationResponse struct {
+    Calls   int64   `json:"calls" xml:"calls"`
+    Errors  int64   `json:"errors" xml:"errors"`
+    Slow    int64   `json:"slow" xml:"slow"`
+    TotalMS float64 `json:"total_ms" xml:"total_ms"`
+    MaxMS   float64 `json:"max_ms" xml:"max_ms"`
+}
+
+// Runtime stats handler. Reports the process's goroutines, heap and GC
+// activity alongside the size of the comment store, for diagnosing memory
+// growth without a profiler, and counts the log entries a buffered logger
+// has dropped. The calls, failures and latency of each store method show
+// where time in the store goes; they come from metrics, and are left out
+// when it is nil. Uptime counts from when started.
+func handleRuntimeStats(logger *logging.Logger, store storage.CommentRepository, metrics *storage.Metrics, started time.Time) http.Handler {
+    if metrics == nil {
+        metrics = storage.NewMetrics(0)
+    }
+
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+
+        count, err := store.Count(ctx)
+        if err != nil {
+            logger.Error(ctx, "failed to count comments", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+        usage, err := store.MemoryUsage(ctx)
+        if err != nil {
+            logger.Error(ctx, "failed to read store memory usage", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        var mem runtime.MemStats
+        runtime.ReadMemStats(&mem)
+        resp := runtimeStatsResponse{
+            UptimeSeconds:     int64(time.Since(started).Seconds()),
+            Goroutines:        runtime.NumGoroutine(),
+            HeapAllocBytes:    mem.HeapAlloc,
+            HeapInuseBytes:    mem.HeapInuse,
+            HeapObjects:       mem.HeapObjects,
+            SysBytes:          mem.Sys,
+            GCCycles:          mem.NumGC,
+            GCPauseTotalMS:    float64(mem.PauseTotalNs) / float64(time.Millisecond),
+            Comments:          count,
+            StoreMemoryBytes:  usage,
+            LogEntriesDropped: logger.Dropped(),
+            StoreOperations:   make(storeOperations),
+        }
+        for method, op := range metrics.Operations() {
+            resp.StoreOperations[method] = storeOperationResponse{
+                Calls:   op.Calls,
+                Errors:  op.Errors,
+                Slow:    op.Slow,
+                TotalMS: float64(op.Total) / float64(time.Millisecond),
+                MaxMS:   float64(op.Max) / float64(time.Millisecond),
+            }
+        }
+        if mem.LastGC > 0 {
+            last := time.Unix(0, int64(mem.LastGC)).UTC()
+            resp.LastGC = &last
+        }
+
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            logger.Error(ctx, "failed to encode response", "error", err)
+        }
+    })
+}
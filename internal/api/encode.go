@@ -3,40 +3,352 @@
 package api
 
 import (
+    "bytes"
     "context"
     "encoding/json"
+    "encoding/xml"
+    "errors"
     "fmt"
+    "golang.org/x/text/unicode/norm"
+    "io"
+    "mime"
     "net/http"
+    "reflect"
+    "strconv"
+    "strings"
+    "web-service/internal/storage"
+    "web-service/internal/tracing"
 )
 
 // Validator interface as described in the article
 type Validator interface {
-    Valid(ctx context.Context) map[string]string
+    Valid(ctx context.Context) map[string][]string
 }
 
-// encode encodes the response. The _r parameter is reserved for future use
-// (content negotiation, request-specific encoding options, etc.)
-func encode[T any](w http.ResponseWriter, _r *http.Request, status int, v T) error {
-    w.Header().Set("Content-Type", "application/json")
+// normalizer is implemented by requests that normalize their text before
+// being validated, so validation sees what will be stored. Settings that
+// affect normalization come from ctx.
+type normalizer interface {
+    normalize(ctx context.Context)
+}
+
+// normalizeText puts s in Unicode Normalization Form C, so text that looks
+// the same compares, counts and searches the same however it was composed.
+func normalizeText(s string) string {
+    return norm.NFC.String(s)
+}
+
+// encode encodes the response in the format negotiated from the request's
+// Accept header, or by newNegotiationMiddleware before the handler ran.
+// JSON is used when the client expresses no preference; if no supported
+// format is acceptable a 406 is written instead of v. JSON field
+// names follow the request's X-Field-Case header.
+func encode[T any](w http.ResponseWriter, r *http.Request, status int, v T) error {
+    if mediaType, ok := r.Context().Value(mediaTypeKey).(string); ok {
+        return writeBody(w, r, status, mediaType, v)
+    }
+    mediaType, ok := negotiate(r)
+    if !ok {
+        encodeNotAcceptable(w, r)
+        return nil
+    }
+    return writeBody(w, r, status, mediaType, v)
+}
+
+// encodeNotAcceptable writes a 406 listing the supported media types.
+// Caching headers a handler set for the body it couldn't send are dropped.
+func encodeNotAcceptable(w http.ResponseWriter, r *http.Request) {
+    w.Header().Del("Cache-Control")
+    w.Header().Del("ETag")
+    w.Header().Del("Last-Modified")
+    encodeError(w, r, http.StatusNotAcceptable, codeNotAcceptable, "not acceptable; supported types: "+strings.Join(supportedMediaTypes, ", "))
+}
+
+// writeBody writes v as mediaType with status. Plain JSON field names
+// follow the request's X-Field-Case header. v is encoded before anything is
+// written, so a value that fails to encode gets a 500 problem rather than
+// status with a truncated body; the encoding error is still returned for
+// the caller to log.
+func writeBody(w http.ResponseWriter, r *http.Request, status int, mediaType string, v any) error {
+    var buf bytes.Buffer
+    if err := encodeBody(&buf, r, mediaType, v); err != nil {
+        switch v.(type) {
+        case Problem, errorResponse:
+            // Only a problem failing to encode could bring us back here
+            http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+        default:
+            // Caching headers describe the body that failed, not the 500
+            w.Header().Del("Cache-Control")
+            w.Header().Del("ETag")
+            w.Header().Del("Last-Modified")
+            encodeInternalError(w, r, err)
+        }
+        return err
+    }
+
+    w.Header().Set("Content-Type", mediaType)
+    w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+    w.Header().Add("Vary", "Accept")
+    w.Header().Add("Vary", fieldCaseHeader)
     w.WriteHeader(status)
-    if err := json.NewEncoder(w).Encode(v); err != nil {
-        return fmt.Errorf("encode json: %w", err)
+    _, err := buf.WriteTo(w)
+    return err
+}
+
+// encodeBody encodes v to buf as mediaType.
+func encodeBody(buf *bytes.Buffer, r *http.Request, mediaType string, v any) error {
+    switch mediaType {
+    case mediaTypeXML, mediaTypeProblemXML:
+        buf.WriteString(xml.Header)
+        if err := xml.NewEncoder(buf).Encode(xmlValue(v)); err != nil {
+            return fmt.Errorf("encode xml: %w", err)
+        }
+    default:
+        out := v
+        if mediaType == mediaTypeJSON && camelCase(r) {
+            out = camelJSON{V: v}
+        }
+        if err := json.NewEncoder(buf).Encode(out); err != nil {
+            return fmt.Errorf("encode json: %w", err)
+        }
+    }
+    return nil
+}
+
+// Error codes carried in error responses, so clients can branch on the
+// kind of failure without parsing messages.
+const (
+    codeInvalidRequest      = "invalid_request"
+    codeUnauthorized        = "unauthorized"
+    codeForbidden           = "forbidden"
+    codeNotFound            = "not_found"
+    codeMethodNotAllowed    = "method_not_allowed"
+    codeNotAcceptable       = "not_acceptable"
+    codeUnsupportedMedia    = "unsupported_media_type"
+    codeDuplicate           = "duplicate_content"
+    codeIdempotencyConflict = "idempotency_conflict"
+    codeVersionConflict     = "version_conflict"
+    codeRateLimited         = "rate_limited"
+    codeTimeout             = "timeout"
+    codeUnavailable         = "unavailable"
+    codeInternal            = "internal"
+)
+
+// errorResponse is the body of error responses before problem details,
+// still written when config.ErrorFormat is legacy:
+//
+//	{"error": {"code": "not_found", "message": "Comment not found"}}
+type errorResponse struct {
+    XMLName xml.Name  `json:"-" xml:"response"`
+    Error   errorBody `json:"error" xml:"error"`
+}
+
+type errorBody struct {
+    Code      string     `json:"code" xml:"code"`
+    Message   string     `json:"message" xml:"message"`
+    Details   problemMap `json:"details,omitempty" xml:"details,omitempty"`
+    RequestID string     `json:"request_id,omitempty" xml:"request_id,omitempty"`
+
+    // CurrentVersion is the comment's version when an update conflicted.
+    CurrentVersion int64 `json:"current_version,omitempty" xml:"current_version,omitempty"`
+    // Position and Expected say where a search query failed to parse.
+    Position *int     `json:"position,omitempty" xml:"position,omitempty"`
+    Expected []string `json:"expected,omitempty" xml:"expected,omitempty"`
+}
+
+// problemMap holds validation problems keyed by field name. A field can
+// have several problems, listed in the order they were found.
+type problemMap map[string][]string
+
+// add records problem with field, after any already reported.
+func (m problemMap) add(field, problem string) {
+    m[field] = append(m[field], problem)
+}
+
+func (m problemMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+    return marshalXMLEntries(e, start, m)
+}
+
+// encodeError writes an error response with status, code and message.
+func encodeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+    writeProblem(w, r, newProblem(r, status, code, message))
+}
+
+// encodeBadRequest writes a 400 for a request that failed to decode or
+// validate, listing any validation problems as details. Without them, a
+// decodeError's problems are listed instead. A body that isn't JSON at all
+// gets 415.
+func encodeBadRequest(w http.ResponseWriter, r *http.Request, err error, problems map[string][]string) {
+    if errors.Is(err, errUnsupportedMediaType) {
+        encodeError(w, r, http.StatusUnsupportedMediaType, codeUnsupportedMedia, "Request body must be "+mediaTypeJSON)
+        return
+    }
+    var de *decodeError
+    if len(problems) == 0 && errors.As(err, &de) {
+        problems = de.problems
+    }
+    if len(problems) > 0 {
+        encodeProblems(w, r, problems)
+        return
+    }
+    encodeError(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+}
+
+// encodeProblems writes a 400 listing validation problems keyed by field.
+func encodeProblems(w http.ResponseWriter, r *http.Request, problems map[string][]string) {
+    p := newProblem(r, http.StatusBadRequest, codeInvalidRequest, "Request has invalid fields")
+    p.addInvalidParams(problems)
+    writeProblem(w, r, p)
+}
+
+// encodeInternalError writes a 500 carrying the request ID for err, or a
+// 503 when err is transient: the store was unavailable or the request ran
+// past its timeout.
+func encodeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+    status, code, msg := http.StatusInternalServerError, codeInternal, "Internal Server Error"
+    switch {
+    case errors.Is(err, context.DeadlineExceeded), errors.Is(r.Context().Err(), context.DeadlineExceeded):
+        status, code, msg = http.StatusServiceUnavailable, codeTimeout, "Request timed out"
+    case errors.Is(err, storage.ErrUnavailable):
+        status, code, msg = http.StatusServiceUnavailable, codeUnavailable, "Service Unavailable"
+    }
+    if status == http.StatusServiceUnavailable {
+        w.Header().Set("Retry-After", "1")
+    }
+    tracing.RecordError(r.Context(), err)
+    encodeError(w, r, status, code, msg)
+}
+
+// requestBody returns r's body, with camelCase keys rewritten to snake_case
+// when the request selected camelCase field names.
+func requestBody(r *http.Request) (io.Reader, error) {
+    if !camelCase(r) {
+        return r.Body, nil
+    }
+    return snakeBody(r.Body)
+}
+
+// decodeError is a request body that isn't the JSON a handler expects,
+// with problems describing what is wrong in terms a client can act on.
+// encodeBadRequest reports the problems in place of the raw error, which
+// names Go types.
+type decodeError struct {
+    err      error
+    problems problemMap
+}
+
+func (e *decodeError) Error() string { return "decode json: " + e.err.Error() }
+
+func (e *decodeError) Unwrap() error { return e.err }
+
+// errUnsupportedMediaType is returned when decoding a body whose
+// Content-Type isn't JSON.
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// checkContentType requires a request with a body to declare it as JSON,
+// optionally with a UTF-8 charset, so a client sending form data hears so
+// rather than getting a decode error. A bodiless request with no
+// Content-Type passes, to be reported as missing its body.
+func checkContentType(r *http.Request) error {
+    ct := r.Header.Get("Content-Type")
+    if ct == "" && r.ContentLength == 0 {
+        return nil
+    }
+    mediaType, params, err := mime.ParseMediaType(ct)
+    if err != nil || mediaType != mediaTypeJSON {
+        return fmt.Errorf("%w %q", errUnsupportedMediaType, ct)
+    }
+    if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+        return fmt.Errorf("%w %q", errUnsupportedMediaType, ct)
     }
     return nil
 }
 
+// decodeBody decodes r's body into v, rejecting fields v doesn't have so
+// misspelled fields aren't silently ignored.
+func decodeBody(r *http.Request, v any) error {
+    if err := checkContentType(r); err != nil {
+        return err
+    }
+    body, err := requestBody(r)
+    if err != nil {
+        return fmt.Errorf("read body: %w", err)
+    }
+    dec := json.NewDecoder(body)
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(v); err != nil {
+        return &decodeError{err: err, problems: jsonProblems(err)}
+    }
+    return nil
+}
+
+// jsonProblems describes a JSON decoding error as problems keyed by the
+// offending field, or by "body" when the error isn't about one field.
+func jsonProblems(err error) problemMap {
+    problems := make(problemMap)
+    var syntaxErr *json.SyntaxError
+    var typeErr *json.UnmarshalTypeError
+    switch {
+    case errors.Is(err, io.EOF):
+        problems.add("body", "request body is required")
+    case errors.Is(err, io.ErrUnexpectedEOF):
+        problems.add("body", "request body is not valid JSON: unexpected end of input")
+    case errors.As(err, &syntaxErr):
+        problems.add("body", fmt.Sprintf("request body is not valid JSON: error at byte %d", syntaxErr.Offset))
+    case errors.As(err, &typeErr) && typeErr.Field == "":
+        problems.add("body", "request body must be "+jsonType(typeErr.Type))
+    case errors.As(err, &typeErr):
+        problems.add(typeErr.Field, fmt.Sprintf("%s must be %s, got %s", typeErr.Field, jsonType(typeErr.Type), typeErr.Value))
+    case strings.HasPrefix(err.Error(), "json: unknown field "):
+        // encoding/json has no error type for unknown fields
+        field, uerr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+        if uerr != nil {
+            field = strings.TrimPrefix(err.Error(), "json: unknown field ")
+        }
+        problems.add(field, "unknown field "+field)
+    default:
+        problems.add("body", "request body could not be decoded")
+    }
+    return problems
+}
+
+// jsonType names the JSON type a Go type decodes from, with an article.
+func jsonType(t reflect.Type) string {
+    for t.Kind() == reflect.Pointer {
+        t = t.Elem()
+    }
+    switch t.Kind() {
+    case reflect.String:
+        return "a string"
+    case reflect.Bool:
+        return "a boolean"
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return "an integer"
+    case reflect.Float32, reflect.Float64:
+        return "a number"
+    case reflect.Slice, reflect.Array:
+        return "an array"
+    case reflect.Map, reflect.Struct:
+        return "an object"
+    default:
+        return "a different type"
+    }
+}
+
 func decode[T any](r *http.Request) (T, error) {
     var v T
-    if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
-        return v, fmt.Errorf("decode json: %w", err)
-    }
-    return v, nil
+    err := decodeBody(r, &v)
+    return v, err
 }
 
-func decodeValid[T Validator](r *http.Request) (T, map[string]string, error) {
+func decodeValid[T Validator](r *http.Request) (T, map[string][]string, error) {
     var v T
-    if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
-        return v, nil, fmt.Errorf("decode json: %w", err)
+    if err := decodeBody(r, &v); err != nil {
+        return v, nil, err
+    }
+    if n, ok := any(&v).(normalizer); ok {
+        n.normalize(r.Context())
     }
     if problems := v.Valid(r.Context()); len(problems) > 0 {
         return v, problems, fmt.Errorf("invalid %T: %d problems", v, len(problems))
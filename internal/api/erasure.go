@@ -0,0 +1,126 @@
+// internal/api/erasure.go
+
+package api
+
+import (
+    "cmp"
+    "crypto/rand"
+    "encoding/xml"
+    "errors"
+    "net/http"
+    "time"
+    "web-service/internal/config"
+    "web-service/internal/erasure"
+    "web-service/pkg/logging"
+)
+
+type erasureStoreResponse struct {
+    Store      string `json:"store" xml:"store"`
+    Removed    int    `json:"removed" xml:"removed"`
+    Anonymized int    `json:"anonymized" xml:"anonymized"`
+}
+
+type erasureReceiptResponse struct {
+    XMLName     xml.Name               `json:"-" xml:"erasure_receipt"`
+    ID          string                 `json:"id" xml:"id"`
+    Subject     string                 `json:"subject" xml:"subject"`
+    Stores      []erasureStoreResponse `json:"stores" xml:"stores>store"`
+    Removed     int                    `json:"removed" xml:"removed"`
+    Anonymized  int                    `json:"anonymized" xml:"anonymized"`
+    StartedAt   time.Time              `json:"started_at" xml:"started_at"`
+    CompletedAt time.Time              `json:"completed_at" xml:"completed_at"`
+    Signature   string                 `json:"signature" xml:"signature"`
+}
+
+func toErasureReceiptResponse(r erasure.Receipt) erasureReceiptResponse {
+    resp := erasureReceiptResponse{
+        ID:          r.ID,
+        Subject:     r.Subject,
+        Stores:      make([]erasureStoreResponse, len(r.Stores)),
+        Removed:     r.Removed(),
+        Anonymized:  r.Anonymized(),
+        StartedAt:   r.StartedAt,
+        CompletedAt: r.CompletedAt,
+        Signature:   r.Signature,
+    }
+    for i, s := range r.Stores {
+        resp.Stores[i] = erasureStoreResponse{Store: s.Store, Removed: s.Removed, Anonymized: s.Anonymized}
+    }
+    return resp
+}
+
+// erasureKey returns the key erasure receipts are signed with:
+// config.ErasureKey, else the JWT secret, else a random key that lasts as
+// long as the process.
+func erasureKey(config *config.Config) []byte {
+    if key := cmp.Or(config.ErasureKey, config.JWTSecret); key != "" {
+        return []byte(key)
+    }
+    key := make([]byte, 32)
+    rand.Read(key)
+    return key
+}
+
+// Erase user handler. Walks every registered store and returns the signed
+// receipt. If some stores fail it returns 503 naming them; repeating the
+// request resumes with just those.
+func handleEraseUser(logger *logging.Logger, erasures *erasure.Service) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        adminID := UserIDFromContext(ctx)
+        userID := r.PathValue("id")
+        subject := erasures.Subject(userID)
+
+        receipt, err := erasures.Erase(ctx, userID)
+        var incomplete *erasure.IncompleteError
+        if errors.As(err, &incomplete) {
+            details := make(problemMap, len(incomplete.Failed))
+            for store, err := range incomplete.Failed {
+                logger.Error(ctx, "user erasure failed in store",
+                    "error", err,
+                    "store", store,
+                    "subject", subject,
+                    "admin_id", adminID,
+                )
+                details.add(store, "erasure failed; retry to resume")
+            }
+            p := newProblem(r, http.StatusServiceUnavailable, codeUnavailable, "Erasure incomplete; retry to resume")
+            p.addInvalidParams(details)
+            w.Header().Set("Retry-After", "1")
+            writeProblem(w, r, p)
+            return
+        }
+        if err != nil {
+            logger.Error(ctx, "user erasure failed", "error", err, "subject", subject)
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        // Audit by subject hash only, so the log doesn't keep the identity
+        // that was just erased
+        logger.Info(ctx, "user erased",
+            "subject", subject,
+            "receipt_id", receipt.ID,
+            "removed", receipt.Removed(),
+            "anonymized", receipt.Anonymized(),
+            "admin_id", adminID,
+        )
+        if err := encode(w, r, http.StatusOK, toErasureReceiptResponse(receipt)); err != nil {
+            logger.Error(ctx, "failed to encode response", "error", err)
+        }
+    })
+}
+
+// Get erasure receipt handler
+func handleGetErasureReceipt(logger *logging.Logger, erasures *erasure.Service) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        receipt, ok := erasures.Receipt(r.PathValue("id"))
+        if !ok {
+            encodeError(w, r, http.StatusNotFound, codeNotFound, "Erasure receipt not found")
+            return
+        }
+        if err := encode(w, r, http.StatusOK, toErasureReceiptResponse(receipt)); err != nil {
+            logger.Error(r.Context(), "failed to encode response", "error", err)
+        }
+    })
+}
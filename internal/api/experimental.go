@@ -0,0 +1,113 @@
+// internal/api/experimental.go
+
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+const (
+    experimentalPrefix = "/api/experimental"
+
+    // enableExperimentalHeader must name the experiment being called, so
+    // clients can't come to depend on an experiment by accident.
+    enableExperimentalHeader = "X-Enable-Experimental"
+)
+
+// experimentalEndpoint describes a mounted experimental route. They are
+// kept out of the stable API description.
+type experimentalEndpoint struct {
+    Name   string
+    Method string
+    Path   string
+}
+
+// experimentalRoutes mounts routes under /api/experimental/ only when their
+// experiment is enabled in config, each wrapped in middleware.
+type experimentalRoutes struct {
+    mux        *http.ServeMux
+    enabled    map[string]bool
+    middleware []Middleware
+    endpoints  []experimentalEndpoint
+}
+
+func newExperimentalRoutes(mux *http.ServeMux, enabled []string, mw ...Middleware) *experimentalRoutes {
+    e := &experimentalRoutes{
+        mux:        mux,
+        enabled:    make(map[string]bool, len(enabled)),
+        middleware: mw,
+    }
+    for _, name := range enabled {
+        e.enabled[name] = true
+    }
+    return e
+}
+
+// handle mounts h for experiment name at pattern, a method and path relative
+// to /api/experimental such as "GET /threads/{id}". It does nothing unless
+// the experiment is enabled.
+func (e *experimentalRoutes) handle(name, pattern string, h http.Handler) {
+    if !e.enabled[name] {
+        return
+    }
+    method, path, ok := strings.Cut(pattern, " ")
+    if !ok {
+        method, path = "", pattern
+    }
+    e.endpoints = append(e.endpoints, experimentalEndpoint{Name: name, Method: method, Path: experimentalPrefix + path})
+    e.mux.Handle(strings.TrimSpace(method+" "+experimentalPrefix+path), Chain(requireExperimentOptIn(name, h), e.middleware...))
+}
+
+// unknown returns enabled experiments that no route was registered for,
+// which usually means a typo in EXPERIMENTAL_FEATURES.
+func (e *experimentalRoutes) unknown() []string {
+    registered := make(map[string]bool)
+    for _, ep := range e.endpoints {
+        registered[ep.Name] = true
+    }
+    var names []string
+    for name := range e.enabled {
+        if !registered[name] {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// requireExperimentOptIn marks responses as experimental and rejects calls
+// that don't opt in to experiment name via X-Enable-Experimental.
+func requireExperimentOptIn(name string, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("X-Experimental", "true")
+        w.Header().Set("Warning", fmt.Sprintf(`299 - "Experimental endpoint %s may change or be removed without notice"`, name))
+
+        optedIn := false
+        for _, v := range strings.Split(r.Header.Get(enableExperimentalHeader), ",") {
+            if strings.TrimSpace(v) == name {
+                optedIn = true
+                break
+            }
+        }
+        if !optedIn {
+            encodeError(w, r, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("experimental endpoint requires %s: %s", enableExperimentalHeader, name))
+            return
+        }
+
+        next.ServeHTTP(w, r)
+    })
+}
+
+// addExperimentalRoutes registers every experimental endpoint. Each one is a
+// single exp.handle call naming its experiment, e.g.
+//
+//	exp.handle("threads-v2", "GET /threads/{id}", handleThreadV2(logger, commentStore))
+//
+// No experiments are currently running.
+func addExperimentalRoutes(exp *experimentalRoutes, logger *logging.Logger, commentStore storage.CommentRepository) {
+}
@@ -0,0 +1,67 @@
+// internal/api/experimental_test.go
+
+package api
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "testing"
+)
+
+func TestExperimentalRoutes(t *testing.T) {
+    ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+    })
+
+    tests := []struct {
+        name        string
+        enabled     []string
+        optIn       string
+        wantStatus  int
+        wantHeaders bool
+    }{
+        {name: "disabled is not mounted", enabled: nil, optIn: "threads-v2", wantStatus: http.StatusNotFound},
+        {name: "enabled without opt-in", enabled: []string{"threads-v2"}, wantStatus: http.StatusBadRequest, wantHeaders: true},
+        {name: "enabled with other opt-in", enabled: []string{"threads-v2"}, optIn: "graphql", wantStatus: http.StatusBadRequest, wantHeaders: true},
+        {name: "enabled with opt-in", enabled: []string{"threads-v2"}, optIn: "threads-v2", wantStatus: http.StatusOK, wantHeaders: true},
+        {name: "opt-in list", enabled: []string{"threads-v2"}, optIn: "graphql, threads-v2", wantStatus: http.StatusOK, wantHeaders: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            mux := http.NewServeMux()
+            exp := newExperimentalRoutes(mux, tt.enabled)
+            exp.handle("threads-v2", "GET /threads/{id}", ok)
+
+            req := httptest.NewRequest(http.MethodGet, "/api/experimental/threads/42", nil)
+            if tt.optIn != "" {
+                req.Header.Set(enableExperimentalHeader, tt.optIn)
+            }
+            rec := httptest.NewRecorder()
+            mux.ServeHTTP(rec, req)
+
+            if rec.Code != tt.wantStatus {
+                t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+            }
+            gotHeaders := rec.Header().Get("X-Experimental") == "true" && rec.Header().Get("Warning") != ""
+            if gotHeaders != tt.wantHeaders {
+                t.Errorf("expected experimental headers %v, got X-Experimental %q, Warning %q",
+                    tt.wantHeaders, rec.Header().Get("X-Experimental"), rec.Header().Get("Warning"))
+            }
+        })
+    }
+}
+
+func TestExperimentalRoutesUnknown(t *testing.T) {
+    exp := newExperimentalRoutes(http.NewServeMux(), []string{"threads-v2", "thraeds-v2"})
+    exp.handle("threads-v2", "GET /threads/{id}", http.NotFoundHandler())
+
+    if got := exp.unknown(); !reflect.DeepEqual(got, []string{"thraeds-v2"}) {
+        t.Errorf("expected the typo to be reported, got %v", got)
+    }
+    want := []experimentalEndpoint{{Name: "threads-v2", Method: "GET", Path: "/api/experimental/threads/{id}"}}
+    if !reflect.DeepEqual(exp.endpoints, want) {
+        t.Errorf("expected endpoints %v, got %v", want, exp.endpoints)
+    }
+}
@@ -0,0 +1,94 @@
+// internal/api/faults.go
+
+package api
+
+import (
+    "encoding/xml"
+    "fmt"
+    "net/http"
+    "time"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// faultSpec is the wire form of a storage.Fault. Latency is a Go duration
+// string such as "250ms".
+type faultSpec struct {
+    Method      string   `json:"method,omitempty" xml:"method,omitempty"`
+    IDs         []string `json:"ids,omitempty" xml:"ids>id,omitempty"`
+    Probability float64  `json:"probability,omitempty" xml:"probability,omitempty"`
+    Latency     string   `json:"latency,omitempty" xml:"latency,omitempty"`
+    Fail        bool     `json:"fail,omitempty" xml:"fail,omitempty"`
+}
+
+type faultsBody struct {
+    XMLName xml.Name    `json:"-" xml:"faults"`
+    Faults  []faultSpec `json:"faults" xml:"fault"`
+}
+
+func toFaultsBody(faults []storage.Fault) faultsBody {
+    body := faultsBody{Faults: make([]faultSpec, len(faults))}
+    for i, f := range faults {
+        body.Faults[i] = faultSpec{
+            Method:      f.Method,
+            IDs:         f.IDs,
+            Probability: f.Probability,
+            Fail:        f.Fail,
+        }
+        if f.Latency > 0 {
+            body.Faults[i].Latency = f.Latency.String()
+        }
+    }
+    return body
+}
+
+// List injected storage faults handler
+func handleListFaults(logger *logging.Logger, faults *storage.FaultInjector) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if err := encode(w, r, http.StatusOK, toFaultsBody(faults.Faults())); err != nil {
+            logger.Error(r.Context(), "failed to encode response", "error", err)
+        }
+    })
+}
+
+// Replace injected storage faults handler. An empty list clears them.
+func handleSetFaults(logger *logging.Logger, faults *storage.FaultInjector) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+
+        req, err := decode[faultsBody](r)
+        if err != nil {
+            encodeBadRequest(w, r, err, nil)
+            return
+        }
+
+        parsed := make([]storage.Fault, len(req.Faults))
+        for i, spec := range req.Faults {
+            parsed[i] = storage.Fault{
+                Method:      spec.Method,
+                IDs:         spec.IDs,
+                Probability: spec.Probability,
+                Fail:        spec.Fail,
+            }
+            if spec.Latency != "" {
+                if parsed[i].Latency, err = time.ParseDuration(spec.Latency); err != nil {
+                    encodeError(w, r, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("fault %d: invalid latency %q", i, spec.Latency))
+                    return
+                }
+            }
+        }
+        if err := faults.Set(parsed); err != nil {
+            encodeError(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+            return
+        }
+
+        logger.Warn(ctx, "storage faults updated",
+            "faults", len(parsed),
+            "user_id", userID,
+        )
+        if err := encode(w, r, http.StatusOK, toFaultsBody(faults.Faults())); err != nil {
+            logger.Error(ctx, "failed to encode response", "error", err)
+        }
+    })
+}
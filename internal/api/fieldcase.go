@@ -0,0 +1,174 @@
+// internal/api/fieldcase.go
+
+package api
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "reflect"
+    "strings"
+    "unicode"
+)
+
+// fieldCaseHeader selects the JSON field naming used for a request and its
+// response: "snake" (the default) or "camel".
+const fieldCaseHeader = "X-Field-Case"
+
+// camelCase reports whether r asked for camelCase field names.
+func camelCase(r *http.Request) bool {
+    return r != nil && strings.EqualFold(strings.TrimSpace(r.Header.Get(fieldCaseHeader)), "camel")
+}
+
+// snakeToCamel converts created_at to createdAt.
+func snakeToCamel(s string) string {
+    parts := strings.Split(s, "_")
+    for i := 1; i < len(parts); i++ {
+        if parts[i] != "" {
+            parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+        }
+    }
+    return strings.Join(parts, "")
+}
+
+// camelToSnake converts createdAt to created_at.
+func camelToSnake(s string) string {
+    var b strings.Builder
+    for i, r := range s {
+        if unicode.IsUpper(r) {
+            if i > 0 {
+                b.WriteByte('_')
+            }
+            r = unicode.ToLower(r)
+        }
+        b.WriteRune(r)
+    }
+    return b.String()
+}
+
+// camelJSON marshals V with struct field names converted to camelCase.
+// Keys of data maps (such as per-author counts) are left as they are; only
+// problem maps, which are keyed by field, and string-to-string maps have
+// their keys converted too.
+type camelJSON struct {
+    V any
+}
+
+var (
+    jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+    stringMapType     = reflect.TypeOf(map[string]string(nil))
+    problemMapType    = reflect.TypeOf(problemMap(nil))
+)
+
+func (c camelJSON) MarshalJSON() ([]byte, error) {
+    return json.Marshal(camelValue(reflect.ValueOf(c.V)))
+}
+
+// camelValue rebuilds v as plain maps and slices with camelCase keys.
+func camelValue(v reflect.Value) any {
+    if !v.IsValid() {
+        return nil
+    }
+    if v.Type().Implements(jsonMarshalerType) {
+        return v.Interface()
+    }
+
+    switch v.Kind() {
+    case reflect.Pointer, reflect.Interface:
+        if v.IsNil() {
+            return nil
+        }
+        return camelValue(v.Elem())
+    case reflect.Struct:
+        out := make(map[string]any)
+        for i := 0; i < v.NumField(); i++ {
+            field := v.Type().Field(i)
+            if !field.IsExported() {
+                continue
+            }
+            name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+            if name == "-" {
+                continue
+            }
+            if name == "" {
+                name = field.Name
+            }
+            fv := v.Field(i)
+            if strings.Contains(opts, "omitempty") && isEmptyValue(fv) {
+                continue
+            }
+            out[snakeToCamel(name)] = camelValue(fv)
+        }
+        return out
+    case reflect.Slice, reflect.Array:
+        if v.Kind() == reflect.Slice && v.IsNil() {
+            return nil
+        }
+        out := make([]any, v.Len())
+        for i := range out {
+            out[i] = camelValue(v.Index(i))
+        }
+        return out
+    case reflect.Map:
+        if v.Type().ConvertibleTo(stringMapType) {
+            m := v.Convert(stringMapType).Interface().(map[string]string)
+            out := make(map[string]string, len(m))
+            for k, val := range m {
+                out[snakeToCamel(k)] = val
+            }
+            return out
+        }
+        if v.Type().ConvertibleTo(problemMapType) {
+            m := v.Convert(problemMapType).Interface().(problemMap)
+            out := make(problemMap, len(m))
+            for k, val := range m {
+                out[snakeToCamel(k)] = val
+            }
+            return out
+        }
+        return v.Interface()
+    default:
+        return v.Interface()
+    }
+}
+
+// isEmptyValue mirrors encoding/json's omitempty rules.
+func isEmptyValue(v reflect.Value) bool {
+    switch v.Kind() {
+    case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+        return v.Len() == 0
+    case reflect.Bool,
+        reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+        reflect.Float32, reflect.Float64,
+        reflect.Interface, reflect.Pointer:
+        return v.IsZero()
+    }
+    return false
+}
+
+// snakeBody rewrites a camelCase JSON object body to snake_case keys so it
+// decodes into the request structs. Bodies that aren't objects are passed
+// through for the decoder to reject.
+func snakeBody(body io.Reader) (io.Reader, error) {
+    data, err := io.ReadAll(body)
+    if err != nil {
+        return nil, err
+    }
+
+    var fields map[string]json.RawMessage
+    if err := json.Unmarshal(data, &fields); err != nil {
+        return bytes.NewReader(data), nil
+    }
+    snake := make(map[string]json.RawMessage, len(fields))
+    for k, v := range fields {
+        snake[camelToSnake(k)] = v
+    }
+
+    data, err = json.Marshal(snake)
+    if err != nil {
+        return nil, err
+    }
+    return bytes.NewReader(data), nil
+}
@@ -0,0 +1,202 @@
+// internal/api/flags.go
+
+package api
+
+import (
+    "context"
+    "encoding/xml"
+    "net/http"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+const maxFlagReasonLength = 500
+
+type flagRequest struct {
+    Reason string `json:"reason,omitempty"`
+}
+
+var _ normalizer = (*flagRequest)(nil)
+
+func (r *flagRequest) normalize(ctx context.Context) {
+    r.Reason = strings.TrimSpace(normalizeText(r.Reason))
+}
+
+func (r flagRequest) Valid(ctx context.Context) map[string][]string {
+    problems := make(problemMap)
+    if utf8.RuneCountInString(r.Reason) > maxFlagReasonLength {
+        problems.add("reason", "reason must be at most "+strconv.Itoa(maxFlagReasonLength)+" characters")
+    }
+    return problems
+}
+
+type flagResponse struct {
+    XMLName   xml.Name  `json:"-" xml:"flag"`
+    CommentID string    `json:"comment_id" xml:"comment_id"`
+    UserID    string    `json:"user_id" xml:"user_id"`
+    Reason    string    `json:"reason,omitempty" xml:"reason,omitempty"`
+    CreatedAt timestamp `json:"created_at" xml:"created_at"`
+}
+
+func toFlagResponse(f storage.Flag) flagResponse {
+    return flagResponse{
+        CommentID: f.CommentID,
+        UserID:    f.UserID,
+        Reason:    f.Reason,
+        CreatedAt: timestamp(f.CreatedAt),
+    }
+}
+
+type flaggedCommentResponse struct {
+    Comment       commentResponse `json:"comment" xml:"comment"`
+    FlagCount     int             `json:"flag_count" xml:"flag_count"`
+    LastFlaggedAt timestamp       `json:"last_flagged_at" xml:"last_flagged_at"`
+    Flags         []flagResponse  `json:"flags" xml:"flags>flag"`
+}
+
+type flaggedCommentsResponse struct {
+    XMLName  xml.Name                 `json:"-" xml:"flagged_comments"`
+    Comments []flaggedCommentResponse `json:"comments" xml:"comment"`
+}
+
+// addFlagCounts fills in the flag count on each of resp for admins, the
+// only ones shown it.
+func addFlagCounts(ctx context.Context, store storage.CommentRepository, resp []commentResponse) error {
+    if UserRoleFromContext(ctx) != "admin" {
+        return nil
+    }
+    counts, err := store.Flags().Counts(ctx)
+    if err != nil {
+        return err
+    }
+    for i := range resp {
+        n := counts[resp[i].ID]
+        resp[i].FlagCount = &n
+    }
+    return nil
+}
+
+// Flag comment handler. Reports a comment for moderation with an optional
+// reason. A user flags a comment once: repeating the request returns 200
+// with their original flag rather than 201.
+func handleFlagComment(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        commentID := r.PathValue("id")
+        l := logger.With("comment_id", commentID, "user_id", userID)
+
+        // The body is optional; without one the flag has no reason
+        var req flagRequest
+        if r.ContentLength != 0 {
+            var problems map[string][]string
+            var err error
+            req, problems, err = decodeValid[flagRequest](r)
+            if err != nil {
+                l.Error(ctx, "failed to decode request", "error", err)
+                encodeBadRequest(w, r, err, problems)
+                return
+            }
+        }
+
+        if _, err := store.Get(ctx, commentID); err != nil {
+            if err == storage.ErrNotFound {
+                encodeError(w, r, http.StatusNotFound, codeNotFound, "Comment not found")
+                return
+            }
+            l.Error(ctx, "failed to get comment", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        flag, created, err := store.Flags().Add(ctx, storage.Flag{
+            CommentID: commentID,
+            UserID:    userID,
+            Reason:    req.Reason,
+        })
+        if err != nil {
+            l.Error(ctx, "failed to flag comment", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        status := http.StatusOK
+        if created {
+            status = http.StatusCreated
+            l.Info(ctx, "comment flagged")
+        }
+        if err := encode(w, r, status, toFlagResponse(flag)); err != nil {
+            l.Error(ctx, "failed to encode response", "error", err)
+        }
+    })
+}
+
+// Admin flag listing handler. Lists flagged comments for moderators, most
+// flagged first, with every flag on each.
+func handleListFlags(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+
+        flagged, err := store.Flags().List(ctx)
+        if err != nil {
+            logger.Error(ctx, "failed to list flags",
+                "error", err,
+                "user_id", userID,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        reacted, err := store.ReactedTo(ctx, userID)
+        if err != nil {
+            logger.Error(ctx, "failed to load reactions",
+                "error", err,
+                "user_id", userID,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        resp := flaggedCommentsResponse{Comments: make([]flaggedCommentResponse, 0, len(flagged))}
+        for _, fc := range flagged {
+            // A comment removed while being flagged may leave flags behind
+            // briefly; they aren't worth moderating
+            comment, err := store.Get(ctx, fc.CommentID)
+            if err == storage.ErrNotFound {
+                continue
+            }
+            if err != nil {
+                logger.Error(ctx, "failed to get flagged comment",
+                    "error", err,
+                    "comment_id", fc.CommentID,
+                    "user_id", userID,
+                )
+                encodeInternalError(w, r, err)
+                return
+            }
+
+            item := flaggedCommentResponse{
+                Comment:       toCommentResponse(comment, reacted[comment.ID]),
+                FlagCount:     len(fc.Flags),
+                LastFlaggedAt: timestamp(fc.LastFlaggedAt),
+                Flags:         make([]flagResponse, len(fc.Flags)),
+            }
+            item.Comment.FlagCount = &item.FlagCount
+            for i, f := range fc.Flags {
+                item.Flags[i] = toFlagResponse(f)
+            }
+            resp.Comments = append(resp.Comments, item)
+        }
+
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            logger.Error(ctx, "failed to encode response",
+                "error", err,
+                "user_id", userID,
+            )
+        }
+    })
+}
@@ -4,11 +4,27 @@ package api
 
 import (
     "context"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "math"
     "net/http"
+    "net/url"
+    "runtime"
+    "sort"
+    "strconv"
     "strings"
+    "sync"
     "time"
-    "web-service/internal/storage"
+    "unicode/utf8"
     "web-service/internal/auth"
+    "web-service/internal/config"
+    "web-service/internal/health"
+    "web-service/internal/markup"
+    "web-service/internal/realip"
+    "web-service/internal/similarity"
+    "web-service/internal/storage"
+    "web-service/internal/version"
     "web-service/pkg/logging"
 )
 
@@ -16,281 +32,895 @@ import (
 type createCommentRequest struct {
     Content string `json:"content"`
     Author  string `json:"author"`
+
+    // Optional lifetime, as an absolute expiry or as TTL seconds from now.
+    // Only honoured on create.
+    ExpiresAt *time.Time `json:"expires_at,omitempty"`
+    TTL       int64      `json:"ttl,omitempty"`
+
+    // Attachments are absolute http or https URLs, such as images. An
+    // update replaces the comment's attachments, so leaving them out
+    // clears them.
+    Attachments []string `json:"attachments,omitempty"`
+}
+
+// updateCommentRequest is a createCommentRequest that may name the
+// version it was edited from, so an update made from a stale copy is
+// refused rather than overwriting a newer edit. A version of 0, or none,
+// updates whatever version is current.
+type updateCommentRequest struct {
+    createCommentRequest
+    Version int64 `json:"version,omitempty"`
+}
+
+// Valid checks the comment as createCommentRequest does, and the version.
+func (r updateCommentRequest) Valid(ctx context.Context) map[string][]string {
+    problems := problemMap(r.createCommentRequest.Valid(ctx))
+    if r.Version < 0 {
+        problems.add("version", "version must not be negative")
+    }
+    return problems
 }
 
 type commentResponse struct {
-    ID        string    `json:"id"`
-    Content   string    `json:"content"`
-    Author    string    `json:"author"`
-    CreatedAt time.Time `json:"created_at"`
-    UserID    string    `json:"user_id,omitempty"`
+    XMLName   xml.Name   `json:"-" xml:"comment"`
+    ID        string     `json:"id" xml:"id"`
+    Content   string     `json:"content" xml:"content"`
+    Author    string     `json:"author" xml:"author"`
+    CreatedAt timestamp  `json:"created_at" xml:"created_at"`
+    UserID    string     `json:"user_id,omitempty" xml:"user_id,omitempty"`
+    ExpiresAt *timestamp `json:"expires_at,omitempty" xml:"expires_at,omitempty"`
+    Version   int64      `json:"version" xml:"version"`
+
+    Attachments []string `json:"attachments,omitempty" xml:"attachments>attachment,omitempty"`
+
+    ReactionCount    int  `json:"reaction_count" xml:"reaction_count"`
+    ViewerHasReacted bool `json:"viewer_has_reacted" xml:"viewer_has_reacted"`
+
+    // FlagCount is only set for admins; see addFlagCounts.
+    FlagCount *int `json:"flag_count,omitempty" xml:"flag_count,omitempty"`
+
+    // ContentHTML is Content rendered from Markdown to sanitized HTML,
+    // only set when the request asks for it with render=html.
+    ContentHTML string `json:"content_html,omitempty" xml:"content_html,omitempty"`
+}
+
+// commentLimits bound the length of comment fields, in characters. A zero
+// limit is not enforced.
+type commentLimits struct {
+    MinContent int
+    MaxContent int
+    MaxAuthor  int
+
+    // MaxAttachments is a count rather than a length.
+    MaxAttachments   int
+    MaxAttachmentURL int
+}
+
+// defaultCommentLimits apply when a request carries no limits of its own.
+var defaultCommentLimits = commentLimits{MinContent: 1, MaxContent: 1000, MaxAuthor: 100, MaxAttachments: 4, MaxAttachmentURL: 2048}
+
+func newCommentLimits(config *config.Config) commentLimits {
+    return commentLimits{
+        MinContent:       config.CommentMinLength,
+        MaxContent:       config.CommentMaxLength,
+        MaxAuthor:        config.CommentMaxAuthorLength,
+        MaxAttachments:   config.CommentMaxAttachments,
+        MaxAttachmentURL: config.CommentMaxAttachmentLength,
+    }
+}
+
+// withCommentLimits returns a copy of ctx carrying limits for
+// createCommentRequest.Valid and authorNameRequest.Valid.
+func withCommentLimits(ctx context.Context, limits commentLimits) context.Context {
+    return context.WithValue(ctx, commentLimitsKey, limits)
+}
+
+func commentLimitsFromContext(ctx context.Context) commentLimits {
+    if limits, ok := ctx.Value(commentLimitsKey).(commentLimits); ok {
+        return limits
+    }
+    return defaultCommentLimits
+}
+
+// withRequestTime returns a copy of ctx carrying now, the time by the
+// store's clock that createCommentRequest.Valid checks expires_at against.
+func withRequestTime(ctx context.Context, now time.Time) context.Context {
+    return context.WithValue(ctx, requestTimeKey, now)
+}
+
+// requestTimeFromContext returns the request time in ctx, or the current
+// time if it carries none.
+func requestTimeFromContext(ctx context.Context) time.Time {
+    if now, ok := ctx.Value(requestTimeKey).(time.Time); ok {
+        return now
+    }
+    return time.Now()
+}
+
+// withContentPolicy returns a copy of ctx carrying the content policy for
+// createCommentRequest.normalize and renderContent.
+func withContentPolicy(ctx context.Context, policy string) context.Context {
+    return context.WithValue(ctx, contentPolicyKey, policy)
+}
+
+// contentPolicyFromContext returns the content policy in ctx, treating
+// content as plain text unless a request carries another.
+func contentPolicyFromContext(ctx context.Context) string {
+    if policy, ok := ctx.Value(contentPolicyKey).(string); ok && policy != "" {
+        return policy
+    }
+    return config.ContentPolicyText
+}
+
+// newContentPolicyMiddleware puts policy in the context of every request
+// below it, for the handlers that store and render comment content.
+func newContentPolicyMiddleware(policy string) Middleware {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            next.ServeHTTP(w, r.WithContext(withContentPolicy(r.Context(), policy)))
+        })
+    }
+}
+
+// sanitizeContent applies a config.ContentPolicy to content. Plain text is
+// kept as written, since "a<b" is text rather than a tag; it is escaped
+// when rendered instead.
+func sanitizeContent(policy, content string) string {
+    if policy == config.ContentPolicyAllowlist {
+        return markup.Sanitize(content)
+    }
+    return content
+}
+
+// normalize puts the content and author in NFC, and sanitizes the content
+// under the policy in ctx, before they are validated and stored.
+func (r *createCommentRequest) normalize(ctx context.Context) {
+    r.Content = sanitizeContent(contentPolicyFromContext(ctx), normalizeText(r.Content))
+    r.Author = normalizeText(r.Author)
 }
 
-// Validator implementation
-func (r createCommentRequest) Valid(ctx context.Context) map[string]string {
-    problems := make(map[string]string)
-    if strings.TrimSpace(r.Content) == "" {
-        problems["content"] = "content is required"
+// Validator implementation, checking lengths in characters against the
+// limits in ctx
+func (r createCommentRequest) Valid(ctx context.Context) map[string][]string {
+    limits := commentLimitsFromContext(ctx)
+    problems := make(problemMap)
+    content := strings.TrimSpace(r.Content)
+    switch {
+    case content == "":
+        problems.add("content", "content is required")
+    case utf8.RuneCountInString(content) < limits.MinContent:
+        problems.add("content", fmt.Sprintf("content must be at least %d characters", limits.MinContent))
     }
-    if len(r.Content) > 1000 {
-        problems["content"] = "content must be less than 1000 characters"
+    if limits.MaxContent > 0 && utf8.RuneCountInString(r.Content) > limits.MaxContent {
+        problems.add("content", fmt.Sprintf("content must be at most %d characters", limits.MaxContent))
     }
     if strings.TrimSpace(r.Author) == "" {
-        problems["author"] = "author is required"
+        problems.add("author", "author is required")
+    }
+    if limits.MaxAuthor > 0 && utf8.RuneCountInString(r.Author) > limits.MaxAuthor {
+        problems.add("author", fmt.Sprintf("author must be at most %d characters", limits.MaxAuthor))
+    }
+    if r.TTL < 0 {
+        problems.add("ttl", "ttl must be a positive number of seconds")
+    }
+    if r.ExpiresAt != nil {
+        if r.TTL != 0 {
+            problems.add("expires_at", "set either expires_at or ttl, not both")
+        } else if !r.ExpiresAt.After(requestTimeFromContext(ctx)) {
+            problems.add("expires_at", "expires_at must be in the future")
+        }
+    }
+    if limits.MaxAttachments > 0 && len(r.Attachments) > limits.MaxAttachments {
+        problems.add("attachments", fmt.Sprintf("at most %d attachments are allowed", limits.MaxAttachments))
+    }
+    for i, attachment := range r.Attachments {
+        field := fmt.Sprintf("attachments[%d]", i)
+        if limits.MaxAttachmentURL > 0 && utf8.RuneCountInString(attachment) > limits.MaxAttachmentURL {
+            problems.add(field, fmt.Sprintf("attachment must be at most %d characters", limits.MaxAttachmentURL))
+        } else if !validAttachmentURL(attachment) {
+            problems.add(field, "attachment must be an absolute http or https URL")
+        }
     }
     return problems
 }
 
-// Comment handler
-func handleComments(logger *logging.Logger, store *storage.CommentStore) http.Handler {
+// validAttachmentURL reports whether s is an absolute http or https URL
+// with a host.
+func validAttachmentURL(s string) bool {
+    u, err := url.Parse(s)
+    if err != nil {
+        return false
+    }
+    return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// expiry returns when the requested comment should expire, or the zero time
+// if it shouldn't.
+func (r createCommentRequest) expiry(now time.Time) time.Time {
+    switch {
+    case r.ExpiresAt != nil:
+        return *r.ExpiresAt
+    case r.TTL > 0:
+        return now.Add(time.Duration(r.TTL) * time.Second)
+    default:
+        return time.Time{}
+    }
+}
+
+// listBufferPool recycles the response slices built by list requests so a
+// steady stream of lists doesn't allocate a fresh large slice each time.
+var listBufferPool = sync.Pool{
+    New: func() any {
+        buf := make([]commentResponse, 0, 64)
+        return &buf
+    },
+}
+
+// toCommentResponse maps a stored comment to its wire representation.
+// viewerHasReacted reports whether the requesting user reacted to it.
+func toCommentResponse(c storage.Comment, viewerHasReacted bool) commentResponse {
+    return commentResponse{
+        ID:               c.ID,
+        Content:          c.Content,
+        Author:           c.Author,
+        CreatedAt:        timestamp(c.CreatedAt),
+        UserID:           c.UserID,
+        ExpiresAt:        newTimestamp(c.ExpiresAt),
+        Version:          c.Version,
+        Attachments:      c.Attachments,
+        ReactionCount:    c.ReactionCount,
+        ViewerHasReacted: viewerHasReacted,
+    }
+}
+
+// renderHTML is the render parameter asking for content_html.
+const renderHTML = "html"
+
+// renderContent fills in ContentHTML when render asks for it, escaping
+// HTML in content under the plain text policy in ctx. Content is left as
+// stored so clients can still edit it.
+func renderContent(ctx context.Context, resp []commentResponse, render string) {
+    if render != renderHTML {
+        return
+    }
+    renderer := markup.Render
+    if contentPolicyFromContext(ctx) == config.ContentPolicyText {
+        renderer = markup.RenderText
+    }
+    for i := range resp {
+        resp[i].ContentHTML = renderer(resp[i].Content)
+    }
+}
+
+const (
+    defaultListLimit = 20
+    maxListLimit     = 100
+)
+
+// pageComments sorts resp oldest first, ties broken by ID, and returns the
+// page of at most limit comments starting at offset.
+func pageComments(resp []commentResponse, limit, offset int) []commentResponse {
+    sort.Slice(resp, func(i, j int) bool {
+        a, b := time.Time(resp[i].CreatedAt), time.Time(resp[j].CreatedAt)
+        if !a.Equal(b) {
+            return a.Before(b)
+        }
+        return resp[i].ID < resp[j].ID
+    })
+    total := len(resp)
+    return resp[min(offset, total):min(offset+limit, total)]
+}
+
+// listPage is the paging and rendering a comment list was asked for.
+// Without limit or offset parameters the list isn't paged at all.
+type listPage struct {
+    paged         bool
+    limit, offset int
+    render        string
+}
+
+// parseListPage reads the limit, offset and render parameters of a comment
+// list, returning the problems with any of them.
+func parseListPage(r *http.Request) (listPage, problemMap) {
+    params := newQueryParams(r)
+    var page listPage
+    page.paged = params.values.Has("limit") || params.values.Has("offset")
+    page.limit, page.offset = params.page(defaultListLimit, maxListLimit)
+    page.render = params.oneOf("render", renderHTML)
+    return page, params.Problems()
+}
+
+// writeCommentList answers with the page of resp asked for, its flag counts
+// and rendering filled in, and X-Total-Count counting all of resp, plus a
+// Link header when paged.
+func writeCommentList(w http.ResponseWriter, r *http.Request, l *logging.Logger, store storage.CommentRepository, resp []commentResponse, page listPage) {
+    ctx := r.Context()
+    total := len(resp)
+    if page.paged {
+        resp = pageComments(resp, page.limit, page.offset)
+    }
+    if err := addFlagCounts(ctx, store, resp); err != nil {
+        l.Error(ctx, "failed to load flag counts",
+            "error", err,
+        )
+        encodeInternalError(w, r, err)
+        return
+    }
+    renderContent(ctx, resp, page.render)
+
+    if page.paged {
+        setPageHeaders(w, r, total, page.limit, page.offset)
+    } else {
+        w.Header().Set(totalCountHeader, strconv.Itoa(total))
+    }
+
+    if err := encode(w, r, http.StatusOK, resp); err != nil {
+        l.Error(ctx, "failed to encode response",
+            "error", err,
+        )
+    }
+}
+
+// List comments handler. Without limit or offset parameters it lists every
+// comment; with either it returns that page, oldest first, with the
+// X-Total-Count and Link headers describing the rest. Responses may be
+// cached privately for config.CommentListMaxAge and carry the store's
+// Last-Modified, answering If-Modified-Since with 304 when nothing changed.
+// With render=html each comment includes its content rendered as HTML.
+func handleListComments(logger *logging.Logger, config *config.Config, store storage.CommentRepository) http.Handler {
+    cacheControl := fmt.Sprintf("private, max-age=%d", int(config.CommentListMaxAge.Seconds()))
+
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         ctx := r.Context()
         userID := UserIDFromContext(ctx)
+        l := logger.With("user_id", userID)
 
-        switch r.Method {
-        case http.MethodGet:
-            comments, err := store.List(ctx)
-            if err != nil {
-                logger.Error(ctx, "failed to list comments",
-                    "error", err,
-                    "user_id", userID,
-                )
-                http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-                return
-            }
+        // Negotiate before the caching headers and the 304 check, so a
+        // client that can read none of our types gets 406 and nothing else
+        if _, ok := negotiate(r); !ok {
+            encodeNotAcceptable(w, r)
+            return
+        }
 
-            // Map to response type
-            resp := make([]commentResponse, len(comments))
-            for i, c := range comments {
-                resp[i] = commentResponse{
-                    ID:        c.ID,
-                    Content:   c.Content,
-                    Author:    c.Author,
-                    CreatedAt: c.CreatedAt,
-                    UserID:    c.UserID,
-                }
-            }
+        page, problems := parseListPage(r)
+        if problems != nil {
+            encodeProblems(w, r, problems)
+            return
+        }
 
-            if err := encode(w, r, http.StatusOK, resp); err != nil {
-                logger.Error(ctx, "failed to encode response",
-                    "error", err,
-                    "user_id", userID,
-                )
-                return
-            }
+        // Read the modification time before the comments, so a change
+        // made while listing makes the list look older, not newer
+        modified, err := store.LastModified(ctx)
+        if err != nil {
+            l.Error(ctx, "failed to read last modified time",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+        w.Header().Set("Cache-Control", cacheControl)
+        if setLastModified(w, modified, time.Now()) && notModifiedSince(r, modified) {
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
 
-        case http.MethodPost:
-            req, problems, err := decodeValid[createCommentRequest](r)
-            if err != nil {
-                logger.Error(ctx, "failed to decode request",
-                    "error", err,
-                    "user_id", userID,
-                )
-                http.Error(w, err.Error(), http.StatusBadRequest)
+        // Map straight into a pooled response buffer rather than
+        // copying the store into an intermediate slice first
+        buf := listBufferPool.Get().(*[]commentResponse)
+        defer func() {
+            clear(*buf)
+            *buf = (*buf)[:0]
+            listBufferPool.Put(buf)
+        }()
+
+        reacted, err := store.ReactedTo(ctx, userID)
+        if err != nil {
+            l.Error(ctx, "failed to load reactions",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        resp := *buf
+        err = store.Range(ctx, func(c storage.Comment) bool {
+            resp = append(resp, toCommentResponse(c, reacted[c.ID]))
+            return true
+        })
+        *buf = resp
+        if err != nil {
+            l.Error(ctx, "failed to list comments",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        // The total is what was listed rather than a separate Count, so the
+        // headers agree with the page even as comments come and go
+        writeCommentList(w, r, l, store, resp, page)
+    })
+}
+
+// Create comment handler. A requested lifetime is capped at
+// config.CommentMaxTTL, and beyond config.CommentTTLAdminThreshold needs the
+// admin role. Near-duplicates of recent comments are screened by dups,
+// which is nil when the check is off. Requests carrying an Idempotency-Key
+// are deduplicated through idem, also nil when off.
+func handleCreateComment(logger *logging.Logger, config *config.Config, store storage.CommentRepository, dups *duplicateChecker, idem *idempotencyCache) http.Handler {
+    limits := newCommentLimits(config)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        // Lifetimes are measured on the store's clock, which decides
+        // expiry, and from one instant for validation and the TTL bounds
+        now := store.Now()
+        r = r.WithContext(withRequestTime(withCommentLimits(r.Context(), limits), now))
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        l := logger.With("user_id", userID)
+
+        req, problems, err := decodeValid[createCommentRequest](r)
+        if err != nil {
+            l.Error(ctx, "failed to decode request",
+                "error", err,
+            )
+            encodeBadRequest(w, r, err, problems)
+            return
+        }
+
+        var idemKey string
+        if key := r.Header.Get(idempotencyKeyHeader); key != "" && idem != nil {
+            if len(key) > maxIdempotencyKeyLength {
+                encodeError(w, r, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength))
                 return
             }
-            if len(problems) > 0 {
-                if err := encode(w, r, http.StatusBadRequest, problems); err != nil {
-                    logger.Error(ctx, "failed to encode validation problems",
+            idemKey = idempotencyScope(userID, key)
+            state, prev := idem.begin(idemKey, requestFingerprint(req))
+            switch state {
+            case idempotencyReplay:
+                l.Info(ctx, "replayed idempotent comment creation",
+                    "comment_id", prev.ID,
+                )
+                w.Header().Set(idempotentReplayedHeader, "true")
+                if err := encode(w, r, http.StatusCreated, *prev); err != nil {
+                    l.Error(ctx, "failed to encode response",
                         "error", err,
-                        "user_id", userID,
                     )
                 }
                 return
+            case idempotencyInFlight:
+                encodeError(w, r, http.StatusConflict, codeIdempotencyConflict, "A request with this Idempotency-Key is still in progress")
+                return
+            case idempotencyMismatch:
+                encodeError(w, r, http.StatusUnprocessableEntity, codeIdempotencyConflict, "Idempotency-Key was already used for a different request")
+                return
             }
+            defer idem.release(idemKey)
+        }
 
-            comment, err := store.Create(ctx, storage.Comment{
-                Content: req.Content,
-                Author:  req.Author,
-                UserID:  userID,
-            })
-            if err != nil {
-                logger.Error(ctx, "failed to create comment",
-                    "error", err,
-                    "user_id", userID,
-                )
-                http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+        expiresAt := req.expiry(now)
+        if !expiresAt.IsZero() {
+            lifetime := expiresAt.Sub(now)
+            if config.CommentMaxTTL > 0 && lifetime > config.CommentMaxTTL {
+                problems := problemMap{
+                    "ttl": {fmt.Sprintf("lifetime must not exceed %s", config.CommentMaxTTL)},
+                }
+                encodeProblems(w, r, problems)
                 return
             }
-
-            resp := commentResponse{
-                ID:        comment.ID,
-                Content:   comment.Content,
-                Author:    comment.Author,
-                CreatedAt: comment.CreatedAt,
-                UserID:    comment.UserID,
+            if config.CommentTTLAdminThreshold > 0 && lifetime > config.CommentTTLAdminThreshold &&
+                UserRoleFromContext(ctx) != "admin" {
+                encodeError(w, r, http.StatusForbidden, codeForbidden, fmt.Sprintf("Lifetimes beyond %s require the admin role", config.CommentTTLAdminThreshold))
+                return
             }
+        }
 
-            if err := encode(w, r, http.StatusCreated, resp); err != nil {
-                logger.Error(ctx, "failed to encode response",
-                    "error", err,
-                    "user_id", userID,
-                )
+        var fp similarity.Fingerprint
+        fingerprinted := false
+        if dups != nil {
+            var rejected *similarity.Match
+            fp, fingerprinted, rejected = dups.check(ctx, userID, req.Content)
+            if rejected != nil {
+                encodeError(w, r, http.StatusConflict, codeDuplicate, "Comment is too similar to a recent comment")
                 return
             }
+        }
+
+        comment, err := store.Create(ctx, storage.Comment{
+            Content:     req.Content,
+            Author:      req.Author,
+            UserID:      userID,
+            ExpiresAt:   expiresAt,
+            Attachments: req.Attachments,
+        })
+        if err != nil {
+            l.Error(ctx, "failed to create comment",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+        if fingerprinted {
+            dups.record(userID, comment.ID, fp)
+        }
 
-        default:
-            http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+        resp := toCommentResponse(comment, false)
+        if idemKey != "" {
+            idem.complete(idemKey, resp)
+        }
+        if err := encode(w, r, http.StatusCreated, resp); err != nil {
+            l.Error(ctx, "failed to encode response",
+                "error", err,
+            )
         }
     })
 }
 
-// Add this to internal/api/handlers.go after the other handlers
-
-// Single comment handler
-func handleComment(logger *logging.Logger, store *storage.CommentStore) http.Handler {
+// Get comment handler. With render=html the response includes the content
+// rendered as HTML.
+func handleGetComment(logger *logging.Logger, store storage.CommentRepository) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         ctx := r.Context()
         userID := UserIDFromContext(ctx)
+        commentID := r.PathValue("id")
+        l := logger.With("comment_id", commentID, "user_id", userID)
 
-        // Extract comment ID from URL
-        commentID := strings.TrimPrefix(r.URL.Path, "/api/v1/comments/")
-        if commentID == "" {
-            http.Error(w, "Comment ID required", http.StatusBadRequest)
+        params := newQueryParams(r)
+        render := params.oneOf("render", renderHTML)
+        if problems := params.Problems(); problems != nil {
+            encodeProblems(w, r, problems)
             return
         }
 
-        switch r.Method {
-        case http.MethodGet:
-            comment, err := store.Get(ctx, commentID)
-            if err != nil {
-                if err == storage.ErrNotFound {
-                    http.Error(w, "Comment not found", http.StatusNotFound)
-                    return
-                }
-                logger.Error(ctx, "failed to get comment",
-                    "error", err,
-                    "comment_id", commentID,
-                    "user_id", userID,
-                )
-                http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+        comment, err := store.Get(ctx, commentID)
+        if err != nil {
+            if err == storage.ErrNotFound {
+                encodeError(w, r, http.StatusNotFound, codeNotFound, "Comment not found")
                 return
             }
+            l.Error(ctx, "failed to get comment", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
 
-            resp := commentResponse{
-                ID:        comment.ID,
-                Content:   comment.Content,
-                Author:    comment.Author,
-                CreatedAt: comment.CreatedAt,
-                UserID:    comment.UserID,
-            }
+        reacted, err := store.ReactedTo(ctx, userID)
+        if err != nil {
+            l.Error(ctx, "failed to load reactions", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
 
-            if err := encode(w, r, http.StatusOK, resp); err != nil {
-                logger.Error(ctx, "failed to encode response",
-                    "error", err,
-                    "comment_id", commentID,
-                    "user_id", userID,
-                )
-            }
+        resp := []commentResponse{toCommentResponse(comment, reacted[commentID])}
+        if err := addFlagCounts(ctx, store, resp); err != nil {
+            l.Error(ctx, "failed to load flag counts", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+        renderContent(ctx, resp, render)
 
-        case http.MethodPut:
-            req, problems, err := decodeValid[createCommentRequest](r)
-            if err != nil {
-                logger.Error(ctx, "failed to decode request",
-                    "error", err,
-                    "user_id", userID,
-                )
-                http.Error(w, err.Error(), http.StatusBadRequest)
-                return
-            }
-            if len(problems) > 0 {
-                if err := encode(w, r, http.StatusBadRequest, problems); err != nil {
-                    logger.Error(ctx, "failed to encode validation problems",
-                        "error", err,
-                        "user_id", userID,
-                    )
-                }
-                return
-            }
+        setETag(w, comment.Version)
+        if err := encode(w, r, http.StatusOK, resp[0]); err != nil {
+            l.Error(ctx, "failed to encode response", "error", err)
+        }
+    })
+}
 
-            // Verify the comment exists and belongs to the user
-            existing, err := store.Get(ctx, commentID)
-            if err != nil {
-                if err == storage.ErrNotFound {
-                    http.Error(w, "Comment not found", http.StatusNotFound)
-                    return
-                }
-                logger.Error(ctx, "failed to get comment",
-                    "error", err,
-                    "comment_id", commentID,
-                    "user_id", userID,
-                )
-                http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-                return
-            }
+// Update comment handler
+func handleUpdateComment(logger *logging.Logger, config *config.Config, store storage.CommentRepository) http.Handler {
+    limits := newCommentLimits(config)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        r = r.WithContext(withCommentLimits(r.Context(), limits))
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        commentID := r.PathValue("id")
+        l := logger.With("comment_id", commentID, "user_id", userID)
+
+        req, problems, err := decodeValid[updateCommentRequest](r)
+        if err != nil {
+            l.Error(ctx, "failed to decode request", "error", err)
+            encodeBadRequest(w, r, err, problems)
+            return
+        }
 
-            if existing.UserID != userID {
-                http.Error(w, "Forbidden", http.StatusForbidden)
+        // The version edited from may be given in the body or If-Match
+        version, err := ifMatchVersion(r)
+        if err != nil {
+            encodeProblems(w, r, problemMap{"If-Match": {err.Error()}})
+            return
+        }
+        if req.Version != 0 {
+            if version != 0 && version != req.Version {
+                encodeProblems(w, r, problemMap{"version": {"version and If-Match name different versions"}})
                 return
             }
+            version = req.Version
+        }
 
-            comment, err := store.Update(ctx, commentID, storage.Comment{
-                Content: req.Content,
-                Author:  req.Author,
-                UserID:  userID,
-            })
-            if err != nil {
-                logger.Error(ctx, "failed to update comment",
-                    "error", err,
-                    "comment_id", commentID,
-                    "user_id", userID,
-                )
-                http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+        // Verify the comment exists and belongs to the user
+        existing, err := store.Get(ctx, commentID)
+        if err != nil {
+            if err == storage.ErrNotFound {
+                encodeError(w, r, http.StatusNotFound, codeNotFound, "Comment not found")
                 return
             }
+            l.Error(ctx, "failed to get comment", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
 
-            resp := commentResponse{
-                ID:        comment.ID,
-                Content:   comment.Content,
-                Author:    comment.Author,
-                CreatedAt: comment.CreatedAt,
-                UserID:    comment.UserID,
-            }
+        if existing.UserID != userID {
+            encodeError(w, r, http.StatusForbidden, codeForbidden, "Forbidden")
+            return
+        }
 
-            if err := encode(w, r, http.StatusOK, resp); err != nil {
-                logger.Error(ctx, "failed to encode response",
-                    "error", err,
-                    "comment_id", commentID,
-                    "user_id", userID,
-                )
+        comment, err := store.Update(ctx, commentID, version, storage.Comment{
+            Content:     req.Content,
+            Author:      req.Author,
+            UserID:      userID,
+            Attachments: req.Attachments,
+        })
+        if err != nil {
+            switch {
+            case errors.Is(err, storage.ErrVersionConflict):
+                l.Info(ctx, "comment update conflicted", "version", version, "current_version", comment.Version)
+                setETag(w, comment.Version)
+                p := newProblem(r, http.StatusConflict, codeVersionConflict, "Comment has changed since the version being updated")
+                p.CurrentVersion = comment.Version
+                writeProblem(w, r, p)
+            case errors.Is(err, storage.ErrNotFound):
+                encodeError(w, r, http.StatusNotFound, codeNotFound, "Comment not found")
+            default:
+                l.Error(ctx, "failed to update comment", "error", err)
+                encodeInternalError(w, r, err)
             }
+            return
+        }
+
+        reacted, err := store.ReactedTo(ctx, userID)
+        if err != nil {
+            l.Error(ctx, "failed to load reactions", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        setETag(w, comment.Version)
+        if err := encode(w, r, http.StatusOK, toCommentResponse(comment, reacted[commentID])); err != nil {
+            l.Error(ctx, "failed to encode response", "error", err)
+        }
+    })
+}
 
-        case http.MethodDelete:
-            // Verify the comment exists and belongs to the user
-            existing, err := store.Get(ctx, commentID)
-            if err != nil {
-                if err == storage.ErrNotFound {
-                    http.Error(w, "Comment not found", http.StatusNotFound)
+// Delete comment handler. Deletes are idempotent while the store keeps
+// tombstones: repeating a delete of your own comment within the tombstone
+// window returns 204 with X-Already-Deleted: true, while a comment that never
+// existed (or whose tombstone expired) is 404. Admins may delete anyone's
+// comment for moderation; each such deletion is logged at WARN for audit.
+func handleDeleteComment(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        commentID := r.PathValue("id")
+        l := logger.With("comment_id", commentID, "user_id", userID)
+
+        // Verify the comment exists and belongs to the user
+        existing, err := store.Get(ctx, commentID)
+        if err != nil {
+            if err == storage.ErrNotFound {
+                if tombstone, err := store.Deleted(ctx, commentID); err == nil && tombstone.UserID == userID {
+                    w.Header().Set("X-Already-Deleted", "true")
+                    w.WriteHeader(http.StatusNoContent)
                     return
                 }
-                logger.Error(ctx, "failed to get comment",
-                    "error", err,
-                    "comment_id", commentID,
-                    "user_id", userID,
-                )
-                http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+                encodeError(w, r, http.StatusNotFound, codeNotFound, "Comment not found")
                 return
             }
+            l.Error(ctx, "failed to get comment", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
 
-            if existing.UserID != userID {
-                http.Error(w, "Forbidden", http.StatusForbidden)
-                return
+        moderated := existing.UserID != userID
+        if moderated && UserRoleFromContext(ctx) != "admin" {
+            encodeError(w, r, http.StatusForbidden, codeForbidden, "Forbidden")
+            return
+        }
+
+        if err := store.Delete(ctx, commentID); err != nil {
+            l.Error(ctx, "failed to delete comment", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+        if moderated {
+            l.Warn(ctx, "admin deleted another user's comment", "owner_id", existing.UserID)
+        }
+
+        w.WriteHeader(http.StatusNoContent)
+    })
+}
+
+type statsResponse struct {
+    XMLName         xml.Name         `json:"-" xml:"stats"`
+    Total           int              `json:"total" xml:"total"`
+    Last24h         int              `json:"last_24h" xml:"last_24h"`
+    Last7d          int              `json:"last_7d" xml:"last_7d"`
+    DistinctAuthors int              `json:"distinct_authors" xml:"distinct_authors"`
+    Mine            int              `json:"mine" xml:"mine"`
+    ByAuthor        countMap         `json:"by_author" xml:"by_author"`
+    ByUser          countMap         `json:"by_user" xml:"by_user"`
+    Oldest          *timestamp       `json:"oldest,omitempty" xml:"oldest,omitempty"`
+    Newest          *timestamp       `json:"newest,omitempty" xml:"newest,omitempty"`
+    Cleanup         *cleanupResponse `json:"cleanup,omitempty" xml:"cleanup,omitempty"`
+}
+
+// cleanupResponse is the last run of the cleanup job.
+type cleanupResponse struct {
+    LastRun timestamp `json:"last_run" xml:"last_run"`
+    Expired int       `json:"expired" xml:"expired"`
+    Purged  int       `json:"purged" xml:"purged"`
+}
+
+// commentStats are the store-wide figures behind the stats endpoint.
+type commentStats struct {
+    storage.Stats
+    Last24h int
+    Last7d  int
+}
+
+func loadCommentStats(ctx context.Context, store storage.CommentRepository) (commentStats, error) {
+    stats, err := store.Stats(ctx)
+    if err != nil {
+        return commentStats{}, err
+    }
+    now := time.Now()
+    last24h, err := store.CountSince(ctx, now.Add(-24*time.Hour))
+    if err != nil {
+        return commentStats{}, err
+    }
+    last7d, err := store.CountSince(ctx, now.Add(-7*24*time.Hour))
+    if err != nil {
+        return commentStats{}, err
+    }
+    return commentStats{Stats: stats, Last24h: last24h, Last7d: last7d}, nil
+}
+
+// statsCache reuses the store-wide comment stats for ttl, so dashboards
+// polling the stats endpoint don't each scan the store. Concurrent misses
+// wait for one recomputation rather than all scanning at once.
+type statsCache struct {
+    ttl time.Duration
+    now func() time.Time
+
+    mu      sync.Mutex
+    stats   commentStats
+    expires time.Time
+}
+
+// newStatsCache returns a cache keeping stats for ttl, or nil when
+// ttl <= 0 and stats should be computed for every request.
+func newStatsCache(ttl time.Duration) *statsCache {
+    if ttl <= 0 {
+        return nil
+    }
+    return &statsCache{ttl: ttl, now: time.Now}
+}
+
+// get returns the cached stats, recomputing them once they expire. A nil
+// cache always recomputes.
+func (c *statsCache) get(ctx context.Context, store storage.CommentRepository) (commentStats, error) {
+    if c == nil {
+        return loadCommentStats(ctx, store)
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.now().Before(c.expires) {
+        return c.stats, nil
+    }
+    stats, err := loadCommentStats(ctx, store)
+    if err != nil {
+        return commentStats{}, err
+    }
+    c.stats, c.expires = stats, c.now().Add(c.ttl)
+    return stats, nil
+}
+
+// Comment stats handler. Store-wide figures come from cache, which may be
+// nil; Mine, the caller's own count, is taken from them. The cleanup job's
+// last run is included when cleanup, which may be nil, reports one.
+func handleCommentStats(logger *logging.Logger, store storage.CommentRepository, cache *statsCache, cleanup func() (CleanupRun, bool)) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        l := logger.With("user_id", userID)
+
+        stats, err := cache.get(ctx, store)
+        if err != nil {
+            l.Error(ctx, "failed to compute comment stats",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        resp := statsResponse{
+            Total:           stats.Total,
+            Last24h:         stats.Last24h,
+            Last7d:          stats.Last7d,
+            DistinctAuthors: len(stats.ByAuthor),
+            Mine:            stats.ByUser[userID],
+            ByAuthor:        stats.ByAuthor,
+            ByUser:          stats.ByUser,
+        }
+        if stats.Total > 0 {
+            resp.Oldest = newTimestamp(stats.Oldest)
+            resp.Newest = newTimestamp(stats.Newest)
+        }
+        if cleanup != nil {
+            if run, ok := cleanup(); ok {
+                resp.Cleanup = &cleanupResponse{LastRun: timestamp(run.Time), Expired: run.Expired, Purged: run.Purged}
             }
+        }
 
-            if err := store.Delete(ctx, commentID); err != nil {
-                logger.Error(ctx, "failed to delete comment",
-                    "error", err,
-                    "comment_id", commentID,
-                    "user_id", userID,
-                )
-                http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            l.Error(ctx, "failed to encode response",
+                "error", err,
+            )
+        }
+    })
+}
+
+type reactionResponse struct {
+    XMLName          xml.Name `json:"-" xml:"reaction"`
+    CommentID        string   `json:"comment_id" xml:"comment_id"`
+    ReactionCount    int      `json:"reaction_count" xml:"reaction_count"`
+    ViewerHasReacted bool     `json:"viewer_has_reacted" xml:"viewer_has_reacted"`
+}
+
+// Add reaction handler
+func handleAddReaction(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return handleReaction(logger, store, true)
+}
+
+// Remove reaction handler
+func handleRemoveReaction(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return handleReaction(logger, store, false)
+}
+
+// handleReaction adds or removes the caller's reaction. Both directions are
+// idempotent, so repeating a request returns 200 with the same result.
+func handleReaction(logger *logging.Logger, store storage.CommentRepository, react bool) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        commentID := r.PathValue("id")
+        l := logger.With("comment_id", commentID, "user_id", userID)
+
+        toggle := store.RemoveReaction
+        if react {
+            toggle = store.AddReaction
+        }
+
+        count, err := toggle(ctx, commentID, userID)
+        if err != nil {
+            if err == storage.ErrNotFound {
+                encodeError(w, r, http.StatusNotFound, codeNotFound, "Comment not found")
                 return
             }
+            l.Error(ctx, "failed to update reaction",
+                "error", err,
+                "react", react,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
 
-            w.WriteHeader(http.StatusNoContent)
-
-        default:
-            http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+        resp := reactionResponse{
+            CommentID:        commentID,
+            ReactionCount:    count,
+            ViewerHasReacted: react,
+        }
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            l.Error(ctx, "failed to encode response", "error", err)
         }
     })
 }
@@ -302,65 +932,84 @@ type loginRequest struct {
 }
 
 type loginResponse struct {
-    Token     string `json:"token"`
-    ExpiresIn int64  `json:"expires_in"`
+    XMLName   xml.Name `json:"-" xml:"login"`
+    Token     string   `json:"token" xml:"token"`
+    ExpiresIn int64    `json:"expires_in" xml:"expires_in"`
 }
 
-func (r loginRequest) Valid(ctx context.Context) map[string]string {
-    problems := make(map[string]string)
+func (r loginRequest) Valid(ctx context.Context) map[string][]string {
+    problems := make(problemMap)
     if strings.TrimSpace(r.Username) == "" {
-        problems["username"] = "username is required"
+        problems.add("username", "username is required")
     }
     if strings.TrimSpace(r.Password) == "" {
-        problems["password"] = "password is required"
+        problems.add("password", "password is required")
     }
     return problems
 }
 
-// Login handler
-func handleLogin(logger *logging.Logger, jwtManager *auth.JWTManager) http.Handler {
+// Login handler. Credentials are checked against users, and the token
+// carries the authenticated user's ID and role. Repeated failures for a
+// username or client IP lock it out for config.LoginLockout.
+func handleLogin(logger *logging.Logger, config *config.Config, tokens auth.TokenService, users storage.UserStore, lockout *loginLockout) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         ctx := r.Context()
 
-        if r.Method != http.MethodPost {
-            http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-            return
-        }
-
         req, problems, err := decodeValid[loginRequest](r)
         if err != nil {
             logger.Error(ctx, "failed to decode login request", "error", err)
-            http.Error(w, err.Error(), http.StatusBadRequest)
+            encodeBadRequest(w, r, err, problems)
             return
         }
-        if len(problems) > 0 {
-            if err := encode(w, r, http.StatusBadRequest, problems); err != nil {
-                logger.Error(ctx, "failed to encode validation problems", "error", err)
-            }
+
+        clientIP := realip.FromRequest(r)
+        userKey := "user:" + req.Username
+        attempt, wait := lockout.reserve(
+            lockoutLimit{key: userKey, limit: config.LoginMaxFailures},
+            lockoutLimit{key: "ip:" + clientIP, limit: config.LoginMaxFailuresPerIP},
+        )
+        if wait > 0 {
+            logger.Warn(ctx, "login attempt while locked out",
+                "username", req.Username,
+                "client_ip", clientIP,
+            )
+            w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+            encodeError(w, r, http.StatusTooManyRequests, codeRateLimited, "Too many failed login attempts")
             return
         }
 
-        // In a real application, you would validate credentials against a database
-        // This is just for demonstration
-        if req.Username != "test" || req.Password != "test123" {
+        // The attempt already counts as a failure; invalid credentials
+        // leave it standing
+        user, err := users.Authenticate(ctx, req.Username, req.Password)
+        if errors.Is(err, storage.ErrInvalidCredentials) {
             logger.Warn(ctx, "invalid login attempt",
                 "username", req.Username,
-                "remote_addr", r.RemoteAddr,
+                "client_ip", clientIP,
             )
-            http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+            encodeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Invalid credentials")
+            return
+        }
+        attempt.cancel()
+        if err != nil {
+            logger.Error(ctx, "failed to authenticate", "error", err)
+            encodeInternalError(w, r, err)
             return
         }
+        // A success clears the username's failures, but not the IP's: one
+        // good password must not wipe out a spray of guesses at other
+        // accounts, so those expire with their window
+        lockout.reset(userKey)
 
-        token, err := jwtManager.GenerateToken(req.Username, "user")
+        token, err := tokens.GenerateToken(user.ID, user.Role)
         if err != nil {
             logger.Error(ctx, "failed to generate token", "error", err)
-            http.Error(w, "Internal server error", http.StatusInternalServerError)
+            encodeInternalError(w, r, err)
             return
         }
 
         resp := loginResponse{
             Token:     token,
-            ExpiresIn: 24 * 60 * 60, // 24 hours in seconds
+            ExpiresIn: int64(tokens.Expiry() / time.Second),
         }
 
         if err := encode(w, r, http.StatusOK, resp); err != nil {
@@ -370,13 +1019,14 @@ func handleLogin(logger *logging.Logger, jwtManager *auth.JWTManager) http.Handl
 
         logger.Info(ctx, "successful login",
             "username", req.Username,
-            "remote_addr", r.RemoteAddr,
+            "client_ip", clientIP,
         )
     })
 }
 
-// Health check handler
-func handleHealthz(logger *logging.Logger) http.Handler {
+// Liveness probe handler, also served as /healthz. It only shows the
+// process is up and serving; see handleReadyz for dependencies.
+func handleLivez(logger *logging.Logger) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         if err := encode(w, r, http.StatusOK, map[string]string{
             "status": "ok",
@@ -385,4 +1035,140 @@ func handleHealthz(logger *logging.Logger) http.Handler {
             logger.Error(r.Context(), "failed to encode health check response", "error", err)
         }
     })
-}
\ No newline at end of file
+}
+
+type healthDetailResponse struct {
+    XMLName       xml.Name `json:"-" xml:"health"`
+    Status        string   `json:"status" xml:"status"`
+    Time          string   `json:"time" xml:"time"`
+    UptimeSeconds int64    `json:"uptime_seconds" xml:"uptime_seconds"`
+    Comments      int      `json:"comments" xml:"comments"`
+    GoVersion     string   `json:"go_version" xml:"go_version"`
+    Goroutines    int      `json:"goroutines" xml:"goroutines"`
+    Version       string   `json:"version" xml:"version"`
+}
+
+// Health detail handler, for admins. Adds to /healthz the process uptime,
+// counted from started, the number of comments, the Go version and the
+// goroutine count: an at-a-glance picture during incidents that /healthz
+// keeps to itself so load balancers get a cheap answer.
+func handleHealthDetail(logger *logging.Logger, store storage.CommentRepository, started time.Time) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+
+        count, err := store.Count(ctx)
+        if err != nil {
+            logger.Error(ctx, "failed to count comments", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        resp := healthDetailResponse{
+            Status:        "ok",
+            Time:          time.Now().UTC().Format(time.RFC3339),
+            UptimeSeconds: int64(time.Since(started).Seconds()),
+            Comments:      count,
+            GoVersion:     runtime.Version(),
+            Goroutines:    runtime.NumGoroutine(),
+            Version:       version.Get().Version,
+        }
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            logger.Error(ctx, "failed to encode health detail response", "error", err)
+        }
+    })
+}
+
+type readyResponse struct {
+    XMLName  xml.Name     `json:"-" xml:"readiness"`
+    Status   string       `json:"status" xml:"status"`
+    Degraded bool         `json:"degraded" xml:"degraded"`
+    Time     string       `json:"time" xml:"time"`
+    Checks   []readyCheck `json:"checks" xml:"checks>check"`
+}
+
+type readyCheck struct {
+    Name      string        `json:"name" xml:"name"`
+    Status    health.Status `json:"status" xml:"status"`
+    LatencyMS float64       `json:"latency_ms" xml:"latency_ms"`
+    History   []readyResult `json:"history" xml:"history>result"`
+}
+
+type readyResult struct {
+    Status    health.Status `json:"status" xml:"status"`
+    LatencyMS float64       `json:"latency_ms" xml:"latency_ms"`
+    Time      string        `json:"time" xml:"time"`
+}
+
+func latencyMS(d time.Duration) float64 {
+    return float64(d.Microseconds()) / 1000
+}
+
+// Readiness probe handler. Returns 503 while a dependency is down so the
+// instance is taken out of load balancing without being restarted; slow
+// but usable dependencies are reported as degraded with a 200. The body
+// carries each check's recent history.
+func handleReadyz(logger *logging.Logger, checks *health.Monitor) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        report := checks.Run(r.Context())
+
+        status, resp := http.StatusOK, readyResponse{
+            Status:   "ok",
+            Degraded: report.Status == health.StatusDegraded,
+            Time:     time.Now().UTC().Format(time.RFC3339),
+            Checks:   make([]readyCheck, 0, len(report.Checks)),
+        }
+        if report.Status == health.StatusDown {
+            status, resp.Status = http.StatusServiceUnavailable, "unavailable"
+        }
+        for _, c := range report.Checks {
+            if c.Status != health.StatusOK {
+                logger.Warn(r.Context(), "readiness check unhealthy",
+                    "check", c.Name,
+                    "status", c.Status,
+                    "latency", c.Latency,
+                    "error", c.Err,
+                )
+            }
+            check := readyCheck{
+                Name:      c.Name,
+                Status:    c.Status,
+                LatencyMS: latencyMS(c.Latency),
+                History:   make([]readyResult, len(c.History)),
+            }
+            for i, h := range c.History {
+                check.History[i] = readyResult{
+                    Status:    h.Status,
+                    LatencyMS: latencyMS(h.Latency),
+                    Time:      h.Time.UTC().Format(time.RFC3339Nano),
+                }
+            }
+            resp.Checks = append(resp.Checks, check)
+        }
+
+        if err := encode(w, r, status, resp); err != nil {
+            logger.Error(r.Context(), "failed to encode readiness response", "error", err)
+        }
+    })
+}
+
+type versionResponse struct {
+    XMLName   xml.Name `json:"-" xml:"version"`
+    Version   string   `json:"version" xml:"version"`
+    Commit    string   `json:"commit" xml:"commit"`
+    BuildDate string   `json:"build_date" xml:"build_date"`
+}
+
+// Version handler, reporting the running build
+func handleVersion(logger *logging.Logger) http.Handler {
+    build := version.Get()
+    resp := versionResponse{
+        Version:   build.Version,
+        Commit:    build.Commit,
+        BuildDate: build.BuildDate,
+    }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            logger.Error(r.Context(), "failed to encode version response", "error", err)
+        }
+    })
+}
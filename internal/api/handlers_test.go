@@ -0,0 +1,303 @@
+// internal/api/handlers_test.go
+
+package api
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "slices"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+    "web-service/internal/health"
+    "web-service/pkg/logging"
+)
+
+func TestHandleReadyz(t *testing.T) {
+    logger := logging.NewLogger(io.Discard)
+
+    tests := []struct {
+        name       string
+        ping       health.CheckerFunc
+        wantStatus int
+    }{
+        {name: "store reachable", ping: func(context.Context) error { return nil }, wantStatus: http.StatusOK},
+        {name: "store down", ping: func(context.Context) error { return errors.New("connection refused") }, wantStatus: http.StatusServiceUnavailable},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            checks := health.NewMonitor()
+            checks.Add("storage", tt.ping, health.Budget{Timeout: time.Second})
+            rec := httptest.NewRecorder()
+            handleReadyz(logger, checks).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+            if rec.Code != tt.wantStatus {
+                t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+            }
+        })
+    }
+}
+
+func TestHandleReadyzDegraded(t *testing.T) {
+    logger := logging.NewLogger(io.Discard)
+
+    now := time.Now()
+    checks := health.NewMonitor(health.WithClock(func() time.Time { return now }))
+    checks.Add("storage", health.CheckerFunc(func(context.Context) error {
+        now = now.Add(300 * time.Millisecond)
+        return nil
+    }), health.Budget{Timeout: time.Second, Degraded: 100 * time.Millisecond})
+
+    rec := httptest.NewRecorder()
+    handleReadyz(logger, checks).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+    }
+
+    var resp readyResponse
+    if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+        t.Fatal(err)
+    }
+    if !resp.Degraded {
+        t.Error("expected degraded flag")
+    }
+    if len(resp.Checks) != 1 || resp.Checks[0].Status != health.StatusDegraded || resp.Checks[0].LatencyMS != 300 {
+        t.Errorf("expected degraded storage check at 300ms, got %+v", resp.Checks)
+    }
+    if len(resp.Checks) == 1 && len(resp.Checks[0].History) != 1 {
+        t.Errorf("expected one history entry, got %d", len(resp.Checks[0].History))
+    }
+}
+
+func TestCreateCommentRequestValid(t *testing.T) {
+    limits := commentLimits{MinContent: 3, MaxContent: 10, MaxAuthor: 5, MaxAttachments: 2, MaxAttachmentURL: 30}
+
+    tests := []struct {
+        name   string
+        req    createCommentRequest
+        limits *commentLimits
+        want   map[string][]string
+    }{
+        {name: "valid", req: createCommentRequest{Content: "hello", Author: "Al"}, limits: &limits},
+        {name: "empty", req: createCommentRequest{Content: "", Author: "Al"}, limits: &limits,
+            want: map[string][]string{"content": {"content is required"}}},
+        {name: "whitespace only", req: createCommentRequest{Content: " \t\n ", Author: "Al"}, limits: &limits,
+            want: map[string][]string{"content": {"content is required"}}},
+        {name: "too short", req: createCommentRequest{Content: " hi ", Author: "Al"}, limits: &limits,
+            want: map[string][]string{"content": {"content must be at least 3 characters"}}},
+        {name: "too long", req: createCommentRequest{Content: "hello world", Author: "Al"}, limits: &limits,
+            want: map[string][]string{"content": {"content must be at most 10 characters"}}},
+        {name: "characters not bytes", req: createCommentRequest{Content: "héllö wörl", Author: "Al"}, limits: &limits},
+        {name: "long author", req: createCommentRequest{Content: "hello", Author: "Alexander"}, limits: &limits,
+            want: map[string][]string{"author": {"author must be at most 5 characters"}}},
+        {name: "blank and too long", req: createCommentRequest{Content: strings.Repeat(" ", 11), Author: "Al"}, limits: &limits,
+            want: map[string][]string{"content": {"content is required", "content must be at most 10 characters"}}},
+        {name: "default limits", req: createCommentRequest{Content: strings.Repeat("x", 1001), Author: "Al"},
+            want: map[string][]string{"content": {"content must be at most 1000 characters"}}},
+        {name: "zero limits are off", req: createCommentRequest{Content: strings.Repeat("x", 5000), Author: strings.Repeat("a", 500)}, limits: &commentLimits{}},
+        {name: "attachments", req: createCommentRequest{Content: "hello", Author: "Al", Attachments: []string{"https://example.com/a.png", "HTTP://example.com/b"}}, limits: &limits},
+        {name: "too many attachments", req: createCommentRequest{Content: "hello", Author: "Al", Attachments: []string{"https://a.example", "https://b.example", "https://c.example"}}, limits: &limits,
+            want: map[string][]string{"attachments": {"at most 2 attachments are allowed"}}},
+        {name: "invalid attachments", req: createCommentRequest{Content: "hello", Author: "Al", Attachments: []string{"https://a.example", "/relative.png", "ftp://b.example/c"}}, limits: &commentLimits{MaxAttachmentURL: 30},
+            want: map[string][]string{
+                "attachments[1]": {"attachment must be an absolute http or https URL"},
+                "attachments[2]": {"attachment must be an absolute http or https URL"},
+            }},
+        {name: "long attachment", req: createCommentRequest{Content: "hello", Author: "Al", Attachments: []string{"https://example.com/" + strings.Repeat("x", 11)}}, limits: &limits,
+            want: map[string][]string{"attachments[0]": {"attachment must be at most 30 characters"}}},
+        {name: "empty attachment", req: createCommentRequest{Content: "hello", Author: "Al", Attachments: []string{""}}, limits: &limits,
+            want: map[string][]string{"attachments[0]": {"attachment must be an absolute http or https URL"}}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ctx := context.Background()
+            if tt.limits != nil {
+                ctx = withCommentLimits(ctx, *tt.limits)
+            }
+            got := tt.req.Valid(ctx)
+            if len(got) != len(tt.want) {
+                t.Fatalf("expected problems %v, got %v", tt.want, got)
+            }
+            for field, msgs := range tt.want {
+                if !slices.Equal(got[field], msgs) {
+                    t.Errorf("%s: expected %q, got %q", field, msgs, got[field])
+                }
+            }
+        })
+    }
+}
+
+// TestCreateCommentRequestUnicode decodes requests as the handlers do, so
+// content is normalized before its characters are counted.
+func TestCreateCommentRequestUnicode(t *testing.T) {
+    const decomposed = "e\u0301" // e and a combining acute accent; é in NFC
+
+    tests := []struct {
+        name        string
+        content     string
+        author      string
+        wantContent string
+        wantField   string
+    }{
+        {name: "multi-byte at limit", content: strings.Repeat("あ", 1000), author: "Al"},
+        {name: "multi-byte over limit", content: strings.Repeat("あ", 1001), author: "Al", wantField: "content"},
+        {name: "emoji at limit", content: strings.Repeat("😀", 1000), author: "Al"},
+        {name: "emoji over limit", content: strings.Repeat("😀", 1001), author: "Al", wantField: "content"},
+        {name: "combining characters composed", content: strings.Repeat(decomposed, 1000), author: "Al",
+            wantContent: strings.Repeat("\u00e9", 1000)},
+        {name: "combining characters over limit", content: strings.Repeat(decomposed, 1001), author: "Al", wantField: "content"},
+        {name: "author at limit", content: "hello", author: strings.Repeat("李", 100)},
+        {name: "author over limit", content: "hello", author: strings.Repeat("李", 101), wantField: "author"},
+        {name: "decomposed author at limit", content: "hello", author: strings.Repeat(decomposed, 100)},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            body, _ := json.Marshal(map[string]string{"content": tt.content, "author": tt.author})
+            r := httptest.NewRequest(http.MethodPost, "/api/v1/comments", strings.NewReader(string(body)))
+            r.Header.Set("Content-Type", "application/json")
+            req, problems, _ := decodeValid[createCommentRequest](r)
+
+            if tt.wantField == "" && len(problems) > 0 {
+                t.Fatalf("expected no problems, got %v", problems)
+            }
+            if tt.wantField != "" && (len(problems[tt.wantField]) != 1 || !strings.Contains(problems[tt.wantField][0], "at most")) {
+                t.Fatalf("expected %s to be too long, got %v", tt.wantField, problems)
+            }
+            if tt.wantContent != "" && req.Content != tt.wantContent {
+                t.Errorf("expected content normalized to NFC")
+            }
+        })
+    }
+}
+
+func TestDecodeValidJSONProblems(t *testing.T) {
+    tests := []struct {
+        name  string
+        body  string
+        field string
+        want  string
+    }{
+        {name: "wrong type", body: `{"content": 123, "author": "Al"}`, field: "content", want: "content must be a string, got number"},
+        {name: "wrong integer type", body: `{"content": "hi", "author": "Al", "ttl": "soon"}`, field: "ttl", want: "ttl must be an integer, got string"},
+        {name: "unknown field", body: `{"content": "hi", "autor": "Al"}`, field: "autor", want: "unknown field autor"},
+        {name: "syntax", body: `{"content": "hi",}`, field: "body", want: "request body is not valid JSON: error at byte 18"},
+        {name: "truncated", body: `{"content": "hi"`, field: "body", want: "request body is not valid JSON: unexpected end of input"},
+        {name: "empty", body: ``, field: "body", want: "request body is required"},
+        {name: "not an object", body: `["hi"]`, field: "body", want: "request body must be an object"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := httptest.NewRequest(http.MethodPost, "/api/v1/comments", strings.NewReader(tt.body))
+            r.Header.Set("Content-Type", "application/json")
+            _, _, err := decodeValid[createCommentRequest](r)
+            if err == nil {
+                t.Fatal("expected an error")
+            }
+
+            rec := httptest.NewRecorder()
+            encodeBadRequest(rec, r, err, nil)
+            var resp Problem
+            if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+                t.Fatal(err)
+            }
+            if got := resp.InvalidParams; !slices.Equal(got, []InvalidParam{{Name: tt.field, Reason: tt.want}}) {
+                t.Errorf("expected %s: %q, got %v", tt.field, tt.want, got)
+            }
+        })
+    }
+}
+
+func TestDecodeContentType(t *testing.T) {
+    tests := []struct {
+        name        string
+        contentType string
+        body        string
+        wantStatus  int
+    }{
+        {name: "json", contentType: "application/json", body: `{"content": "hi", "author": "Al"}`},
+        {name: "json with charset", contentType: "application/json; charset=UTF-8", body: `{"content": "hi", "author": "Al"}`},
+        {name: "json with other charset", contentType: "application/json; charset=iso-8859-1", body: `{"content": "hi", "author": "Al"}`, wantStatus: http.StatusUnsupportedMediaType},
+        {name: "form", contentType: "application/x-www-form-urlencoded", body: "content=hi&author=Al", wantStatus: http.StatusUnsupportedMediaType},
+        {name: "text", contentType: "text/plain", body: `{"content": "hi", "author": "Al"}`, wantStatus: http.StatusUnsupportedMediaType},
+        {name: "missing", body: `{"content": "hi", "author": "Al"}`, wantStatus: http.StatusUnsupportedMediaType},
+        {name: "missing without a body", wantStatus: http.StatusBadRequest},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := httptest.NewRequest(http.MethodPost, "/api/v1/comments", strings.NewReader(tt.body))
+            if tt.contentType != "" {
+                r.Header.Set("Content-Type", tt.contentType)
+            }
+            _, problems, err := decodeValid[createCommentRequest](r)
+            if tt.wantStatus == 0 {
+                if err != nil {
+                    t.Fatalf("expected the body decoded, got %v", err)
+                }
+                return
+            }
+
+            rec := httptest.NewRecorder()
+            encodeBadRequest(rec, r, err, problems)
+            if rec.Code != tt.wantStatus {
+                t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+            }
+        })
+    }
+}
+
+// failingMarshaler fails to encode, as a broken custom marshaller would.
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+    return nil, errors.New("cannot marshal")
+}
+
+func TestEncodeFailure(t *testing.T) {
+    r := httptest.NewRequest(http.MethodGet, "/api/v1/comments", nil)
+    rec := httptest.NewRecorder()
+    err := encode(rec, r, http.StatusCreated, map[string]any{"id": "c1", "broken": failingMarshaler{}})
+    if err == nil {
+        t.Fatal("expected the encoding error returned")
+    }
+
+    // Nothing of the failed body is sent, only a 500 problem
+    if rec.Code != http.StatusInternalServerError {
+        t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+    }
+    if ct := rec.Header().Get("Content-Type"); ct != mediaTypeProblemJSON {
+        t.Errorf("expected Content-Type %s, got %q", mediaTypeProblemJSON, ct)
+    }
+    var p Problem
+    if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+        t.Fatalf("expected a complete problem body, got %q: %v", rec.Body, err)
+    }
+    if p.Code != codeInternal || p.Status != http.StatusInternalServerError {
+        t.Errorf("expected an internal error problem, got %+v", p)
+    }
+    if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+        t.Errorf("expected Content-Length %d, got %q", rec.Body.Len(), got)
+    }
+}
+
+func TestEncodeContentLength(t *testing.T) {
+    for _, accept := range []string{"application/json", "application/xml"} {
+        r := httptest.NewRequest(http.MethodGet, "/api/v1/comments/c1", nil)
+        r.Header.Set("Accept", accept)
+        rec := httptest.NewRecorder()
+        if err := encode(rec, r, http.StatusOK, commentResponse{ID: "c1", Content: "hi"}); err != nil {
+            t.Fatal(err)
+        }
+        if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+            t.Errorf("%s: expected Content-Length %d, got %q", accept, rec.Body.Len(), got)
+        }
+    }
+}
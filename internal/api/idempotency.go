@@ -0,0 +1,141 @@
+// internal/api/idempotency.go
+
+package api
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/json"
+    "strings"
+    "sync"
+    "time"
+    "web-service/internal/erasure"
+)
+
+// idempotencyKeyHeader lets clients retry a comment creation safely: a
+// repeat with the same key returns the comment the first request created.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayedHeader marks a response replayed for a repeated key.
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotencyEntries bounds the keys kept before expired ones are swept.
+const maxIdempotencyEntries = 10000
+
+type idempotencyState int
+
+const (
+    idempotencyNew idempotencyState = iota
+    idempotencyReplay
+    idempotencyInFlight
+    idempotencyMismatch
+)
+
+type idempotencyEntry struct {
+    fingerprint [sha256.Size]byte
+    resp        *commentResponse // nil while the first request is in flight
+    expires     time.Time
+}
+
+// idempotencyCache remembers the comment created for each idempotency key
+// for ttl.
+type idempotencyCache struct {
+    mu      sync.Mutex
+    entries map[string]*idempotencyEntry
+    ttl     time.Duration
+    now     func() time.Time
+}
+
+// newIdempotencyCache returns a cache keeping keys for ttl, or nil when
+// ttl <= 0 and the header should be ignored.
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+    if ttl <= 0 {
+        return nil
+    }
+    return &idempotencyCache{
+        entries: make(map[string]*idempotencyEntry),
+        ttl:     ttl,
+        now:     time.Now,
+    }
+}
+
+// idempotencyScope scopes key to userID, so one user can't replay another's
+// comment by guessing their key.
+func idempotencyScope(userID, key string) string {
+    return userID + "\x00" + key
+}
+
+// requestFingerprint hashes a create request so a key reused for a
+// different comment can be told apart from a retry.
+func requestFingerprint(req createCommentRequest) [sha256.Size]byte {
+    data, _ := json.Marshal(req)
+    return sha256.Sum256(data)
+}
+
+// begin claims key for a request with fingerprint. When the key was used
+// before it returns the state of that use and, for a replay, the response
+// it produced. A claimed key must be finished with complete or release.
+func (c *idempotencyCache) begin(key string, fingerprint [sha256.Size]byte) (idempotencyState, *commentResponse) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    now := c.now()
+    if e, ok := c.entries[key]; ok && (e.resp == nil || now.Before(e.expires)) {
+        switch {
+        case e.fingerprint != fingerprint:
+            return idempotencyMismatch, nil
+        case e.resp == nil:
+            return idempotencyInFlight, nil
+        default:
+            return idempotencyReplay, e.resp
+        }
+    }
+
+    if len(c.entries) >= maxIdempotencyEntries {
+        for k, e := range c.entries {
+            if e.resp != nil && !now.Before(e.expires) {
+                delete(c.entries, k)
+            }
+        }
+    }
+    c.entries[key] = &idempotencyEntry{fingerprint: fingerprint}
+    return idempotencyNew, nil
+}
+
+// complete stores the response for a claimed key.
+func (c *idempotencyCache) complete(key string, resp commentResponse) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if e, ok := c.entries[key]; ok && e.resp == nil {
+        e.resp = &resp
+        e.expires = c.now().Add(c.ttl)
+    }
+}
+
+// release gives up a claimed key whose request failed, so a retry can try
+// again. Completed keys are left alone.
+func (c *idempotencyCache) release(key string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if e, ok := c.entries[key]; ok && e.resp == nil {
+        delete(c.entries, key)
+    }
+}
+
+// EraseUser drops the keys userID used, and with them the copies of their
+// comments kept for replay.
+func (c *idempotencyCache) EraseUser(ctx context.Context, userID string) (erasure.Report, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    var report erasure.Report
+    for key := range c.entries {
+        if strings.HasPrefix(key, idempotencyScope(userID, "")) {
+            delete(c.entries, key)
+            report.Removed++
+        }
+    }
+    return report, nil
+}
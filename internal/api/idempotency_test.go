@@ -0,0 +1,63 @@
+// internal/api/idempotency_test.go
+
+package api
+
+import (
+    "testing"
+    "time"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    c := newIdempotencyCache(time.Hour)
+    c.now = func() time.Time { return now }
+
+    req := createCommentRequest{Content: "hello", Author: "Alice"}
+    fp := requestFingerprint(req)
+
+    if state, _ := c.begin("u1\x00k", fp); state != idempotencyNew {
+        t.Fatalf("first use: expected new, got %v", state)
+    }
+    if state, _ := c.begin("u1\x00k", fp); state != idempotencyInFlight {
+        t.Errorf("concurrent use: expected in flight, got %v", state)
+    }
+
+    c.complete("u1\x00k", commentResponse{ID: "c1"})
+    state, resp := c.begin("u1\x00k", fp)
+    if state != idempotencyReplay || resp == nil || resp.ID != "c1" {
+        t.Errorf("retry: expected replay of c1, got %v %+v", state, resp)
+    }
+
+    other := requestFingerprint(createCommentRequest{Content: "changed", Author: "Alice"})
+    if state, _ := c.begin("u1\x00k", other); state != idempotencyMismatch {
+        t.Errorf("different body: expected mismatch, got %v", state)
+    }
+
+    now = now.Add(time.Hour)
+    if state, _ := c.begin("u1\x00k", other); state != idempotencyNew {
+        t.Errorf("after ttl: expected new, got %v", state)
+    }
+}
+
+func TestIdempotencyCacheRelease(t *testing.T) {
+    c := newIdempotencyCache(time.Hour)
+    fp := requestFingerprint(createCommentRequest{Content: "hello", Author: "Alice"})
+
+    c.begin("k", fp)
+    c.release("k")
+    if state, _ := c.begin("k", fp); state != idempotencyNew {
+        t.Errorf("after failed request: expected new, got %v", state)
+    }
+
+    c.complete("k", commentResponse{ID: "c1"})
+    c.release("k")
+    if state, _ := c.begin("k", fp); state != idempotencyReplay {
+        t.Errorf("release after complete: expected replay, got %v", state)
+    }
+}
+
+func TestIdempotencyCacheDisabled(t *testing.T) {
+    if c := newIdempotencyCache(0); c != nil {
+        t.Error("expected no cache for zero ttl")
+    }
+}
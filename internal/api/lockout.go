@@ -0,0 +1,159 @@
+// internal/api/lockout.go
+
+package api
+
+import (
+    "sync"
+    "time"
+)
+
+// maxLockoutEntries bounds the failure counters kept before stale ones are
+// swept.
+const maxLockoutEntries = 10000
+
+type lockoutEntry struct {
+    failures    int
+    firstFailed time.Time
+    lockedUntil time.Time
+}
+
+// loginLockout counts failed logins per key, such as a username or client
+// IP, and locks a key out for a cooldown once it reaches its limit within
+// the failure window.
+type loginLockout struct {
+    mu       sync.Mutex
+    entries  map[string]*lockoutEntry
+    window   time.Duration
+    cooldown time.Duration
+    now      func() time.Time
+}
+
+func newLoginLockout(window, cooldown time.Duration) *loginLockout {
+    return &loginLockout{
+        entries:  make(map[string]*lockoutEntry),
+        window:   window,
+        cooldown: cooldown,
+        now:      time.Now,
+    }
+}
+
+// lockedFor returns how much longer key is locked out, or zero.
+func (l *loginLockout) lockedFor(key string) time.Duration {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if e, ok := l.entries[key]; ok {
+        if wait := e.lockedUntil.Sub(l.now()); wait > 0 {
+            return wait
+        }
+    }
+    return 0
+}
+
+// lockoutLimit pairs a lockout key with the failures it may reach within
+// the window. A limit <= 0 never locks.
+type lockoutLimit struct {
+    key   string
+    limit int
+}
+
+// loginAttempt is a login counted against its keys by reserve. Its
+// failures stand unless cancelled.
+type loginAttempt struct {
+    lockout *loginLockout
+    counted []countedKey
+}
+
+// countedKey records what reserve did to an entry, so cancel can undo it.
+type countedKey struct {
+    key   string
+    entry *lockoutEntry
+    // prev is the entry before the attempt, kept when the attempt locked
+    // the key
+    prev   lockoutEntry
+    locked bool
+}
+
+// reserve counts a login attempt as a failure against each key before the
+// credentials are checked, so that concurrent guesses can't all pass the
+// lockout check before any of them fails. The check and the count happen
+// under one lock. When a key is already locked, reserve counts nothing
+// and returns how long the longest lockout has left.
+func (l *loginLockout) reserve(limits ...lockoutLimit) (*loginAttempt, time.Duration) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := l.now()
+    var wait time.Duration
+    for _, lim := range limits {
+        if e, ok := l.entries[lim.key]; ok {
+            wait = max(wait, e.lockedUntil.Sub(now))
+        }
+    }
+    if wait > 0 {
+        return nil, wait
+    }
+
+    attempt := &loginAttempt{lockout: l}
+    for _, lim := range limits {
+        if lim.limit <= 0 {
+            continue
+        }
+        e, ok := l.entries[lim.key]
+        if !ok || (now.Sub(e.firstFailed) > l.window && !now.Before(e.lockedUntil)) {
+            if len(l.entries) >= maxLockoutEntries {
+                l.sweep(now)
+            }
+            e = &lockoutEntry{firstFailed: now}
+            l.entries[lim.key] = e
+        }
+        c := countedKey{key: lim.key, entry: e, prev: *e}
+        e.failures++
+        if e.failures >= lim.limit {
+            e.lockedUntil = now.Add(l.cooldown)
+            e.failures = 0
+            e.firstFailed = now
+            c.locked = true
+        }
+        attempt.counted = append(attempt.counted, c)
+    }
+    return attempt, 0
+}
+
+// cancel takes back the failures reserve counted, for an attempt whose
+// credentials checked out or that failed for reasons of its own. Entries
+// that have since rolled over to a new window are left alone.
+func (a *loginAttempt) cancel() {
+    l := a.lockout
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    for _, c := range a.counted {
+        if l.entries[c.key] != c.entry {
+            continue
+        }
+        switch {
+        case c.locked:
+            *c.entry = c.prev
+        case c.entry.failures > 0:
+            c.entry.failures--
+        }
+    }
+}
+
+// reset clears key's failures after a successful login.
+func (l *loginLockout) reset(key string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    delete(l.entries, key)
+}
+
+// sweep drops entries that are neither locked nor within the window.
+// Callers must hold mu.
+func (l *loginLockout) sweep(now time.Time) {
+    for key, e := range l.entries {
+        if now.Sub(e.firstFailed) > l.window && !now.Before(e.lockedUntil) {
+            delete(l.entries, key)
+        }
+    }
+}
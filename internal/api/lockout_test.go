@@ -0,0 +1,150 @@
+// internal/api/lockout_test.go
+
+package api
+
+import (
+    "sync"
+    "testing"
+    "time"
+)
+
+func newTestLockout(now *time.Time) *loginLockout {
+    l := newLoginLockout(time.Minute, 5*time.Minute)
+    l.now = func() time.Time { return *now }
+    return l
+}
+
+func TestLoginLockout(t *testing.T) {
+    start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+    t.Run("locks after limit", func(t *testing.T) {
+        now := start
+        l := newTestLockout(&now)
+        for i := 0; i < 2; i++ {
+            l.reserve(lockoutLimit{key: "user:bob", limit: 3})
+            if wait := l.lockedFor("user:bob"); wait != 0 {
+                t.Fatalf("locked after %d failures", i+1)
+            }
+        }
+        l.reserve(lockoutLimit{key: "user:bob", limit: 3})
+        if wait := l.lockedFor("user:bob"); wait != 5*time.Minute {
+            t.Errorf("expected 5m lockout, got %v", wait)
+        }
+        if wait := l.lockedFor("user:alice"); wait != 0 {
+            t.Errorf("other key locked for %v", wait)
+        }
+    })
+
+    t.Run("lockout expires", func(t *testing.T) {
+        now := start
+        l := newTestLockout(&now)
+        l.reserve(lockoutLimit{key: "ip:10.0.0.1", limit: 1})
+        now = now.Add(4 * time.Minute)
+        if wait := l.lockedFor("ip:10.0.0.1"); wait != time.Minute {
+            t.Errorf("expected 1m left, got %v", wait)
+        }
+        now = now.Add(time.Minute)
+        if wait := l.lockedFor("ip:10.0.0.1"); wait != 0 {
+            t.Errorf("still locked for %v after cooldown", wait)
+        }
+    })
+
+    t.Run("failures outside window are forgotten", func(t *testing.T) {
+        now := start
+        l := newTestLockout(&now)
+        l.reserve(lockoutLimit{key: "user:bob", limit: 2})
+        now = now.Add(2 * time.Minute)
+        l.reserve(lockoutLimit{key: "user:bob", limit: 2})
+        if wait := l.lockedFor("user:bob"); wait != 0 {
+            t.Errorf("locked for %v by failures spread past the window", wait)
+        }
+    })
+
+    t.Run("reset clears failures", func(t *testing.T) {
+        now := start
+        l := newTestLockout(&now)
+        l.reserve(lockoutLimit{key: "user:bob", limit: 2})
+        l.reset("user:bob")
+        l.reserve(lockoutLimit{key: "user:bob", limit: 2})
+        if wait := l.lockedFor("user:bob"); wait != 0 {
+            t.Errorf("locked for %v after reset", wait)
+        }
+    })
+
+    t.Run("cancel takes back the failure", func(t *testing.T) {
+        now := start
+        l := newTestLockout(&now)
+        l.reserve(lockoutLimit{key: "ip:10.0.0.1", limit: 2})
+        attempt, _ := l.reserve(lockoutLimit{key: "ip:10.0.0.1", limit: 2})
+        if wait := l.lockedFor("ip:10.0.0.1"); wait == 0 {
+            t.Fatal("expected the reserved attempt to lock the key")
+        }
+        attempt.cancel()
+        if wait := l.lockedFor("ip:10.0.0.1"); wait != 0 {
+            t.Fatalf("still locked for %v after cancel", wait)
+        }
+        // The failure before the cancelled attempt still counts
+        l.reserve(lockoutLimit{key: "ip:10.0.0.1", limit: 2})
+        if wait := l.lockedFor("ip:10.0.0.1"); wait != 5*time.Minute {
+            t.Errorf("expected 5m lockout, got %v", wait)
+        }
+    })
+
+    t.Run("locked key reserves nothing", func(t *testing.T) {
+        now := start
+        l := newTestLockout(&now)
+        l.reserve(lockoutLimit{key: "user:bob", limit: 1})
+        attempt, wait := l.reserve(
+            lockoutLimit{key: "user:bob", limit: 1},
+            lockoutLimit{key: "ip:10.0.0.1", limit: 1},
+        )
+        if attempt != nil || wait != 5*time.Minute {
+            t.Fatalf("expected a 5m wait and no attempt, got %v and %v", attempt, wait)
+        }
+        if wait := l.lockedFor("ip:10.0.0.1"); wait != 0 {
+            t.Errorf("refused attempt counted against the IP: locked for %v", wait)
+        }
+    })
+
+    t.Run("zero limit never locks", func(t *testing.T) {
+        now := start
+        l := newTestLockout(&now)
+        for i := 0; i < 100; i++ {
+            l.reserve(lockoutLimit{key: "user:bob", limit: 0})
+        }
+        if wait := l.lockedFor("user:bob"); wait != 0 {
+            t.Errorf("locked for %v with limit 0", wait)
+        }
+    })
+}
+
+func TestLoginLockoutConcurrent(t *testing.T) {
+    l := newLoginLockout(time.Minute, time.Minute)
+
+    // However the guesses interleave, no more than the limit get through
+    // to the credentials check
+    var (
+        wg       sync.WaitGroup
+        mu       sync.Mutex
+        admitted int
+    )
+    for i := 0; i < 50; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if attempt, _ := l.reserve(lockoutLimit{key: "user:bob", limit: 5}); attempt != nil {
+                mu.Lock()
+                admitted++
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    if admitted != 5 {
+        t.Errorf("expected 5 attempts admitted, got %d", admitted)
+    }
+    if wait := l.lockedFor("user:bob"); wait <= 0 {
+        t.Error("expected lockout after 50 concurrent failures")
+    }
+}
@@ -0,0 +1,144 @@
+// internal/api/me.go
+
+package api
+
+import (
+    "context"
+    "encoding/xml"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+    "web-service/internal/config"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// authorNameChangeInterval is how often a user may change their author name.
+const authorNameChangeInterval = 24 * time.Hour
+
+type authorNameRequest struct {
+    AuthorName string `json:"author_name"`
+    // RewriteComments also rewrites the author on the user's existing
+    // comments; otherwise their history is left as it was.
+    RewriteComments bool `json:"rewrite_comments"`
+}
+
+type authorNameResponse struct {
+    XMLName         xml.Name `json:"-" xml:"author_name"`
+    AuthorName      string   `json:"author_name" xml:"name"`
+    CommentsUpdated int      `json:"comments_updated" xml:"comments_updated"`
+}
+
+var _ normalizer = (*authorNameRequest)(nil)
+
+func (r *authorNameRequest) normalize(ctx context.Context) {
+    r.AuthorName = normalizeText(r.AuthorName)
+}
+
+func (r authorNameRequest) Valid(ctx context.Context) map[string][]string {
+    problems := make(problemMap)
+    if strings.TrimSpace(r.AuthorName) == "" {
+        problems.add("author_name", "author_name is required")
+    }
+    // An author name ends up as the author of comments, so it has the
+    // same limit
+    if limit := commentLimitsFromContext(ctx).MaxAuthor; limit > 0 && utf8.RuneCountInString(r.AuthorName) > limit {
+        problems.add("author_name", "author_name must be at most "+strconv.Itoa(limit)+" characters")
+    }
+    return problems
+}
+
+// intervalLimiter allows one action per key every interval.
+type intervalLimiter struct {
+    mu       sync.Mutex
+    last     map[string]time.Time
+    interval time.Duration
+    now      func() time.Time
+}
+
+func newIntervalLimiter(interval time.Duration) *intervalLimiter {
+    return &intervalLimiter{
+        last:     make(map[string]time.Time),
+        interval: interval,
+        now:      time.Now,
+    }
+}
+
+// reserve claims key's slot, or returns how long until it is free. A
+// claimed slot whose action then fails should be given back with release.
+func (l *intervalLimiter) reserve(key string) (time.Duration, bool) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := l.now()
+    if last, ok := l.last[key]; ok {
+        if wait := last.Add(l.interval).Sub(now); wait > 0 {
+            return wait, false
+        }
+    }
+    l.last[key] = now
+    return 0, true
+}
+
+func (l *intervalLimiter) release(key string) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    delete(l.last, key)
+}
+
+// Author name rectification handler. Lets users correct the name shown on
+// their comments, optionally rewriting every existing comment in one batch.
+// Rewrites are allowed at most once per authorNameChangeInterval.
+func handleUpdateAuthorName(logger *logging.Logger, config *config.Config, store storage.CommentRepository, limiter *intervalLimiter) http.Handler {
+    limits := newCommentLimits(config)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        r = r.WithContext(withCommentLimits(r.Context(), limits))
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        l := logger.With("user_id", userID)
+
+        req, problems, err := decodeValid[authorNameRequest](r)
+        if err != nil {
+            l.Error(ctx, "failed to decode request",
+                "error", err,
+            )
+            encodeBadRequest(w, r, err, problems)
+            return
+        }
+        name := strings.TrimSpace(req.AuthorName)
+
+        // Without rewrite_comments nothing is stored, so only a rewrite
+        // uses up the day's change
+        updated := 0
+        if req.RewriteComments {
+            if wait, ok := limiter.reserve(userID); !ok {
+                w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+                encodeError(w, r, http.StatusTooManyRequests, codeRateLimited, "Author name can only be changed once per day")
+                return
+            }
+            updated, err = store.RenameAuthor(ctx, userID, name)
+            if err != nil {
+                limiter.release(userID)
+                l.Error(ctx, "failed to rewrite author name",
+                    "error", err,
+                )
+                encodeInternalError(w, r, err)
+                return
+            }
+        }
+
+        l.Info(ctx, "audit: author name rectified",
+            "rewrite_comments", req.RewriteComments,
+            "comments_updated", updated,
+        )
+
+        if err := encode(w, r, http.StatusOK, authorNameResponse{AuthorName: name, CommentsUpdated: updated}); err != nil {
+            l.Error(ctx, "failed to encode response",
+                "error", err,
+            )
+        }
+    })
+}
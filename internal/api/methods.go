@@ -0,0 +1,62 @@
+// internal/api/methods.go
+
+package api
+
+import (
+    "net/http"
+    "slices"
+    "strings"
+)
+
+// routedMethods are the methods routes are registered under. OPTIONS is
+// answered for every route by the CORS middleware rather than registered.
+var routedMethods = []string{
+    http.MethodDelete,
+    http.MethodGet,
+    http.MethodHead,
+    http.MethodPatch,
+    http.MethodPost,
+    http.MethodPut,
+}
+
+// allowedMethods returns the methods mux serves at r's path, found by
+// routing r under each method in turn. It is empty when no route matches
+// the path.
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+    var methods []string
+    probe := *r
+    for _, method := range routedMethods {
+        probe.Method = method
+        if _, pattern := mux.Handler(&probe); pattern != "" {
+            methods = append(methods, method)
+        }
+    }
+    return methods
+}
+
+// allowHeader formats methods, plus OPTIONS, for an Allow header, sorted
+// as the mux sorts its own.
+func allowHeader(methods []string) string {
+    methods = append(slices.Clone(methods), http.MethodOptions)
+    slices.Sort(methods)
+    return strings.Join(methods, ", ")
+}
+
+// newMethodNotAllowedHandler serves mux, answering a request whose path
+// has routes but none for its method with 405 and an Allow header listing
+// the methods that do, and one whose path has no routes at all with a 404
+// in the API's error format rather than the mux's plain text.
+func newMethodNotAllowedHandler(mux *http.ServeMux) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if _, pattern := mux.Handler(r); pattern == "" {
+            if methods := allowedMethods(mux, r); len(methods) > 0 {
+                w.Header().Set("Allow", allowHeader(methods))
+                encodeError(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+                return
+            }
+            encodeError(w, r, http.StatusNotFound, codeNotFound, "Not found")
+            return
+        }
+        mux.ServeHTTP(w, r)
+    })
+}
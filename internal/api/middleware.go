@@ -3,60 +3,283 @@
 package api
 
 import (
+    "cmp"
     "context"
+    "errors"
     "net/http"
     "strings"
     "time"
     "web-service/internal/auth"
+    "web-service/internal/config"
+    "web-service/internal/realip"
+    "web-service/pkg/logging"
 )
 
 type contextKey string
 
 const (
-    UserIDKey contextKey = "user_id"
+    UserIDKey   contextKey = "user_id"
     UserRoleKey contextKey = "user_role"
+
+    // untimedContextKey holds a request's context from before the timeout
+    // middleware, for routes opted out with withoutTimeout.
+    untimedContextKey contextKey = "untimed_context"
+
+    // commentLimitsKey holds the commentLimits createCommentRequest is
+    // validated against.
+    commentLimitsKey contextKey = "comment_limits"
+
+    // requestTimeKey holds the time, by the store's clock, a
+    // createCommentRequest's expiry is checked against.
+    requestTimeKey contextKey = "request_time"
+
+    // contentPolicyKey holds the config.ContentPolicy createCommentRequest
+    // content is sanitized under.
+    contentPolicyKey contextKey = "content_policy"
+
+    // errorFormatKey holds the config.ErrorFormat error responses take.
+    errorFormatKey contextKey = "error_format"
+
+    // mediaTypeKey holds the response media type negotiated before a
+    // request that changes something reaches its handler.
+    mediaTypeKey contextKey = "media_type"
 )
 
-func newAuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
-    jwtManager := auth.NewJWTManager(jwtSecret, 24*time.Hour)
+// Middleware wraps a handler with behaviour of its own.
+type Middleware func(http.Handler) http.Handler
 
+// Chain wraps h in mw, the first outermost, so
+// Chain(h, authenticate, requireAdmin) authenticates before checking the
+// role.
+func Chain(h http.Handler, mw ...Middleware) http.Handler {
+    for i := len(mw) - 1; i >= 0; i-- {
+        h = mw[i](h)
+    }
+    return h
+}
+
+// newAuthMiddleware rejects requests without a valid bearer token with
+// 401. Routes opt in with Chain; public routes are registered without it.
+func newAuthMiddleware(logger *logging.Logger, tokens auth.TokenService) Middleware {
     return func(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-            // Skip auth for health check and other public endpoints
-            if r.URL.Path == "/healthz" || r.URL.Path == "/api/v1/login" {
-                next.ServeHTTP(w, r)
-                return
-            }
-
             authHeader := r.Header.Get("Authorization")
             if !strings.HasPrefix(authHeader, "Bearer ") {
-                http.Error(w, "Unauthorized", http.StatusUnauthorized)
+                encodeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
                 return
             }
 
             tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-            claims, err := jwtManager.ValidateToken(tokenStr)
+            claims, err := tokens.ValidateToken(tokenStr)
             if err != nil {
-                http.Error(w, "Invalid token", http.StatusUnauthorized)
+                reason := "invalid"
+                switch {
+                case errors.Is(err, auth.ErrTokenExpired):
+                    reason = "expired"
+                case errors.Is(err, auth.ErrInvalidIssuer):
+                    reason = "issuer"
+                case errors.Is(err, auth.ErrInvalidAudience):
+                    reason = "audience"
+                }
+                logger.Warn(r.Context(), "rejected token",
+                    "reason", reason,
+                    "error", err.Error(),
+                    "client_ip", realip.FromRequest(r),
+                )
+                encodeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Invalid token")
                 return
             }
 
-            // Add user info to context
+            // Add user info to context, and to the access log entry
             ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
             ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
+            logging.SetUserID(ctx, claims.UserID)
             next.ServeHTTP(w, r.WithContext(ctx))
         })
     }
 }
 
-func newCORSMiddleware() func(http.Handler) http.Handler {
+// newJWTManager builds the token manager described by config.
+func newJWTManager(config *config.Config) *auth.JWTManager {
+    opts := []auth.Option{
+        auth.WithIssuer(config.JWTIssuer),
+        auth.WithAudience(config.JWTAudience),
+        auth.WithLeeway(config.JWTLeeway),
+    }
+    if config.JWTAlgorithm == "RS256" {
+        return auth.NewJWTManagerRS256(config.JWTPrivateKey, config.JWTPublicKey, config.JWTExpiry, opts...)
+    }
+    current := auth.HMACKey{ID: config.JWTKeyID, Secret: config.JWTSecret}
+    return auth.NewJWTManagerKeys(current, config.JWTPreviousKeys, config.JWTExpiry, opts...)
+}
+
+// newRequireRoleMiddleware rejects requests whose authenticated role is not
+// role with 403, so protected endpoints are forbidden rather than hidden.
+func newRequireRoleMiddleware(role string) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if UserRoleFromContext(r.Context()) != role {
+                encodeError(w, r, http.StatusForbidden, codeForbidden, "Forbidden")
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// newTimeoutMiddleware cancels each request's context after timeout, so
+// handlers and the store operations they call give up and the request
+// fails with 503 (see encodeInternalError). A timeout <= 0 disables it.
+func newTimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        if timeout <= 0 {
+            return next
+        }
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            ctx, cancel := context.WithTimeout(r.Context(), timeout)
+            defer cancel()
+
+            ctx = context.WithValue(ctx, untimedContextKey, r.Context())
+            next.ServeHTTP(w, r.WithContext(ctx))
+        })
+    }
+}
+
+// withoutTimeout exempts a long-running route from the request timeout by
+// restoring the context it had before newTimeoutMiddleware, keeping any
+// values added since.
+func withoutTimeout(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        parent, ok := r.Context().Value(untimedContextKey).(context.Context)
+        if !ok {
+            next.ServeHTTP(w, r)
+            return
+        }
+        next.ServeHTTP(w, r.WithContext(untimedContext{Context: parent, values: r.Context()}))
+    })
+}
+
+// withRedactedBodies keeps a route's request and response bodies out of
+// the body log, for routes such as login whose whole exchange is secret.
+func withRedactedBodies(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        logging.RedactBodies(r.Context())
+        next.ServeHTTP(w, r)
+    })
+}
+
+// untimedContext takes cancellation from its embedded Context and values
+// from values.
+type untimedContext struct {
+    context.Context
+    values context.Context
+}
+
+func (c untimedContext) Value(key any) any {
+    return c.values.Value(key)
+}
+
+// newDrainingMiddleware rejects requests that arrive once shutdown has
+// closed done with 503 and Retry-After, so load balancers stop routing here
+// while requests already in flight finish. A nil done never closes.
+func newDrainingMiddleware(done <-chan struct{}) Middleware {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            select {
+            case <-done:
+                w.Header().Set("Connection", "close")
+                w.Header().Set("Retry-After", "1")
+                encodeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "Server is shutting down")
+                return
+            default:
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// noContentRoutes are the routes that answer success with 204 and no body,
+// so there is nothing for a client's Accept header to refuse.
+var noContentRoutes = map[string]bool{
+    "DELETE /api/v1/comments/{id}": true,
+}
+
+// newNegotiationMiddleware answers a request that would change something
+// with 406 before any handler runs when it accepts none of the supported
+// media types, so a client told its request failed can rely on nothing
+// having been written. The negotiated type is kept for encode. Safe
+// methods are left to negotiate as they encode, since routes such as the
+// event stream serve types of their own, and so are the routes in
+// noContentRoutes, found through mux.
+func newNegotiationMiddleware(mux *http.ServeMux) Middleware {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            switch r.Method {
+            case http.MethodGet, http.MethodHead, http.MethodOptions:
+                next.ServeHTTP(w, r)
+                return
+            }
+            if _, pattern := mux.Handler(r); noContentRoutes[pattern] {
+                next.ServeHTTP(w, r)
+                return
+            }
+            mediaType, ok := negotiate(r)
+            if !ok {
+                encodeNotAcceptable(w, r)
+                return
+            }
+            next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), mediaTypeKey, mediaType)))
+        })
+    }
+}
+
+// Default security header values, used unless config overrides them.
+const (
+    defaultFrameOptions   = "DENY"
+    defaultReferrerPolicy = "no-referrer"
+    defaultHSTS           = "max-age=63072000; includeSubDomains"
+)
+
+// newSecurityHeadersMiddleware sets hardening headers on every response.
+// Strict-Transport-Security is only sent over TLS, where browsers honour it.
+func newSecurityHeadersMiddleware(config *config.Config) func(http.Handler) http.Handler {
+    frameOptions := cmp.Or(config.SecurityFrameOptions, defaultFrameOptions)
+    referrerPolicy := cmp.Or(config.SecurityReferrerPolicy, defaultReferrerPolicy)
+    hsts := cmp.Or(config.SecurityHSTS, defaultHSTS)
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            h := w.Header()
+            h.Set("X-Content-Type-Options", "nosniff")
+            h.Set("X-Frame-Options", frameOptions)
+            h.Set("Referrer-Policy", referrerPolicy)
+            if r.TLS != nil {
+                h.Set("Strict-Transport-Security", hsts)
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// newCORSMiddleware sets CORS headers on every response and answers
+// preflight OPTIONS requests itself, with the methods mux serves at the
+// path in Allow and Access-Control-Allow-Methods. A path with no routes
+// gets 404.
+func newCORSMiddleware(mux *http.ServeMux) func(http.Handler) http.Handler {
     return func(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
             w.Header().Set("Access-Control-Allow-Origin", "*")
-            w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-            w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+            w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Field-Case, X-Request-ID, X-Enable-Experimental, X-Consistency-Token, Idempotency-Key, If-Match, If-Modified-Since")
+            w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Consistency-Token, X-Total-Count, Link, ETag, Last-Modified, X-Already-Deleted, Idempotent-Replayed, Retry-After")
 
-            if r.Method == "OPTIONS" {
+            if r.Method == http.MethodOptions {
+                methods := allowedMethods(mux, r)
+                if len(methods) == 0 {
+                    encodeError(w, r, http.StatusNotFound, codeNotFound, "Not found")
+                    return
+                }
+                w.Header().Set("Allow", allowHeader(methods))
+                w.Header().Set("Access-Control-Allow-Methods", allowHeader(methods))
                 w.WriteHeader(http.StatusOK)
                 return
             }
@@ -79,4 +302,4 @@ func UserRoleFromContext(ctx context.Context) string {
         return role
     }
     return ""
-}
\ No newline at end of file
+}
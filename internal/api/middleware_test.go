@@ -0,0 +1,102 @@
+// internal/api/middleware_test.go
+
+package api
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+    // slow stands in for a handler stuck on a store call that honours ctx
+    slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-r.Context().Done():
+            encodeInternalError(w, r, r.Context().Err())
+        case <-time.After(200 * time.Millisecond):
+            if r.Context().Value(UserIDKey) != "u1" {
+                t.Error("expected context values to survive withoutTimeout")
+            }
+            w.WriteHeader(http.StatusOK)
+        }
+    })
+
+    tests := []struct {
+        name       string
+        handler    http.Handler
+        timeout    time.Duration
+        wantStatus int
+    }{
+        {name: "exceeded", handler: slow, timeout: 20 * time.Millisecond, wantStatus: http.StatusServiceUnavailable},
+        {name: "disabled", handler: slow, timeout: 0, wantStatus: http.StatusOK},
+        {name: "opted out", handler: withoutTimeout(slow), timeout: 20 * time.Millisecond, wantStatus: http.StatusOK},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodGet, "/", nil)
+            req = req.WithContext(context.WithValue(req.Context(), UserIDKey, "u1"))
+            rec := httptest.NewRecorder()
+
+            newTimeoutMiddleware(tt.timeout)(tt.handler).ServeHTTP(rec, req)
+            if rec.Code != tt.wantStatus {
+                t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+            }
+        })
+    }
+}
+
+func TestChain(t *testing.T) {
+    var order []string
+    mark := func(name string) Middleware {
+        return func(next http.Handler) http.Handler {
+            return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                order = append(order, name)
+                next.ServeHTTP(w, r)
+            })
+        }
+    }
+    h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        order = append(order, "handler")
+    })
+
+    Chain(h, mark("outer"), mark("inner")).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+    if got := strings.Join(order, ","); got != "outer,inner,handler" {
+        t.Errorf("expected outer,inner,handler, got %s", got)
+    }
+}
+
+func TestDrainingMiddleware(t *testing.T) {
+    ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+    })
+    closed := make(chan struct{})
+    close(closed)
+
+    tests := []struct {
+        name       string
+        done       <-chan struct{}
+        wantStatus int
+    }{
+        {name: "serving", done: make(chan struct{}), wantStatus: http.StatusOK},
+        {name: "no signal", done: nil, wantStatus: http.StatusOK},
+        {name: "shutting down", done: closed, wantStatus: http.StatusServiceUnavailable},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := httptest.NewRecorder()
+            newDrainingMiddleware(tt.done)(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+            if rec.Code != tt.wantStatus {
+                t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+            }
+            if tt.wantStatus == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+                t.Error("expected Retry-After on 503")
+            }
+        })
+    }
+}
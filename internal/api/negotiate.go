@@ -0,0 +1,151 @@
+// internal/api/negotiate.go
+
+package api
+
+import (
+    "encoding/xml"
+    "net/http"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+const (
+    mediaTypeJSON = "application/json"
+    mediaTypeXML  = "application/xml"
+)
+
+// supportedMediaTypes lists the response formats in order of server
+// preference. The first entry is used when the client has no preference.
+var supportedMediaTypes = []string{mediaTypeJSON, mediaTypeXML}
+
+// negotiate picks the response media type for r based on its Accept header.
+// It returns false when none of the supported types is acceptable.
+func negotiate(r *http.Request) (string, bool) {
+    if r == nil {
+        return mediaTypeJSON, true
+    }
+    accept := strings.TrimSpace(r.Header.Get("Accept"))
+    if accept == "" {
+        return mediaTypeJSON, true
+    }
+
+    best, bestQ := "", 0.0
+    for _, mediaType := range supportedMediaTypes {
+        if q := acceptQuality(accept, mediaType); q > bestQ {
+            best, bestQ = mediaType, q
+        }
+    }
+    return best, best != ""
+}
+
+// acceptQuality returns the q-value the Accept header assigns to mediaType,
+// using the most specific matching range as RFC 9110 requires.
+func acceptQuality(accept, mediaType string) float64 {
+    typ, _, _ := strings.Cut(mediaType, "/")
+
+    q, specificity := 0.0, -1
+    for _, part := range strings.Split(accept, ",") {
+        rng, params, _ := strings.Cut(part, ";")
+        rng = strings.ToLower(strings.TrimSpace(rng))
+
+        var s int
+        switch {
+        case rng == mediaType:
+            s = 2
+        case rng == typ+"/*":
+            s = 1
+        case rng == "*/*":
+            s = 0
+        default:
+            continue
+        }
+        if s <= specificity {
+            continue
+        }
+        specificity = s
+        q = parseQuality(params)
+    }
+    return q
+}
+
+func parseQuality(params string) float64 {
+    for _, p := range strings.Split(params, ";") {
+        key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+        if !ok || strings.ToLower(strings.TrimSpace(key)) != "q" {
+            continue
+        }
+        q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+        if err != nil || q < 0 || q > 1 {
+            return 0
+        }
+        return q
+    }
+    return 1
+}
+
+// xmlValue adapts v for encoding/xml, which cannot marshal top-level slices
+// or maps on its own.
+func xmlValue(v any) any {
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Slice, reflect.Array:
+        items := make([]any, rv.Len())
+        for i := range items {
+            items[i] = rv.Index(i).Interface()
+        }
+        return xmlList{Items: items}
+    case reflect.Map:
+        if m, ok := v.(map[string]string); ok {
+            return xmlMap(m)
+        }
+    }
+    return v
+}
+
+// xmlList wraps list responses in a single root element.
+type xmlList struct {
+    XMLName xml.Name `xml:"items"`
+    Items   []any    `xml:"item"`
+}
+
+// xmlMap encodes a string map as <response><entry key="k">v</entry></response>,
+// with entries sorted by key so the output is stable.
+type xmlMap map[string]string
+
+func (m xmlMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+    start.Name = xml.Name{Local: "response"}
+    return marshalXMLEntries(e, start, m)
+}
+
+// countMap is a map of counts that also encodes as XML entries, for use as a
+// field in response structs.
+type countMap map[string]int
+
+func (m countMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+    return marshalXMLEntries(e, start, m)
+}
+
+func marshalXMLEntries[V any](e *xml.Encoder, start xml.StartElement, m map[string]V) error {
+    if err := e.EncodeToken(start); err != nil {
+        return err
+    }
+
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+
+    for _, k := range keys {
+        entry := xml.StartElement{
+            Name: xml.Name{Local: "entry"},
+            Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k}},
+        }
+        if err := e.EncodeElement(m[k], entry); err != nil {
+            return err
+        }
+    }
+    return e.EncodeToken(start.End())
+}
@@ -0,0 +1,364 @@
+// internal/api/openapi.go
+
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "reflect"
+    "strings"
+    "time"
+    "web-service/internal/version"
+    "web-service/pkg/logging"
+)
+
+// openAPIVersion is the OpenAPI specification version the document
+// follows.
+const openAPIVersion = "3.0.3"
+
+// openAPIComponents maps each schema in the document to the Go type it is
+// derived from, so the spec follows the request and response structs
+// rather than drifting from them.
+var openAPIComponents = map[string]reflect.Type{
+    "CreateCommentRequest": reflect.TypeOf(createCommentRequest{}),
+    "UpdateCommentRequest": reflect.TypeOf(updateCommentRequest{}),
+    "Comment":              reflect.TypeOf(commentResponse{}),
+    "LoginRequest":         reflect.TypeOf(loginRequest{}),
+    "LoginResponse":        reflect.TypeOf(loginResponse{}),
+    "Problem":              reflect.TypeOf(Problem{}),
+}
+
+var (
+    timeType      = reflect.TypeOf(time.Time{})
+    timestampType = reflect.TypeOf(timestamp{})
+)
+
+// schemaRef returns a reference to the component derived from t, or t's
+// schema inline if it has no component.
+func schemaRef(t reflect.Type) map[string]any {
+    for name, ct := range openAPIComponents {
+        if ct == t {
+            return map[string]any{"$ref": "#/components/schemas/" + name}
+        }
+    }
+    return schemaOf(t)
+}
+
+// schemaOf derives a JSON schema for t following encoding/json's rules:
+// fields are named by their json tag, "-" fields are skipped, fields
+// without omitempty are required, and the fields of untagged embedded
+// structs are promoted.
+func schemaOf(t reflect.Type) map[string]any {
+    if t == timeType || t == timestampType {
+        return map[string]any{"type": "string", "format": "date-time"}
+    }
+
+    switch t.Kind() {
+    case reflect.Pointer:
+        return schemaOf(t.Elem())
+    case reflect.String:
+        return map[string]any{"type": "string"}
+    case reflect.Bool:
+        return map[string]any{"type": "boolean"}
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+        return map[string]any{"type": "integer", "format": "int32"}
+    case reflect.Int64, reflect.Uint64:
+        return map[string]any{"type": "integer", "format": "int64"}
+    case reflect.Float32, reflect.Float64:
+        return map[string]any{"type": "number"}
+    case reflect.Slice, reflect.Array:
+        return map[string]any{"type": "array", "items": schemaRef(t.Elem())}
+    case reflect.Map:
+        return map[string]any{"type": "object", "additionalProperties": schemaRef(t.Elem())}
+    case reflect.Struct:
+        properties := make(map[string]any)
+        var required []string
+        for i := 0; i < t.NumField(); i++ {
+            field := t.Field(i)
+            name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+            if field.Anonymous && field.Type.Kind() == reflect.Struct && name == "" {
+                embedded := schemaOf(field.Type)
+                for k, v := range embedded["properties"].(map[string]any) {
+                    properties[k] = v
+                }
+                if req, ok := embedded["required"].([]string); ok {
+                    required = append(required, req...)
+                }
+                continue
+            }
+            if !field.IsExported() {
+                continue
+            }
+            if name == "-" {
+                continue
+            }
+            if name == "" {
+                name = field.Name
+            }
+            properties[name] = schemaRef(field.Type)
+            if !strings.Contains(opts, "omitempty") {
+                required = append(required, name)
+            }
+        }
+        schema := map[string]any{
+            "type":                 "object",
+            "properties":           properties,
+            "additionalProperties": false,
+        }
+        if len(required) > 0 {
+            schema["required"] = required
+        }
+        return schema
+    default:
+        return map[string]any{}
+    }
+}
+
+// newOpenAPISpec builds the OpenAPI document for the public API. The login
+// operation is only described when this instance issues tokens.
+func newOpenAPISpec(canLogin bool) map[string]any {
+    schemas := make(map[string]any, len(openAPIComponents))
+    for name, t := range openAPIComponents {
+        schemas[name] = schemaOf(t)
+    }
+
+    ref := func(name string) map[string]any {
+        return map[string]any{"$ref": "#/components/schemas/" + name}
+    }
+    body := func(description string, schema map[string]any) map[string]any {
+        return map[string]any{
+            "description": description,
+            "content":     map[string]any{mediaTypeJSON: map[string]any{"schema": schema}},
+        }
+    }
+    errorResp := func(description string) map[string]any {
+        return map[string]any{
+            "description": description,
+            "content":     map[string]any{mediaTypeProblemJSON: map[string]any{"schema": ref("Problem")}},
+        }
+    }
+    withHeaders := func(response, headers map[string]any) map[string]any {
+        response["headers"] = headers
+        return response
+    }
+    public := []any{}
+    idParam := []any{map[string]any{
+        "name":     "id",
+        "in":       "path",
+        "required": true,
+        "schema":   map[string]any{"type": "string"},
+    }}
+    etagHeader := map[string]any{
+        "description": "The comment's version, to send back in If-Match",
+        "schema":      map[string]any{"type": "string"},
+    }
+    renderParam := map[string]any{
+        "name":        "render",
+        "in":          "query",
+        "description": "html adds content_html, the content rendered from Markdown to sanitized HTML",
+        "schema":      map[string]any{"type": "string", "enum": []string{renderHTML}},
+    }
+
+    paths := map[string]any{
+        "/api/v1/comments": map[string]any{
+            "get": map[string]any{
+                "operationId": "listComments",
+                "summary":     "List comments",
+                "description": "Lists every comment, or with limit or offset one page of them, oldest first",
+                "parameters": []any{
+                    map[string]any{
+                        "name":   "limit",
+                        "in":     "query",
+                        "schema": map[string]any{"type": "integer", "minimum": 1, "maximum": maxListLimit, "default": defaultListLimit},
+                    },
+                    map[string]any{
+                        "name":   "offset",
+                        "in":     "query",
+                        "schema": map[string]any{"type": "integer", "minimum": 0, "default": 0},
+                    },
+                    renderParam,
+                    map[string]any{
+                        "name":        "If-Modified-Since",
+                        "in":          "header",
+                        "description": "The Last-Modified of a cached copy, answered with 304 if it is current",
+                        "schema":      map[string]any{"type": "string"},
+                    },
+                },
+                "responses": map[string]any{
+                    "200": withHeaders(body("The comments", map[string]any{"type": "array", "items": ref("Comment")}), map[string]any{
+                        totalCountHeader: map[string]any{
+                            "description": "The number of comments across all pages",
+                            "schema":      map[string]any{"type": "integer"},
+                        },
+                        "Link": map[string]any{
+                            "description": "The first, previous, next and last pages, when paginating",
+                            "schema":      map[string]any{"type": "string"},
+                        },
+                        "Last-Modified": map[string]any{
+                            "description": "When the comments last changed",
+                            "schema":      map[string]any{"type": "string"},
+                        },
+                        "Cache-Control": map[string]any{
+                            "description": "How long the list may be cached privately",
+                            "schema":      map[string]any{"type": "string"},
+                        },
+                    }),
+                    "304": map[string]any{"description": "Not modified since If-Modified-Since"},
+                    "400": errorResp("Invalid limit, offset or render"),
+                    "401": errorResp("Missing or invalid token"),
+                },
+            },
+            "post": map[string]any{
+                "operationId": "createComment",
+                "summary":     "Create a comment",
+                "parameters": []any{map[string]any{
+                    "name":        idempotencyKeyHeader,
+                    "in":          "header",
+                    "description": "Makes retries safe: a repeat with the same key returns the comment first created",
+                    "schema":      map[string]any{"type": "string", "maxLength": maxIdempotencyKeyLength},
+                }},
+                "requestBody": map[string]any{
+                    "required": true,
+                    "content":  map[string]any{mediaTypeJSON: map[string]any{"schema": ref("CreateCommentRequest")}},
+                },
+                "responses": map[string]any{
+                    "201": body("The created comment", ref("Comment")),
+                    "400": errorResp("Invalid comment"),
+                    "415": errorResp("Body is not JSON"),
+                    "401": errorResp("Missing or invalid token"),
+                    "403": errorResp("Lifetime needs the admin role"),
+                    "409": errorResp("Too similar to a recent comment, or a request with the same Idempotency-Key is in progress"),
+                    "422": errorResp("Idempotency-Key already used for a different request"),
+                },
+            },
+        },
+        "/api/v1/comments/{id}": map[string]any{
+            "parameters": idParam,
+            "get": map[string]any{
+                "operationId": "getComment",
+                "summary":     "Get a comment",
+                "parameters":  []any{renderParam},
+                "responses": map[string]any{
+                    "200": withHeaders(body("The comment", ref("Comment")), map[string]any{
+                        "ETag": etagHeader,
+                    }),
+                    "400": errorResp("Invalid render"),
+                    "401": errorResp("Missing or invalid token"),
+                    "404": errorResp("Comment not found"),
+                },
+            },
+            "put": map[string]any{
+                "operationId": "updateComment",
+                "summary":     "Update one of your comments",
+                "parameters": []any{map[string]any{
+                    "name":        "If-Match",
+                    "in":          "header",
+                    "description": "The ETag of the version being updated, as an alternative to version in the body",
+                    "schema":      map[string]any{"type": "string"},
+                }},
+                "requestBody": map[string]any{
+                    "required": true,
+                    "content":  map[string]any{mediaTypeJSON: map[string]any{"schema": ref("UpdateCommentRequest")}},
+                },
+                "responses": map[string]any{
+                    "200": withHeaders(body("The updated comment", ref("Comment")), map[string]any{
+                        "ETag": etagHeader,
+                    }),
+                    "400": errorResp("Invalid comment, version or If-Match"),
+                    "415": errorResp("Body is not JSON"),
+                    "401": errorResp("Missing or invalid token"),
+                    "403": errorResp("Not your comment"),
+                    "404": errorResp("Comment not found"),
+                    "409": withHeaders(errorResp("Comment changed since the version being updated; current_version gives its version now"), map[string]any{
+                        "ETag": etagHeader,
+                    }),
+                },
+            },
+            "delete": map[string]any{
+                "operationId": "deleteComment",
+                "summary":     "Delete one of your comments, or any comment as an admin",
+                "responses": map[string]any{
+                    "204": map[string]any{"description": "Deleted"},
+                    "401": errorResp("Missing or invalid token"),
+                    "403": errorResp("Not your comment and not an admin"),
+                    "404": errorResp("Comment not found"),
+                },
+            },
+        },
+        "/healthz": map[string]any{
+            "get": map[string]any{
+                "operationId": "healthz",
+                "summary":     "Liveness check",
+                "security":    public,
+                "responses": map[string]any{
+                    "200": body("The process is serving", map[string]any{
+                        "type":     "object",
+                        "required": []string{"status", "time"},
+                        "properties": map[string]any{
+                            "status": map[string]any{"type": "string"},
+                            "time":   map[string]any{"type": "string", "format": "date-time"},
+                        },
+                    }),
+                },
+            },
+        },
+    }
+    if canLogin {
+        paths["/api/v1/login"] = map[string]any{
+            "post": map[string]any{
+                "operationId": "login",
+                "summary":     "Exchange credentials for a token",
+                "security":    public,
+                "requestBody": map[string]any{
+                    "required": true,
+                    "content":  map[string]any{mediaTypeJSON: map[string]any{"schema": ref("LoginRequest")}},
+                },
+                "responses": map[string]any{
+                    "200": body("A bearer token", ref("LoginResponse")),
+                    "400": errorResp("Missing username or password"),
+                    "415": errorResp("Body is not JSON"),
+                    "401": errorResp("Invalid credentials"),
+                    "429": errorResp("Locked out after repeated failures"),
+                },
+            },
+        }
+    }
+
+    return map[string]any{
+        "openapi": openAPIVersion,
+        "info": map[string]any{
+            "title":   "Comments API",
+            "version": version.Get().Version,
+        },
+        "paths": paths,
+        "components": map[string]any{
+            "schemas": schemas,
+            "securitySchemes": map[string]any{
+                "bearerAuth": map[string]any{
+                    "type":         "http",
+                    "scheme":       "bearer",
+                    "bearerFormat": "JWT",
+                },
+            },
+        },
+        "security": []any{map[string]any{"bearerAuth": []string{}}},
+    }
+}
+
+// OpenAPI handler, serving the document built by newOpenAPISpec. It is
+// always JSON, whatever the request's Accept and X-Field-Case headers.
+func handleOpenAPI(logger *logging.Logger, canLogin bool) http.Handler {
+    spec, err := json.Marshal(newOpenAPISpec(canLogin))
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if err != nil {
+            logger.Error(r.Context(), "failed to encode openapi spec", "error", err)
+            encodeInternalError(w, r, err)
+            return
+        }
+        w.Header().Set("Content-Type", mediaTypeJSON)
+        if _, err := w.Write(spec); err != nil {
+            logger.Error(r.Context(), "failed to write openapi spec", "error", err)
+        }
+    })
+}
@@ -0,0 +1,231 @@
+// internal/api/openapi_test.go
+
+package api
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// Recorded example payloads, as clients send and receive them.
+var openAPIExamples = []struct {
+    schema  string
+    payload string
+}{
+    {"CreateCommentRequest", `{"content":"Great post","author":"Alice"}`},
+    {"CreateCommentRequest", `{"content":"Gone soon","author":"Alice","ttl":3600}`},
+    {"CreateCommentRequest", `{"content":"Gone soon","author":"Alice","expires_at":"2030-01-01T00:00:00Z"}`},
+    {"CreateCommentRequest", `{"content":"Look","author":"Alice","attachments":["https://example.com/cat.png"]}`},
+    {"Comment", `{"id":"c1","content":"Great post","author":"Alice","created_at":"2024-05-01T10:00:00.000Z","user_id":"u1","version":1,"reaction_count":2,"viewer_has_reacted":true}`},
+    {"Comment", `{"id":"c2","content":"Gone soon","author":"Bob","created_at":"2024-05-01T10:00:00.000Z","expires_at":"2024-05-01T11:00:00.000Z","version":3,"reaction_count":0,"viewer_has_reacted":false}`},
+    {"Comment", `{"id":"c3","content":"Look","author":"Alice","created_at":"2024-05-01T10:00:00.000Z","version":1,"attachments":["https://example.com/cat.png"],"reaction_count":0,"viewer_has_reacted":false}`},
+    {"UpdateCommentRequest", `{"content":"Great post, edited","author":"Alice"}`},
+    {"UpdateCommentRequest", `{"content":"Great post, edited","author":"Alice","version":2}`},
+    {"UpdateCommentRequest", `{"content":"Great post, edited","author":"Alice","attachments":[]}`},
+    {"LoginRequest", `{"username":"test","password":"test123"}`},
+    {"LoginResponse", `{"token":"eyJhbGciOiJIUzI1NiJ9.e30.sig","expires_in":86400}`},
+    {"Problem", `{"type":"urn:web-service:problem:not_found","title":"Not Found","status":404,"detail":"Comment not found","code":"not_found","request_id":"req-1"}`},
+    {"Problem", `{"type":"urn:web-service:problem:invalid_request","title":"Bad Request","status":400,"detail":"Request has invalid fields","invalid-params":[{"name":"content","reason":"content is required"}],"code":"invalid_request"}`},
+    {"Problem", `{"type":"urn:web-service:problem:version_conflict","title":"Conflict","status":409,"code":"version_conflict","current_version":4}`},
+    {"Problem", `{"type":"urn:web-service:problem:invalid_request","title":"Bad Request","status":400,"detail":"unterminated string","code":"invalid_request","position":11,"expected":["\""]}`},
+}
+
+func loadOpenAPISpec(t *testing.T) map[string]any {
+    t.Helper()
+
+    rec := httptest.NewRecorder()
+    handleOpenAPI(logging.NewLogger(io.Discard), true).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+    }
+    var spec map[string]any
+    if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+        t.Fatalf("spec is not valid JSON: %v", err)
+    }
+    return spec
+}
+
+func TestOpenAPIExamples(t *testing.T) {
+    spec := loadOpenAPISpec(t)
+    if spec["openapi"] != openAPIVersion {
+        t.Errorf("expected openapi %s, got %v", openAPIVersion, spec["openapi"])
+    }
+
+    for _, ex := range openAPIExamples {
+        t.Run(ex.schema, func(t *testing.T) {
+            var v any
+            if err := json.Unmarshal([]byte(ex.payload), &v); err != nil {
+                t.Fatal(err)
+            }
+            if err := validateSchema(spec, componentRef(ex.schema), v, ex.schema); err != nil {
+                t.Errorf("%s: %v", ex.payload, err)
+            }
+        })
+    }
+}
+
+// TestOpenAPIMatchesEncoding checks values as the handlers encode them
+// against the spec, so the schemas derived by reflection stay in step with
+// what encoding/json actually writes.
+func TestOpenAPIMatchesEncoding(t *testing.T) {
+    spec := loadOpenAPISpec(t)
+    now := time.Now()
+
+    values := map[string]any{
+        "Comment": toCommentResponse(storage.Comment{
+            ID: "c1", Content: "hi", Author: "Alice", UserID: "u1",
+            CreatedAt: now, ExpiresAt: now.Add(time.Hour), ReactionCount: 1,
+        }, true),
+        "LoginResponse": loginResponse{Token: "t", ExpiresIn: 60},
+        "Problem": func() Problem {
+            p := newProblem(httptest.NewRequest(http.MethodGet, "/", nil), http.StatusBadRequest, codeInvalidRequest, "bad")
+            p.addInvalidParams(problemMap{"content": {"required"}})
+            return p
+        }(),
+    }
+    for name, value := range values {
+        data, err := json.Marshal(value)
+        if err != nil {
+            t.Fatal(err)
+        }
+        var v any
+        if err := json.Unmarshal(data, &v); err != nil {
+            t.Fatal(err)
+        }
+        if err := validateSchema(spec, componentRef(name), v, name); err != nil {
+            t.Errorf("%s: %v", data, err)
+        }
+    }
+}
+
+func TestOpenAPIRejectsMismatches(t *testing.T) {
+    spec := loadOpenAPISpec(t)
+
+    tests := []struct {
+        schema  string
+        payload string
+    }{
+        {"CreateCommentRequest", `{"content":"no author"}`},
+        {"CreateCommentRequest", `{"content":"x","author":"y","ttl":"soon"}`},
+        {"UpdateCommentRequest", `{"content":"x","author":"y","version":"2"}`},
+        {"Comment", `{"id":"c1","content":"x","author":"y","created_at":"yesterday","reaction_count":0,"viewer_has_reacted":false}`},
+        {"LoginResponse", `{"token":"t","expires_in":60,"refresh_token":"r"}`},
+    }
+    for _, tt := range tests {
+        var v any
+        if err := json.Unmarshal([]byte(tt.payload), &v); err != nil {
+            t.Fatal(err)
+        }
+        if err := validateSchema(spec, componentRef(tt.schema), v, tt.schema); err == nil {
+            t.Errorf("%s: expected %s to be rejected", tt.payload, tt.schema)
+        }
+    }
+}
+
+func TestOpenAPIPaths(t *testing.T) {
+    tests := []struct {
+        canLogin bool
+        want     []string
+    }{
+        {true, []string{"/api/v1/comments", "/api/v1/comments/{id}", "/api/v1/login", "/healthz"}},
+        {false, []string{"/api/v1/comments", "/api/v1/comments/{id}", "/healthz"}},
+    }
+    for _, tt := range tests {
+        paths := newOpenAPISpec(tt.canLogin)["paths"].(map[string]any)
+        var got []string
+        for p := range paths {
+            got = append(got, p)
+        }
+        sort.Strings(got)
+        if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+            t.Errorf("canLogin %v: expected paths %v, got %v", tt.canLogin, tt.want, got)
+        }
+    }
+}
+
+func componentRef(name string) map[string]any {
+    return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// validateSchema checks v against the subset of JSON Schema the spec uses.
+func validateSchema(spec, schema map[string]any, v any, path string) error {
+    if r, ok := schema["$ref"].(string); ok {
+        name := strings.TrimPrefix(r, "#/components/schemas/")
+        resolved, ok := spec["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+        if !ok {
+            return fmt.Errorf("%s: unresolved $ref %s", path, r)
+        }
+        return validateSchema(spec, resolved, v, path)
+    }
+
+    switch schema["type"] {
+    case "string":
+        s, ok := v.(string)
+        if !ok {
+            return fmt.Errorf("%s: expected string, got %T", path, v)
+        }
+        if schema["format"] == "date-time" {
+            if _, err := time.Parse(time.RFC3339, s); err != nil {
+                return fmt.Errorf("%s: expected date-time, got %q", path, s)
+            }
+        }
+    case "boolean":
+        if _, ok := v.(bool); !ok {
+            return fmt.Errorf("%s: expected boolean, got %T", path, v)
+        }
+    case "integer":
+        n, ok := v.(float64)
+        if !ok || n != float64(int64(n)) {
+            return fmt.Errorf("%s: expected integer, got %v", path, v)
+        }
+    case "number":
+        if _, ok := v.(float64); !ok {
+            return fmt.Errorf("%s: expected number, got %T", path, v)
+        }
+    case "array":
+        items, ok := v.([]any)
+        if !ok {
+            return fmt.Errorf("%s: expected array, got %T", path, v)
+        }
+        for i, item := range items {
+            if err := validateSchema(spec, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+                return err
+            }
+        }
+    case "object":
+        obj, ok := v.(map[string]any)
+        if !ok {
+            return fmt.Errorf("%s: expected object, got %T", path, v)
+        }
+        required, _ := schema["required"].([]any)
+        for _, name := range required {
+            if _, ok := obj[name.(string)]; !ok {
+                return fmt.Errorf("%s: missing required %s", path, name)
+            }
+        }
+        properties, _ := schema["properties"].(map[string]any)
+        for name, value := range obj {
+            propSchema, ok := properties[name].(map[string]any)
+            if !ok {
+                extra, ok := schema["additionalProperties"].(map[string]any)
+                if !ok {
+                    return fmt.Errorf("%s: unexpected property %s", path, name)
+                }
+                propSchema = extra
+            }
+            if err := validateSchema(spec, propSchema, value, path+"."+name); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
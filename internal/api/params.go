@@ -0,0 +1,147 @@
+// internal/api/params.go
+
+package api
+
+import (
+    "fmt"
+    "math"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// queryParams reads URL query parameters, collecting a problem for each
+// invalid one keyed by parameter name, in the same shape Valid returns.
+type queryParams struct {
+    values   url.Values
+    problems problemMap
+}
+
+func newQueryParams(r *http.Request) *queryParams {
+    return &queryParams{values: r.URL.Query(), problems: make(problemMap)}
+}
+
+// intRange returns the integer parameter name, or def when it is absent.
+// Values that aren't integers between min and max inclusive are recorded
+// as problems and def is returned in their place.
+func (q *queryParams) intRange(name string, def, min, max int) int {
+    v := q.values.Get(name)
+    if v == "" {
+        return def
+    }
+    n, err := strconv.Atoi(v)
+    if err != nil || n < min || n > max {
+        q.problems.add(name, rangeMessage(name, min, max))
+        return def
+    }
+    return n
+}
+
+func rangeMessage(name string, min, max int) string {
+    switch {
+    case min == 0 && max == math.MaxInt:
+        return name + " must be a non-negative integer"
+    case max == math.MaxInt:
+        return fmt.Sprintf("%s must be an integer of at least %d", name, min)
+    default:
+        return fmt.Sprintf("%s must be an integer between %d and %d", name, min, max)
+    }
+}
+
+// time returns the time parameter name in RFC 3339 format, or the zero
+// time when it is absent. Values that don't parse are recorded as problems.
+func (q *queryParams) time(name string) time.Time {
+    v := q.values.Get(name)
+    if v == "" {
+        return time.Time{}
+    }
+    t, err := time.Parse(time.RFC3339, v)
+    if err != nil {
+        q.problems.add(name, name+" must be an RFC 3339 time")
+        return time.Time{}
+    }
+    return t
+}
+
+// oneOf returns the parameter name, or "" when it is absent. Values other
+// than those allowed are recorded as problems and "" is returned in their
+// place.
+func (q *queryParams) oneOf(name string, allowed ...string) string {
+    v := q.values.Get(name)
+    if v == "" {
+        return ""
+    }
+    for _, a := range allowed {
+        if v == a {
+            return v
+        }
+    }
+    q.problems.add(name, fmt.Sprintf("%s must be one of %s", name, strings.Join(allowed, ", ")))
+    return ""
+}
+
+// boolean returns the boolean parameter name, or false when it is absent.
+// Values strconv.ParseBool doesn't accept are recorded as problems.
+func (q *queryParams) boolean(name string) bool {
+    v := q.values.Get(name)
+    if v == "" {
+        return false
+    }
+    b, err := strconv.ParseBool(v)
+    if err != nil {
+        q.problems.add(name, name+" must be true or false")
+        return false
+    }
+    return b
+}
+
+// page reads the limit and offset of a paginated list. limit defaults to
+// defaultLimit and must be between 1 and maxLimit; offset defaults to 0.
+func (q *queryParams) page(defaultLimit, maxLimit int) (limit, offset int) {
+    limit = q.intRange("limit", defaultLimit, 1, maxLimit)
+    offset = q.intRange("offset", 0, 0, math.MaxInt)
+    return limit, offset
+}
+
+// totalCountHeader carries the number of items across all pages of a
+// list, alongside a Link header to the pages themselves.
+const totalCountHeader = "X-Total-Count"
+
+// setPageHeaders describes the page of a limit/offset list at offset out
+// of total items: X-Total-Count, and a Link header (RFC 8288) to the first,
+// previous, next and last pages, keeping the request's other parameters.
+// Previous and next are left out at either end.
+func setPageHeaders(w http.ResponseWriter, r *http.Request, total, limit, offset int) {
+    w.Header().Set(totalCountHeader, strconv.Itoa(total))
+
+    link := func(offset int, rel string) string {
+        q := r.URL.Query()
+        q.Set("limit", strconv.Itoa(limit))
+        q.Set("offset", strconv.Itoa(offset))
+        u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+        return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+    }
+    last := 0
+    if total > 0 {
+        last = (total - 1) / limit * limit
+    }
+    links := []string{link(0, "first")}
+    if offset > 0 {
+        links = append(links, link(max(offset-limit, 0), "prev"))
+    }
+    if offset+limit < total {
+        links = append(links, link(offset+limit, "next"))
+    }
+    links = append(links, link(last, "last"))
+    w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// Problems returns the problems found so far, or nil if there were none.
+func (q *queryParams) Problems() map[string][]string {
+    if len(q.problems) == 0 {
+        return nil
+    }
+    return q.problems
+}
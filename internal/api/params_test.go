@@ -0,0 +1,77 @@
+// internal/api/params_test.go
+
+package api
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestQueryParamsPage(t *testing.T) {
+    tests := []struct {
+        name         string
+        query        string
+        wantLimit    int
+        wantOffset   int
+        wantProblems []string
+    }{
+        {name: "defaults", query: "", wantLimit: 20, wantOffset: 0},
+        {name: "explicit", query: "limit=5&offset=10", wantLimit: 5, wantOffset: 10},
+        {name: "maximum limit", query: "limit=100", wantLimit: 100},
+        {name: "zero limit", query: "limit=0", wantLimit: 20, wantProblems: []string{"limit"}},
+        {name: "negative limit", query: "limit=-1", wantLimit: 20, wantProblems: []string{"limit"}},
+        {name: "oversized limit", query: "limit=101", wantLimit: 20, wantProblems: []string{"limit"}},
+        {name: "non-numeric limit", query: "limit=ten", wantLimit: 20, wantProblems: []string{"limit"}},
+        {name: "negative offset", query: "offset=-5", wantLimit: 20, wantProblems: []string{"offset"}},
+        {name: "both invalid", query: "limit=x&offset=y", wantLimit: 20, wantProblems: []string{"limit", "offset"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            params := newQueryParams(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil))
+            limit, offset := params.page(20, 100)
+            if limit != tt.wantLimit || offset != tt.wantOffset {
+                t.Errorf("got limit %d offset %d, want %d and %d", limit, offset, tt.wantLimit, tt.wantOffset)
+            }
+
+            problems := params.Problems()
+            if len(problems) != len(tt.wantProblems) {
+                t.Fatalf("expected problems for %v, got %v", tt.wantProblems, problems)
+            }
+            for _, name := range tt.wantProblems {
+                if len(problems[name]) != 1 {
+                    t.Errorf("expected a problem for %s, got %v", name, problems)
+                }
+            }
+        })
+    }
+}
+
+func TestQueryParamsTime(t *testing.T) {
+    tests := []struct {
+        name        string
+        query       string
+        want        time.Time
+        wantProblem bool
+    }{
+        {name: "absent", query: ""},
+        {name: "utc", query: "after=2024-03-01T12:00:00Z", want: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+        {name: "offset", query: "after=2024-03-01T14:00:00%2B02:00", want: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+        {name: "date only", query: "after=2024-03-01", wantProblem: true},
+        {name: "garbage", query: "after=yesterday", wantProblem: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            params := newQueryParams(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil))
+            if got := params.time("after"); !got.Equal(tt.want) {
+                t.Errorf("got %v, want %v", got, tt.want)
+            }
+            if got := len(params.Problems()["after"]) == 1; got != tt.wantProblem {
+                t.Errorf("expected problem %v, got %v", tt.wantProblem, params.Problems())
+            }
+        })
+    }
+}
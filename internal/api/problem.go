@@ -0,0 +1,150 @@
+// internal/api/problem.go
+
+package api
+
+import (
+    "context"
+    "encoding/xml"
+    "net/http"
+    "sort"
+    "web-service/internal/config"
+    "web-service/pkg/logging"
+)
+
+const (
+    mediaTypeProblemJSON = "application/problem+json"
+    mediaTypeProblemXML  = "application/problem+xml"
+)
+
+// problemTypePrefix is prefixed to an error code to make the problem
+// type URI, so each code is its own type.
+const problemTypePrefix = "urn:web-service:problem:"
+
+// Problem is an RFC 7807 problem details document, the body of every error
+// response:
+//
+//	{"type": "urn:web-service:problem:not_found", "title": "Not Found",
+//	 "status": 404, "detail": "Comment not found", "code": "not_found"}
+//
+// Code, RequestID and the members after them are extensions. Members are
+// named as RFC 7807 names them whatever X-Field-Case asks for.
+type Problem struct {
+    XMLName       xml.Name       `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+    Type          string         `json:"type" xml:"type"`
+    Title         string         `json:"title" xml:"title"`
+    Status        int            `json:"status" xml:"status"`
+    Detail        string         `json:"detail,omitempty" xml:"detail,omitempty"`
+    InvalidParams []InvalidParam `json:"invalid-params,omitempty" xml:"invalid-params>i,omitempty"`
+
+    // Code is the error code, for clients to branch on without parsing
+    // the type.
+    Code string `json:"code" xml:"code"`
+    // RequestID lets a user quote the failing request so it can be found
+    // in the logs.
+    RequestID string `json:"request_id,omitempty" xml:"request_id,omitempty"`
+    // CurrentVersion is the comment's version when an update conflicted.
+    CurrentVersion int64 `json:"current_version,omitempty" xml:"current_version,omitempty"`
+    // Position and Expected say where a search query failed to parse and
+    // what was expected there.
+    Position *int     `json:"position,omitempty" xml:"position,omitempty"`
+    Expected []string `json:"expected,omitempty" xml:"expected,omitempty"`
+}
+
+// InvalidParam is a problem with one request field. A field with several
+// problems is listed once for each.
+type InvalidParam struct {
+    Name   string `json:"name" xml:"name"`
+    Reason string `json:"reason" xml:"reason"`
+}
+
+// newProblem returns the problem for an error with status and code, titled
+// with the status text.
+func newProblem(r *http.Request, status int, code, detail string) Problem {
+    return Problem{
+        Type:      problemTypePrefix + code,
+        Title:     http.StatusText(status),
+        Status:    status,
+        Detail:    detail,
+        Code:      code,
+        RequestID: logging.RequestIDFromContext(r.Context()),
+    }
+}
+
+// addInvalidParams lists problems, sorted by field name, as p's invalid
+// params.
+func (p *Problem) addInvalidParams(problems map[string][]string) {
+    fields := make([]string, 0, len(problems))
+    for field := range problems {
+        fields = append(fields, field)
+    }
+    sort.Strings(fields)
+    for _, field := range fields {
+        for _, reason := range problems[field] {
+            p.InvalidParams = append(p.InvalidParams, InvalidParam{Name: field, Reason: reason})
+        }
+    }
+}
+
+// legacy returns p in the error response shape used before problem details.
+func (p Problem) legacy() errorResponse {
+    resp := errorResponse{Error: errorBody{
+        Code:           p.Code,
+        Message:        p.Detail,
+        RequestID:      p.RequestID,
+        CurrentVersion: p.CurrentVersion,
+        Position:       p.Position,
+        Expected:       p.Expected,
+    }}
+    if len(p.InvalidParams) > 0 {
+        resp.Error.Details = make(problemMap)
+        for _, param := range p.InvalidParams {
+            resp.Error.Details.add(param.Name, param.Reason)
+        }
+    }
+    return resp
+}
+
+// writeProblem writes p as the response, in the request's error format.
+// Problems are XML when the client prefers it and JSON otherwise, even if
+// it accepts neither, so an error is never itself answered with 406.
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+    mediaType, ok := negotiate(r)
+    if !ok {
+        mediaType = mediaTypeJSON
+    }
+    if errorFormatFromContext(r.Context()) == config.ErrorFormatLegacy {
+        writeBody(w, r, p.Status, mediaType, p.legacy())
+        return
+    }
+    if mediaType == mediaTypeXML {
+        mediaType = mediaTypeProblemXML
+    } else {
+        mediaType = mediaTypeProblemJSON
+    }
+    writeBody(w, r, p.Status, mediaType, p)
+}
+
+// withErrorFormat returns a copy of ctx carrying the config.ErrorFormat
+// for writeProblem.
+func withErrorFormat(ctx context.Context, format string) context.Context {
+    return context.WithValue(ctx, errorFormatKey, format)
+}
+
+// errorFormatFromContext returns the error format in ctx, problem details
+// unless a request carries another.
+func errorFormatFromContext(ctx context.Context) string {
+    if format, ok := ctx.Value(errorFormatKey).(string); ok && format != "" {
+        return format
+    }
+    return config.ErrorFormatProblem
+}
+
+// newErrorFormatMiddleware makes every error response below it, including
+// auth failures and unmatched routes, take the shape format selects.
+func newErrorFormatMiddleware(format string) Middleware {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            next.ServeHTTP(w, r.WithContext(withErrorFormat(r.Context(), format)))
+        })
+    }
+}
\ No newline at end of file
@@ -3,10 +3,13 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"time"
 	"web-service/internal/auth"
 	"web-service/internal/config"
+	"web-service/internal/erasure"
+	"web-service/internal/health"
 	"web-service/internal/storage"
 	"web-service/pkg/logging"
 )
@@ -15,13 +18,104 @@ func addRoutes(
     mux *http.ServeMux,
     logger *logging.Logger,
     config *config.Config,
-    commentStore *storage.CommentStore,
+    tokens auth.TokenService,
+    users storage.UserStore,
+    commentStore storage.CommentRepository,
+    checks []readinessCheck,
+    shutdown <-chan struct{},
+    cleanup func() (CleanupRun, bool),
+    storeMetrics *storage.Metrics,
 ) {
-    jwtManager := auth.NewJWTManager(config.JWTSecret, 24*time.Hour)
-
-    mux.Handle("/api/v1/login", handleLogin(logger, jwtManager))
-    mux.Handle("/api/v1/comments", handleComments(logger, commentStore))
-    mux.Handle("/api/v1/comments/", handleComment(logger, commentStore))
-    mux.Handle("/healthz", handleHealthz(logger))
-    mux.Handle("/", http.NotFoundHandler())
-}
\ No newline at end of file
+
+    // Public routes are served without a token
+    mux.Handle("GET /livez", handleLivez(logger))
+    mux.Handle("GET /healthz", handleLivez(logger))
+    mux.Handle("GET /readyz", handleReadyz(logger, newReadinessChecks(config, commentStore, checks)))
+    mux.Handle("GET /version", handleVersion(logger))
+    mux.Handle("GET /api/v1/openapi.json", handleOpenAPI(logger, tokens.CanSign()))
+
+    // In verify-only RS256 mode tokens come from the central auth service,
+    // so there is no login endpoint. Its bodies hold credentials and
+    // tokens, so body logging records only their sizes
+    if tokens.CanSign() {
+        mux.Handle("POST /api/v1/login", withRedactedBodies(handleLogin(logger, config, tokens, users, newLoginLockout(config.LoginFailureWindow, config.LoginLockout))))
+    }
+
+    dups := newDuplicateChecker(logger, config)
+    idem := newIdempotencyCache(config.IdempotencyKeyTTL)
+
+    // Every store holding personal data is registered for erasure
+    erasures := erasure.NewService(erasureKey(config))
+    erasures.Register("comments", commentStore)
+    erasures.Register("flags", commentStore.Flags())
+    if e, ok := users.(erasure.Eraser); ok {
+        erasures.Register("users", e)
+    }
+    if dups != nil {
+        erasures.Register("similarity", dups)
+    }
+    if idem != nil {
+        erasures.Register("idempotency", idem)
+    }
+
+    // Everything else needs a token, and admin routes the admin role
+    authenticate := newAuthMiddleware(logger, tokens)
+    admin := []Middleware{authenticate, newRequireRoleMiddleware("admin")}
+
+    mux.Handle("GET /api/v1/comments", Chain(handleListComments(logger, config, commentStore), authenticate))
+    mux.Handle("POST /api/v1/comments", Chain(handleCreateComment(logger, config, commentStore, dups, idem), authenticate))
+    mux.Handle("GET /api/v1/comments/events", Chain(withoutTimeout(handleCommentEvents(logger, commentStore, shutdown)), authenticate))
+    mux.Handle("GET /api/v1/comments/stream", Chain(withoutTimeout(handleCommentStream(logger, commentStore, shutdown)), authenticate))
+    mux.Handle("GET /api/v1/comments/stats", Chain(handleCommentStats(logger, commentStore, newStatsCache(config.StatsCacheTTL), cleanup), authenticate))
+    mux.Handle("GET /api/v1/comments/{id}", Chain(handleGetComment(logger, commentStore), authenticate))
+    mux.Handle("PUT /api/v1/comments/{id}", Chain(handleUpdateComment(logger, config, commentStore), authenticate))
+    mux.Handle("DELETE /api/v1/comments/{id}", Chain(handleDeleteComment(logger, commentStore), authenticate))
+    mux.Handle("POST /api/v1/comments/{id}/reactions", Chain(handleAddReaction(logger, commentStore), authenticate))
+    mux.Handle("DELETE /api/v1/comments/{id}/reactions", Chain(handleRemoveReaction(logger, commentStore), authenticate))
+    mux.Handle("POST /api/v1/comments/{id}/flags", Chain(handleFlagComment(logger, commentStore), authenticate))
+    mux.Handle("GET /api/v1/users/me/comments", Chain(handleListUserComments(logger, commentStore), authenticate))
+    mux.Handle("GET /api/v1/users/{user_id}/comments", Chain(handleListUserComments(logger, commentStore), authenticate))
+    mux.Handle("POST /api/v1/me/author-name", Chain(handleUpdateAuthorName(logger, config, commentStore, newIntervalLimiter(authorNameChangeInterval)), authenticate))
+    mux.Handle("GET /health/detail", Chain(handleHealthDetail(logger, commentStore, time.Now()), admin...))
+    mux.Handle("GET /api/v1/admin/comments", Chain(handleAdminListComments(logger, commentStore), admin...))
+    mux.Handle("GET /api/v1/admin/comments/search", Chain(handleSearchComments(logger, commentStore), admin...))
+    mux.Handle("GET /api/v1/admin/flags", Chain(handleListFlags(logger, commentStore), admin...))
+    mux.Handle("POST /api/v1/admin/users/{id}/erase", Chain(handleEraseUser(logger, erasures), admin...))
+    mux.Handle("DELETE /api/v1/admin/users/{user_id}/comments", Chain(handleDeleteUserComments(logger, commentStore), admin...))
+    mux.Handle("GET /api/v1/admin/erasures/{id}", Chain(handleGetErasureReceipt(logger, erasures), admin...))
+    if faults := commentStore.FaultInjector(); faults != nil {
+        mux.Handle("GET /api/v1/admin/faults", Chain(handleListFaults(logger, faults), admin...))
+        mux.Handle("PUT /api/v1/admin/faults", Chain(handleSetFaults(logger, faults), admin...))
+    }
+    if config.DebugEndpoints {
+        addDebugRoutes(mux, admin, logger, commentStore, storeMetrics)
+    }
+
+    exp := newExperimentalRoutes(mux, config.ExperimentalFeatures, authenticate)
+    addExperimentalRoutes(exp, logger, commentStore)
+    for _, name := range exp.unknown() {
+        logger.Warn(context.Background(), "unknown experimental feature enabled", "feature", name)
+    }
+}
+
+// readinessCheck is an extra check added to /readyz with
+// WithReadinessCheck.
+type readinessCheck struct {
+    name    string
+    checker health.Checker
+}
+
+// newReadinessChecks builds the dependency checks behind /readyz: storage,
+// budgeted by config, then any extra checks.
+func newReadinessChecks(config *config.Config, commentStore storage.CommentRepository, extra []readinessCheck) *health.Monitor {
+    checks := health.NewMonitor()
+    checks.Add("storage", health.CheckerFunc(commentStore.Ping), health.Budget{
+        Timeout:   config.ReadyTimeout,
+        Degraded:  config.ReadyDegradedLatency,
+        FailAfter: config.ReadyFailAfter,
+    })
+    for _, c := range extra {
+        checks.Add(c.name, c.checker, health.Budget{})
+    }
+    return checks
+}
@@ -0,0 +1,135 @@
+// internal/api/search.go
+
+package api
+
+import (
+    "errors"
+    "net/http"
+    "strings"
+    "web-service/internal/query"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+const (
+    defaultSearchLimit = 20
+    maxSearchLimit     = 100
+)
+
+type searchResponse struct {
+    Comments []commentResponse `json:"comments" xml:"comments>comment"`
+    Total    int               `json:"total" xml:"total"`
+    Limit    int               `json:"limit" xml:"limit"`
+    Offset   int               `json:"offset" xml:"offset"`
+}
+
+// Admin comment search handler
+func handleSearchComments(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        userID := UserIDFromContext(ctx)
+        l := logger.With("user_id", userID)
+
+        params := newQueryParams(r)
+        limit, offset := params.page(defaultSearchLimit, maxSearchLimit)
+        if problems := params.Problems(); problems != nil {
+            encodeProblems(w, r, problems)
+            return
+        }
+
+        filter := storage.Filter{}
+        if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+            node, err := query.Parse(q)
+            if err != nil {
+                var syntaxErr *query.SyntaxError
+                if !errors.As(err, &syntaxErr) {
+                    encodeError(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+                    return
+                }
+                p := newProblem(r, http.StatusBadRequest, codeInvalidRequest, syntaxErr.Error())
+                p.Position = &syntaxErr.Pos
+                p.Expected = syntaxErr.Expected
+                writeProblem(w, r, p)
+                return
+            }
+            filter = toStorageFilter(node)
+        }
+
+        comments, total, err := store.Search(ctx, filter, offset, limit)
+        if err != nil {
+            l.Error(ctx, "failed to search comments",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        reacted, err := store.ReactedTo(ctx, userID)
+        if err != nil {
+            l.Error(ctx, "failed to load reactions",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        resp := searchResponse{
+            Comments: make([]commentResponse, len(comments)),
+            Total:    total,
+            Limit:    limit,
+            Offset:   offset,
+        }
+        for i, c := range comments {
+            resp.Comments[i] = toCommentResponse(c, reacted[c.ID])
+        }
+        if err := addFlagCounts(ctx, store, resp.Comments); err != nil {
+            l.Error(ctx, "failed to load flag counts",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        setPageHeaders(w, r, total, limit, offset)
+        if err := encode(w, r, http.StatusOK, resp); err != nil {
+            l.Error(ctx, "failed to encode response",
+                "error", err,
+            )
+        }
+    })
+}
+
+// toStorageFilter translates a parsed query into the store's filter tree.
+func toStorageFilter(node query.Node) storage.Filter {
+    switch n := node.(type) {
+    case query.And:
+        return storage.Filter{Op: storage.FilterAnd, Children: toStorageFilters(n.Nodes)}
+    case query.Or:
+        return storage.Filter{Op: storage.FilterOr, Children: toStorageFilters(n.Nodes)}
+    case query.Not:
+        return storage.Filter{Op: storage.FilterNot, Children: []storage.Filter{toStorageFilter(n.Node)}}
+    case query.Term:
+        switch n.Field {
+        case query.FieldAuthor:
+            return storage.Filter{Op: storage.FilterAuthor, Value: n.Value}
+        case query.FieldUser:
+            return storage.Filter{Op: storage.FilterUser, Value: n.Value}
+        case query.FieldBefore:
+            return storage.Filter{Op: storage.FilterBefore, Time: n.Time}
+        case query.FieldAfter:
+            return storage.Filter{Op: storage.FilterAfter, Time: n.Time}
+        default:
+            return storage.Filter{Op: storage.FilterContent, Value: n.Value}
+        }
+    default:
+        return storage.Filter{}
+    }
+}
+
+func toStorageFilters(nodes []query.Node) []storage.Filter {
+    filters := make([]storage.Filter, len(nodes))
+    for i, n := range nodes {
+        filters[i] = toStorageFilter(n)
+    }
+    return filters
+}
@@ -3,17 +3,151 @@
 package api
 
 import (
+    "context"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/trace"
     "net/http"
+    "time"
+    "web-service/internal/auth"
     "web-service/internal/config"
+    "web-service/internal/health"
+    "web-service/internal/realip"
     "web-service/internal/storage"
+    "web-service/internal/tracing"
+    "web-service/internal/util"
     "web-service/pkg/logging"
 )
 
+// ServerOption configures NewServer.
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+    tokens   auth.TokenService
+    users    storage.UserStore
+    checks   []readinessCheck
+    tracer   trace.TracerProvider
+    shutdown <-chan struct{}
+    cleanup  func() (CleanupRun, bool)
+    metrics  *storage.Metrics
+}
+
+// WithTokenService replaces the JWT manager NewServer would build from
+// config, e.g. with a fake issuer in tests.
+func WithTokenService(tokens auth.TokenService) ServerOption {
+    return func(o *serverOptions) {
+        o.tokens = tokens
+    }
+}
+
+// WithUserStore replaces the store of config.Users that NewServer would
+// otherwise authenticate logins against.
+func WithUserStore(users storage.UserStore) ServerOption {
+    return func(o *serverOptions) {
+        o.users = users
+    }
+}
+
+// WithReadinessCheck adds a check to /readyz alongside the storage check.
+// The check is unbudgeted, so it should answer immediately.
+func WithReadinessCheck(name string, checker health.Checker) ServerOption {
+    return func(o *serverOptions) {
+        o.checks = append(o.checks, readinessCheck{name: name, checker: checker})
+    }
+}
+
+// WithTracerProvider sets where request spans go, the global provider
+// unless set.
+func WithTracerProvider(tp trace.TracerProvider) ServerOption {
+    return func(o *serverOptions) {
+        o.tracer = tp
+    }
+}
+
+// WithShutdownSignal ends long-lived responses, such as the comment
+// stream, when done is closed, so they don't hold up graceful shutdown,
+// and answers requests arriving after that with 503.
+func WithShutdownSignal(done <-chan struct{}) ServerOption {
+    return func(o *serverOptions) {
+        o.shutdown = done
+    }
+}
+
+// CleanupRun is the outcome of a run of the comment cleanup job: when it
+// ran, and how many expired and past-retention comments it removed.
+type CleanupRun struct {
+    Time    time.Time
+    Expired int
+    Purged  int
+}
+
+// WithCleanupStatus reports the cleanup job's last run on the stats
+// endpoint. last returns false until the job has run.
+func WithCleanupStatus(last func() (CleanupRun, bool)) ServerOption {
+    return func(o *serverOptions) {
+        o.cleanup = last
+    }
+}
+
+// WithStoreMetrics reports the store operations recorded in metrics, by
+// the storage.InstrumentedStore wrapping the comment store, on
+// /debug/vars.
+func WithStoreMetrics(metrics *storage.Metrics) ServerOption {
+    return func(o *serverOptions) {
+        o.metrics = metrics
+    }
+}
+
+// maxLoggedBodyBytes bounds how much of each body LogHTTPBodies logs.
+const maxLoggedBodyBytes = 4 << 10
+
+// devUserHash is the hash of test123, the password of the test user seeded
+// in development.
+const devUserHash = "pbkdf2-sha256$210000$ok/CFg6VBrd7/Q5doaz1oA$c7EURo0YM0Yae7Tc4KvlU1AH0Hu0WbDX5K55Bj2WVRo"
+
+// newUserStore builds the login store from config.Users, adding the
+// test/test123 user when running in development.
+func newUserStore(logger *logging.Logger, config *config.Config) *storage.MemoryUserStore {
+    users := storage.NewMemoryUserStore()
+    for _, u := range config.Users {
+        // Config validated the seeds, so Add can't fail
+        users.Add(storage.User{ID: u.Username, Username: u.Username, Role: u.Role, PasswordHash: u.PasswordHash})
+    }
+    if config.Environment == "development" {
+        if err := users.Add(storage.User{ID: "test", Username: "test", Role: "user", PasswordHash: devUserHash}); err == nil {
+            logger.Warn(context.Background(), "development login test/test123 enabled")
+        }
+    }
+    return users
+}
+
 func NewServer(
     logger *logging.Logger,
     config *config.Config,
-    commentStore *storage.CommentStore,
+    commentStore storage.CommentRepository,
+    opts ...ServerOption,
 ) http.Handler {
+    var o serverOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    // One token service issues tokens at login and validates them in the
+    // auth middleware, so both agree on keys and expiry
+    tokens := o.tokens
+    if tokens == nil {
+        tokens = newJWTManager(config)
+    }
+
+    users := o.users
+    if users == nil {
+        users = newUserStore(logger, config)
+    }
+
+    tp := o.tracer
+    if tp == nil {
+        tp = otel.GetTracerProvider()
+    }
+
     mux := http.NewServeMux()
 
     // Add routes with all dependencies
@@ -21,20 +155,69 @@ func NewServer(
         mux,
         logger,
         config,
+        tokens,
+        users,
         commentStore,
+        o.checks,
+        o.shutdown,
+        o.cleanup,
+        o.metrics,
     )
 
-    // Add middleware stack
-    var handler http.Handler = mux
-    handler = logging.NewLoggingMiddleware(logger, handler)
+    // Add middleware stack. Requests for a route under a method it
+    // doesn't serve get 405 with the methods it does
+    handler := newMethodNotAllowedHandler(mux)
+
+    // Consistency tokens sit closest to the routes so the write position
+    // is read after the handler's mutation
+    handler = newConsistencyMiddleware(logger, commentStore)(handler)
 
-    // Create and apply auth middleware
-    authMiddleware := newAuthMiddleware(config.JWTSecret)
-    handler = authMiddleware(handler)
+    // Bound every request; long-running routes opt out with withoutTimeout
+    handler = newTimeoutMiddleware(config.RequestTimeout)(handler)
+
+    // Turn away new requests once shutdown starts, before they reach a
+    // route, while those already past here finish
+    handler = newDrainingMiddleware(o.shutdown)(handler)
+
+    // Refuse writes the client can't read the answer to before they happen
+    handler = newNegotiationMiddleware(mux)(handler)
 
     // Create and apply CORS middleware
-    corsMiddleware := newCORSMiddleware()
+    corsMiddleware := newCORSMiddleware(mux)
     handler = corsMiddleware(handler)
 
+    // Security headers wrap everything below so auth failures, 404s and
+    // preflights carry them too
+    handler = newSecurityHeadersMiddleware(config)(handler)
+
+    // Every error response below takes the configured shape
+    handler = newErrorFormatMiddleware(config.ErrorFormat)(handler)
+
+    // Comment content is stored and rendered under the configured policy
+    handler = newContentPolicyMiddleware(config.ContentPolicy)(handler)
+
+    // Body logging sits inside request logging so its entries carry the
+    // request ID, and outside the routes so rejected requests are logged too
+    if config.LogHTTPBodies {
+        handler = logging.NewBodyLoggingMiddleware(logger, maxLoggedBodyBytes, handler)
+    }
+
+    // Logging wraps everything below so every response, including auth
+    // failures and preflights, carries a request ID
+    handler = logging.NewLoggingMiddleware(logger, handler,
+        logging.WithRequestIDGenerator(util.GenerateID),
+        logging.WithClientIP(realip.FromRequest),
+    )
+
+    // Tracing wraps logging so every entry carries the trace and span IDs.
+    // Spans are named after the route the mux will match
+    handler = tracing.NewMiddleware(tp, func(r *http.Request) string {
+        _, pattern := mux.Handler(r)
+        return pattern
+    })(handler)
+
+    // Resolve the client IP first so the request log records it
+    handler = realip.NewMiddleware(realip.NewResolver(config.TrustedProxies))(handler)
+
     return handler
 }
\ No newline at end of file
@@ -0,0 +1,103 @@
+// internal/api/server_test.go
+
+package api
+
+import (
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+    "web-service/internal/auth"
+    "web-service/internal/config"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// fakeIssuer mints tokens of the form "fake:<user>:<role>" without any key.
+type fakeIssuer struct{}
+
+func (fakeIssuer) GenerateToken(userID, role string) (string, error) {
+    return "fake:" + userID + ":" + role, nil
+}
+
+func (fakeIssuer) ValidateToken(tokenStr string) (*auth.Claims, error) {
+    parts := strings.Split(tokenStr, ":")
+    if len(parts) != 3 || parts[0] != "fake" {
+        return nil, errors.New("not a fake token")
+    }
+    return &auth.Claims{UserID: parts[1], Role: parts[2]}, nil
+}
+
+func (fakeIssuer) Expiry() time.Duration { return time.Minute }
+func (fakeIssuer) CanSign() bool         { return true }
+
+func TestNewServerWithTokenService(t *testing.T) {
+    cfg := &config.Config{JWTSecret: "unused", Environment: "test"}
+    srv := httptest.NewServer(NewServer(logging.NewLogger(io.Discard), cfg, storage.NewCommentStore(), WithTokenService(fakeIssuer{})))
+    defer srv.Close()
+
+    get := func(t *testing.T, token string) int {
+        t.Helper()
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments", nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+
+    token, _ := fakeIssuer{}.GenerateToken("u1", "user")
+    if status := get(t, token); status != http.StatusOK {
+        t.Errorf("fake token: expected status %d, got %d", http.StatusOK, status)
+    }
+
+    signed, err := auth.NewJWTManager(cfg.JWTSecret, time.Hour).GenerateToken("u1", "user")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if status := get(t, signed); status != http.StatusUnauthorized {
+        t.Errorf("JWT with the configured secret: expected status %d, got %d", http.StatusUnauthorized, status)
+    }
+}
+
+func TestRouteAuthentication(t *testing.T) {
+    cfg := &config.Config{JWTSecret: "unused", Environment: "test", DebugEndpoints: true}
+    srv := httptest.NewServer(NewServer(logging.NewLogger(io.Discard), cfg, storage.NewCommentStore(), WithTokenService(fakeIssuer{})))
+    defer srv.Close()
+
+    tests := []struct {
+        path       string
+        wantStatus int
+    }{
+        {path: "/healthz", wantStatus: http.StatusOK},
+        {path: "/livez", wantStatus: http.StatusOK},
+        {path: "/readyz", wantStatus: http.StatusOK},
+        {path: "/version", wantStatus: http.StatusOK},
+        {path: "/api/v1/openapi.json", wantStatus: http.StatusOK},
+        {path: "/api/v1/comments", wantStatus: http.StatusUnauthorized},
+        {path: "/api/v1/comments/42", wantStatus: http.StatusUnauthorized},
+        {path: "/api/v1/admin/comments", wantStatus: http.StatusUnauthorized},
+        {path: "/debug/vars", wantStatus: http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.path, func(t *testing.T) {
+            resp, err := http.Get(srv.URL + tt.path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            resp.Body.Close()
+            if resp.StatusCode != tt.wantStatus {
+                t.Errorf("expected status %d without a token, got %d", tt.wantStatus, resp.StatusCode)
+            }
+        })
+    }
+}
@@ -0,0 +1,77 @@
+// internal/api/similar.go
+
+package api
+
+import (
+    "context"
+    "sync/atomic"
+    "web-service/internal/config"
+    "web-service/internal/erasure"
+    "web-service/internal/similarity"
+    "web-service/pkg/logging"
+)
+
+// duplicateChecker screens new comments for near-duplicates of recent ones,
+// to catch spammers who tweak a character or two between posts.
+type duplicateChecker struct {
+    logger  *logging.Logger
+    action  string
+    index   *similarity.Index
+    flagged atomic.Uint64
+}
+
+// newDuplicateChecker returns the checker described by config, or nil when
+// config.SimilarityAction is off.
+func newDuplicateChecker(logger *logging.Logger, config *config.Config) *duplicateChecker {
+    if config.SimilarityAction == "" || config.SimilarityAction == "off" {
+        return nil
+    }
+    return &duplicateChecker{
+        logger: logger,
+        action: config.SimilarityAction,
+        index:  similarity.NewIndex(config.SimilarityThreshold, config.SimilarityWindow),
+    }
+}
+
+// check fingerprints content and compares it with recent comments. It
+// returns the match when the comment must be rejected; under the flag
+// action matches are logged and counted but let through. When ok, fp
+// should be passed to record once the comment is created.
+func (d *duplicateChecker) check(ctx context.Context, userID, content string) (fp similarity.Fingerprint, ok bool, rejected *similarity.Match) {
+    fp, ok = similarity.Compute(content)
+    if !ok {
+        return fp, false, nil
+    }
+    match, found := d.index.Check(userID, fp)
+    if !found {
+        return fp, true, nil
+    }
+
+    if d.action == "reject" {
+        d.logger.Warn(ctx, "rejected near-duplicate comment",
+            "user_id", userID,
+            "matched_comment_id", match.CommentID,
+            "matched_user_id", match.UserID,
+            "similarity", match.Similarity,
+        )
+        return fp, true, &match
+    }
+    d.logger.Warn(ctx, "flagged near-duplicate comment",
+        "user_id", userID,
+        "matched_comment_id", match.CommentID,
+        "matched_user_id", match.UserID,
+        "similarity", match.Similarity,
+        "flagged_total", d.flagged.Add(1),
+    )
+    return fp, true, nil
+}
+
+// record adds a created comment's fingerprint to the recent window.
+func (d *duplicateChecker) record(userID, commentID string, fp similarity.Fingerprint) {
+    d.index.Add(userID, commentID, fp)
+}
+
+// EraseUser drops the fingerprints of userID's comments.
+func (d *duplicateChecker) EraseUser(ctx context.Context, userID string) (erasure.Report, error) {
+    return erasure.Report{Removed: d.index.Forget(userID)}, nil
+}
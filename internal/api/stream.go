@@ -0,0 +1,139 @@
+// internal/api/stream.go
+
+package api
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "time"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+const (
+    // streamBuffer is how many events a stream may fall behind by before
+    // the store drops it.
+    streamBuffer = 64
+
+    // streamHeartbeat is how often an idle stream sends a comment line, so
+    // proxies don't time the connection out.
+    streamHeartbeat = 30 * time.Second
+)
+
+// deletedEventResponse is the data of a deleted event.
+type deletedEventResponse struct {
+    ID string `json:"id"`
+}
+
+// Comment stream handler. Pushes comment changes as server-sent events
+// until the client disconnects or the server shuts down: created and
+// updated events carry the comment, deleted events its ID. A client that
+// falls too far behind has its stream ended, and should reconnect and
+// refetch the list.
+func handleCommentStream(logger *logging.Logger, store storage.CommentRepository, shutdown <-chan struct{}) http.Handler {
+    return newStreamHandler(logger, store, shutdown, writeEvent)
+}
+
+// New comment events handler. A simpler feed than the comment stream for
+// read-only dashboards: one unnamed event per created comment, carrying
+// the comment.
+func handleCommentEvents(logger *logging.Logger, store storage.CommentRepository, shutdown <-chan struct{}) http.Handler {
+    return newStreamHandler(logger, store, shutdown, writeCreatedEvent)
+}
+
+// newStreamHandler streams the store's events as server-sent events, each
+// written by write, which may skip events by writing nothing.
+func newStreamHandler(
+    logger *logging.Logger,
+    store storage.CommentRepository,
+    shutdown <-chan struct{},
+    write func(w http.ResponseWriter, r *http.Request, e storage.Event) error,
+) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        l := logger.With("user_id", UserIDFromContext(ctx))
+
+        events, cancel := store.Subscribe(streamBuffer)
+        defer cancel()
+
+        rc := http.NewResponseController(w)
+        w.Header().Set("Content-Type", "text/event-stream")
+        w.Header().Set("Cache-Control", "no-cache")
+        // Stop nginx-style proxies holding events back in their buffers
+        w.Header().Set("X-Accel-Buffering", "no")
+        w.WriteHeader(http.StatusOK)
+        if err := rc.Flush(); err != nil {
+            l.Error(ctx, "failed to start comment stream", "error", err)
+            return
+        }
+
+        heartbeat := time.NewTicker(streamHeartbeat)
+        defer heartbeat.Stop()
+
+        for {
+            var err error
+            select {
+            case <-ctx.Done():
+                return
+            case <-shutdown:
+                return
+            case <-heartbeat.C:
+                _, err = fmt.Fprint(w, ": heartbeat\n\n")
+            case e, ok := <-events:
+                if !ok {
+                    l.Warn(ctx, "comment stream fell behind, closing")
+                    return
+                }
+                err = write(w, r, e)
+            }
+            if err == nil {
+                err = rc.Flush()
+            }
+            if err != nil {
+                l.Info(ctx, "comment stream ended", "error", err)
+                return
+            }
+        }
+    })
+}
+
+// writeEvent writes e as a server-sent event named after its type.
+func writeEvent(w http.ResponseWriter, r *http.Request, e storage.Event) error {
+    var data any = toCommentResponse(e.Comment, false)
+    if e.Type == storage.EventDeleted {
+        data = deletedEventResponse{ID: e.Comment.ID}
+    }
+    b, err := eventData(r, data)
+    if err != nil {
+        return err
+    }
+    _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
+    return err
+}
+
+// writeCreatedEvent writes a created comment as an unnamed server-sent
+// event, skipping other changes.
+func writeCreatedEvent(w http.ResponseWriter, r *http.Request, e storage.Event) error {
+    if e.Type != storage.EventCreated {
+        return nil
+    }
+    b, err := eventData(r, toCommentResponse(e.Comment, false))
+    if err != nil {
+        return err
+    }
+    _, err = fmt.Fprintf(w, "data: %s\n\n", b)
+    return err
+}
+
+// eventData encodes v as JSON in the request's field case.
+func eventData(r *http.Request, v any) ([]byte, error) {
+    if camelCase(r) {
+        v = camelJSON{V: v}
+    }
+    b, err := json.Marshal(v)
+    if err != nil {
+        return nil, fmt.Errorf("encode event: %w", err)
+    }
+    return b, nil
+}
@@ -0,0 +1,41 @@
+// internal/api/timestamp.go
+
+package api
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// timestampFormat is RFC 3339 with exactly three fractional digits.
+const timestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// timestamp is a time as responses show it: RFC 3339 in UTC with
+// millisecond precision, so every time has the same shape whatever clock
+// or zone produced it. The store keeps times at that precision, so a time
+// reads back exactly as it was first shown.
+type timestamp time.Time
+
+// newTimestamp returns t as a timestamp, or nil for the zero time.
+func newTimestamp(t time.Time) *timestamp {
+    if t.IsZero() {
+        return nil
+    }
+    ts := timestamp(t)
+    return &ts
+}
+
+func (t timestamp) String() string {
+    return time.Time(t).UTC().Format(timestampFormat)
+}
+
+// MarshalText is used for XML.
+func (t timestamp) MarshalText() ([]byte, error) {
+    return []byte(t.String()), nil
+}
+
+// MarshalJSON is implemented as well as MarshalText so camelJSON passes
+// timestamps through rather than treating them as structs.
+func (t timestamp) MarshalJSON() ([]byte, error) {
+    return json.Marshal(t.String())
+}
@@ -0,0 +1,54 @@
+// internal/api/users.go
+
+package api
+
+import (
+    "net/http"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// User comments handler. Lists the comments of the user named in the path,
+// or the caller's own under /users/me, paginated and sorted as the main
+// list is. Comments are public, so any authenticated user may list anyone's;
+// a user without comments gets an empty list rather than 404.
+func handleListUserComments(logger *logging.Logger, store storage.CommentRepository) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := r.Context()
+        viewerID := UserIDFromContext(ctx)
+        userID := r.PathValue("user_id")
+        if userID == "" {
+            userID = viewerID
+        }
+        l := logger.With("user_id", viewerID, "author_id", userID)
+
+        page, problems := parseListPage(r)
+        if problems != nil {
+            encodeProblems(w, r, problems)
+            return
+        }
+
+        comments, err := store.ListByUser(ctx, userID)
+        if err != nil {
+            l.Error(ctx, "failed to list user comments",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+        reacted, err := store.ReactedTo(ctx, viewerID)
+        if err != nil {
+            l.Error(ctx, "failed to load reactions",
+                "error", err,
+            )
+            encodeInternalError(w, r, err)
+            return
+        }
+
+        resp := make([]commentResponse, len(comments))
+        for i, c := range comments {
+            resp[i] = toCommentResponse(c, reacted[c.ID])
+        }
+        writeCommentList(w, r, l, store, resp, page)
+    })
+}
\ No newline at end of file
@@ -3,60 +3,272 @@
 package auth
 
 import (
+    "crypto/rsa"
+    "errors"
     "fmt"
     "time"
     "github.com/golang-jwt/jwt/v5"
 )
 
+// DefaultLeeway is the clock skew tolerated when checking a token's time
+// based claims.
+const DefaultLeeway = 30 * time.Second
+
+// Errors returned by ValidateToken, so callers can tell why a token was
+// rejected. They wrap the underlying jwt error.
+var (
+    ErrTokenExpired    = errors.New("token expired")
+    ErrInvalidIssuer   = errors.New("token issuer missing or mismatched")
+    ErrInvalidAudience = errors.New("token audience missing or mismatched")
+    ErrUnknownKeyID    = errors.New("token signed with an unknown key")
+
+    // ErrVerifyOnly is returned by GenerateToken when the manager holds
+    // only a public key.
+    ErrVerifyOnly = errors.New("token manager is verify-only")
+)
+
 type Claims struct {
     UserID string `json:"user_id"`
     Role   string `json:"role"`
     jwt.RegisteredClaims
 }
 
+// HMACKey is a named HS256 secret. Tokens it signs carry ID in their kid
+// header.
+type HMACKey struct {
+    ID     string
+    Secret string
+}
+
+// JWTManager issues and validates tokens for a single algorithm family:
+// HS256 with a shared secret, or RS256 with an RSA key pair.
 type JWTManager struct {
-    secretKey []byte
-    expiry    time.Duration
+    method    jwt.SigningMethod
+    signKey   interface{} // nil when verify-only
+    verifyKey interface{}
+    // keyID is the kid of signKey, set on generated tokens when non-empty.
+    keyID string
+    // keys are the HS256 keys accepted by kid, including the current one;
+    // nil in RS256 mode.
+    keys     map[string]interface{}
+    expiry   time.Duration
+    leeway   time.Duration
+    issuer   string
+    audience string
+    now      func() time.Time
+}
+
+// TokenService issues and validates tokens. JWTManager is the production
+// implementation; tests can substitute their own issuer.
+type TokenService interface {
+    GenerateToken(userID, role string) (string, error)
+    ValidateToken(tokenStr string) (*Claims, error)
+    // Expiry returns how long generated tokens are valid for.
+    Expiry() time.Duration
+    // CanSign reports whether GenerateToken can issue tokens.
+    CanSign() bool
+}
+
+var _ TokenService = (*JWTManager)(nil)
+
+// Option configures a JWTManager.
+type Option func(*JWTManager)
+
+// WithIssuer sets the iss claim on generated tokens and requires it on
+// validated ones.
+func WithIssuer(issuer string) Option {
+    return func(m *JWTManager) {
+        m.issuer = issuer
+    }
+}
+
+// WithAudience sets the aud claim on generated tokens and requires it on
+// validated ones.
+func WithAudience(audience string) Option {
+    return func(m *JWTManager) {
+        m.audience = audience
+    }
+}
+
+// WithLeeway sets the clock skew tolerated when validating a token's time
+// claims, DefaultLeeway unless set.
+func WithLeeway(leeway time.Duration) Option {
+    return func(m *JWTManager) {
+        m.leeway = leeway
+    }
+}
+
+// WithClock replaces the clock used to stamp generated tokens and to check
+// the time claims of validated ones, so tests can control expiry.
+func WithClock(now func() time.Time) Option {
+    return func(m *JWTManager) {
+        m.now = now
+    }
+}
+
+// NewJWTManager returns an HS256 manager signing with secretKey.
+func NewJWTManager(secretKey string, expiry time.Duration, opts ...Option) *JWTManager {
+    return NewJWTManagerKeys(HMACKey{Secret: secretKey}, nil, expiry, opts...)
 }
 
-func NewJWTManager(secretKey string, expiry time.Duration) *JWTManager {
-    return &JWTManager{
-        secretKey: []byte(secretKey),
+// NewJWTManagerKeys returns an HS256 manager that signs with current and
+// still accepts tokens signed with any of previous, so the secret can be
+// rotated without invalidating every token at once. A token's kid header
+// picks the key it is checked against; tokens without one, issued before
+// keys had IDs, are checked against each key in turn. Tokens naming a key
+// not in the set are rejected.
+func NewJWTManagerKeys(current HMACKey, previous []HMACKey, expiry time.Duration, opts ...Option) *JWTManager {
+    m := &JWTManager{
+        method:    jwt.SigningMethodHS256,
+        signKey:   []byte(current.Secret),
+        verifyKey: []byte(current.Secret),
+        keyID:     current.ID,
+        keys:      map[string]interface{}{current.ID: []byte(current.Secret)},
         expiry:    expiry,
+        leeway:    DefaultLeeway,
+        now:       time.Now,
+    }
+    for _, k := range previous {
+        if _, ok := m.keys[k.ID]; !ok {
+            m.keys[k.ID] = []byte(k.Secret)
+        }
+    }
+    for _, opt := range opts {
+        opt(m)
+    }
+    return m
+}
+
+// NewJWTManagerRS256 returns an RS256 manager. With a nil privateKey it is
+// verify-only and GenerateToken returns ErrVerifyOnly; with a nil publicKey
+// the private key's public half verifies. With neither, every token is
+// rejected.
+func NewJWTManagerRS256(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey, expiry time.Duration, opts ...Option) *JWTManager {
+    m := &JWTManager{
+        method: jwt.SigningMethodRS256,
+        expiry: expiry,
+        leeway: DefaultLeeway,
+        now:    time.Now,
+    }
+    if privateKey != nil {
+        m.signKey = privateKey
+        if publicKey == nil {
+            publicKey = &privateKey.PublicKey
+        }
     }
+    if publicKey != nil {
+        m.verifyKey = publicKey
+    }
+    for _, opt := range opts {
+        opt(m)
+    }
+    return m
+}
+
+// Expiry returns how long generated tokens are valid for.
+func (m *JWTManager) Expiry() time.Duration {
+    return m.expiry
+}
+
+// CanSign reports whether the manager can issue tokens.
+func (m *JWTManager) CanSign() bool {
+    return m.signKey != nil
 }
 
 func (m *JWTManager) GenerateToken(userID, role string) (string, error) {
+    now := m.now()
     claims := &Claims{
         UserID: userID,
         Role:   role,
         RegisteredClaims: jwt.RegisteredClaims{
-            ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.expiry)),
-            IssuedAt:  jwt.NewNumericDate(time.Now()),
-            NotBefore: jwt.NewNumericDate(time.Now()),
+            Issuer:    m.issuer,
+            ExpiresAt: jwt.NewNumericDate(now.Add(m.expiry)),
+            IssuedAt:  jwt.NewNumericDate(now),
+            NotBefore: jwt.NewNumericDate(now),
         },
     }
+    if m.audience != "" {
+        claims.Audience = jwt.ClaimStrings{m.audience}
+    }
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString(m.secretKey)
+    if !m.CanSign() {
+        return "", ErrVerifyOnly
+    }
+
+    token := jwt.NewWithClaims(m.method, claims)
+    if m.keyID != "" {
+        token.Header["kid"] = m.keyID
+    }
+    return token.SignedString(m.signKey)
 }
 
 func (m *JWTManager) ValidateToken(tokenStr string) (*Claims, error) {
-    token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+    // Only accept the configured algorithm, so an HS256 token signed with
+    // the RSA public key can't pass in RS256 mode (algorithm confusion)
+    opts := []jwt.ParserOption{
+        jwt.WithValidMethods([]string{m.method.Alg()}),
+        jwt.WithLeeway(m.leeway),
+        jwt.WithTimeFunc(m.now),
+    }
+    if m.issuer != "" {
+        opts = append(opts, jwt.WithIssuer(m.issuer))
+    }
+    if m.audience != "" {
+        opts = append(opts, jwt.WithAudience(m.audience))
+    }
+
+    claims := &Claims{}
+    token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+        if token.Method.Alg() != m.method.Alg() {
             return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
         }
-        return m.secretKey, nil
-    })
+        return m.keyFor(token)
+    }, opts...)
 
     if err != nil {
-        return nil, fmt.Errorf("invalid token: %w", err)
+        return nil, fmt.Errorf("invalid token: %w", m.classify(err, claims))
     }
 
-    claims, ok := token.Claims.(*Claims)
-    if !ok || !token.Valid {
+    if !token.Valid {
         return nil, fmt.Errorf("invalid token claims")
     }
 
     return claims, nil
-}
\ No newline at end of file
+}
+
+// keyFor returns the key to verify token with: the HS256 key its kid names,
+// or every HS256 key when it names none.
+func (m *JWTManager) keyFor(token *jwt.Token) (interface{}, error) {
+    if m.keys == nil {
+        return m.verifyKey, nil
+    }
+    kid, _ := token.Header["kid"].(string)
+    if kid == "" {
+        set := jwt.VerificationKeySet{}
+        for _, key := range m.keys {
+            set.Keys = append(set.Keys, key)
+        }
+        return set, nil
+    }
+    key, ok := m.keys[kid]
+    if !ok {
+        return nil, fmt.Errorf("%w: kid %q", ErrUnknownKeyID, kid)
+    }
+    return key, nil
+}
+
+// classify wraps err with the package error describing why validation
+// failed, where there is one.
+func (m *JWTManager) classify(err error, claims *Claims) error {
+    missing := errors.Is(err, jwt.ErrTokenRequiredClaimMissing)
+    switch {
+    case errors.Is(err, jwt.ErrTokenExpired):
+        return fmt.Errorf("%w: %w", ErrTokenExpired, err)
+    case errors.Is(err, jwt.ErrTokenInvalidIssuer), missing && m.issuer != "" && claims.Issuer == "":
+        return fmt.Errorf("%w: %w", ErrInvalidIssuer, err)
+    case errors.Is(err, jwt.ErrTokenInvalidAudience), missing && m.audience != "" && len(claims.Audience) == 0:
+        return fmt.Errorf("%w: %w", ErrInvalidAudience, err)
+    default:
+        return err
+    }
+}
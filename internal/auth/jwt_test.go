@@ -0,0 +1,239 @@
+// internal/auth/jwt_test.go
+
+package auth
+
+import (
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "errors"
+    "testing"
+    "time"
+    "github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidateTokenIssuerAudience(t *testing.T) {
+    const secret = "shared-secret"
+    verifier := NewJWTManager(secret, time.Hour, WithIssuer("web-service"), WithAudience("web-service"))
+
+    tests := []struct {
+        name    string
+        minter  *JWTManager
+        wantErr error
+    }{
+        {name: "matching", minter: verifier},
+        {name: "missing both", minter: NewJWTManager(secret, time.Hour), wantErr: ErrInvalidIssuer},
+        {name: "other issuer", minter: NewJWTManager(secret, time.Hour, WithIssuer("billing"), WithAudience("web-service")), wantErr: ErrInvalidIssuer},
+        {name: "missing audience", minter: NewJWTManager(secret, time.Hour, WithIssuer("web-service")), wantErr: ErrInvalidAudience},
+        {name: "other audience", minter: NewJWTManager(secret, time.Hour, WithIssuer("web-service"), WithAudience("billing")), wantErr: ErrInvalidAudience},
+        {name: "expired", minter: NewJWTManager(secret, -time.Hour, WithIssuer("web-service"), WithAudience("web-service")), wantErr: ErrTokenExpired},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            token, err := tt.minter.GenerateToken("user-1", "user")
+            if err != nil {
+                t.Fatal(err)
+            }
+            claims, err := verifier.ValidateToken(token)
+            if tt.wantErr == nil {
+                if err != nil {
+                    t.Fatalf("unexpected error: %v", err)
+                }
+                if claims.UserID != "user-1" {
+                    t.Errorf("expected user-1, got %q", claims.UserID)
+                }
+                return
+            }
+            if !errors.Is(err, tt.wantErr) {
+                t.Errorf("expected %v, got %v", tt.wantErr, err)
+            }
+        })
+    }
+}
+
+func TestValidateTokenLeeway(t *testing.T) {
+    m := NewJWTManager("secret", time.Hour)
+
+    // Issued by a host whose clock runs a few seconds ahead
+    future := time.Now().Add(5 * time.Second)
+    token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+        UserID: "user-1",
+        RegisteredClaims: jwt.RegisteredClaims{
+            ExpiresAt: jwt.NewNumericDate(future.Add(time.Hour)),
+            IssuedAt:  jwt.NewNumericDate(future),
+            NotBefore: jwt.NewNumericDate(future),
+        },
+    }).SignedString([]byte("secret"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := m.ValidateToken(token); err != nil {
+        t.Errorf("expected token within leeway to validate, got %v", err)
+    }
+
+    strict := NewJWTManager("secret", time.Hour, WithLeeway(0))
+    if _, err := strict.ValidateToken(token); err == nil {
+        t.Error("expected token from the future to be rejected without leeway")
+    }
+}
+
+func TestWithClock(t *testing.T) {
+    now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+    m := NewJWTManager("secret", time.Hour, WithClock(func() time.Time { return now }), WithLeeway(0))
+
+    token, err := m.GenerateToken("user-1", "user")
+    if err != nil {
+        t.Fatal(err)
+    }
+    claims, err := m.ValidateToken(token)
+    if err != nil {
+        t.Fatalf("expected a fresh token to validate, got %v", err)
+    }
+    if !claims.IssuedAt.Equal(now) || !claims.ExpiresAt.Equal(now.Add(time.Hour)) {
+        t.Errorf("expected the token stamped by the clock, got issued %v expiring %v", claims.IssuedAt, claims.ExpiresAt)
+    }
+
+    now = now.Add(59 * time.Minute)
+    if _, err := m.ValidateToken(token); err != nil {
+        t.Errorf("expected the token valid before expiry, got %v", err)
+    }
+    now = now.Add(time.Minute)
+    if _, err := m.ValidateToken(token); !errors.Is(err, ErrTokenExpired) {
+        t.Errorf("expected ErrTokenExpired once the clock passes expiry, got %v", err)
+    }
+}
+
+func TestKeyRotation(t *testing.T) {
+    legacy, err := NewJWTManager("v1-secret", time.Hour).GenerateToken("user-1", "user")
+    if err != nil {
+        t.Fatal(err)
+    }
+    v1 := NewJWTManagerKeys(HMACKey{ID: "v1", Secret: "v1-secret"}, nil, time.Hour)
+    oldToken, err := v1.GenerateToken("user-1", "user")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Rotate to v2, keeping v1 valid for the tokens it signed
+    v2 := NewJWTManagerKeys(HMACKey{ID: "v2", Secret: "v2-secret"}, []HMACKey{{ID: "v1", Secret: "v1-secret"}}, time.Hour)
+    newToken, err := v2.GenerateToken("user-2", "user")
+    if err != nil {
+        t.Fatal(err)
+    }
+    parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if parsed.Header["kid"] != "v2" {
+        t.Errorf("expected new tokens signed with kid v2, got %v", parsed.Header["kid"])
+    }
+
+    for name, token := range map[string]string{"legacy without kid": legacy, "old key": oldToken, "current key": newToken} {
+        if _, err := v2.ValidateToken(token); err != nil {
+            t.Errorf("%s: expected token to validate, got %v", name, err)
+        }
+    }
+
+    // Once v1 is dropped from the set its tokens are rejected
+    v3 := NewJWTManagerKeys(HMACKey{ID: "v3", Secret: "v3-secret"}, []HMACKey{{ID: "v2", Secret: "v2-secret"}}, time.Hour)
+    if _, err := v3.ValidateToken(oldToken); !errors.Is(err, ErrUnknownKeyID) {
+        t.Errorf("expected %v for a retired key, got %v", ErrUnknownKeyID, err)
+    }
+    if _, err := v3.ValidateToken(legacy); err == nil {
+        t.Error("expected legacy token signed with a retired key to be rejected")
+    }
+    if _, err := v3.ValidateToken(newToken); err != nil {
+        t.Errorf("expected v2 token to still validate, got %v", err)
+    }
+
+    // A kid naming a key in the set doesn't let another key's secret pass
+    forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "attacker"})
+    forged.Header["kid"] = "v2"
+    forgedStr, err := forged.SignedString([]byte("v3-secret"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := v3.ValidateToken(forgedStr); err == nil {
+        t.Error("expected token signed with a different key than its kid names to be rejected")
+    }
+}
+
+func TestRoundTrip(t *testing.T) {
+    key, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name     string
+        minter   *JWTManager
+        verifier *JWTManager
+    }{
+        {name: "HS256", minter: NewJWTManager("secret", time.Hour), verifier: NewJWTManager("secret", time.Hour)},
+        {name: "RS256", minter: NewJWTManagerRS256(key, nil, time.Hour), verifier: NewJWTManagerRS256(key, nil, time.Hour)},
+        {name: "RS256 verify-only", minter: NewJWTManagerRS256(key, nil, time.Hour), verifier: NewJWTManagerRS256(nil, &key.PublicKey, time.Hour)},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            token, err := tt.minter.GenerateToken("user-1", "admin")
+            if err != nil {
+                t.Fatal(err)
+            }
+            claims, err := tt.verifier.ValidateToken(token)
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if claims.UserID != "user-1" || claims.Role != "admin" {
+                t.Errorf("unexpected claims %+v", claims)
+            }
+        })
+    }
+}
+
+func TestVerifyOnlyCannotSign(t *testing.T) {
+    key, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatal(err)
+    }
+    m := NewJWTManagerRS256(nil, &key.PublicKey, time.Hour)
+    if m.CanSign() {
+        t.Error("expected verify-only manager")
+    }
+    if _, err := m.GenerateToken("user-1", "user"); !errors.Is(err, ErrVerifyOnly) {
+        t.Errorf("expected ErrVerifyOnly, got %v", err)
+    }
+}
+
+func TestRS256RejectsOtherAlgorithms(t *testing.T) {
+    key, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatal(err)
+    }
+    verifier := NewJWTManagerRS256(nil, &key.PublicKey, time.Hour)
+
+    // Algorithm confusion: an HS256 token keyed with the public key bytes,
+    // which an attacker can obtain
+    publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+    forged, err := NewJWTManager(string(publicDER), time.Hour).GenerateToken("attacker", "admin")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := verifier.ValidateToken(forged); err == nil {
+        t.Error("expected HS256 token to be rejected in RS256 mode")
+    }
+
+    // And the reverse: an HS256 manager must not accept RS256 tokens
+    rs256, err := NewJWTManagerRS256(key, nil, time.Hour).GenerateToken("user-1", "user")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := NewJWTManager("secret", time.Hour).ValidateToken(rs256); err == nil {
+        t.Error("expected RS256 token to be rejected in HS256 mode")
+    }
+}
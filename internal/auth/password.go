@@ -0,0 +1,121 @@
+// internal/auth/password.go
+
+package auth
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// passwordScheme prefixes hashes produced by HashPassword.
+const passwordScheme = "pbkdf2-sha256"
+
+// DefaultPasswordIterations is the PBKDF2 work factor for new hashes. The
+// count is stored in each hash, so it can be raised without invalidating
+// existing ones.
+const DefaultPasswordIterations = 210000
+
+const (
+    passwordSaltLen = 16
+    passwordKeyLen  = 32
+)
+
+// ErrMalformedHash is returned by CheckPassword for hashes it can't parse.
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// HashPassword returns a salted PBKDF2-SHA256 hash of password in the form
+// pbkdf2-sha256$<iterations>$<salt>$<key>, with base64 salt and key.
+func HashPassword(password string) (string, error) {
+    return hashPassword(password, DefaultPasswordIterations)
+}
+
+func hashPassword(password string, iterations int) (string, error) {
+    salt := make([]byte, passwordSaltLen)
+    if _, err := rand.Read(salt); err != nil {
+        return "", fmt.Errorf("generating salt: %w", err)
+    }
+    key := pbkdf2SHA256([]byte(password), salt, iterations, passwordKeyLen)
+    return strings.Join([]string{
+        passwordScheme,
+        strconv.Itoa(iterations),
+        base64.RawStdEncoding.EncodeToString(salt),
+        base64.RawStdEncoding.EncodeToString(key),
+    }, "$"), nil
+}
+
+// CheckPassword reports whether password matches hash, comparing in
+// constant time.
+func CheckPassword(hash, password string) (bool, error) {
+    iterations, salt, key, err := parsePasswordHash(hash)
+    if err != nil {
+        return false, err
+    }
+    got := pbkdf2SHA256([]byte(password), salt, iterations, len(key))
+    return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+// ValidatePasswordHash checks that hash is in the form HashPassword
+// produces.
+func ValidatePasswordHash(hash string) error {
+    _, _, _, err := parsePasswordHash(hash)
+    return err
+}
+
+func parsePasswordHash(hash string) (iterations int, salt, key []byte, err error) {
+    parts := strings.Split(hash, "$")
+    if len(parts) != 4 || parts[0] != passwordScheme {
+        return 0, nil, nil, ErrMalformedHash
+    }
+    iterations, err = strconv.Atoi(parts[1])
+    if err != nil || iterations < 1 {
+        return 0, nil, nil, ErrMalformedHash
+    }
+    salt, err = base64.RawStdEncoding.DecodeString(parts[2])
+    if err != nil {
+        return 0, nil, nil, ErrMalformedHash
+    }
+    key, err = base64.RawStdEncoding.DecodeString(parts[3])
+    if err != nil || len(key) == 0 {
+        return 0, nil, nil, ErrMalformedHash
+    }
+    return iterations, salt, key, nil
+}
+
+// pbkdf2SHA256 derives a keyLen-byte key from password and salt as in
+// RFC 8018 section 5.2, with HMAC-SHA256 as the PRF.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+    prf := hmac.New(sha256.New, password)
+    hashLen := prf.Size()
+    blocks := (keyLen + hashLen - 1) / hashLen
+
+    key := make([]byte, 0, blocks*hashLen)
+    var counter [4]byte
+    u := make([]byte, hashLen)
+    for block := 1; block <= blocks; block++ {
+        prf.Reset()
+        prf.Write(salt)
+        binary.BigEndian.PutUint32(counter[:], uint32(block))
+        prf.Write(counter[:])
+        u = prf.Sum(u[:0])
+
+        t := append([]byte(nil), u...)
+        for i := 1; i < iterations; i++ {
+            prf.Reset()
+            prf.Write(u)
+            u = prf.Sum(u[:0])
+            for j := range t {
+                t[j] ^= u[j]
+            }
+        }
+        key = append(key, t...)
+    }
+    return key[:keyLen]
+}
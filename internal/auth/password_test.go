@@ -0,0 +1,68 @@
+// internal/auth/password_test.go
+
+package auth
+
+import (
+    "encoding/hex"
+    "errors"
+    "testing"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+    // Test vectors from RFC 7914 section 11
+    tests := []struct {
+        password, salt string
+        iterations     int
+        keyLen         int
+        want           string
+    }{
+        {password: "passwd", salt: "salt", iterations: 1, keyLen: 64, want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+        {password: "Password", salt: "NaCl", iterations: 80000, keyLen: 64, want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+    }
+    for _, tt := range tests {
+        got := hex.EncodeToString(pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen))
+        if got != tt.want {
+            t.Errorf("pbkdf2(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+        }
+    }
+}
+
+func TestHashPassword(t *testing.T) {
+    hash, err := hashPassword("correct horse", 1000)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := ValidatePasswordHash(hash); err != nil {
+        t.Fatalf("hash %q failed validation: %v", hash, err)
+    }
+
+    if ok, err := CheckPassword(hash, "correct horse"); err != nil || !ok {
+        t.Errorf("expected the right password to match, got %v, %v", ok, err)
+    }
+    if ok, err := CheckPassword(hash, "battery staple"); err != nil || ok {
+        t.Errorf("expected the wrong password not to match, got %v, %v", ok, err)
+    }
+
+    other, err := hashPassword("correct horse", 1000)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if other == hash {
+        t.Error("expected hashes of the same password to differ by salt")
+    }
+}
+
+func TestCheckPasswordMalformed(t *testing.T) {
+    for _, hash := range []string{
+        "",
+        "test123",
+        "bcrypt$10$abc$def",
+        "pbkdf2-sha256$0$c2FsdA$a2V5",
+        "pbkdf2-sha256$1000$!!$a2V5",
+        "pbkdf2-sha256$1000$c2FsdA$",
+    } {
+        if _, err := CheckPassword(hash, "x"); !errors.Is(err, ErrMalformedHash) {
+            t.Errorf("CheckPassword(%q): expected ErrMalformedHash, got %v", hash, err)
+        }
+    }
+}
@@ -3,25 +3,695 @@
 package config
 
 import (
+    "crypto/rsa"
+    "errors"
     "fmt"
+    "net/netip"
+    "net/url"
+    "os"
+    "slices"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+    "web-service/internal/auth"
+    "web-service/internal/realip"
 )
 
+// databaseBackend describes a storage backend selectable by DATABASE_URL.
+type databaseBackend struct {
+    // needsLocation is true for backends that must be given a host or a
+    // file path in the URL.
+    needsLocation bool
+}
+
+// databaseBackends maps each supported DATABASE_URL scheme to its backend.
+var databaseBackends = map[string]databaseBackend{
+    "memory": {needsLocation: false},
+}
+
+// validateDatabaseURL checks that raw parses and names a supported backend,
+// with a host or path when that backend needs one.
+func validateDatabaseURL(raw string) error {
+    u, err := url.Parse(raw)
+    if err != nil {
+        return fmt.Errorf("DATABASE_URL is not a valid URL: %w", err)
+    }
+
+    backend, ok := databaseBackends[strings.ToLower(u.Scheme)]
+    if !ok {
+        schemes := make([]string, 0, len(databaseBackends))
+        for scheme := range databaseBackends {
+            schemes = append(schemes, scheme+"://")
+        }
+        sort.Strings(schemes)
+        return fmt.Errorf("DATABASE_URL has unsupported scheme %q; supported schemes: %s",
+            u.Scheme, strings.Join(schemes, ", "))
+    }
+
+    if backend.needsLocation && u.Host == "" && strings.Trim(u.Path, "/") == "" {
+        return fmt.Errorf("DATABASE_URL with scheme %q must include a host or path", u.Scheme)
+    }
+    return nil
+}
+
+// Supported JWT_ALGORITHM values.
+const (
+    JWTAlgorithmHS256 = "HS256"
+    JWTAlgorithmRS256 = "RS256"
+)
+
+// Supported SIMILARITY_ACTION values.
+const (
+    SimilarityActionOff    = "off"
+    SimilarityActionFlag   = "flag"
+    SimilarityActionReject = "reject"
+)
+
+// Supported COMMENT_IDS values.
+const (
+    CommentIDsRandom   = "random"
+    CommentIDsSortable = "sortable"
+)
+
+// Supported CONTENT_POLICY values.
+const (
+    ContentPolicyText      = "text"
+    ContentPolicyAllowlist = "allowlist"
+    ContentPolicyNone      = "none"
+)
+
+// Supported ERROR_FORMAT values.
+const (
+    ErrorFormatProblem = "problem"
+    ErrorFormatLegacy  = "legacy"
+)
+
+// Supported LOG_OVERFLOW values.
+const (
+    LogOverflowDrop  = "drop"
+    LogOverflowBlock = "block"
+)
+
+// UserSeed is a login account from USERS.
+type UserSeed struct {
+    Username     string
+    Role         string
+    PasswordHash string
+}
+
 type Config struct {
     DatabaseURL string
     JWTSecret   string
     Environment string
+
+    // Host and Port are where the server listens, localhost:8080 unless
+    // set. The --host and --port flags override them.
+    Host string
+    Port string
+
+    // Socket, when set, is the path of a Unix domain socket to listen on
+    // instead of Host and Port, created with permissions SocketMode
+    // (default 0660).
+    Socket     string
+    SocketMode os.FileMode
+
+    // ListenRetries is how many more times to try listening while the
+    // address is in use, such as during a restart on the same host, before
+    // giving up. The wait doubles after each attempt. Zero, the default,
+    // fails at once.
+    ListenRetries int
+
+    // UnknownFileKeys lists the keys in the config file that aren't
+    // settings, which were ignored.
+    UnknownFileKeys []string
+
+    // JWTAlgorithm is HS256 (shared JWT_SECRET, the default) or RS256.
+    // In RS256 mode JWTPublicKey verifies tokens and JWTPrivateKey, when
+    // set, signs them; without a private key the service is verify-only.
+    JWTAlgorithm  string
+    JWTPublicKey  *rsa.PublicKey
+    JWTPrivateKey *rsa.PrivateKey
+
+    // JWTKeyID names JWTSecret in the kid header of issued tokens, and
+    // JWTPreviousKeys are retired secrets still accepted for the tokens
+    // they signed, from JWT_PREVIOUS_KEYS entries of the form kid:secret.
+    // Rotating the secret means moving the old one there under its ID
+    // until its tokens have expired. HS256 only.
+    JWTKeyID        string
+    JWTPreviousKeys []auth.HMACKey
+
+    // JWTIssuer and JWTAudience are set on issued tokens and required on
+    // incoming ones. Both default to "web-service".
+    JWTIssuer   string
+    JWTAudience string
+
+    // JWTExpiry is how long issued tokens are valid for (default 24h), and
+    // JWTLeeway the clock skew tolerated when validating them (default 30s).
+    JWTExpiry time.Duration
+    JWTLeeway time.Duration
+
+    // Users are the accounts that can log in, from the comma-separated
+    // USERS entries of the form username:role:hash, where role is user or
+    // admin and hash comes from `server --hash-password`. In development a
+    // test/test123 user is also available.
+    Users []UserSeed
+
+    // Failed logins lock a username out for LoginLockout after
+    // LoginMaxFailures failures within LoginFailureWindow, and a client IP
+    // after LoginMaxFailuresPerIP. A zero limit disables that lockout.
+    LoginMaxFailures      int
+    LoginMaxFailuresPerIP int
+    LoginFailureWindow    time.Duration
+    LoginLockout          time.Duration
+
+    // TrustedProxies lists the proxies, as CIDRs or IPs from the
+    // comma-separated TRUSTED_PROXIES, whose X-Forwarded-For and X-Real-IP
+    // headers are believed when resolving client IPs.
+    TrustedProxies []netip.Prefix
+
+    // RequestTimeout bounds how long a request may run before its context
+    // is cancelled and it fails with 503. Zero disables it.
+    RequestTimeout time.Duration
+
+    // ShutdownTimeout is how long graceful shutdown waits for in-flight
+    // requests before closing their connections.
+    ShutdownTimeout time.Duration
+
+    // ShutdownDrainDelay is how long shutdown keeps accepting connections
+    // before it stops listening, failing /readyz and answering every
+    // request with 503 and Retry-After, so load balancers notice and stop
+    // routing here. Set it to more than their check interval. Zero, the
+    // default, stops listening at once.
+    ShutdownDrainDelay time.Duration
+
+    // Readiness probes of the store are budgeted: slower than
+    // ReadyDegradedLatency is degraded, and slower than ReadyTimeout for
+    // ReadyFailAfter probes in a row is not ready. A zero duration disables
+    // that budget.
+    ReadyTimeout         time.Duration
+    ReadyDegradedLatency time.Duration
+    ReadyFailAfter       int
+
+    // Overrides for the security headers set on every response. Empty
+    // values keep the API's safe defaults.
+    SecurityFrameOptions   string
+    SecurityReferrerPolicy string
+    SecurityHSTS           string
+
+    // MemoryBudget caps the approximate bytes held by the in-memory store.
+    // Zero means unlimited.
+    MemoryBudget int64
+
+    // StoreSlowThreshold is how long a store operation may take before it
+    // is logged at WARN as slow (default 100ms). Zero logs none.
+    StoreSlowThreshold time.Duration
+
+    // CommentRetention is how long comments are kept before the cleanup
+    // job deletes them. Zero keeps them indefinitely; the job still sweeps
+    // expired comments.
+    CommentRetention time.Duration
+    // CleanupInterval is how often the cleanup job runs.
+    CleanupInterval time.Duration
+
+    // CommentMaxTTL bounds the lifetime a comment may be given at creation,
+    // and lifetimes beyond CommentTTLAdminThreshold need the admin role.
+    // Zero leaves the respective limit off.
+    CommentMaxTTL            time.Duration
+    CommentTTLAdminThreshold time.Duration
+
+    // DeleteIdempotencyWindow is how long a repeated DELETE of the same
+    // comment still succeeds with 204. Zero makes repeats 404.
+    DeleteIdempotencyWindow time.Duration
+
+    // Length limits for comments, in characters. CommentMinLength applies
+    // to content without surrounding whitespace. Zero leaves a limit off.
+    CommentMinLength       int
+    CommentMaxLength       int
+    CommentMaxAuthorLength int
+
+    // CommentMaxAttachments caps how many attachment URLs a comment may
+    // carry, and CommentMaxAttachmentLength how long each may be, in
+    // characters. Zero leaves a limit off.
+    CommentMaxAttachments      int
+    CommentMaxAttachmentLength int
+
+    // ErasureKey signs user erasure receipts and hashes the erased user's
+    // ID in them. It defaults to the JWT secret.
+    ErasureKey string
+
+    // CommentIDs selects how comment IDs are generated: random, the
+    // default, or sortable, which encode creation time and sort in
+    // creation order.
+    CommentIDs string
+
+    // ContentPolicy is how HTML in comment content is treated: "text" (the
+    // default) stores content as written and escapes it when rendered, so
+    // "a<b" stays text, "allowlist" keeps only harmless formatting tags,
+    // and "none" stores content as written and renders its HTML sanitized.
+    ContentPolicy string
+
+    // ErrorFormat is the shape of error response bodies: "problem" (the
+    // default) for RFC 7807 problem details, or "legacy" for the
+    // {"error": {...}} object used before, kept for one release while
+    // clients move over.
+    ErrorFormat string
+
+    // IdempotencyKeyTTL is how long an Idempotency-Key on comment creation
+    // is remembered, so a retry returns the comment the first request
+    // created. Zero ignores the header.
+    IdempotencyKeyTTL time.Duration
+
+    // StatsCacheTTL is how long the store-wide comment stats are reused
+    // before being recomputed (default 5s). Zero computes them for every
+    // request.
+    StatsCacheTTL time.Duration
+
+    // CommentListMaxAge is the max-age clients may cache the comment list
+    // for without revalidating (default 0, always revalidate). Revalidation
+    // is answered with 304 when nothing has changed.
+    CommentListMaxAge time.Duration
+
+    // SimilarityAction is what happens to a new comment at least
+    // SimilarityThreshold similar to one of the last SimilarityWindow
+    // comments: "off" (the default) skips the check, "flag" logs and counts
+    // it, and "reject" refuses it with 409.
+    SimilarityAction    string
+    SimilarityThreshold float64
+    SimilarityWindow    int
+
+    // FaultInjection enables storage fault injection and its admin
+    // endpoint for chaos testing. It is refused in production.
+    FaultInjection bool
+
+    // DebugEndpoints mounts the admin-only pprof and runtime stats
+    // endpoints under /debug. It defaults to on outside production and is
+    // set explicitly with ENABLE_DEBUG_ENDPOINTS.
+    DebugEndpoints bool
+
+    // LogHTTPBodies logs request and response bodies, redacted, at DEBUG
+    // whatever the logger's level. It is ignored in production.
+    LogHTTPBodies bool
+
+    // LogBufferSize, when positive, buffers up to that many log entries
+    // for a background goroutine to write, so a slow stdout doesn't slow
+    // requests. LogOverflow decides what happens when the buffer is full:
+    // "drop" (the default) discards the oldest entry and "block" waits.
+    LogBufferSize int
+    LogOverflow   string
+
+    // LogStackTraces adds a stack trace to ERROR log entries. It is on
+    // unless LOG_STACK_TRACES turns it off.
+    LogStackTraces bool
+
+    // OTLPEndpoint is the OTLP/HTTP collector URL spans are exported to,
+    // such as http://localhost:4318. Empty disables export, though incoming
+    // trace context still reaches the logs.
+    OTLPEndpoint string
+
+    // ExperimentalFeatures names the experimental endpoints to mount,
+    // from the comma-separated EXPERIMENTAL_FEATURES.
+    ExperimentalFeatures []string
 }
 
+// Load reads the configuration through getenv, falling back to values from
+// an optional .env file (see withEnvFile) for variables that are unset.
 func Load(getenv func(string) string) (*Config, error) {
+    return LoadFile("", getenv)
+}
+
+// load reads the configuration through getenv and applies defaults.
+func load(getenv func(string) string) (*Config, error) {
     cfg := &Config{
         DatabaseURL: getenv("DATABASE_URL"),
         JWTSecret:   getenv("JWT_SECRET"),
         Environment: getenv("ENVIRONMENT"),
+        JWTIssuer:   getenv("JWT_ISSUER"),
+        JWTAudience: getenv("JWT_AUDIENCE"),
+
+        SecurityFrameOptions:   getenv("SECURITY_FRAME_OPTIONS"),
+        SecurityReferrerPolicy: getenv("SECURITY_REFERRER_POLICY"),
+        SecurityHSTS:           getenv("SECURITY_HSTS"),
+    }
+
+    // Problems are collected rather than returned as found, so one run
+    // reports every bad setting
+    var errs []error
+
+    if err := loadJWTKeys(cfg, getenv); err != nil {
+        errs = append(errs, err)
+    }
+
+    cfg.Host = "localhost"
+    if v := getenv("HOST"); v != "" {
+        cfg.Host = v
     }
 
-    // Only JWT_SECRET is required for now since we're using in-memory store
-    if cfg.JWTSecret == "" {
-        return nil, fmt.Errorf("JWT_SECRET is required")
+    cfg.Port = "8080"
+    if v := getenv("PORT"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 || n > 65535 {
+            errs = append(errs, fmt.Errorf("PORT must be a port number, got %q", v))
+        }
+        cfg.Port = v
+    }
+
+    cfg.Socket = getenv("SOCKET")
+    cfg.SocketMode = 0o660
+    if v := getenv("SOCKET_MODE"); v != "" {
+        mode, err := strconv.ParseUint(v, 8, 32)
+        if err != nil || mode > 0o777 {
+            errs = append(errs, fmt.Errorf("SOCKET_MODE must be octal permissions such as 0660, got %q", v))
+        }
+        cfg.SocketMode = os.FileMode(mode)
+    }
+
+    if v := getenv("LISTEN_RETRIES"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("LISTEN_RETRIES must be a non-negative integer, got %q", v))
+        }
+        cfg.ListenRetries = n
+    }
+
+    cfg.JWTExpiry = 24 * time.Hour
+    if v := getenv("JWT_EXPIRY"); v != "" {
+        expiry, err := time.ParseDuration(v)
+        if err != nil || expiry <= 0 {
+            errs = append(errs, fmt.Errorf("JWT_EXPIRY must be a positive duration, got %q", v))
+        }
+        cfg.JWTExpiry = expiry
+    }
+
+    cfg.JWTLeeway = 30 * time.Second
+    if v := getenv("JWT_LEEWAY"); v != "" {
+        leeway, err := time.ParseDuration(v)
+        if err != nil || leeway < 0 {
+            errs = append(errs, fmt.Errorf("JWT_LEEWAY must be a non-negative duration, got %q", v))
+        }
+        cfg.JWTLeeway = leeway
+    }
+
+    if v := getenv("USERS"); v != "" {
+        users, err := parseUsers(v)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("USERS: %w", err))
+        }
+        cfg.Users = users
+    }
+
+    cfg.LoginMaxFailures = 5
+    if v := getenv("LOGIN_MAX_FAILURES"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("LOGIN_MAX_FAILURES must be a non-negative integer, got %q", v))
+        }
+        cfg.LoginMaxFailures = n
+    }
+
+    cfg.LoginMaxFailuresPerIP = 20
+    if v := getenv("LOGIN_MAX_FAILURES_PER_IP"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("LOGIN_MAX_FAILURES_PER_IP must be a non-negative integer, got %q", v))
+        }
+        cfg.LoginMaxFailuresPerIP = n
+    }
+
+    cfg.LoginFailureWindow = 15 * time.Minute
+    if v := getenv("LOGIN_FAILURE_WINDOW"); v != "" {
+        window, err := time.ParseDuration(v)
+        if err != nil || window <= 0 {
+            errs = append(errs, fmt.Errorf("LOGIN_FAILURE_WINDOW must be a positive duration, got %q", v))
+        }
+        cfg.LoginFailureWindow = window
+    }
+
+    cfg.LoginLockout = 15 * time.Minute
+    if v := getenv("LOGIN_LOCKOUT"); v != "" {
+        lockout, err := time.ParseDuration(v)
+        if err != nil || lockout <= 0 {
+            errs = append(errs, fmt.Errorf("LOGIN_LOCKOUT must be a positive duration, got %q", v))
+        }
+        cfg.LoginLockout = lockout
+    }
+
+    if v := getenv("TRUSTED_PROXIES"); v != "" {
+        proxies, err := realip.ParsePrefixes(strings.Split(v, ","))
+        if err != nil {
+            errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %w", err))
+        }
+        cfg.TrustedProxies = proxies
+    }
+
+    cfg.RequestTimeout = 30 * time.Second
+    if v := getenv("REQUEST_TIMEOUT"); v != "" {
+        timeout, err := time.ParseDuration(v)
+        if err != nil || timeout < 0 {
+            errs = append(errs, fmt.Errorf("REQUEST_TIMEOUT must be a non-negative duration, got %q", v))
+        }
+        cfg.RequestTimeout = timeout
+    }
+
+    cfg.ShutdownTimeout = 10 * time.Second
+    if v := getenv("SHUTDOWN_TIMEOUT"); v != "" {
+        timeout, err := time.ParseDuration(v)
+        if err != nil || timeout <= 0 {
+            errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT must be a positive duration, got %q", v))
+        }
+        cfg.ShutdownTimeout = timeout
+    }
+
+    if v := getenv("SHUTDOWN_DRAIN_DELAY"); v != "" {
+        delay, err := time.ParseDuration(v)
+        if err != nil || delay < 0 {
+            errs = append(errs, fmt.Errorf("SHUTDOWN_DRAIN_DELAY must be a non-negative duration, got %q", v))
+        }
+        cfg.ShutdownDrainDelay = delay
+    }
+
+    cfg.ReadyTimeout = 2 * time.Second
+    if v := getenv("READY_TIMEOUT"); v != "" {
+        timeout, err := time.ParseDuration(v)
+        if err != nil || timeout < 0 {
+            errs = append(errs, fmt.Errorf("READY_TIMEOUT must be a non-negative duration, got %q", v))
+        }
+        cfg.ReadyTimeout = timeout
+    }
+
+    cfg.ReadyDegradedLatency = 500 * time.Millisecond
+    if v := getenv("READY_DEGRADED_LATENCY"); v != "" {
+        latency, err := time.ParseDuration(v)
+        if err != nil || latency < 0 {
+            errs = append(errs, fmt.Errorf("READY_DEGRADED_LATENCY must be a non-negative duration, got %q", v))
+        }
+        cfg.ReadyDegradedLatency = latency
+    }
+
+    cfg.ReadyFailAfter = 3
+    if v := getenv("READY_FAIL_AFTER"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("READY_FAIL_AFTER must be a non-negative integer, got %q", v))
+        }
+        cfg.ReadyFailAfter = n
+    }
+
+    if v := getenv("MEMORY_BUDGET"); v != "" {
+        budget, err := strconv.ParseInt(v, 10, 64)
+        if err != nil || budget < 0 {
+            errs = append(errs, fmt.Errorf("MEMORY_BUDGET must be a non-negative number of bytes, got %q", v))
+        }
+        cfg.MemoryBudget = budget
+    }
+
+    cfg.StoreSlowThreshold = 100 * time.Millisecond
+    if v := getenv("STORE_SLOW_THRESHOLD"); v != "" {
+        threshold, err := time.ParseDuration(v)
+        if err != nil || threshold < 0 {
+            errs = append(errs, fmt.Errorf("STORE_SLOW_THRESHOLD must be a non-negative duration, got %q", v))
+        }
+        cfg.StoreSlowThreshold = threshold
+    }
+
+    if v := getenv("COMMENT_RETENTION"); v != "" {
+        retention, err := time.ParseDuration(v)
+        if err != nil || retention < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_RETENTION must be a non-negative duration, got %q", v))
+        }
+        cfg.CommentRetention = retention
+    }
+
+    cfg.CleanupInterval = time.Hour
+    if v := getenv("CLEANUP_INTERVAL"); v != "" {
+        interval, err := time.ParseDuration(v)
+        if err != nil || interval <= 0 {
+            errs = append(errs, fmt.Errorf("CLEANUP_INTERVAL must be a positive duration, got %q", v))
+        }
+        cfg.CleanupInterval = interval
+    }
+
+    cfg.CommentMaxTTL = 30 * 24 * time.Hour
+    if v := getenv("COMMENT_MAX_TTL"); v != "" {
+        ttl, err := time.ParseDuration(v)
+        if err != nil || ttl < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_MAX_TTL must be a non-negative duration, got %q", v))
+        }
+        cfg.CommentMaxTTL = ttl
+    }
+
+    cfg.CommentTTLAdminThreshold = 7 * 24 * time.Hour
+    if v := getenv("COMMENT_TTL_ADMIN_THRESHOLD"); v != "" {
+        threshold, err := time.ParseDuration(v)
+        if err != nil || threshold < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_TTL_ADMIN_THRESHOLD must be a non-negative duration, got %q", v))
+        }
+        cfg.CommentTTLAdminThreshold = threshold
+    }
+
+    if v := getenv("DELETE_IDEMPOTENCY_WINDOW"); v != "" {
+        window, err := time.ParseDuration(v)
+        if err != nil || window < 0 {
+            errs = append(errs, fmt.Errorf("DELETE_IDEMPOTENCY_WINDOW must be a non-negative duration, got %q", v))
+        }
+        cfg.DeleteIdempotencyWindow = window
+    }
+
+    cfg.CommentMinLength = 1
+    if v := getenv("COMMENT_MIN_LENGTH"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_MIN_LENGTH must be a non-negative integer, got %q", v))
+        }
+        cfg.CommentMinLength = n
+    }
+
+    cfg.CommentMaxLength = 1000
+    if v := getenv("COMMENT_MAX_LENGTH"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_MAX_LENGTH must be a non-negative integer, got %q", v))
+        }
+        cfg.CommentMaxLength = n
+    }
+
+    cfg.CommentMaxAuthorLength = 100
+    if v := getenv("COMMENT_MAX_AUTHOR_LENGTH"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_MAX_AUTHOR_LENGTH must be a non-negative integer, got %q", v))
+        }
+        cfg.CommentMaxAuthorLength = n
+    }
+
+    cfg.CommentMaxAttachments = 4
+    if v := getenv("COMMENT_MAX_ATTACHMENTS"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_MAX_ATTACHMENTS must be a non-negative integer, got %q", v))
+        }
+        cfg.CommentMaxAttachments = n
+    }
+
+    cfg.CommentMaxAttachmentLength = 2048
+    if v := getenv("COMMENT_MAX_ATTACHMENT_LENGTH"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_MAX_ATTACHMENT_LENGTH must be a non-negative integer, got %q", v))
+        }
+        cfg.CommentMaxAttachmentLength = n
+    }
+
+    cfg.CommentIDs = CommentIDsRandom
+    if v := getenv("COMMENT_IDS"); v != "" {
+        switch v = strings.ToLower(v); v {
+        case CommentIDsRandom, CommentIDsSortable:
+            cfg.CommentIDs = v
+        default:
+            errs = append(errs, fmt.Errorf("COMMENT_IDS must be random or sortable, got %q", v))
+        }
+    }
+
+    cfg.ContentPolicy = ContentPolicyText
+    if v := getenv("CONTENT_POLICY"); v != "" {
+        switch v = strings.ToLower(v); v {
+        case ContentPolicyText, ContentPolicyAllowlist, ContentPolicyNone:
+            cfg.ContentPolicy = v
+        default:
+            errs = append(errs, fmt.Errorf("CONTENT_POLICY must be text, allowlist or none, got %q", v))
+        }
+    }
+
+    cfg.ErrorFormat = ErrorFormatProblem
+    if v := getenv("ERROR_FORMAT"); v != "" {
+        switch v = strings.ToLower(v); v {
+        case ErrorFormatProblem, ErrorFormatLegacy:
+            cfg.ErrorFormat = v
+        default:
+            errs = append(errs, fmt.Errorf("ERROR_FORMAT must be problem or legacy, got %q", v))
+        }
+    }
+
+    cfg.ErasureKey = getenv("ERASURE_KEY")
+
+    cfg.IdempotencyKeyTTL = 24 * time.Hour
+    if v := getenv("IDEMPOTENCY_KEY_TTL"); v != "" {
+        ttl, err := time.ParseDuration(v)
+        if err != nil || ttl < 0 {
+            errs = append(errs, fmt.Errorf("IDEMPOTENCY_KEY_TTL must be a non-negative duration, got %q", v))
+        }
+        cfg.IdempotencyKeyTTL = ttl
+    }
+
+    cfg.StatsCacheTTL = 5 * time.Second
+    if v := getenv("STATS_CACHE_TTL"); v != "" {
+        ttl, err := time.ParseDuration(v)
+        if err != nil || ttl < 0 {
+            errs = append(errs, fmt.Errorf("STATS_CACHE_TTL must be a non-negative duration, got %q", v))
+        }
+        cfg.StatsCacheTTL = ttl
+    }
+
+    if v := getenv("COMMENT_LIST_MAX_AGE"); v != "" {
+        age, err := time.ParseDuration(v)
+        if err != nil || age < 0 {
+            errs = append(errs, fmt.Errorf("COMMENT_LIST_MAX_AGE must be a non-negative duration, got %q", v))
+        }
+        cfg.CommentListMaxAge = age
+    }
+
+    cfg.SimilarityAction = SimilarityActionOff
+    if v := getenv("SIMILARITY_ACTION"); v != "" {
+        switch v = strings.ToLower(v); v {
+        case SimilarityActionOff, SimilarityActionFlag, SimilarityActionReject:
+            cfg.SimilarityAction = v
+        default:
+            errs = append(errs, fmt.Errorf("SIMILARITY_ACTION must be off, flag or reject, got %q", v))
+        }
+    }
+
+    cfg.SimilarityThreshold = 0.8
+    if v := getenv("SIMILARITY_THRESHOLD"); v != "" {
+        threshold, err := strconv.ParseFloat(v, 64)
+        if err != nil || threshold <= 0 || threshold > 1 {
+            errs = append(errs, fmt.Errorf("SIMILARITY_THRESHOLD must be a number in (0, 1], got %q", v))
+        }
+        cfg.SimilarityThreshold = threshold
+    }
+
+    cfg.SimilarityWindow = 1000
+    if v := getenv("SIMILARITY_WINDOW"); v != "" {
+        window, err := strconv.Atoi(v)
+        if err != nil || window <= 0 {
+            errs = append(errs, fmt.Errorf("SIMILARITY_WINDOW must be a positive number of comments, got %q", v))
+        }
+        cfg.SimilarityWindow = window
+    }
+
+    for _, name := range strings.Split(getenv("EXPERIMENTAL_FEATURES"), ",") {
+        if name = strings.TrimSpace(name); name != "" {
+            cfg.ExperimentalFeatures = append(cfg.ExperimentalFeatures, name)
+        }
     }
 
     // Set defaults
@@ -29,10 +699,245 @@ func Load(getenv func(string) string) (*Config, error) {
         cfg.Environment = "development"
     }
 
+    if v := getenv("FAULT_INJECTION"); v != "" {
+        enabled, err := strconv.ParseBool(v)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("FAULT_INJECTION must be a boolean, got %q", v))
+        }
+        cfg.FaultInjection = enabled
+    }
+
+    cfg.DebugEndpoints = cfg.Environment != "production"
+    if v := getenv("ENABLE_DEBUG_ENDPOINTS"); v != "" {
+        enabled, err := strconv.ParseBool(v)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("ENABLE_DEBUG_ENDPOINTS must be a boolean, got %q", v))
+        }
+        cfg.DebugEndpoints = enabled
+    }
+
+    if v := getenv("LOG_HTTP_BODIES"); v != "" {
+        enabled, err := strconv.ParseBool(v)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("LOG_HTTP_BODIES must be a boolean, got %q", v))
+        }
+        cfg.LogHTTPBodies = enabled && cfg.Environment != "production"
+    }
+
+    if v := getenv("LOG_BUFFER_SIZE"); v != "" {
+        size, err := strconv.Atoi(v)
+        if err != nil || size < 0 {
+            errs = append(errs, fmt.Errorf("LOG_BUFFER_SIZE must be a non-negative number of entries, got %q", v))
+        }
+        cfg.LogBufferSize = size
+    }
+
+    cfg.LogOverflow = LogOverflowDrop
+    if v := getenv("LOG_OVERFLOW"); v != "" {
+        switch v = strings.ToLower(v); v {
+        case LogOverflowDrop, LogOverflowBlock:
+            cfg.LogOverflow = v
+        default:
+            errs = append(errs, fmt.Errorf("LOG_OVERFLOW must be drop or block, got %q", v))
+        }
+    }
+
+    cfg.LogStackTraces = true
+    if v := getenv("LOG_STACK_TRACES"); v != "" {
+        enabled, err := strconv.ParseBool(v)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("LOG_STACK_TRACES must be a boolean, got %q", v))
+        }
+        cfg.LogStackTraces = enabled
+    }
+
+    if v := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+        u, err := url.Parse(v)
+        if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+            errs = append(errs, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT must be an http or https URL, got %q", v))
+        }
+        cfg.OTLPEndpoint = v
+    }
+    if cfg.JWTIssuer == "" {
+        cfg.JWTIssuer = "web-service"
+    }
+    if cfg.JWTAudience == "" {
+        cfg.JWTAudience = "web-service"
+    }
+
     // If no DATABASE_URL, use in-memory
     if cfg.DatabaseURL == "" {
         cfg.DatabaseURL = "memory://"
     }
 
+    if err := errors.Join(append(errs, cfg.Validate())...); err != nil {
+        return nil, err
+    }
     return cfg, nil
-}
\ No newline at end of file
+}
+
+// Supported ENVIRONMENT values.
+var environments = []string{"development", "test", "staging", "production"}
+
+// minProductionSecretLength is the shortest JWT_SECRET accepted in
+// production, where a guessable secret lets anyone mint tokens.
+const minProductionSecretLength = 32
+
+// Validate checks the settings against each other and the environment,
+// returning every problem found joined into one error, or nil.
+func (c *Config) Validate() error {
+    var errs []error
+    if !slices.Contains(environments, c.Environment) {
+        errs = append(errs, fmt.Errorf("ENVIRONMENT must be one of %s, got %q", strings.Join(environments, ", "), c.Environment))
+    }
+    if err := validateDatabaseURL(c.DatabaseURL); err != nil {
+        errs = append(errs, err)
+    }
+    if c.JWTAlgorithm == JWTAlgorithmHS256 {
+        switch {
+        case c.JWTSecret == "":
+            errs = append(errs, fmt.Errorf("JWT_SECRET is required"))
+        case c.Environment == "production" && len(c.JWTSecret) < minProductionSecretLength:
+            errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d characters in production", minProductionSecretLength))
+        }
+    }
+    if c.CommentMaxLength > 0 && c.CommentMinLength > c.CommentMaxLength {
+        errs = append(errs, fmt.Errorf("COMMENT_MIN_LENGTH (%d) must not exceed COMMENT_MAX_LENGTH (%d)", c.CommentMinLength, c.CommentMaxLength))
+    }
+    if c.FaultInjection && c.Environment == "production" {
+        errs = append(errs, fmt.Errorf("FAULT_INJECTION cannot be enabled in production"))
+    }
+    // The durations load checks, for configs built in code rather than
+    // loaded: a zero CLEANUP_INTERVAL would panic the cleanup ticker
+    for _, d := range []struct {
+        name     string
+        value    time.Duration
+        positive bool
+    }{
+        {"JWT_EXPIRY", c.JWTExpiry, true},
+        {"JWT_LEEWAY", c.JWTLeeway, false},
+        {"LOGIN_FAILURE_WINDOW", c.LoginFailureWindow, true},
+        {"LOGIN_LOCKOUT", c.LoginLockout, true},
+        {"REQUEST_TIMEOUT", c.RequestTimeout, false},
+        {"SHUTDOWN_TIMEOUT", c.ShutdownTimeout, true},
+        {"SHUTDOWN_DRAIN_DELAY", c.ShutdownDrainDelay, false},
+        {"READY_TIMEOUT", c.ReadyTimeout, false},
+        {"READY_DEGRADED_LATENCY", c.ReadyDegradedLatency, false},
+        {"STORE_SLOW_THRESHOLD", c.StoreSlowThreshold, false},
+        {"COMMENT_RETENTION", c.CommentRetention, false},
+        {"CLEANUP_INTERVAL", c.CleanupInterval, true},
+        {"COMMENT_MAX_TTL", c.CommentMaxTTL, false},
+        {"COMMENT_TTL_ADMIN_THRESHOLD", c.CommentTTLAdminThreshold, false},
+        {"DELETE_IDEMPOTENCY_WINDOW", c.DeleteIdempotencyWindow, false},
+        {"IDEMPOTENCY_KEY_TTL", c.IdempotencyKeyTTL, false},
+        {"STATS_CACHE_TTL", c.StatsCacheTTL, false},
+        {"COMMENT_LIST_MAX_AGE", c.CommentListMaxAge, false},
+    } {
+        switch {
+        case d.positive && d.value <= 0:
+            errs = append(errs, fmt.Errorf("%s must be a positive duration, got %s", d.name, d.value))
+        case d.value < 0:
+            errs = append(errs, fmt.Errorf("%s must be a non-negative duration, got %s", d.name, d.value))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// loadJWTKeys applies JWT_ALGORITHM and loads the key material it needs:
+// JWT_SECRET for HS256, or JWT_PUBLIC_KEY_FILE and/or JWT_PRIVATE_KEY_FILE
+// for RS256. The RS256 mode never needs a shared secret.
+func loadJWTKeys(cfg *Config, getenv func(string) string) error {
+    cfg.JWTAlgorithm = strings.ToUpper(strings.TrimSpace(getenv("JWT_ALGORITHM")))
+    if cfg.JWTAlgorithm == "" {
+        cfg.JWTAlgorithm = JWTAlgorithmHS256
+    }
+
+    switch cfg.JWTAlgorithm {
+    case JWTAlgorithmHS256:
+        cfg.JWTKeyID = strings.TrimSpace(getenv("JWT_KEY_ID"))
+        if v := getenv("JWT_PREVIOUS_KEYS"); v != "" {
+            if cfg.JWTKeyID == "" {
+                return fmt.Errorf("JWT_PREVIOUS_KEYS requires JWT_KEY_ID, so tokens name the key that signed them")
+            }
+            keys, err := parseHMACKeys(v, cfg.JWTKeyID)
+            if err != nil {
+                return fmt.Errorf("JWT_PREVIOUS_KEYS: %w", err)
+            }
+            cfg.JWTPreviousKeys = keys
+        }
+    case JWTAlgorithmRS256:
+        publicPath := getenv("JWT_PUBLIC_KEY_FILE")
+        privatePath := getenv("JWT_PRIVATE_KEY_FILE")
+        if publicPath == "" && privatePath == "" {
+            return fmt.Errorf("JWT_ALGORITHM=RS256 requires JWT_PUBLIC_KEY_FILE or JWT_PRIVATE_KEY_FILE")
+        }
+        if publicPath != "" {
+            key, err := loadRSAPublicKey(publicPath)
+            if err != nil {
+                return err
+            }
+            cfg.JWTPublicKey = key
+        }
+        if privatePath != "" {
+            key, err := loadRSAPrivateKey(privatePath)
+            if err != nil {
+                return err
+            }
+            cfg.JWTPrivateKey = key
+        }
+    default:
+        return fmt.Errorf("JWT_ALGORITHM must be %s or %s, got %q",
+            JWTAlgorithmHS256, JWTAlgorithmRS256, cfg.JWTAlgorithm)
+    }
+    return nil
+}
+
+// parseHMACKeys parses JWT_PREVIOUS_KEYS entries of the form kid:secret,
+// none of which may reuse currentID.
+func parseHMACKeys(v, currentID string) ([]auth.HMACKey, error) {
+    var keys []auth.HMACKey
+    seen := map[string]bool{currentID: true}
+    for _, entry := range strings.Split(v, ",") {
+        if entry = strings.TrimSpace(entry); entry == "" {
+            continue
+        }
+        id, secret, ok := strings.Cut(entry, ":")
+        if !ok || id == "" || secret == "" {
+            return nil, fmt.Errorf("entry must be kid:secret")
+        }
+        if seen[id] {
+            return nil, fmt.Errorf("key %q listed twice or reuses JWT_KEY_ID", id)
+        }
+        seen[id] = true
+        keys = append(keys, auth.HMACKey{ID: id, Secret: secret})
+    }
+    return keys, nil
+}
+
+// parseUsers parses USERS entries of the form username:role:hash.
+func parseUsers(v string) ([]UserSeed, error) {
+    var users []UserSeed
+    seen := make(map[string]bool)
+    for _, entry := range strings.Split(v, ",") {
+        if entry = strings.TrimSpace(entry); entry == "" {
+            continue
+        }
+        parts := strings.SplitN(entry, ":", 3)
+        if len(parts) != 3 || parts[0] == "" {
+            return nil, fmt.Errorf("entry %q must be username:role:hash", entry)
+        }
+        u := UserSeed{Username: parts[0], Role: parts[1], PasswordHash: parts[2]}
+        if u.Role != "user" && u.Role != "admin" {
+            return nil, fmt.Errorf("user %q: role must be user or admin, got %q", u.Username, u.Role)
+        }
+        if err := auth.ValidatePasswordHash(u.PasswordHash); err != nil {
+            return nil, fmt.Errorf("user %q: %w", u.Username, err)
+        }
+        if seen[u.Username] {
+            return nil, fmt.Errorf("user %q listed twice", u.Username)
+        }
+        seen[u.Username] = true
+        users = append(users, u)
+    }
+    return users, nil
+}
@@ -0,0 +1,86 @@
+// internal/config/dotenv.go
+
+package config
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "strings"
+)
+
+// defaultEnvFile is read when ENV_FILE is not set.
+const defaultEnvFile = ".env"
+
+// withEnvFile layers the KEY=VALUE pairs in the env file under getenv, so
+// real environment variables always win. The file is ENV_FILE, or .env in
+// the working directory; a missing file is not an error.
+func withEnvFile(getenv func(string) string) (func(string) string, error) {
+    path := getenv("ENV_FILE")
+    if path == "" {
+        path = defaultEnvFile
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        if errors.Is(err, fs.ErrNotExist) {
+            return getenv, nil
+        }
+        return nil, fmt.Errorf("opening env file: %w", err)
+    }
+    defer f.Close()
+
+    values, err := parseEnvFile(f)
+    if err != nil {
+        return nil, fmt.Errorf("parsing env file %s: %w", path, err)
+    }
+
+    return func(key string) string {
+        if v := getenv(key); v != "" {
+            return v
+        }
+        return values[key]
+    }, nil
+}
+
+// parseEnvFile reads KEY=VALUE lines. Blank lines and lines starting with #
+// are skipped, an optional "export " prefix is allowed, and values may be
+// wrapped in single or double quotes to keep surrounding spaces or a #.
+// Unquoted values end at an inline " #" comment.
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+    values := make(map[string]string)
+    scanner := bufio.NewScanner(r)
+    for lineNo := 1; scanner.Scan(); lineNo++ {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        line = strings.TrimPrefix(line, "export ")
+
+        key, value, ok := strings.Cut(line, "=")
+        key = strings.TrimSpace(key)
+        if !ok || key == "" || strings.ContainsAny(key, " \t") {
+            return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+        }
+
+        value = strings.TrimSpace(value)
+        switch {
+        case len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]:
+            value = value[1 : len(value)-1]
+        case len(value) > 0 && (value[0] == '"' || value[0] == '\''):
+            return nil, fmt.Errorf("line %d: unterminated quoted value", lineNo)
+        default:
+            if i := strings.Index(value, " #"); i >= 0 {
+                value = strings.TrimSpace(value[:i])
+            }
+        }
+        values[key] = value
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return values, nil
+}
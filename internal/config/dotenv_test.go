@@ -0,0 +1,80 @@
+// internal/config/dotenv_test.go
+
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+    input := `
+# comment
+JWT_SECRET=from-file
+export ENVIRONMENT=staging
+QUOTED="  spaced # not a comment "
+SINGLE='single'
+INLINE=value # trailing comment
+EMPTY=
+`
+    values, err := parseEnvFile(strings.NewReader(input))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    want := map[string]string{
+        "JWT_SECRET":  "from-file",
+        "ENVIRONMENT": "staging",
+        "QUOTED":      "  spaced # not a comment ",
+        "SINGLE":      "single",
+        "INLINE":      "value",
+        "EMPTY":       "",
+    }
+    for k, v := range want {
+        if values[k] != v {
+            t.Errorf("%s: expected %q, got %q", k, v, values[k])
+        }
+    }
+}
+
+func TestParseEnvFileErrors(t *testing.T) {
+    for _, input := range []string{"NO_EQUALS", "=value", "BAD KEY=x", `OPEN="unterminated`} {
+        if _, err := parseEnvFile(strings.NewReader(input)); err == nil {
+            t.Errorf("expected error for %q", input)
+        }
+    }
+}
+
+func TestLoadEnvFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "test.env")
+    if err := os.WriteFile(path, []byte("JWT_SECRET=file-secret-long-enough-for-production\nENVIRONMENT=staging\n"), 0o600); err != nil {
+        t.Fatal(err)
+    }
+
+    env := map[string]string{
+        "ENV_FILE":    path,
+        "ENVIRONMENT": "production",
+    }
+    cfg, err := Load(func(key string) string { return env[key] })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cfg.JWTSecret != "file-secret-long-enough-for-production" {
+        t.Errorf("expected JWTSecret from file, got %q", cfg.JWTSecret)
+    }
+    if cfg.Environment != "production" {
+        t.Errorf("expected real env to win, got %q", cfg.Environment)
+    }
+}
+
+func TestLoadMissingEnvFile(t *testing.T) {
+    env := map[string]string{
+        "ENV_FILE":   filepath.Join(t.TempDir(), "absent.env"),
+        "JWT_SECRET": "secret",
+    }
+    if _, err := Load(func(key string) string { return env[key] }); err != nil {
+        t.Fatalf("expected missing env file to be ignored, got %v", err)
+    }
+}
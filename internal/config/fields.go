@@ -0,0 +1,100 @@
+// internal/config/fields.go
+
+package config
+
+import (
+    "fmt"
+    "net/url"
+    "strings"
+)
+
+// LogFields returns the effective configuration as alternating keys and
+// values for a log entry, so a deployment can be checked against what was
+// intended. Settings are listed explicitly rather than reflected over, so
+// secrets never appear: the JWT secret and keys, previous keys, the
+// erasure key and password hashes are reported only as whether or how
+// many are set, the database by its scheme, and URLs without credentials.
+func (c *Config) LogFields() []interface{} {
+    previousKeys := make([]string, len(c.JWTPreviousKeys))
+    for i, k := range c.JWTPreviousKeys {
+        previousKeys[i] = k.ID
+    }
+    proxies := make([]string, len(c.TrustedProxies))
+    for i, p := range c.TrustedProxies {
+        proxies[i] = p.String()
+    }
+
+    return []interface{}{
+        "environment", c.Environment,
+        "host", c.Host,
+        "port", c.Port,
+        "socket", c.Socket,
+        "socket_mode", fmt.Sprintf("%#o", uint32(c.SocketMode)),
+        "listen_retries", c.ListenRetries,
+        "database_scheme", databaseScheme(c.DatabaseURL),
+        "jwt_algorithm", c.JWTAlgorithm,
+        "jwt_can_sign", c.JWTAlgorithm != JWTAlgorithmRS256 || c.JWTPrivateKey != nil,
+        "jwt_key_id", c.JWTKeyID,
+        "jwt_previous_keys", previousKeys,
+        "jwt_issuer", c.JWTIssuer,
+        "jwt_audience", c.JWTAudience,
+        "jwt_expiry", c.JWTExpiry.String(),
+        "jwt_leeway", c.JWTLeeway.String(),
+        "users", len(c.Users),
+        "login_max_failures", c.LoginMaxFailures,
+        "login_max_failures_per_ip", c.LoginMaxFailuresPerIP,
+        "login_failure_window", c.LoginFailureWindow.String(),
+        "login_lockout", c.LoginLockout.String(),
+        "trusted_proxies", proxies,
+        "request_timeout", c.RequestTimeout.String(),
+        "shutdown_timeout", c.ShutdownTimeout.String(),
+        "shutdown_drain_delay", c.ShutdownDrainDelay.String(),
+        "ready_timeout", c.ReadyTimeout.String(),
+        "memory_budget", c.MemoryBudget,
+        "store_slow_threshold", c.StoreSlowThreshold.String(),
+        "comment_retention", c.CommentRetention.String(),
+        "cleanup_interval", c.CleanupInterval.String(),
+        "comment_max_ttl", c.CommentMaxTTL.String(),
+        "comment_min_length", c.CommentMinLength,
+        "comment_max_length", c.CommentMaxLength,
+        "comment_max_attachments", c.CommentMaxAttachments,
+        "comment_max_attachment_length", c.CommentMaxAttachmentLength,
+        "comment_ids", c.CommentIDs,
+        "content_policy", c.ContentPolicy,
+        "error_format", c.ErrorFormat,
+        "delete_idempotency_window", c.DeleteIdempotencyWindow.String(),
+        "idempotency_key_ttl", c.IdempotencyKeyTTL.String(),
+        "stats_cache_ttl", c.StatsCacheTTL.String(),
+        "comment_list_max_age", c.CommentListMaxAge.String(),
+        "erasure_key_set", c.ErasureKey != "",
+        "similarity_action", c.SimilarityAction,
+        "fault_injection", c.FaultInjection,
+        "debug_endpoints", c.DebugEndpoints,
+        "log_http_bodies", c.LogHTTPBodies,
+        "log_buffer_size", c.LogBufferSize,
+        "otlp_endpoint", redactURL(c.OTLPEndpoint),
+        "experimental_features", c.ExperimentalFeatures,
+    }
+}
+
+// databaseScheme returns the scheme of a database URL, which says which
+// backend is in use without the credentials or location after it.
+func databaseScheme(raw string) string {
+    scheme, _, ok := strings.Cut(raw, "://")
+    if !ok {
+        return ""
+    }
+    return strings.ToLower(scheme)
+}
+
+// redactURL returns raw without its user info or query, either of which
+// may carry credentials.
+func redactURL(raw string) string {
+    u, err := url.Parse(raw)
+    if err != nil {
+        return ""
+    }
+    u.User = nil
+    u.RawQuery = ""
+    return u.String()
+}
@@ -0,0 +1,309 @@
+// internal/config/file.go
+
+package config
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// fileKeys lists the keys a config file may set. Each is the lower-case
+// form of the environment variable it stands in for, so database_url in the
+// file supplies DATABASE_URL. New settings must be added here to be
+// settable from a file.
+var fileKeys = []string{
+    "database_url",
+    "jwt_secret",
+    "environment",
+    "host",
+    "port",
+    "socket",
+    "socket_mode",
+    "listen_retries",
+    "jwt_issuer",
+    "jwt_audience",
+    "jwt_expiry",
+    "jwt_leeway",
+    "jwt_algorithm",
+    "jwt_public_key_file",
+    "jwt_private_key_file",
+    "jwt_key_id",
+    "jwt_previous_keys",
+    "users",
+    "login_max_failures",
+    "login_max_failures_per_ip",
+    "login_failure_window",
+    "login_lockout",
+    "trusted_proxies",
+    "request_timeout",
+    "shutdown_timeout",
+    "shutdown_drain_delay",
+    "ready_timeout",
+    "ready_degraded_latency",
+    "ready_fail_after",
+    "security_frame_options",
+    "security_referrer_policy",
+    "security_hsts",
+    "memory_budget",
+    "store_slow_threshold",
+    "comment_retention",
+    "cleanup_interval",
+    "comment_min_length",
+    "comment_max_length",
+    "comment_max_author_length",
+    "comment_max_attachments",
+    "comment_max_attachment_length",
+    "comment_ids",
+    "content_policy",
+    "error_format",
+    "comment_max_ttl",
+    "comment_ttl_admin_threshold",
+    "delete_idempotency_window",
+    "idempotency_key_ttl",
+    "stats_cache_ttl",
+    "comment_list_max_age",
+    "erasure_key",
+    "similarity_action",
+    "similarity_threshold",
+    "similarity_window",
+    "experimental_features",
+    "fault_injection",
+    "enable_debug_endpoints",
+    "log_http_bodies",
+    "log_buffer_size",
+    "log_overflow",
+    "log_stack_traces",
+    "otel_exporter_otlp_endpoint",
+}
+
+// LoadFile is Load with the settings in the YAML or JSON file at path
+// layered underneath the environment. Settings resolve in this order, each
+// overriding the one before:
+//
+//  1. defaults
+//  2. the config file
+//  3. the .env file (see withEnvFile)
+//  4. environment variables
+//  5. command-line flags, which server.Run applies on top
+//
+// Keys in the file that aren't settings are ignored and listed in the
+// config's UnknownFileKeys. An empty path is the same as Load.
+func LoadFile(path string, getenv func(string) string) (*Config, error) {
+    getenv, err := withEnvFile(getenv)
+    if err != nil {
+        return nil, err
+    }
+    if path == "" {
+        return load(getenv)
+    }
+
+    values, unknown, err := readConfigFile(path)
+    if err != nil {
+        return nil, err
+    }
+    cfg, err := load(func(key string) string {
+        if v := getenv(key); v != "" {
+            return v
+        }
+        return values[key]
+    })
+    if err != nil {
+        return nil, err
+    }
+    cfg.UnknownFileKeys = unknown
+    return cfg, nil
+}
+
+// readConfigFile parses the file at path, choosing the format by extension,
+// and returns its settings keyed by environment variable name along with
+// the sorted keys that aren't settings.
+func readConfigFile(path string) (values map[string]string, unknown []string, err error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, nil, fmt.Errorf("reading config file: %w", err)
+    }
+
+    var raw map[string]string
+    switch ext := strings.ToLower(filepath.Ext(path)); ext {
+    case ".json":
+        raw, err = parseJSONConfig(data)
+    case ".yaml", ".yml":
+        raw, err = parseYAMLConfig(bytes.NewReader(data))
+    default:
+        return nil, nil, fmt.Errorf("config file %s: unsupported extension %q (want .json, .yaml or .yml)", path, ext)
+    }
+    if err != nil {
+        return nil, nil, fmt.Errorf("parsing config file %s: %w", path, err)
+    }
+
+    known := make(map[string]bool, len(fileKeys))
+    for _, k := range fileKeys {
+        known[k] = true
+    }
+    values = make(map[string]string, len(raw))
+    for k, v := range raw {
+        if !known[k] {
+            unknown = append(unknown, k)
+            continue
+        }
+        values[strings.ToUpper(k)] = v
+    }
+    sort.Strings(unknown)
+    return values, unknown, nil
+}
+
+// parseJSONConfig reads a flat JSON object. Strings, numbers and booleans
+// are kept as written; arrays of them are joined with commas.
+func parseJSONConfig(data []byte) (map[string]string, error) {
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    var fields map[string]any
+    if err := dec.Decode(&fields); err != nil {
+        return nil, err
+    }
+
+    values := make(map[string]string, len(fields))
+    for k, v := range fields {
+        var s string
+        var err error
+        if list, ok := v.([]any); ok {
+            items := make([]string, len(list))
+            for i, item := range list {
+                if items[i], err = jsonScalar(item); err != nil {
+                    break
+                }
+            }
+            s = strings.Join(items, ",")
+        } else {
+            s, err = jsonScalar(v)
+        }
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", k, err)
+        }
+        values[k] = s
+    }
+    return values, nil
+}
+
+func jsonScalar(v any) (string, error) {
+    switch v := v.(type) {
+    case string:
+        return v, nil
+    case json.Number:
+        return v.String(), nil
+    case bool:
+        return fmt.Sprint(v), nil
+    case nil:
+        return "", nil
+    default:
+        return "", fmt.Errorf("expected a string, number or boolean")
+    }
+}
+
+// parseYAMLConfig reads the flat subset of YAML a config file needs:
+// "key: value" lines with optional quotes and # comments, and lists written
+// either inline as [a, b] or as indented "- item" lines under a bare key.
+// Lists are joined with commas.
+func parseYAMLConfig(r io.Reader) (map[string]string, error) {
+    values := make(map[string]string)
+    var listKey string
+    var list []string
+    flush := func() {
+        if listKey != "" {
+            values[listKey] = strings.Join(list, ",")
+            listKey, list = "", nil
+        }
+    }
+
+    scanner := bufio.NewScanner(r)
+    for lineNo := 1; scanner.Scan(); lineNo++ {
+        raw := scanner.Text()
+        line := strings.TrimSpace(raw)
+        if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+            continue
+        }
+
+        if strings.HasPrefix(line, "- ") || line == "-" {
+            if listKey == "" || (raw[0] != ' ' && raw[0] != '\t') {
+                return nil, fmt.Errorf("line %d: list item outside a list", lineNo)
+            }
+            item, err := yamlScalar(strings.TrimSpace(strings.TrimPrefix(line, "-")))
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %w", lineNo, err)
+            }
+            list = append(list, item)
+            continue
+        }
+        flush()
+
+        if raw[0] == ' ' || raw[0] == '\t' {
+            return nil, fmt.Errorf("line %d: nested mappings are not supported", lineNo)
+        }
+        key, value, ok := strings.Cut(line, ":")
+        key = strings.TrimSpace(key)
+        if !ok || key == "" || strings.ContainsAny(key, " \t") {
+            return nil, fmt.Errorf("line %d: expected key: value", lineNo)
+        }
+        if _, dup := values[key]; dup {
+            return nil, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+        }
+
+        value = strings.TrimSpace(value)
+        switch {
+        case value == "" || strings.HasPrefix(value, "#"):
+            listKey = key
+            values[key] = ""
+        case strings.HasPrefix(value, "["):
+            end := strings.LastIndex(value, "]")
+            if end < 0 {
+                return nil, fmt.Errorf("line %d: unterminated list", lineNo)
+            }
+            var items []string
+            for _, item := range strings.Split(value[1:end], ",") {
+                if item = strings.TrimSpace(item); item == "" {
+                    continue
+                }
+                s, err := yamlScalar(item)
+                if err != nil {
+                    return nil, fmt.Errorf("line %d: %w", lineNo, err)
+                }
+                items = append(items, s)
+            }
+            values[key] = strings.Join(items, ",")
+        default:
+            s, err := yamlScalar(value)
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %w", lineNo, err)
+            }
+            values[key] = s
+        }
+    }
+    flush()
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return values, nil
+}
+
+// yamlScalar unquotes a YAML scalar, dropping a trailing # comment from
+// unquoted values.
+func yamlScalar(value string) (string, error) {
+    if value != "" && (value[0] == '"' || value[0] == '\'') {
+        end := strings.LastIndexByte(value, value[0])
+        if end == 0 {
+            return "", fmt.Errorf("unterminated quoted value")
+        }
+        return value[1:end], nil
+    }
+    if i := strings.Index(value, " #"); i >= 0 {
+        value = strings.TrimSpace(value[:i])
+    }
+    return value, nil
+}
@@ -0,0 +1,136 @@
+// internal/config/file_test.go
+
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestLoadFile(t *testing.T) {
+    files := map[string]string{
+        "config.json": `{
+    "jwt_secret": "file-secret-long-enough-for-production",
+    "environment": "staging",
+    "memory_budget": 1048576,
+    "comment_retention": "72h",
+    "experimental_features": ["threads-v2", "graphql"]
+}`,
+        "config.yaml": `# service settings
+jwt_secret: "file-secret-long-enough-for-production"
+environment: staging # inline comment
+memory_budget: 1048576
+comment_retention: 72h
+experimental_features:
+  - threads-v2
+  - graphql
+`,
+        "config.yml": `jwt_secret: 'file-secret-long-enough-for-production'
+environment: staging
+memory_budget: 1048576
+comment_retention: 72h
+experimental_features: [threads-v2, graphql]
+`,
+    }
+
+    for name, content := range files {
+        t.Run(name, func(t *testing.T) {
+            path := writeConfigFile(t, name, content)
+            env := map[string]string{
+                "ENV_FILE":    filepath.Join(t.TempDir(), "absent.env"),
+                "ENVIRONMENT": "production",
+            }
+            cfg, err := LoadFile(path, func(key string) string { return env[key] })
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if cfg.JWTSecret != "file-secret-long-enough-for-production" {
+                t.Errorf("expected JWTSecret from file, got %q", cfg.JWTSecret)
+            }
+            if cfg.Environment != "production" {
+                t.Errorf("expected env to override file, got %q", cfg.Environment)
+            }
+            if cfg.MemoryBudget != 1048576 {
+                t.Errorf("expected MemoryBudget 1048576, got %d", cfg.MemoryBudget)
+            }
+            if cfg.CommentRetention != 72*time.Hour {
+                t.Errorf("expected CommentRetention 72h, got %s", cfg.CommentRetention)
+            }
+            if got := strings.Join(cfg.ExperimentalFeatures, "|"); got != "threads-v2|graphql" {
+                t.Errorf("expected [threads-v2 graphql], got %v", cfg.ExperimentalFeatures)
+            }
+            if cfg.JWTIssuer != "web-service" {
+                t.Errorf("expected default JWTIssuer, got %q", cfg.JWTIssuer)
+            }
+        })
+    }
+}
+
+func TestLoadFilePrecedence(t *testing.T) {
+    path := writeConfigFile(t, "config.yaml", "jwt_secret: from-config\nenvironment: development\njwt_issuer: from-config\n")
+    envFile := writeConfigFile(t, "test.env", "ENVIRONMENT=staging\nJWT_ISSUER=from-dotenv\n")
+
+    env := map[string]string{
+        "ENV_FILE":   envFile,
+        "JWT_ISSUER": "from-env",
+    }
+    cfg, err := LoadFile(path, func(key string) string { return env[key] })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cfg.JWTSecret != "from-config" || cfg.Environment != "staging" || cfg.JWTIssuer != "from-env" {
+        t.Errorf("unexpected precedence: secret=%q environment=%q issuer=%q", cfg.JWTSecret, cfg.Environment, cfg.JWTIssuer)
+    }
+}
+
+func TestLoadFileUnknownKeys(t *testing.T) {
+    path := writeConfigFile(t, "config.yaml", "jwt_secret: s\njwt_secert: typo\ndatabse_url: memory://\n")
+    env := map[string]string{"ENV_FILE": filepath.Join(t.TempDir(), "absent.env")}
+    cfg, err := LoadFile(path, func(key string) string { return env[key] })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(cfg.UnknownFileKeys, ","); got != "databse_url,jwt_secert" {
+        t.Errorf("expected unknown keys databse_url,jwt_secert, got %v", cfg.UnknownFileKeys)
+    }
+    if cfg.JWTSecret != "s" {
+        t.Errorf("expected known keys still applied, got JWTSecret %q", cfg.JWTSecret)
+    }
+}
+
+func TestLoadFileErrors(t *testing.T) {
+    tests := []struct {
+        name    string
+        file    string
+        content string
+        wantErr string
+    }{
+        {name: "nested json", file: "c.json", content: `{"jwt_secret":{"value":"s"}}`, wantErr: "jwt_secret"},
+        {name: "nested yaml", file: "c.yaml", content: "jwt:\n  secret: s\n", wantErr: "nested mappings"},
+        {name: "duplicate yaml key", file: "c.yaml", content: "jwt_secret: a\njwt_secret: b\n", wantErr: "duplicate key"},
+        {name: "unsupported extension", file: "c.toml", content: "", wantErr: "unsupported extension"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := writeConfigFile(t, tt.file, tt.content)
+            _, err := LoadFile(path, func(string) string { return "" })
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+            }
+        })
+    }
+}
@@ -0,0 +1,64 @@
+// internal/config/keys.go
+
+package config
+
+import (
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/pem"
+    "fmt"
+    "os"
+)
+
+// readPEM reads path and returns its first PEM block.
+func readPEM(name, path string) (*pem.Block, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", name, err)
+    }
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return nil, fmt.Errorf("%s: %s contains no PEM data", name, path)
+    }
+    return block, nil
+}
+
+// loadRSAPublicKey reads a PKIX or PKCS#1 RSA public key from path.
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+    block, err := readPEM("JWT_PUBLIC_KEY_FILE", path)
+    if err != nil {
+        return nil, err
+    }
+    if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+        return key, nil
+    }
+    parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+    if err != nil {
+        return nil, fmt.Errorf("JWT_PUBLIC_KEY_FILE: parsing %s: %w", path, err)
+    }
+    key, ok := parsed.(*rsa.PublicKey)
+    if !ok {
+        return nil, fmt.Errorf("JWT_PUBLIC_KEY_FILE: %s is not an RSA key", path)
+    }
+    return key, nil
+}
+
+// loadRSAPrivateKey reads a PKCS#1 or PKCS#8 RSA private key from path.
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+    block, err := readPEM("JWT_PRIVATE_KEY_FILE", path)
+    if err != nil {
+        return nil, err
+    }
+    if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+        return key, nil
+    }
+    parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+    if err != nil {
+        return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE: parsing %s: %w", path, err)
+    }
+    key, ok := parsed.(*rsa.PrivateKey)
+    if !ok {
+        return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE: %s is not an RSA key", path)
+    }
+    return key, nil
+}
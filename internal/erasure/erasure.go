@@ -0,0 +1,217 @@
+// internal/erasure/erasure.go
+
+// Package erasure erases a user's personal data from every store that
+// holds some, for right-to-erasure requests. Each store reports what it
+// removed or anonymized, and a completed erasure produces a signed receipt
+// that refers to the user only by an opaque hash.
+package erasure
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "web-service/internal/util"
+)
+
+// Report counts what erasing a user did in one store. Records are removed
+// where possible and anonymized where something else still refers to them.
+type Report struct {
+    Removed    int
+    Anonymized int
+}
+
+// Eraser is implemented by stores holding personal data. EraseUser must be
+// safe to repeat: erasing a user with nothing left reports zero counts.
+type Eraser interface {
+    EraseUser(ctx context.Context, userID string) (Report, error)
+}
+
+// StoreReport is one store's part of a receipt.
+type StoreReport struct {
+    Store string
+    Report
+}
+
+// Receipt records a completed erasure. Subject is an opaque hash of the
+// user ID, so the receipt can be kept without keeping the user's identity.
+type Receipt struct {
+    ID          string
+    Subject     string
+    Stores      []StoreReport
+    StartedAt   time.Time
+    CompletedAt time.Time
+    Signature   string
+}
+
+// Removed is the total removed across stores.
+func (r Receipt) Removed() int {
+    n := 0
+    for _, s := range r.Stores {
+        n += s.Removed
+    }
+    return n
+}
+
+// Anonymized is the total anonymized across stores.
+func (r Receipt) Anonymized() int {
+    n := 0
+    for _, s := range r.Stores {
+        n += s.Anonymized
+    }
+    return n
+}
+
+// IncompleteError reports stores that failed during an erasure. The stores
+// that succeeded are remembered, so running the erasure again resumes
+// with the failed ones.
+type IncompleteError struct {
+    Failed map[string]error
+}
+
+func (e *IncompleteError) Error() string {
+    names := make([]string, 0, len(e.Failed))
+    for name := range e.Failed {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    parts := make([]string, len(names))
+    for i, name := range names {
+        parts[i] = fmt.Sprintf("%s: %v", name, e.Failed[name])
+    }
+    return "erasure incomplete: " + strings.Join(parts, "; ")
+}
+
+type store struct {
+    name   string
+    eraser Eraser
+}
+
+// job is an erasure in progress, holding the reports of the stores that
+// have finished.
+type job struct {
+    startedAt time.Time
+    done      map[string]Report
+}
+
+// Service erases users across its registered stores.
+type Service struct {
+    key    []byte
+    stores []store
+    now    func() time.Time
+
+    // run serializes erasures so a resumed job is never run twice at once.
+    run sync.Mutex
+
+    mu       sync.Mutex
+    jobs     map[string]*job // by subject
+    receipts map[string]Receipt
+}
+
+// NewService returns a Service that signs receipts and hashes subjects with
+// key.
+func NewService(key []byte) *Service {
+    return &Service{
+        key:      key,
+        now:      time.Now,
+        jobs:     make(map[string]*job),
+        receipts: make(map[string]Receipt),
+    }
+}
+
+// Register adds a store to be erased under name. It must not be called once
+// the service is in use.
+func (s *Service) Register(name string, e Eraser) {
+    s.stores = append(s.stores, store{name: name, eraser: e})
+}
+
+// Subject returns the opaque hash receipts use in place of userID.
+func (s *Service) Subject(userID string) string {
+    return s.mac("subject", userID)
+}
+
+// Erase erases userID from every registered store and returns the signed
+// receipt. If any store fails it returns an *IncompleteError; calling Erase
+// again resumes with the stores that haven't finished.
+func (s *Service) Erase(ctx context.Context, userID string) (Receipt, error) {
+    s.run.Lock()
+    defer s.run.Unlock()
+
+    subject := s.Subject(userID)
+    s.mu.Lock()
+    j, ok := s.jobs[subject]
+    if !ok {
+        j = &job{startedAt: s.now(), done: make(map[string]Report)}
+        s.jobs[subject] = j
+    }
+    s.mu.Unlock()
+
+    failed := make(map[string]error)
+    for _, st := range s.stores {
+        if _, ok := j.done[st.name]; ok {
+            continue
+        }
+        report, err := st.eraser.EraseUser(ctx, userID)
+        if err != nil {
+            failed[st.name] = err
+            continue
+        }
+        j.done[st.name] = report
+    }
+    if len(failed) > 0 {
+        return Receipt{}, &IncompleteError{Failed: failed}
+    }
+
+    receipt := Receipt{
+        ID:          util.GenerateID(),
+        Subject:     subject,
+        StartedAt:   j.startedAt,
+        CompletedAt: s.now(),
+    }
+    for _, st := range s.stores {
+        receipt.Stores = append(receipt.Stores, StoreReport{Store: st.name, Report: j.done[st.name]})
+    }
+    receipt.Signature = s.sign(receipt)
+
+    s.mu.Lock()
+    delete(s.jobs, subject)
+    s.receipts[receipt.ID] = receipt
+    s.mu.Unlock()
+    return receipt, nil
+}
+
+// Receipt returns the receipt with id.
+func (s *Service) Receipt(id string) (Receipt, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    r, ok := s.receipts[id]
+    return r, ok
+}
+
+// Verify reports whether r's signature is valid for its contents.
+func (s *Service) Verify(r Receipt) bool {
+    return hmac.Equal([]byte(r.Signature), []byte(s.sign(r)))
+}
+
+// sign returns the signature over r's contents, excluding any existing
+// signature.
+func (s *Service) sign(r Receipt) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "%s\n%s\n%s\n%s\n", r.ID, r.Subject,
+        r.StartedAt.UTC().Format(time.RFC3339Nano), r.CompletedAt.UTC().Format(time.RFC3339Nano))
+    for _, st := range r.Stores {
+        fmt.Fprintf(&b, "%s:%d:%d\n", st.Store, st.Removed, st.Anonymized)
+    }
+    return s.mac("receipt", b.String())
+}
+
+func (s *Service) mac(purpose, data string) string {
+    h := hmac.New(sha256.New, s.key)
+    h.Write([]byte(purpose + "\x00" + data))
+    return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
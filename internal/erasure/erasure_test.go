@@ -0,0 +1,121 @@
+// internal/erasure/erasure_test.go
+
+package erasure
+
+import (
+    "context"
+    "errors"
+    "testing"
+)
+
+// fakeStore holds per-user record counts and can be made to fail.
+type fakeStore struct {
+    records   map[string]int
+    anonymize bool
+    failures  int
+    calls     int
+}
+
+func (f *fakeStore) EraseUser(ctx context.Context, userID string) (Report, error) {
+    f.calls++
+    if f.failures > 0 {
+        f.failures--
+        return Report{}, errors.New("store unavailable")
+    }
+    n := f.records[userID]
+    delete(f.records, userID)
+    if f.anonymize {
+        return Report{Anonymized: n}, nil
+    }
+    return Report{Removed: n}, nil
+}
+
+func TestErase(t *testing.T) {
+    comments := &fakeStore{records: map[string]int{"u1": 3, "u2": 1}}
+    audit := &fakeStore{records: map[string]int{"u1": 2}, anonymize: true}
+    svc := NewService([]byte("key"))
+    svc.Register("comments", comments)
+    svc.Register("audit", audit)
+
+    receipt, err := svc.Erase(context.Background(), "u1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if receipt.Removed() != 3 || receipt.Anonymized() != 2 {
+        t.Errorf("expected 3 removed and 2 anonymized, got %d and %d", receipt.Removed(), receipt.Anonymized())
+    }
+    if len(receipt.Stores) != 2 || receipt.Stores[0].Store != "comments" || receipt.Stores[1].Store != "audit" {
+        t.Errorf("expected reports for comments then audit, got %+v", receipt.Stores)
+    }
+    if receipt.Subject == "u1" || receipt.Subject != svc.Subject("u1") {
+        t.Errorf("expected opaque subject, got %q", receipt.Subject)
+    }
+    if comments.records["u2"] != 1 {
+        t.Error("erasing u1 touched u2")
+    }
+
+    if got, ok := svc.Receipt(receipt.ID); !ok || got.Signature != receipt.Signature {
+        t.Error("receipt not retrievable by ID")
+    }
+    if !svc.Verify(receipt) {
+        t.Error("receipt signature does not verify")
+    }
+
+    // Re-running finds nothing left, and still succeeds
+    again, err := svc.Erase(context.Background(), "u1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if again.Removed() != 0 || again.Anonymized() != 0 {
+        t.Errorf("expected an empty re-run, got %d removed and %d anonymized", again.Removed(), again.Anonymized())
+    }
+    if again.ID == receipt.ID {
+        t.Error("expected a new receipt for the re-run")
+    }
+}
+
+func TestEraseResumesAfterFailure(t *testing.T) {
+    comments := &fakeStore{records: map[string]int{"u1": 3}}
+    reactions := &fakeStore{records: map[string]int{"u1": 4}, failures: 1}
+    svc := NewService([]byte("key"))
+    svc.Register("comments", comments)
+    svc.Register("reactions", reactions)
+
+    _, err := svc.Erase(context.Background(), "u1")
+    var incomplete *IncompleteError
+    if !errors.As(err, &incomplete) {
+        t.Fatalf("expected IncompleteError, got %v", err)
+    }
+    if _, ok := incomplete.Failed["reactions"]; !ok || len(incomplete.Failed) != 1 {
+        t.Errorf("expected only reactions to fail, got %v", incomplete.Failed)
+    }
+
+    receipt, err := svc.Erase(context.Background(), "u1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if comments.calls != 1 {
+        t.Errorf("expected comments erased once, got %d calls", comments.calls)
+    }
+    if receipt.Removed() != 7 {
+        t.Errorf("expected the resumed receipt to count 7 removed, got %d", receipt.Removed())
+    }
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+    svc := NewService([]byte("key"))
+    svc.Register("comments", &fakeStore{records: map[string]int{"u1": 1}})
+
+    receipt, err := svc.Erase(context.Background(), "u1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    tampered := receipt
+    tampered.Stores = []StoreReport{{Store: "comments", Report: Report{Removed: 5}}}
+    if svc.Verify(tampered) {
+        t.Error("tampered receipt verified")
+    }
+    if NewService([]byte("other")).Verify(receipt) {
+        t.Error("receipt verified under another key")
+    }
+}
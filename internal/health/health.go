@@ -0,0 +1,212 @@
+// internal/health/health.go
+
+// Package health runs dependency checks for readiness probes against
+// latency budgets. A check that answers, but too slowly, is degraded; one
+// that stays over budget for several probes in a row, or fails outright,
+// is down.
+package health
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "time"
+)
+
+// Status is the state of a check or of a whole report.
+type Status string
+
+const (
+    StatusOK       Status = "ok"
+    StatusDegraded Status = "degraded"
+    StatusDown     Status = "down"
+)
+
+// HistorySize is how many recent results each check keeps.
+const HistorySize = 10
+
+// Checker checks that a dependency can serve requests.
+type Checker interface {
+    Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function, such as a store's Ping, to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// degradedError marks a check failure that leaves the service usable.
+type degradedError struct {
+    err error
+}
+
+func (e *degradedError) Error() string { return e.err.Error() }
+
+func (e *degradedError) Unwrap() error { return e.err }
+
+// Degraded wraps err so a check returning it reports degraded rather than
+// down, for a dependency the service can run without.
+func Degraded(err error) error {
+    return &degradedError{err: err}
+}
+
+// Budget bounds how long a check may take.
+type Budget struct {
+    // Timeout is the most a probe may take. Probes are cancelled after
+    // it, and any probe slower than it is over budget. Zero means no
+    // timeout.
+    Timeout time.Duration
+    // Degraded is the latency above which a probe within Timeout is
+    // degraded. Zero disables the degraded state.
+    Degraded time.Duration
+    // FailAfter is how many consecutive over-budget probes mark the
+    // check down; until then it is degraded. Values below 1 mean the
+    // first over-budget probe.
+    FailAfter int
+}
+
+// Result is the outcome of one probe: the check's status after it, how
+// long it took, and the error if it failed.
+type Result struct {
+    Status  Status
+    Latency time.Duration
+    Time    time.Time
+    Err     error
+}
+
+// CheckReport is a check's current status with its recent history,
+// oldest first.
+type CheckReport struct {
+    Name    string
+    Status  Status
+    Latency time.Duration
+    Err     error
+    History []Result
+}
+
+// Report is the outcome of running every check. Its Status is the worst of
+// theirs.
+type Report struct {
+    Status Status
+    Checks []CheckReport
+}
+
+type check struct {
+    name    string
+    checker Checker
+    budget  Budget
+
+    mu         sync.Mutex
+    history    []Result
+    overBudget int
+}
+
+// Monitor runs a set of checks and tracks their history across probes.
+type Monitor struct {
+    checks []*check
+    now    func() time.Time
+}
+
+// Option configures a Monitor.
+type Option func(*Monitor)
+
+// WithClock sets the clock used to measure latency. It is meant for tests.
+func WithClock(now func() time.Time) Option {
+    return func(m *Monitor) {
+        m.now = now
+    }
+}
+
+// NewMonitor returns a Monitor with no checks.
+func NewMonitor(opts ...Option) *Monitor {
+    m := &Monitor{now: time.Now}
+    for _, opt := range opts {
+        opt(m)
+    }
+    return m
+}
+
+// Add registers a check. It must not be called once the monitor is in use.
+func (m *Monitor) Add(name string, checker Checker, budget Budget) {
+    m.checks = append(m.checks, &check{name: name, checker: checker, budget: budget})
+}
+
+// Run probes every check concurrently and reports their status.
+func (m *Monitor) Run(ctx context.Context) Report {
+    reports := make([]CheckReport, len(m.checks))
+    var wg sync.WaitGroup
+    for i, c := range m.checks {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            reports[i] = m.probe(ctx, c)
+        }()
+    }
+    wg.Wait()
+
+    report := Report{Status: StatusOK, Checks: reports}
+    for _, r := range reports {
+        report.Status = worse(report.Status, r.Status)
+    }
+    return report
+}
+
+func (m *Monitor) probe(ctx context.Context, c *check) CheckReport {
+    if c.budget.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, c.budget.Timeout)
+        defer cancel()
+    }
+    start := m.now()
+    err := c.checker.Check(ctx)
+    result := Result{Latency: m.now().Sub(start), Time: start, Err: err}
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    timedOut := errors.Is(err, context.DeadlineExceeded) ||
+        (c.budget.Timeout > 0 && result.Latency > c.budget.Timeout)
+    var degraded *degradedError
+    switch {
+    case errors.As(err, &degraded):
+        c.overBudget = 0
+        result.Status = StatusDegraded
+    case err != nil && !timedOut:
+        // A dependency that answers with an error is down now; damping
+        // only smooths over slowness.
+        c.overBudget = 0
+        result.Status = StatusDown
+    case timedOut:
+        c.overBudget++
+        result.Status = StatusDegraded
+        if c.overBudget >= c.budget.FailAfter {
+            result.Status = StatusDown
+        }
+    case c.budget.Degraded > 0 && result.Latency > c.budget.Degraded:
+        c.overBudget = 0
+        result.Status = StatusDegraded
+    default:
+        c.overBudget = 0
+        result.Status = StatusOK
+    }
+
+    c.history = append(c.history, result)
+    if len(c.history) > HistorySize {
+        c.history = c.history[len(c.history)-HistorySize:]
+    }
+    return CheckReport{
+        Name:    c.name,
+        Status:  result.Status,
+        Latency: result.Latency,
+        Err:     err,
+        History: append([]Result(nil), c.history...),
+    }
+}
+
+func worse(a, b Status) Status {
+    rank := map[Status]int{StatusOK: 0, StatusDegraded: 1, StatusDown: 2}
+    if rank[b] > rank[a] {
+        return b
+    }
+    return a
+}
@@ -0,0 +1,133 @@
+// internal/health/health_test.go
+
+package health
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+// fakeChecker takes whatever latency and error it is set to, advancing
+// the monitor's clock rather than sleeping.
+type fakeChecker struct {
+    now     *time.Time
+    latency time.Duration
+    err     error
+}
+
+func (f *fakeChecker) Check(ctx context.Context) error {
+    *f.now = f.now.Add(f.latency)
+    return f.err
+}
+
+func newFakeMonitor(budget Budget) (*Monitor, *fakeChecker) {
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    fake := &fakeChecker{now: &now}
+    m := NewMonitor(WithClock(func() time.Time { return now }))
+    m.Add("storage", fake, budget)
+    return m, fake
+}
+
+func TestMonitorTransitions(t *testing.T) {
+    m, fake := newFakeMonitor(Budget{Timeout: time.Second, Degraded: 100 * time.Millisecond, FailAfter: 3})
+
+    steps := []struct {
+        name    string
+        latency time.Duration
+        want    Status
+    }{
+        {"fast", 10 * time.Millisecond, StatusOK},
+        {"slow", 500 * time.Millisecond, StatusDegraded},
+        {"over budget once", 4 * time.Second, StatusDegraded},
+        {"over budget twice", 4 * time.Second, StatusDegraded},
+        {"over budget three times", 4 * time.Second, StatusDown},
+        {"still over budget", 4 * time.Second, StatusDown},
+        {"recovered", 10 * time.Millisecond, StatusOK},
+        {"over budget after recovery", 4 * time.Second, StatusDegraded},
+    }
+    for _, step := range steps {
+        fake.latency = step.latency
+        report := m.Run(context.Background())
+        if report.Status != step.want {
+            t.Fatalf("%s: expected %s, got %s", step.name, step.want, report.Status)
+        }
+        if got := report.Checks[0].Latency; got != step.latency {
+            t.Errorf("%s: expected latency %v, got %v", step.name, step.latency, got)
+        }
+    }
+}
+
+func TestMonitorErrorIsDownImmediately(t *testing.T) {
+    m, fake := newFakeMonitor(Budget{Timeout: time.Second, FailAfter: 3})
+
+    fake.err = errors.New("connection refused")
+    if report := m.Run(context.Background()); report.Status != StatusDown {
+        t.Errorf("expected %s, got %s", StatusDown, report.Status)
+    }
+    fake.err = nil
+    if report := m.Run(context.Background()); report.Status != StatusOK {
+        t.Errorf("expected %s after recovery, got %s", StatusOK, report.Status)
+    }
+}
+
+func TestMonitorDegradedError(t *testing.T) {
+    m, fake := newFakeMonitor(Budget{Timeout: time.Second})
+    fake.err = Degraded(errors.New("issuer unreachable"))
+
+    report := m.Run(context.Background())
+    if report.Status != StatusDegraded {
+        t.Fatalf("expected %s, got %s", StatusDegraded, report.Status)
+    }
+    if report.Checks[0].Err == nil || report.Checks[0].Err.Error() != "issuer unreachable" {
+        t.Errorf("expected the wrapped error, got %v", report.Checks[0].Err)
+    }
+}
+
+func TestMonitorTimeout(t *testing.T) {
+    m := NewMonitor()
+    m.Add("hung", CheckerFunc(func(ctx context.Context) error {
+        <-ctx.Done()
+        return ctx.Err()
+    }), Budget{Timeout: 10 * time.Millisecond})
+
+    report := m.Run(context.Background())
+    if report.Status != StatusDown {
+        t.Errorf("expected %s, got %s", StatusDown, report.Status)
+    }
+}
+
+func TestMonitorHistory(t *testing.T) {
+    m, fake := newFakeMonitor(Budget{Degraded: 100 * time.Millisecond})
+
+    for i := 0; i < HistorySize+5; i++ {
+        fake.latency = time.Duration(i) * 10 * time.Millisecond
+        m.Run(context.Background())
+    }
+
+    history := m.Run(context.Background()).Checks[0].History
+    if len(history) != HistorySize {
+        t.Fatalf("expected %d results, got %d", HistorySize, len(history))
+    }
+    if first := history[0].Latency; first != 60*time.Millisecond {
+        t.Errorf("expected oldest kept latency 60ms, got %v", first)
+    }
+    if last := history[HistorySize-1]; last.Status != StatusDegraded {
+        t.Errorf("expected newest result degraded, got %s", last.Status)
+    }
+}
+
+func TestMonitorWorstStatus(t *testing.T) {
+    m := NewMonitor()
+    m.Add("ok", CheckerFunc(func(context.Context) error { return nil }), Budget{})
+    m.Add("down", CheckerFunc(func(context.Context) error { return errors.New("boom") }), Budget{})
+
+    report := m.Run(context.Background())
+    if report.Status != StatusDown {
+        t.Errorf("expected %s, got %s", StatusDown, report.Status)
+    }
+    if len(report.Checks) != 2 || report.Checks[0].Name != "ok" || report.Checks[0].Status != StatusOK {
+        t.Errorf("expected checks in registration order, got %+v", report.Checks)
+    }
+}
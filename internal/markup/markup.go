@@ -0,0 +1,229 @@
+// internal/markup/markup.go
+
+// Package markup makes user-written comment content safe to show in a web
+// page. Strip reduces content to plain text, Sanitize keeps only an
+// allowlist of harmless formatting tags, and Render and RenderText turn a
+// small subset of Markdown into sanitized HTML.
+package markup
+
+import (
+    xhtml "golang.org/x/net/html"
+    "golang.org/x/net/html/atom"
+    "html"
+    "net/url"
+    "regexp"
+    "strings"
+)
+
+// allowedTags are the tags Sanitize keeps, without attributes except for a
+// link's href.
+var allowedTags = map[atom.Atom]bool{
+    atom.A:          true,
+    atom.B:          true,
+    atom.Blockquote: true,
+    atom.Br:         true,
+    atom.Code:       true,
+    atom.Em:         true,
+    atom.I:          true,
+    atom.Li:         true,
+    atom.Ol:         true,
+    atom.P:          true,
+    atom.Pre:        true,
+    atom.Strong:     true,
+    atom.Ul:         true,
+}
+
+// droppedTags are removed along with their content rather than leaving
+// their text behind.
+var droppedTags = map[atom.Atom]bool{
+    atom.Iframe:   true,
+    atom.Noscript: true,
+    atom.Object:   true,
+    atom.Script:   true,
+    atom.Style:    true,
+    atom.Template: true,
+    atom.Textarea: true,
+    atom.Title:    true,
+}
+
+// allowedSchemes are the URL schemes a link may point to.
+var allowedSchemes = map[string]bool{
+    "http":   true,
+    "https":  true,
+    "mailto": true,
+}
+
+// Strip removes every HTML tag from s, and the content of scripts, styles
+// and the like, leaving the rest of the text exactly as written.
+func Strip(s string) string {
+    var b strings.Builder
+    z := xhtml.NewTokenizer(strings.NewReader(s))
+    var dropping atom.Atom
+    for {
+        tt := z.Next()
+        if tt == xhtml.ErrorToken {
+            return b.String()
+        }
+        tag, _ := z.TagName()
+        a := atom.Lookup(tag)
+        switch {
+        case dropping != 0:
+            if tt == xhtml.EndTagToken && a == dropping {
+                dropping = 0
+            }
+        case tt == xhtml.StartTagToken && droppedTags[a]:
+            dropping = a
+        case tt == xhtml.TextToken:
+            b.Write(z.Raw())
+        }
+    }
+}
+
+// Sanitize returns s as HTML keeping only allowlisted formatting tags,
+// stripped of attributes other than the href of links to http, https and
+// mailto URLs. Other tags are removed, leaving their text; scripts, styles
+// and the like are removed with their content. Tags left open are closed
+// and stray end tags dropped, so the result can't affect the page around
+// it.
+func Sanitize(s string) string {
+    var b strings.Builder
+    z := xhtml.NewTokenizer(strings.NewReader(s))
+    var open []atom.Atom
+    var dropping atom.Atom
+    for {
+        tt := z.Next()
+        if tt == xhtml.ErrorToken {
+            break
+        }
+        token := z.Token()
+        a := token.DataAtom
+        switch {
+        case dropping != 0:
+            if tt == xhtml.EndTagToken && a == dropping {
+                dropping = 0
+            }
+        case tt == xhtml.TextToken:
+            b.WriteString(html.EscapeString(token.Data))
+        case (tt == xhtml.StartTagToken || tt == xhtml.SelfClosingTagToken) && droppedTags[a]:
+            if tt == xhtml.StartTagToken {
+                dropping = a
+            }
+        case (tt == xhtml.StartTagToken || tt == xhtml.SelfClosingTagToken) && allowedTags[a]:
+            b.WriteString(startTag(token))
+            if a != atom.Br && tt == xhtml.StartTagToken {
+                open = append(open, a)
+            }
+        case tt == xhtml.EndTagToken && allowedTags[a]:
+            for i := len(open) - 1; i >= 0; i-- {
+                if open[i] == a {
+                    open = closeTags(&b, open, i)
+                    break
+                }
+            }
+        }
+    }
+    closeTags(&b, open, 0)
+    return b.String()
+}
+
+// closeTags writes end tags for open[from:], innermost first, and returns
+// the tags still open.
+func closeTags(b *strings.Builder, open []atom.Atom, from int) []atom.Atom {
+    for i := len(open) - 1; i >= from; i-- {
+        b.WriteString("</" + open[i].String() + ">")
+    }
+    return open[:from]
+}
+
+// startTag returns the start tag of an allowed token, keeping only a safe
+// href on links.
+func startTag(token xhtml.Token) string {
+    if token.DataAtom != atom.A {
+        return "<" + token.DataAtom.String() + ">"
+    }
+    for _, attr := range token.Attr {
+        if attr.Namespace == "" && attr.Key == "href" && safeURL(attr.Val) {
+            return `<a href="` + html.EscapeString(attr.Val) + `" rel="nofollow noopener">`
+        }
+    }
+    return "<a>"
+}
+
+// safeURL reports whether u is an absolute URL with an allowed scheme.
+func safeURL(u string) bool {
+    parsed, err := url.Parse(strings.TrimSpace(u))
+    return err == nil && allowedSchemes[strings.ToLower(parsed.Scheme)]
+}
+
+var (
+    paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+    codeSpan       = regexp.MustCompile("`([^`\n]+)`")
+    link           = regexp.MustCompile(`\[([^\]\n]+)\]\(([^)\s"<>]+)\)`)
+    strong         = regexp.MustCompile(`\*\*(\S(?:[^*\n]*\S)?)\*\*|__(\S(?:[^_\n]*\S)?)__`)
+    emphasis       = regexp.MustCompile(`\*(\S(?:[^*\n]*\S)?)\*`)
+)
+
+// Render turns s, written in a small subset of Markdown, into sanitized
+// HTML. Blank lines separate paragraphs and single newlines break lines;
+// within a line **strong** or __strong__, *emphasis*, `code` and
+// [links](https://example.com) are recognized. HTML in s is sanitized as
+// by Sanitize rather than escaped, so content kept under an allowlist
+// renders its formatting.
+func Render(s string) string {
+    return render(s, false)
+}
+
+// RenderText is Render for plain text: HTML in s is escaped, so text such
+// as "a<b" is shown as written rather than read as a tag.
+func RenderText(s string) string {
+    return render(s, true)
+}
+
+func render(s string, escape bool) string {
+    s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+    if s == "" {
+        return ""
+    }
+    var b strings.Builder
+    for _, para := range paragraphBreak.Split(s, -1) {
+        b.WriteString("<p>")
+        for i, line := range strings.Split(para, "\n") {
+            if i > 0 {
+                b.WriteString("<br>")
+            }
+            b.WriteString(inline(line, escape))
+        }
+        b.WriteString("</p>")
+    }
+    return Sanitize(b.String())
+}
+
+// inline renders the Markdown within a line, escaping the HTML in it first
+// if escape is set. Code spans are taken first so their contents are shown
+// literally.
+func inline(s string, escape bool) string {
+    text := func(s string) string {
+        if escape {
+            s = html.EscapeString(s)
+        }
+        return formatting(s)
+    }
+    var b strings.Builder
+    for {
+        loc := codeSpan.FindStringSubmatchIndex(s)
+        if loc == nil {
+            b.WriteString(text(s))
+            return b.String()
+        }
+        b.WriteString(text(s[:loc[0]]))
+        b.WriteString("<code>" + html.EscapeString(s[loc[2]:loc[3]]) + "</code>")
+        s = s[loc[1]:]
+    }
+}
+
+// formatting renders links, strong text and emphasis.
+func formatting(s string) string {
+    s = link.ReplaceAllString(s, `<a href="$2">$1</a>`)
+    s = strong.ReplaceAllString(s, "<strong>$1$2</strong>")
+    return emphasis.ReplaceAllString(s, "<em>$1</em>")
+}
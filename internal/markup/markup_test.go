@@ -0,0 +1,100 @@
+// internal/markup/markup_test.go
+
+package markup
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {name: "plain text", in: "just words", want: "just words"},
+        {name: "script removed with content", in: `hi<script>alert("x")</script> there`, want: "hi there"},
+        {name: "tags removed, text kept", in: `<b onclick="x()">bold</b> move`, want: "bold move"},
+        {name: "entities left as written", in: "Tom &amp; Jerry &lt;3", want: "Tom &amp; Jerry &lt;3"},
+        {name: "comparison kept", in: "a < b and 1 <3", want: "a < b and 1 <3"},
+        {name: "markdown kept", in: "some *emphasis*", want: "some *emphasis*"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := Strip(tt.in); got != tt.want {
+                t.Errorf("Strip(%q) = %q, want %q", tt.in, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestSanitize(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {name: "script removed with content", in: `<p>hi<script>alert(1)</script></p>`, want: "<p>hi</p>"},
+        {name: "attributes removed", in: `<b onmouseover="alert(1)">bold</b>`, want: "<b>bold</b>"},
+        {name: "disallowed tag unwrapped", in: `<div><img src=x onerror=alert(1)>text</div>`, want: "text"},
+        {name: "safe link kept", in: `<a href="https://example.com" target="_blank">site</a>`, want: `<a href="https://example.com" rel="nofollow noopener">site</a>`},
+        {name: "javascript link dropped", in: `<a href="javascript:alert(1)">click</a>`, want: "<a>click</a>"},
+        {name: "unclosed tags closed", in: "<ul><li><em>item", want: "<ul><li><em>item</em></li></ul>"},
+        {name: "stray end tag dropped", in: "text</p></div>", want: "text"},
+        {name: "text escaped", in: "a < b & c", want: "a &lt; b &amp; c"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := Sanitize(tt.in); got != tt.want {
+                t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestRender(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {name: "empty", in: "  ", want: ""},
+        {name: "emphasis", in: "some *emphasis* and **strong** and __also strong__", want: "<p>some <em>emphasis</em> and <strong>strong</strong> and <strong>also strong</strong></p>"},
+        {name: "arithmetic is not emphasis", in: "2 * 3 * 4", want: "<p>2 * 3 * 4</p>"},
+        {name: "code shown literally", in: "use `<b>*x*</b>` here", want: "<p>use <code>&lt;b&gt;*x*&lt;/b&gt;</code> here</p>"},
+        {name: "link", in: "see [the docs](https://example.com/docs)", want: `<p>see <a href="https://example.com/docs" rel="nofollow noopener">the docs</a></p>`},
+        {name: "javascript link", in: "[click](javascript:alert(1))", want: "<p><a>click</a>)</p>"},
+        {name: "paragraphs and breaks", in: "one\ntwo\n\nthree", want: "<p>one<br>two</p><p>three</p>"},
+        {name: "script neutralized", in: "*hi* <script>alert(1)</script>", want: "<p><em>hi</em> </p>"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := Render(tt.in); got != tt.want {
+                t.Errorf("Render(%q) = %q, want %q", tt.in, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestRenderText(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {name: "comparison", in: "if a<b then c", want: "<p>if a&lt;b then c</p>"},
+        {name: "generics", in: "use <T any> generics", want: "<p>use &lt;T any&gt; generics</p>"},
+        {name: "tags shown", in: "*hi* <script>alert(1)</script>", want: "<p><em>hi</em> &lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+        {name: "code not escaped twice", in: "use `a<b` here", want: "<p>use <code>a&lt;b</code> here</p>"},
+        {name: "link with query", in: "[q](https://example.com/?a=1&b=2)", want: `<p><a href="https://example.com/?a=1&amp;b=2" rel="nofollow noopener">q</a></p>`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := RenderText(tt.in); got != tt.want {
+                t.Errorf("RenderText(%q) = %q, want %q", tt.in, got, tt.want)
+            }
+        })
+    }
+}
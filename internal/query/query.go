@@ -0,0 +1,376 @@
+// internal/query/query.go
+
+// Package query parses the moderator search language, e.g.
+//
+//	author:bob -user:u123 before:2024-01-01 "exact phrase" (spam OR scam)
+//
+// Terms are implicitly ANDed. OR, AND and NOT are keywords (upper case
+// only), a leading '-' negates a term or group, parentheses group, and
+// double quotes make a phrase. Bare words and phrases match content.
+package query
+
+import (
+    "fmt"
+    "strings"
+    "time"
+    "unicode"
+)
+
+// Fields that may prefix a term, as in author:bob.
+const (
+    FieldContent = "content"
+    FieldAuthor  = "author"
+    FieldUser    = "user"
+    FieldBefore  = "before"
+    FieldAfter   = "after"
+)
+
+var knownFields = []string{FieldAfter, FieldAuthor, FieldBefore, FieldContent, FieldUser}
+
+// Limits bounding query complexity so pathological input is rejected
+// before it reaches the store.
+const (
+    MaxDepth  = 8
+    MaxTerms  = 32
+    MaxLength = 1024
+)
+
+// dateLayout is the accepted format for before: and after: values, in
+// addition to RFC 3339.
+const dateLayout = "2006-01-02"
+
+// Node is a parsed query expression: a Term, Not, And or Or.
+type Node interface {
+    String() string
+}
+
+// Term matches Value against Field. Phrase records whether the value was
+// quoted. Time is set for before: and after: terms.
+type Term struct {
+    Field  string
+    Value  string
+    Phrase bool
+    Time   time.Time
+}
+
+// Not negates its operand.
+type Not struct {
+    Node Node
+}
+
+// And matches when every operand matches.
+type And struct {
+    Nodes []Node
+}
+
+// Or matches when any operand matches.
+type Or struct {
+    Nodes []Node
+}
+
+func (t Term) String() string {
+    v := t.Value
+    if t.Phrase {
+        v = fmt.Sprintf("%q", v)
+    }
+    return t.Field + ":" + v
+}
+
+func (n Not) String() string { return "NOT " + n.Node.String() }
+func (a And) String() string { return "(" + join(a.Nodes, " AND ") + ")" }
+func (o Or) String() string  { return "(" + join(o.Nodes, " OR ") + ")" }
+
+func join(nodes []Node, sep string) string {
+    parts := make([]string, len(nodes))
+    for i, n := range nodes {
+        parts[i] = n.String()
+    }
+    return strings.Join(parts, sep)
+}
+
+// SyntaxError reports where parsing failed and what would have been valid
+// there.
+type SyntaxError struct {
+    Pos      int
+    Msg      string
+    Expected []string
+}
+
+func (e *SyntaxError) Error() string {
+    msg := fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+    if len(e.Expected) > 0 {
+        msg += fmt.Sprintf(" (expected %s)", strings.Join(e.Expected, ", "))
+    }
+    return msg
+}
+
+// Parse parses input into a query tree.
+func Parse(input string) (Node, error) {
+    if len(input) > MaxLength {
+        return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("query longer than %d bytes", MaxLength)}
+    }
+
+    tokens, err := lex(input)
+    if err != nil {
+        return nil, err
+    }
+
+    p := &parser{tokens: tokens}
+    node, err := p.parseOr(0)
+    if err != nil {
+        return nil, err
+    }
+    if tok := p.peek(); tok.kind != tokEOF {
+        return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok), Expected: []string{"OR", "term", "end of query"}}
+    }
+    return node, nil
+}
+
+type tokenKind int
+
+const (
+    tokEOF tokenKind = iota
+    tokWord
+    tokPhrase
+    tokField
+    tokLParen
+    tokRParen
+    tokMinus
+    tokAnd
+    tokOr
+    tokNot
+)
+
+type token struct {
+    kind tokenKind
+    text string
+    pos  int
+}
+
+func (t token) String() string {
+    switch t.kind {
+    case tokEOF:
+        return "end of query"
+    case tokPhrase:
+        return fmt.Sprintf("phrase %q", t.text)
+    case tokField:
+        return fmt.Sprintf("field %q", t.text+":")
+    default:
+        return fmt.Sprintf("%q", t.text)
+    }
+}
+
+func lex(input string) ([]token, error) {
+    var tokens []token
+    runes := []rune(input)
+    for i := 0; i < len(runes); {
+        r := runes[i]
+        switch {
+        case unicode.IsSpace(r):
+            i++
+        case r == '(':
+            tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+            i++
+        case r == ')':
+            tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+            i++
+        case r == '-' && atTermStart(runes, tokens, i):
+            tokens = append(tokens, token{kind: tokMinus, text: "-", pos: i})
+            i++
+        case r == '"':
+            start := i
+            i++
+            var b strings.Builder
+            for i < len(runes) && runes[i] != '"' {
+                if runes[i] == '\\' && i+1 < len(runes) {
+                    i++
+                }
+                b.WriteRune(runes[i])
+                i++
+            }
+            if i >= len(runes) {
+                return nil, &SyntaxError{Pos: start, Msg: "unterminated phrase", Expected: []string{`closing '"'`}}
+            }
+            i++
+            tokens = append(tokens, token{kind: tokPhrase, text: b.String(), pos: start})
+        default:
+            start := i
+            for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()":`, runes[i]) {
+                i++
+            }
+            word := string(runes[start:i])
+            if i < len(runes) && runes[i] == ':' {
+                i++
+                tokens = append(tokens, token{kind: tokField, text: word, pos: start})
+
+                // An unquoted value runs to the next space or parenthesis,
+                // so timestamps like after:2024-01-01T10:00:00Z stay whole.
+                valueStart := i
+                for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+                    i++
+                }
+                if i > valueStart {
+                    tokens = append(tokens, token{kind: tokWord, text: string(runes[valueStart:i]), pos: valueStart})
+                }
+                continue
+            }
+            tok := token{kind: tokWord, text: word, pos: start}
+            switch word {
+            case "AND":
+                tok.kind = tokAnd
+            case "OR":
+                tok.kind = tokOr
+            case "NOT":
+                tok.kind = tokNot
+            }
+            tokens = append(tokens, tok)
+        }
+    }
+    return append(tokens, token{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// atTermStart reports whether position i begins a new term, so a '-' there
+// is negation rather than part of a word like well-known.
+func atTermStart(runes []rune, tokens []token, i int) bool {
+    if i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '(' {
+        return true
+    }
+    last := tokens[len(tokens)-1]
+    return last.kind == tokMinus && last.pos == i-1
+}
+
+type parser struct {
+    tokens []token
+    pos    int
+    terms  int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+    tok := p.tokens[p.pos]
+    if tok.kind != tokEOF {
+        p.pos++
+    }
+    return tok
+}
+
+func (p *parser) parseOr(depth int) (Node, error) {
+    left, err := p.parseAnd(depth)
+    if err != nil {
+        return nil, err
+    }
+    nodes := []Node{left}
+    for p.peek().kind == tokOr {
+        p.next()
+        right, err := p.parseAnd(depth)
+        if err != nil {
+            return nil, err
+        }
+        nodes = append(nodes, right)
+    }
+    if len(nodes) == 1 {
+        return left, nil
+    }
+    return Or{Nodes: nodes}, nil
+}
+
+func (p *parser) parseAnd(depth int) (Node, error) {
+    first, err := p.parseUnary(depth)
+    if err != nil {
+        return nil, err
+    }
+    nodes := []Node{first}
+    for {
+        switch p.peek().kind {
+        case tokEOF, tokOr, tokRParen:
+            if len(nodes) == 1 {
+                return first, nil
+            }
+            return And{Nodes: nodes}, nil
+        case tokAnd:
+            p.next()
+        }
+        node, err := p.parseUnary(depth)
+        if err != nil {
+            return nil, err
+        }
+        nodes = append(nodes, node)
+    }
+}
+
+func (p *parser) parseUnary(depth int) (Node, error) {
+    if depth >= MaxDepth {
+        return nil, &SyntaxError{Pos: p.peek().pos, Msg: fmt.Sprintf("query nested deeper than %d levels", MaxDepth)}
+    }
+    switch p.peek().kind {
+    case tokMinus, tokNot:
+        p.next()
+        node, err := p.parseUnary(depth + 1)
+        if err != nil {
+            return nil, err
+        }
+        return Not{Node: node}, nil
+    case tokLParen:
+        open := p.next()
+        node, err := p.parseOr(depth + 1)
+        if err != nil {
+            return nil, err
+        }
+        if tok := p.next(); tok.kind != tokRParen {
+            return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unclosed group opened at position %d", open.pos), Expected: []string{"')'"}}
+        }
+        return node, nil
+    default:
+        return p.parseTerm()
+    }
+}
+
+func (p *parser) parseTerm() (Node, error) {
+    tok := p.next()
+    field := FieldContent
+    if tok.kind == tokField {
+        field = strings.ToLower(tok.text)
+        if !isKnownField(field) {
+            return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unknown field %q", tok.text), Expected: knownFields}
+        }
+        tok = p.next()
+        if tok.kind != tokWord && tok.kind != tokPhrase {
+            return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("missing value for %s:", field), Expected: []string{"word", "phrase"}}
+        }
+    }
+    if tok.kind != tokWord && tok.kind != tokPhrase {
+        return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok), Expected: []string{"term", "phrase", "field:value", "'('", "'-'"}}
+    }
+
+    p.terms++
+    if p.terms > MaxTerms {
+        return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("query has more than %d terms", MaxTerms)}
+    }
+
+    term := Term{Field: field, Value: tok.text, Phrase: tok.kind == tokPhrase}
+    if field == FieldBefore || field == FieldAfter {
+        t, err := parseDate(tok.text)
+        if err != nil {
+            return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("invalid date %q", tok.text), Expected: []string{"YYYY-MM-DD", "RFC 3339 timestamp"}}
+        }
+        term.Time = t
+    }
+    return term, nil
+}
+
+func isKnownField(field string) bool {
+    for _, f := range knownFields {
+        if f == field {
+            return true
+        }
+    }
+    return false
+}
+
+func parseDate(s string) (time.Time, error) {
+    if t, err := time.Parse(dateLayout, s); err == nil {
+        return t, nil
+    }
+    return time.Parse(time.RFC3339, s)
+}
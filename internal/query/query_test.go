@@ -0,0 +1,120 @@
+// internal/query/query_test.go
+
+package query
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        input string
+        want  string
+    }{
+        {input: "spam", want: "content:spam"},
+        {input: `"exact phrase"`, want: `content:"exact phrase"`},
+        {input: `"say \"hi\""`, want: `content:"say \"hi\""`},
+        {input: "author:bob", want: "author:bob"},
+        {input: "AUTHOR:bob", want: "author:bob"},
+        {input: `author:"bob smith"`, want: `author:"bob smith"`},
+        {input: "user:u1 spam", want: "(user:u1 AND content:spam)"},
+        {input: "a AND b", want: "(content:a AND content:b)"},
+        {input: "a OR b", want: "(content:a OR content:b)"},
+        {input: "a b OR c", want: "((content:a AND content:b) OR content:c)"},
+        {input: "a (b OR c)", want: "(content:a AND (content:b OR content:c))"},
+        {input: "-spam", want: "NOT content:spam"},
+        {input: "NOT spam", want: "NOT content:spam"},
+        {input: "-author:bob", want: "NOT author:bob"},
+        {input: "-(a OR b)", want: "NOT (content:a OR content:b)"},
+        {input: "--a", want: "NOT NOT content:a"},
+        {input: "well-known", want: "content:well-known"},
+        {input: "before:2024-01-01", want: "before:2024-01-01"},
+        {input: "after:2024-01-01T10:00:00Z", want: "after:2024-01-01T10:00:00Z"},
+        {input: "  spaced   out  ", want: "(content:spaced AND content:out)"},
+        {input: "or and not", want: "(content:or AND content:and AND content:not)"},
+        {input: "日本語", want: "content:日本語"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.input, func(t *testing.T) {
+            node, err := Parse(tt.input)
+            if err != nil {
+                t.Fatalf("Parse(%q) error: %v", tt.input, err)
+            }
+            if got := node.String(); got != tt.want {
+                t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseDateValue(t *testing.T) {
+    node, err := Parse("before:2024-03-05")
+    if err != nil {
+        t.Fatal(err)
+    }
+    term, ok := node.(Term)
+    if !ok {
+        t.Fatalf("expected Term, got %T", node)
+    }
+    if got := term.Time.Format("2006-01-02"); got != "2024-03-05" {
+        t.Errorf("expected time 2024-03-05, got %s", got)
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    tests := []struct {
+        input        string
+        wantPos      int
+        wantMsg      string
+        wantExpected string
+    }{
+        {input: "", wantPos: 0, wantMsg: "unexpected end of query", wantExpected: "term"},
+        {input: `"open`, wantPos: 0, wantMsg: "unterminated phrase", wantExpected: `closing '"'`},
+        {input: "tag:spam", wantPos: 0, wantMsg: `unknown field "tag"`, wantExpected: "author"},
+        {input: "author:", wantPos: 7, wantMsg: "missing value for author:", wantExpected: "word"},
+        {input: "author: bob", wantPos: 8, wantMsg: "", wantExpected: ""},
+        {input: "(a OR b", wantPos: 7, wantMsg: "unclosed group opened at position 0", wantExpected: "')'"},
+        {input: "a)", wantPos: 1, wantMsg: `unexpected ")"`, wantExpected: "end of query"},
+        {input: "a OR", wantPos: 4, wantMsg: "unexpected end of query", wantExpected: "term"},
+        {input: "-", wantPos: 1, wantMsg: "unexpected end of query", wantExpected: "term"},
+        {input: "()", wantPos: 1, wantMsg: `unexpected ")"`, wantExpected: "'('"},
+        {input: "before:yesterday", wantPos: 7, wantMsg: `invalid date "yesterday"`, wantExpected: "YYYY-MM-DD"},
+        {input: strings.Repeat("(", MaxDepth) + "a" + strings.Repeat(")", MaxDepth), wantPos: MaxDepth, wantMsg: "nested deeper"},
+        {input: strings.Repeat("-", MaxDepth+1) + "a", wantPos: MaxDepth, wantMsg: "nested deeper"},
+        {input: strings.Repeat("a ", MaxTerms+1), wantPos: MaxTerms * 2, wantMsg: "more than"},
+        {input: strings.Repeat("a", MaxLength+1), wantPos: MaxLength, wantMsg: "longer than"},
+    }
+
+    for _, tt := range tests {
+        name := tt.input
+        if len(name) > 40 {
+            name = name[:40]
+        }
+        t.Run(name, func(t *testing.T) {
+            _, err := Parse(tt.input)
+            if tt.wantMsg == "" {
+                // "author: bob" is valid: the value may follow whitespace.
+                if err != nil {
+                    t.Fatalf("Parse(%q) unexpected error: %v", tt.input, err)
+                }
+                return
+            }
+            var syntaxErr *SyntaxError
+            if !errors.As(err, &syntaxErr) {
+                t.Fatalf("Parse(%q) error = %v, want *SyntaxError", tt.input, err)
+            }
+            if syntaxErr.Pos != tt.wantPos {
+                t.Errorf("Parse(%q) position = %d, want %d", tt.input, syntaxErr.Pos, tt.wantPos)
+            }
+            if !strings.Contains(syntaxErr.Msg, tt.wantMsg) {
+                t.Errorf("Parse(%q) message = %q, want it to contain %q", tt.input, syntaxErr.Msg, tt.wantMsg)
+            }
+            if tt.wantExpected != "" && !strings.Contains(strings.Join(syntaxErr.Expected, ", "), tt.wantExpected) {
+                t.Errorf("Parse(%q) expected = %v, want it to include %q", tt.input, syntaxErr.Expected, tt.wantExpected)
+            }
+        })
+    }
+}
@@ -0,0 +1,148 @@
+// internal/realip/realip.go
+
+// Package realip resolves the client IP of requests that reach the service
+// through trusted reverse proxies or load balancers.
+package realip
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "net/http"
+    "net/netip"
+    "strings"
+)
+
+type contextKey struct{}
+
+// ParsePrefixes parses a list of CIDRs, accepting bare IPs as single-host
+// prefixes.
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+    prefixes := make([]netip.Prefix, 0, len(list))
+    for _, s := range list {
+        s = strings.TrimSpace(s)
+        if s == "" {
+            continue
+        }
+        if !strings.Contains(s, "/") {
+            addr, err := netip.ParseAddr(s)
+            if err != nil {
+                return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+            }
+            addr = addr.Unmap()
+            prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+            continue
+        }
+        prefix, err := netip.ParsePrefix(s)
+        if err != nil {
+            return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+        }
+        prefixes = append(prefixes, prefix.Masked())
+    }
+    return prefixes, nil
+}
+
+// Resolver finds a request's client IP, believing forwarding headers only
+// from peers within its trusted prefixes.
+type Resolver struct {
+    trusted []netip.Prefix
+}
+
+// NewResolver returns a resolver that trusts proxies within trusted. With
+// none, forwarding headers are always ignored.
+func NewResolver(trusted []netip.Prefix) *Resolver {
+    return &Resolver{trusted: trusted}
+}
+
+func (res *Resolver) isTrusted(addr netip.Addr) bool {
+    for _, p := range res.trusted {
+        if p.Contains(addr) {
+            return true
+        }
+    }
+    return false
+}
+
+// ClientIP returns the client IP for r. When the direct peer is a trusted
+// proxy, X-Forwarded-For is walked from the right, skipping trusted hops,
+// and the first untrusted address is the client: addresses to its left
+// were supplied by the client and may be spoofed. Without X-Forwarded-For,
+// X-Real-IP is used. Otherwise the peer itself is the client.
+func (res *Resolver) ClientIP(r *http.Request) string {
+    peer, ok := parseAddr(r.RemoteAddr)
+    if !ok {
+        return r.RemoteAddr
+    }
+    if !res.isTrusted(peer) {
+        return peer.String()
+    }
+
+    if hops := forwardedFor(r.Header); len(hops) > 0 {
+        client := peer
+        for i := len(hops) - 1; i >= 0; i-- {
+            addr, ok := parseAddr(hops[i])
+            if !ok {
+                // A malformed hop can't be trusted to have been added
+                // by a proxy; stop at the last address we could.
+                break
+            }
+            client = addr
+            if !res.isTrusted(addr) {
+                break
+            }
+        }
+        return client.String()
+    }
+
+    if addr, ok := parseAddr(r.Header.Get("X-Real-IP")); ok {
+        return addr.String()
+    }
+    return peer.String()
+}
+
+// forwardedFor returns the X-Forwarded-For hops in order, across every
+// instance of the header.
+func forwardedFor(h http.Header) []string {
+    var hops []string
+    for _, v := range h.Values("X-Forwarded-For") {
+        for _, hop := range strings.Split(v, ",") {
+            if hop = strings.TrimSpace(hop); hop != "" {
+                hops = append(hops, hop)
+            }
+        }
+    }
+    return hops
+}
+
+// parseAddr parses an IP with or without a port.
+func parseAddr(s string) (netip.Addr, bool) {
+    s = strings.TrimSpace(s)
+    if host, _, err := net.SplitHostPort(s); err == nil {
+        s = host
+    }
+    addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+    if err != nil {
+        return netip.Addr{}, false
+    }
+    return addr.Unmap(), true
+}
+
+// NewMiddleware stores each request's client IP, as resolved by res, in its
+// context for FromRequest.
+func NewMiddleware(res *Resolver) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            ctx := context.WithValue(r.Context(), contextKey{}, res.ClientIP(r))
+            next.ServeHTTP(w, r.WithContext(ctx))
+        })
+    }
+}
+
+// FromRequest returns the client IP stored by the middleware, or r's
+// direct peer address when it hasn't run.
+func FromRequest(r *http.Request) string {
+    if ip, ok := r.Context().Value(contextKey{}).(string); ok {
+        return ip
+    }
+    return r.RemoteAddr
+}
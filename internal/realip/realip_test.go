@@ -0,0 +1,122 @@
+// internal/realip/realip_test.go
+
+package realip
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "net/netip"
+    "testing"
+)
+
+func TestParsePrefixes(t *testing.T) {
+    tests := []struct {
+        name    string
+        list    []string
+        want    []string
+        wantErr bool
+    }{
+        {name: "cidrs", list: []string{"10.0.0.0/8", " 2001:db8::/32 "}, want: []string{"10.0.0.0/8", "2001:db8::/32"}},
+        {name: "bare IPs", list: []string{"192.168.1.1", "::1"}, want: []string{"192.168.1.1/32", "::1/128"}},
+        {name: "host bits masked", list: []string{"10.1.2.3/16"}, want: []string{"10.1.0.0/16"}},
+        {name: "empty entries skipped", list: []string{"", " "}, want: []string{}},
+        {name: "invalid IP", list: []string{"10.0.0.256"}, wantErr: true},
+        {name: "invalid CIDR", list: []string{"10.0.0.0/33"}, wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := ParsePrefixes(tt.list)
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("expected an error, got %v", got)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if len(got) != len(tt.want) {
+                t.Fatalf("got %v, want %v", got, tt.want)
+            }
+            for i := range got {
+                if got[i].String() != tt.want[i] {
+                    t.Errorf("prefix %d: got %s, want %s", i, got[i], tt.want[i])
+                }
+            }
+        })
+    }
+}
+
+func TestClientIP(t *testing.T) {
+    trusted, err := ParsePrefixes([]string{"10.0.0.0/8", "192.168.0.1", "fd00::/8"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    res := NewResolver(trusted)
+
+    tests := []struct {
+        name   string
+        peer   string
+        xff    []string
+        realIP string
+        want   string
+    }{
+        {name: "direct client", peer: "203.0.113.7:5000", want: "203.0.113.7"},
+        {name: "untrusted peer spoofing XFF", peer: "203.0.113.7:5000", xff: []string{"1.2.3.4"}, want: "203.0.113.7"},
+        {name: "untrusted peer spoofing X-Real-IP", peer: "203.0.113.7:5000", realIP: "1.2.3.4", want: "203.0.113.7"},
+        {name: "single trusted hop", peer: "10.0.0.2:80", xff: []string{"198.51.100.9"}, want: "198.51.100.9"},
+        {name: "multiple trusted hops", peer: "10.0.0.2:80", xff: []string{"198.51.100.9, 192.168.0.1, 10.1.1.1"}, want: "198.51.100.9"},
+        {name: "rightmost untrusted wins", peer: "10.0.0.2:80", xff: []string{"6.6.6.6, 198.51.100.9, 10.1.1.1"}, want: "198.51.100.9"},
+        {name: "split across headers", peer: "10.0.0.2:80", xff: []string{"6.6.6.6", "198.51.100.9", "10.1.1.1"}, want: "198.51.100.9"},
+        {name: "all hops trusted", peer: "10.0.0.2:80", xff: []string{"10.9.9.9, 10.1.1.1"}, want: "10.9.9.9"},
+        {name: "malformed hop", peer: "10.0.0.2:80", xff: []string{"198.51.100.9, garbage, 10.1.1.1"}, want: "10.1.1.1"},
+        {name: "X-Real-IP from trusted peer", peer: "10.0.0.2:80", realIP: "198.51.100.9", want: "198.51.100.9"},
+        {name: "invalid X-Real-IP", peer: "10.0.0.2:80", realIP: "nope", want: "10.0.0.2"},
+        {name: "IPv6 hops", peer: "[fd00::1]:443", xff: []string{"2001:db8::5, fd00::2"}, want: "2001:db8::5"},
+        {name: "IPv4-mapped peer", peer: "[::ffff:10.0.0.2]:80", xff: []string{"198.51.100.9"}, want: "198.51.100.9"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := httptest.NewRequest(http.MethodGet, "/", nil)
+            r.RemoteAddr = tt.peer
+            for _, v := range tt.xff {
+                r.Header.Add("X-Forwarded-For", v)
+            }
+            if tt.realIP != "" {
+                r.Header.Set("X-Real-IP", tt.realIP)
+            }
+            if got := res.ClientIP(r); got != tt.want {
+                t.Errorf("got %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestNoTrustedProxies(t *testing.T) {
+    r := httptest.NewRequest(http.MethodGet, "/", nil)
+    r.RemoteAddr = "10.0.0.2:80"
+    r.Header.Set("X-Forwarded-For", "198.51.100.9")
+
+    if got := NewResolver(nil).ClientIP(r); got != "10.0.0.2" {
+        t.Errorf("got %s, want the peer address", got)
+    }
+}
+
+func TestMiddleware(t *testing.T) {
+    var got string
+    h := NewMiddleware(NewResolver([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}))(
+        http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            got = FromRequest(r)
+        }))
+
+    r := httptest.NewRequest(http.MethodGet, "/", nil)
+    r.RemoteAddr = "10.0.0.2:80"
+    r.Header.Set("X-Forwarded-For", "198.51.100.9")
+    h.ServeHTTP(httptest.NewRecorder(), r)
+
+    if got != "198.51.100.9" {
+        t.Errorf("got %s, want 198.51.100.9", got)
+    }
+}
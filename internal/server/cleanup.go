@@ -0,0 +1,87 @@
+// internal/server/cleanup.go
+
+package server
+
+import (
+    "context"
+    "sync"
+    "time"
+    "web-service/internal/api"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// cleanupStatus keeps the outcome of the last cleanup run, reported on the
+// stats endpoint.
+type cleanupStatus struct {
+    mu   sync.Mutex
+    last api.CleanupRun
+    ran  bool
+}
+
+func (c *cleanupStatus) record(run api.CleanupRun) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.last, c.ran = run, true
+}
+
+// Last returns the last completed run, or false if there has been none.
+func (c *cleanupStatus) Last() (api.CleanupRun, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.last, c.ran
+}
+
+// runCleanup sweeps expired comments, and deletes comments older than
+// retention when retention > 0, every interval until ctx is cancelled,
+// recording each completed run in status. Runs happen one at a time on the
+// calling goroutine, so one that outlasts the interval delays the next
+// rather than overlapping it.
+func runCleanup(
+    ctx context.Context,
+    logger *logging.Logger,
+    store storage.CommentRepository,
+    status *cleanupStatus,
+    interval time.Duration,
+    retention time.Duration,
+) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    logger.Info(ctx, "comment cleanup scheduled",
+        "interval", interval.String(),
+        "retention", retention.String(),
+    )
+
+    for {
+        select {
+        case <-ctx.Done():
+            logger.Info(ctx, "comment cleanup stopped")
+            return
+        case <-ticker.C:
+            start := time.Now()
+            expired, err := store.DeleteExpired(ctx)
+            if err != nil {
+                if ctx.Err() != nil {
+                    return
+                }
+                logger.Error(ctx, "comment cleanup failed", "error", err)
+                continue
+            }
+
+            purged := 0
+            if retention > 0 {
+                purged, err = store.DeleteOlderThan(ctx, retention)
+                if err != nil {
+                    if ctx.Err() != nil {
+                        return
+                    }
+                    logger.Error(ctx, "comment cleanup failed", "error", err)
+                    continue
+                }
+            }
+            status.record(api.CleanupRun{Time: start, Expired: expired, Purged: purged})
+            logger.Info(ctx, "comment cleanup completed", "expired", expired, "purged", purged)
+        }
+    }
+}
@@ -0,0 +1,99 @@
+// internal/server/cleanup_test.go
+
+package server
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "sync"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+func TestRunCleanup(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    var mu sync.Mutex
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    store := storage.NewCommentStore(storage.WithClock(func() time.Time {
+        mu.Lock()
+        defer mu.Unlock()
+        return now
+    }))
+
+    for _, c := range []storage.Comment{
+        {Content: "old", Author: "Alice", UserID: "alice"},
+        {Content: "expiring", Author: "Alice", UserID: "alice", ExpiresAt: now.Add(time.Minute)},
+    } {
+        if _, err := store.Create(ctx, c); err != nil {
+            t.Fatal(err)
+        }
+    }
+    now = now.Add(2 * time.Hour)
+    if _, err := store.Create(ctx, storage.Comment{Content: "recent", Author: "Bob", UserID: "bob"}); err != nil {
+        t.Fatal(err)
+    }
+
+    var logs bytes.Buffer
+    status := &cleanupStatus{}
+    if _, ran := status.Last(); ran {
+        t.Fatal("expected no run reported before the first")
+    }
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        runCleanup(ctx, logging.NewLogger(&logs), store, status, 5*time.Millisecond, time.Hour)
+    }()
+
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        if _, ran := status.Last(); ran {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatal("cleanup did not run")
+        }
+        time.Sleep(time.Millisecond)
+    }
+
+    cancel()
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("cleanup did not stop when its context was cancelled")
+    }
+
+    if run, _ := status.Last(); run.Time.IsZero() {
+        t.Errorf("expected the run time reported, got %+v", run)
+    }
+    comments, err := store.List(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(comments) != 1 || comments[0].Content != "recent" {
+        t.Errorf("expected only the recent comment kept, got %+v", comments)
+    }
+
+    // The first run removed both; later ones found nothing left
+    dec := json.NewDecoder(&logs)
+    for dec.More() {
+        var entry struct {
+            Message string         `json:"message"`
+            Fields  map[string]any `json:"fields"`
+        }
+        if err := dec.Decode(&entry); err != nil {
+            t.Fatal(err)
+        }
+        if entry.Message == "comment cleanup completed" {
+            if entry.Fields["expired"] != float64(1) || entry.Fields["purged"] != float64(1) {
+                t.Errorf("expected 1 expired and 1 purged, got %v", entry.Fields)
+            }
+            return
+        }
+    }
+    t.Error("no comment cleanup completed entry")
+}
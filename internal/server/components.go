@@ -0,0 +1,288 @@
+// internal/server/components.go
+
+package server
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+    "web-service/internal/api"
+    "web-service/internal/health"
+    "web-service/pkg/logging"
+)
+
+// component is a subsystem Run starts before serving. A required
+// component that fails to start aborts startup; an optional one is
+// reported degraded on /readyz and retried in the background.
+type component struct {
+    name     string
+    required bool
+    // timeout bounds each start attempt. Zero means no timeout.
+    timeout time.Duration
+    // dependsOn names the components that must be running before this one
+    // starts.
+    dependsOn []string
+    // start starts the component. Its context lasts as long as the
+    // component runs, so background work may keep it, and is cancelled if
+    // start overruns the timeout.
+    start func(ctx context.Context) error
+}
+
+// componentState is where a component is in starting up.
+type componentState int
+
+const (
+    componentPending componentState = iota
+    componentRunning
+    componentFailed
+)
+
+// errDependencyNotRunning is the start error of a component whose
+// dependencies haven't started yet.
+var errDependencyNotRunning = errors.New("dependency not running")
+
+// components starts a set of components in dependency order and tracks
+// their state for readiness checks.
+type components struct {
+    logger *logging.Logger
+    list   []*component
+
+    // backoff is the delay before retrying a failed optional component,
+    // doubling after each failure up to maxBackoff.
+    backoff    time.Duration
+    maxBackoff time.Duration
+
+    mu     sync.Mutex
+    states map[string]componentState
+    errs   map[string]error
+    stops  []context.CancelFunc // of the running components
+
+    ctx    context.Context
+    cancel context.CancelFunc
+    wg     sync.WaitGroup
+}
+
+func newComponents(logger *logging.Logger, list ...*component) *components {
+    return &components{
+        logger:     logger,
+        list:       list,
+        backoff:    time.Second,
+        maxBackoff: time.Minute,
+        states:     make(map[string]componentState),
+        errs:       make(map[string]error),
+    }
+}
+
+// order returns the components sorted so each comes after its
+// dependencies, keeping declaration order otherwise.
+func (cs *components) order() ([]*component, error) {
+    byName := make(map[string]*component, len(cs.list))
+    for _, c := range cs.list {
+        if _, ok := byName[c.name]; ok {
+            return nil, fmt.Errorf("component %s declared twice", c.name)
+        }
+        byName[c.name] = c
+    }
+
+    const (
+        unvisited = iota
+        visiting
+        visited
+    )
+    marks := make(map[string]int, len(cs.list))
+    var sorted []*component
+    var visit func(c *component) error
+    visit = func(c *component) error {
+        switch marks[c.name] {
+        case visiting:
+            return fmt.Errorf("component %s depends on itself", c.name)
+        case visited:
+            return nil
+        }
+        marks[c.name] = visiting
+        for _, dep := range c.dependsOn {
+            d, ok := byName[dep]
+            if !ok {
+                return fmt.Errorf("component %s depends on unknown component %s", c.name, dep)
+            }
+            if err := visit(d); err != nil {
+                return err
+            }
+        }
+        marks[c.name] = visited
+        sorted = append(sorted, c)
+        return nil
+    }
+    for _, c := range cs.list {
+        if err := visit(c); err != nil {
+            return nil, err
+        }
+    }
+    return sorted, nil
+}
+
+// start starts every component in dependency order. It returns the
+// failures of all required components joined together, after stopping
+// whatever did start; optional failures are logged and retried until ctx
+// is cancelled or stop is called.
+func (cs *components) start(ctx context.Context) error {
+    sorted, err := cs.order()
+    if err != nil {
+        return err
+    }
+    cs.ctx, cs.cancel = context.WithCancel(ctx)
+
+    var errs []error
+    for _, c := range sorted {
+        err := cs.attempt(c)
+        switch {
+        case err == nil:
+            cs.logger.Info(ctx, "component started", "component", c.name)
+        case c.required:
+            errs = append(errs, fmt.Errorf("starting %s: %w", c.name, err))
+        default:
+            cs.logger.Error(ctx, "optional component failed to start, retrying in background",
+                "component", c.name,
+                "error", err,
+            )
+            cs.wg.Add(1)
+            go func() {
+                defer cs.wg.Done()
+                cs.retry(c)
+            }()
+        }
+    }
+    if len(errs) > 0 {
+        cs.stop()
+        return errors.Join(errs...)
+    }
+    return nil
+}
+
+// attempt makes one attempt at starting c within its timeout and records
+// the outcome.
+func (cs *components) attempt(c *component) error {
+    err := cs.dependenciesRunning(c)
+    if err == nil {
+        // The component keeps its context after a successful start, so
+        // the timeout is a timer rather than a context deadline
+        ctx, cancel := context.WithCancel(cs.ctx)
+        done := make(chan error, 1)
+        go func() { done <- c.start(ctx) }()
+
+        var timeout <-chan time.Time
+        if c.timeout > 0 {
+            timer := time.NewTimer(c.timeout)
+            defer timer.Stop()
+            timeout = timer.C
+        }
+        select {
+        case err = <-done:
+        case <-timeout:
+            err = fmt.Errorf("timed out after %s", c.timeout)
+        }
+        cs.mu.Lock()
+        if err != nil {
+            cancel()
+        } else {
+            cs.stops = append(cs.stops, cancel)
+        }
+        cs.mu.Unlock()
+    }
+
+    cs.mu.Lock()
+    defer cs.mu.Unlock()
+    if err != nil {
+        cs.states[c.name], cs.errs[c.name] = componentFailed, err
+        return err
+    }
+    cs.states[c.name] = componentRunning
+    delete(cs.errs, c.name)
+    return nil
+}
+
+func (cs *components) dependenciesRunning(c *component) error {
+    cs.mu.Lock()
+    defer cs.mu.Unlock()
+    for _, dep := range c.dependsOn {
+        if cs.states[dep] != componentRunning {
+            return fmt.Errorf("%w: %s", errDependencyNotRunning, dep)
+        }
+    }
+    return nil
+}
+
+// retry starts c again with exponential backoff until it runs or the
+// components are stopped.
+func (cs *components) retry(c *component) {
+    delay := cs.backoff
+    for {
+        timer := time.NewTimer(delay)
+        select {
+        case <-cs.ctx.Done():
+            timer.Stop()
+            return
+        case <-timer.C:
+        }
+
+        err := cs.attempt(c)
+        if err == nil {
+            cs.logger.Info(cs.ctx, "optional component recovered", "component", c.name)
+            return
+        }
+        delay = min(delay*2, cs.maxBackoff)
+        if !errors.Is(err, errDependencyNotRunning) {
+            cs.logger.Error(cs.ctx, "optional component still failing",
+                "component", c.name,
+                "error", err,
+                "retry_in", delay.String(),
+            )
+        }
+    }
+}
+
+// stop cancels the components' contexts and waits for pending retries.
+func (cs *components) stop() {
+    if cs.cancel != nil {
+        cs.cancel()
+    }
+    cs.wg.Wait()
+
+    cs.mu.Lock()
+    defer cs.mu.Unlock()
+    for _, stop := range cs.stops {
+        stop()
+    }
+    cs.stops = nil
+}
+
+// checker reports the named component's state as a readiness check: ok
+// once running, degraded while an optional component is failing.
+func (cs *components) checker(name string) health.Checker {
+    return health.CheckerFunc(func(ctx context.Context) error {
+        cs.mu.Lock()
+        defer cs.mu.Unlock()
+        switch cs.states[name] {
+        case componentRunning:
+            return nil
+        case componentFailed:
+            return health.Degraded(cs.errs[name])
+        default:
+            return health.Degraded(errors.New("starting"))
+        }
+    })
+}
+
+// readinessChecks returns a /readyz check for each optional component.
+// Required ones need none: startup fails without them.
+func (cs *components) readinessChecks() []api.ServerOption {
+    var opts []api.ServerOption
+    for _, c := range cs.list {
+        if !c.required {
+            opts = append(opts, api.WithReadinessCheck(c.name, cs.checker(c.name)))
+        }
+    }
+    return opts
+}
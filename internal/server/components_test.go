@@ -0,0 +1,180 @@
+// internal/server/components_test.go
+
+package server
+
+import (
+    "context"
+    "errors"
+    "io"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+    "web-service/internal/health"
+    "web-service/pkg/logging"
+)
+
+func newTestComponents(list ...*component) *components {
+    cs := newComponents(logging.NewLogger(io.Discard), list...)
+    cs.backoff, cs.maxBackoff = time.Millisecond, 5*time.Millisecond
+    return cs
+}
+
+func TestComponentsStartInDependencyOrder(t *testing.T) {
+    var mu sync.Mutex
+    var started []string
+    starter := func(name string) func(context.Context) error {
+        return func(context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            started = append(started, name)
+            return nil
+        }
+    }
+
+    cs := newTestComponents(
+        &component{name: "webhooks", dependsOn: []string{"queue", "store"}, start: starter("webhooks")},
+        &component{name: "queue", dependsOn: []string{"store"}, start: starter("queue")},
+        &component{name: "store", required: true, start: starter("store")},
+    )
+    if err := cs.start(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    defer cs.stop()
+
+    if got := strings.Join(started, " "); got != "store queue webhooks" {
+        t.Errorf("expected start order store queue webhooks, got %s", got)
+    }
+}
+
+func TestComponentsInvalidDependencies(t *testing.T) {
+    noop := func(context.Context) error { return nil }
+    tests := []struct {
+        name string
+        list []*component
+        want string
+    }{
+        {"unknown", []*component{{name: "a", dependsOn: []string{"b"}, start: noop}}, "unknown component b"},
+        {"cycle", []*component{
+            {name: "a", dependsOn: []string{"b"}, start: noop},
+            {name: "b", dependsOn: []string{"a"}, start: noop},
+        }, "depends on itself"},
+        {"duplicate", []*component{{name: "a", start: noop}, {name: "a", start: noop}}, "declared twice"},
+    }
+    for _, tt := range tests {
+        err := newTestComponents(tt.list...).start(context.Background())
+        if err == nil || !strings.Contains(err.Error(), tt.want) {
+            t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+        }
+    }
+}
+
+func TestComponentsRequiredFailure(t *testing.T) {
+    errDB := errors.New("database unreachable")
+    errKeys := errors.New("signing keys missing")
+    var optionalCtx context.Context
+
+    cs := newTestComponents(
+        &component{name: "cache", start: func(ctx context.Context) error {
+            optionalCtx = ctx
+            return nil
+        }},
+        &component{name: "database", required: true, start: func(context.Context) error { return errDB }},
+        &component{name: "keys", required: true, start: func(context.Context) error { return errKeys }},
+    )
+    err := cs.start(context.Background())
+    if !errors.Is(err, errDB) || !errors.Is(err, errKeys) {
+        t.Fatalf("expected both required failures, got %v", err)
+    }
+    if optionalCtx == nil || optionalCtx.Err() == nil {
+        t.Error("expected components that started to be stopped after a required failure")
+    }
+}
+
+func TestComponentsTimeout(t *testing.T) {
+    cs := newTestComponents(&component{
+        name:     "slow",
+        required: true,
+        timeout:  10 * time.Millisecond,
+        start: func(ctx context.Context) error {
+            <-ctx.Done()
+            return ctx.Err()
+        },
+    })
+    err := cs.start(context.Background())
+    if err == nil || !strings.Contains(err.Error(), "timed out") {
+        t.Errorf("expected timeout error, got %v", err)
+    }
+}
+
+func TestComponentsOptionalFailureRecovers(t *testing.T) {
+    var mu sync.Mutex
+    failures := 3
+    var attempts int
+    var dependentStarted bool
+
+    cs := newTestComponents(
+        &component{name: "oidc", start: func(context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            attempts++
+            if attempts <= failures {
+                return errors.New("issuer unreachable")
+            }
+            return nil
+        }},
+        &component{name: "sessions", dependsOn: []string{"oidc"}, start: func(context.Context) error {
+            mu.Lock()
+            defer mu.Unlock()
+            dependentStarted = true
+            return nil
+        }},
+    )
+    checks := health.NewMonitor()
+    checks.Add("oidc", cs.checker("oidc"), health.Budget{})
+    checks.Add("sessions", cs.checker("sessions"), health.Budget{})
+
+    if report := checks.Run(context.Background()); report.Status != health.StatusDegraded {
+        t.Errorf("before start: expected %s, got %s", health.StatusDegraded, report.Status)
+    }
+
+    // Hold the component failing while checking it is reported degraded
+    mu.Lock()
+    failures = 1 << 30
+    mu.Unlock()
+    if err := cs.start(context.Background()); err != nil {
+        t.Fatalf("expected optional failure not to abort startup, got %v", err)
+    }
+    defer cs.stop()
+
+    report := checks.Run(context.Background())
+    if report.Status != health.StatusDegraded {
+        t.Errorf("while failing: expected %s, got %s", health.StatusDegraded, report.Status)
+    }
+    for _, c := range report.Checks {
+        if c.Status != health.StatusDegraded || c.Err == nil {
+            t.Errorf("while failing: expected %s degraded with an error, got %s %v", c.Name, c.Status, c.Err)
+        }
+    }
+
+    // Let it recover; the dependent follows once it runs
+    mu.Lock()
+    failures = attempts + 2
+    mu.Unlock()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        report = checks.Run(context.Background())
+        if report.Status == health.StatusOK {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("expected recovery, still %s", report.Status)
+        }
+        time.Sleep(time.Millisecond)
+    }
+    mu.Lock()
+    defer mu.Unlock()
+    if !dependentStarted {
+        t.Error("expected the dependent component to start after recovery")
+    }
+}
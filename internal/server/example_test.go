@@ -0,0 +1,127 @@
+// internal/server/example_test.go
+
+package server_test
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "time"
+    "web-service/internal/auth"
+    "web-service/internal/config"
+    "web-service/internal/server"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// readOnlyStore is a store of your own: here one serving a fixed list of
+// comments. It implements the reads listing comments needs; the methods it
+// leaves to the embedded interface, such as writes, aren't supported.
+type readOnlyStore struct {
+    storage.CommentRepository
+    comments []storage.Comment
+    flags    *storage.FlagStore
+}
+
+func (s *readOnlyStore) Ping(ctx context.Context) error { return nil }
+
+func (s *readOnlyStore) LastModified(ctx context.Context) (time.Time, error) {
+    return time.Time{}, nil
+}
+
+func (s *readOnlyStore) ReactedTo(ctx context.Context, userID string) (map[string]bool, error) {
+    return nil, nil
+}
+
+func (s *readOnlyStore) Range(ctx context.Context, fn func(storage.Comment) bool) error {
+    for _, c := range s.comments {
+        if !fn(c) {
+            break
+        }
+    }
+    return nil
+}
+
+func (s *readOnlyStore) Flags() *storage.FlagStore { return s.flags }
+
+func (s *readOnlyStore) FaultInjector() *storage.FaultInjector { return nil }
+
+// Embedding the service: serve it on your own listener, with a store and
+// a logger of your own.
+func ExampleNew() {
+    env := map[string]string{"JWT_SECRET": "example-secret", "ENV_FILE": "testdata/absent.env"}
+    cfg, err := config.Load(func(key string) string { return env[key] })
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+
+    store := &readOnlyStore{
+        comments: []storage.Comment{{ID: "c1", Content: "seeded", Author: "Alice", UserID: "alice", Version: 1}},
+        flags:    storage.NewFlagStore(),
+    }
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+
+    srv, err := server.New(
+        server.WithConfig(cfg),
+        server.WithLogger(logging.NewLogger(io.Discard)),
+        server.WithCommentStore(store),
+        server.WithListener(listener),
+    )
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    if err := srv.Start(context.Background()); err != nil {
+        fmt.Println(err)
+        return
+    }
+    defer func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        srv.Shutdown(ctx)
+    }()
+
+    tokens := auth.NewJWTManager(cfg.JWTSecret, time.Hour, auth.WithIssuer(cfg.JWTIssuer), auth.WithAudience(cfg.JWTAudience))
+    token, err := tokens.GenerateToken("bob", "user")
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    req, err := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/api/v1/comments", nil)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    defer resp.Body.Close()
+
+    var comments []struct {
+        Content string `json:"content"`
+        Author  string `json:"author"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+        fmt.Println(err)
+        return
+    }
+    fmt.Println(resp.StatusCode)
+    for _, c := range comments {
+        fmt.Printf("%s by %s\n", c.Content, c.Author)
+    }
+    // Output:
+    // 200
+    // seeded by Alice
+}
@@ -0,0 +1,54 @@
+// internal/server/flags.go
+
+package server
+
+import (
+    "errors"
+    "flag"
+)
+
+// flagSettings maps the flags that stand in for settings to the
+// environment variables they override.
+var flagSettings = map[string]string{
+    "host":           "HOST",
+    "port":           "PORT",
+    "socket":         "SOCKET",
+    "socket-mode":    "SOCKET_MODE",
+    "listen-retries": "LISTEN_RETRIES",
+}
+
+// withFlags layers the settings given as flags over getenv, so a flag set
+// on the command line beats the environment, the .env file and the config
+// file alike. Flags left unset fall through to getenv.
+func withFlags(flags *flag.FlagSet, getenv func(string) string) func(string) string {
+    set := make(map[string]string)
+    flags.Visit(func(f *flag.Flag) {
+        if key, ok := flagSettings[f.Name]; ok {
+            set[key] = f.Value.String()
+        }
+    })
+    return func(key string) string {
+        if v, ok := set[key]; ok {
+            return v
+        }
+        return getenv(key)
+    }
+}
+
+// checkListenFlags rejects --socket alongside --host or --port, which it
+// replaces.
+func checkListenFlags(flags *flag.FlagSet) error {
+    var socket, addr bool
+    flags.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "socket":
+            socket = true
+        case "host", "port":
+            addr = true
+        }
+    })
+    if socket && addr {
+        return errors.New("--socket cannot be combined with --host or --port")
+    }
+    return nil
+}
@@ -0,0 +1,48 @@
+// internal/server/flags_test.go
+
+package server
+
+import (
+    "flag"
+    "io"
+    "os"
+    "path/filepath"
+    "testing"
+    "web-service/internal/config"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "config.yaml")
+    content := "jwt_secret: from-file\nhost: file.example\nport: 1111\njwt_issuer: from-file\n"
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    env := map[string]string{
+        "ENV_FILE": filepath.Join(dir, "absent.env"),
+        "HOST":     "env.example",
+        "PORT":     "2222",
+    }
+
+    flags := flag.NewFlagSet("server", flag.ContinueOnError)
+    flags.SetOutput(io.Discard)
+    flags.String("host", "", "")
+    flags.String("port", "", "")
+    if err := flags.Parse([]string{"--port", "3333"}); err != nil {
+        t.Fatal(err)
+    }
+
+    cfg, err := config.LoadFile(path, withFlags(flags, func(key string) string { return env[key] }))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cfg.JWTIssuer != "from-file" {
+        t.Errorf("expected file value without env or flag, got %q", cfg.JWTIssuer)
+    }
+    if cfg.Host != "env.example" {
+        t.Errorf("expected env to override file, got %q", cfg.Host)
+    }
+    if cfg.Port != "3333" {
+        t.Errorf("expected flag to override env and file, got %q", cfg.Port)
+    }
+}
@@ -4,100 +4,553 @@ package server
 
 import (
     "context"
+    "errors"
     "flag"
     "fmt"
     "io"
     "net"
     "net/http"
+    "os"
+    "sync"
+    "syscall"
     "time"
     "web-service/internal/api"
+    "web-service/internal/auth"
     "web-service/internal/config"
     "web-service/internal/storage"
+    "web-service/internal/tracing"
+    "web-service/internal/util"
+    "web-service/internal/version"
     "web-service/pkg/logging"
 )
 
-func Run(ctx context.Context, w io.Writer, args []string, getenv func(string) string) error {
+// Run runs the service as the command line describes until ctx is done.
+// opts are applied after the configuration and logger Run builds, such as
+// WithReady to learn the address chosen for --port 0.
+func Run(ctx context.Context, w io.Writer, args []string, getenv func(string) string, opts ...Option) error {
     // Parse flags
     flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+    // host, port, socket and listen-retries override settings, applied by
+    // withFlags
+    flags.String("host", "", "Server host, overriding HOST (default localhost)")
+    flags.String("port", "", "Server port, overriding PORT (default 8080)")
+    flags.String("socket", "", "Unix socket to listen on instead of a host and port, overriding SOCKET")
+    flags.String("socket-mode", "", "Permissions of the Unix socket, overriding SOCKET_MODE (default 0660)")
+    flags.String("listen-retries", "", "Times to retry listening while the address is in use, overriding LISTEN_RETRIES (default 0)")
     var (
-        host = flags.String("host", "localhost", "Server host")
-        port = flags.String("port", "8080", "Server port")
+        configPath = flags.String("config", "", "Path to a YAML or JSON config file")
+        showVer    = flags.Bool("version", false, "Print version information and exit")
+        hashPass   = flags.String("hash-password", "", "Print a password hash for the USERS setting and exit")
     )
     if err := flags.Parse(args[1:]); err != nil {
         return fmt.Errorf("parsing flags: %w", err)
     }
+    if err := checkListenFlags(flags); err != nil {
+        return fmt.Errorf("parsing flags: %w", err)
+    }
+
+    build := version.Get()
+    if *showVer {
+        fmt.Fprintf(w, "web-service %s\n", build)
+        return nil
+    }
 
-    // Initialize logger
-    logger := logging.NewLogger(w)
+    if *hashPass != "" {
+        hash, err := auth.HashPassword(*hashPass)
+        if err != nil {
+            return fmt.Errorf("hashing password: %w", err)
+        }
+        fmt.Fprintln(w, hash)
+        return nil
+    }
 
-    // Load config
-    cfg, err := config.Load(getenv)
+    // Load config; see config.LoadFile for how the file, environment and
+    // flags take precedence over each other
+    cfg, err := config.LoadFile(*configPath, withFlags(flags, getenv))
     if err != nil {
         return fmt.Errorf("loading config: %w", err)
     }
 
+    // Buffered entries are flushed last, after everything else has logged
+    logger := newLogger(w, cfg)
+    defer closeLogger(ctx, logger)
+
+    if len(cfg.UnknownFileKeys) > 0 {
+        logger.Warn(ctx, "ignoring unknown keys in config file",
+            "path", *configPath,
+            "keys", cfg.UnknownFileKeys,
+        )
+    }
+
+    srv, err := New(append([]Option{WithConfig(cfg), WithLogger(logger)}, opts...)...)
+    if err != nil {
+        return err
+    }
+    if err := srv.Start(ctx); err != nil {
+        return err
+    }
+
+    // Wait for shutdown signal or error
+    shutdown := func() error {
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownDrainDelay+cfg.ShutdownTimeout)
+        defer cancel()
+        return srv.Shutdown(shutdownCtx)
+    }
+    select {
+    case err := <-srv.serveErr:
+        shutdown()
+        return err
+    case <-ctx.Done():
+        return shutdown()
+    }
+}
+
+// Server is the comment service: the API, its comment store and the
+// subsystems running alongside it, served over HTTP. Build one with New,
+// then Start and Shutdown it, to embed the service in another program;
+// Run does all three for the command line.
+type Server struct {
+    cfg      *config.Config
+    logger   *logging.Logger
+    store    storage.CommentRepository
+    listener net.Listener
+    onReady  func(net.Addr)
+
+    // ownLogger is set when New built the logger, so Shutdown closes it.
+    ownLogger bool
+
+    shutdownTracing func(context.Context) error
+    subsystems      *components
+    background      sync.WaitGroup
+
+    httpServer *http.Server
+    notifier   *shutdownNotifier
+    requests   *inFlight
+
+    // serveErr receives the error that stopped the server serving, if it
+    // wasn't a shutdown, and is closed once it has stopped.
+    serveErr chan error
+
+    shutdownOnce sync.Once
+    shutdownErr  error
+    stopOnce     sync.Once
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithConfig sets the server's configuration, loaded from the environment
+// unless set.
+func WithConfig(cfg *config.Config) Option {
+    return func(s *Server) {
+        s.cfg = cfg
+    }
+}
+
+// WithLogger sets the server's logger. The caller keeps ownership of it:
+// Shutdown leaves it open. Unless set, New builds one from the
+// configuration writing to standard output.
+func WithLogger(logger *logging.Logger) Option {
+    return func(s *Server) {
+        s.logger = logger
+    }
+}
+
+// WithCommentStore sets the store comments are kept in, which may be any
+// storage.CommentRepository, such as a fake in tests. Unless set, New
+// builds a storage.CommentStore from the configuration, traced and with its
+// memory budget, tombstones and fault injection; a supplied store is used
+// as it is. Either way New wraps it in a storage.InstrumentedStore to count
+// and time its operations.
+func WithCommentStore(store storage.CommentRepository) Option {
+    return func(s *Server) {
+        s.store = store
+    }
+}
+
+// WithListener serves on l rather than listening on the configured host
+// and port or socket. Shutdown closes it.
+func WithListener(l net.Listener) Option {
+    return func(s *Server) {
+        s.listener = l
+    }
+}
+
+// WithReady calls ready with the address the server listens on once Start
+// has it serving, which tells a caller listening on port 0 the port the
+// system chose.
+func WithReady(ready func(addr net.Addr)) Option {
+    return func(s *Server) {
+        s.onReady = ready
+    }
+}
+
+// New builds the service described by opts, ready to Start.
+func New(opts ...Option) (*Server, error) {
+    s := &Server{
+        notifier: newShutdownNotifier(),
+        requests: &inFlight{},
+        serveErr: make(chan error, 1),
+    }
+    for _, opt := range opts {
+        opt(s)
+    }
+
+    ctx := context.Background()
+    if s.cfg == nil {
+        cfg, err := config.Load(os.Getenv)
+        if err != nil {
+            return nil, fmt.Errorf("loading config: %w", err)
+        }
+        s.cfg = cfg
+    }
+    // A config passed in with WithConfig hasn't been through Load
+    if err := s.cfg.Validate(); err != nil {
+        return nil, fmt.Errorf("invalid config: %w", err)
+    }
+    cfg := s.cfg
+    if s.logger == nil {
+        s.logger = newLogger(os.Stdout, cfg)
+        s.ownLogger = true
+    }
+    logger := s.logger
+
+    // Record what this instance is running with, secrets left out
+    logger.Info(ctx, "effective configuration", cfg.LogFields()...)
+
+    // Body entries are written at DEBUG whatever the logger's level, which
+    // is the embedder's to set
+    if cfg.LogHTTPBodies {
+        logger.Warn(ctx, "logging HTTP bodies", "environment", cfg.Environment)
+    }
+
+    // Export spans when a collector is configured. Shutdown flushes them
+    // last, after the server has drained, so the final requests' spans are
+    // included
+    build := version.Get()
+    tracerProvider, shutdownTracing, err := tracing.NewProvider(ctx, cfg.OTLPEndpoint, build.Version)
+    if err != nil {
+        return nil, fmt.Errorf("setting up tracing: %w", err)
+    }
+    s.shutdownTracing = shutdownTracing
+
     // Initialize storage
-    commentStore := storage.NewCommentStore()
+    if s.store == nil {
+        storeOpts := []storage.Option{
+            storage.WithTracerProvider(tracerProvider),
+            storage.WithMemoryBudget(cfg.MemoryBudget, func(evicted int, bytes int64) {
+                logger.Warn(ctx, "comment store over memory budget, evicted oldest comments",
+                    "evicted", evicted,
+                    "bytes", bytes,
+                    "budget", cfg.MemoryBudget,
+                )
+            }),
+            storage.WithTombstones(cfg.DeleteIdempotencyWindow),
+        }
+        if cfg.CommentIDs == config.CommentIDsSortable {
+            storeOpts = append(storeOpts, storage.WithIDGenerator(util.GenerateSortableID))
+        }
+        if cfg.FaultInjection {
+            logger.Warn(ctx, "storage fault injection enabled", "environment", cfg.Environment)
+            storeOpts = append(storeOpts, storage.WithFaultInjector(storage.NewFaultInjector()))
+        }
+        s.store = storage.NewCommentStore(storeOpts...)
+    }
+    // Every store operation is counted and timed, and slow ones logged
+    storeMetrics := storage.NewMetrics(cfg.StoreSlowThreshold)
+    commentStore := storage.NewInstrumentedStore(s.store, logger, storeMetrics)
+
+    // The subsystems the server runs alongside. The cleanup job, which
+    // sweeps expired comments and applies the retention policy if one is
+    // set, is optional: the API works without it, so a failure is reported
+    // on /readyz and retried rather than stopping startup. Its last run is
+    // reported on the stats endpoint
+    cleanup := &cleanupStatus{}
+    s.subsystems = newComponents(logger,
+        &component{
+            name:     "comment_store",
+            required: true,
+            timeout:  cfg.ReadyTimeout,
+            start:    commentStore.Ping,
+        },
+        &component{
+            name:      "cleanup",
+            timeout:   cfg.ReadyTimeout,
+            dependsOn: []string{"comment_store"},
+            start: func(ctx context.Context) error {
+                s.background.Add(1)
+                go func() {
+                    defer s.background.Done()
+                    runCleanup(ctx, logger, commentStore, cleanup, cfg.CleanupInterval, cfg.CommentRetention)
+                }()
+                return nil
+            },
+        },
+    )
 
     // Create server using api.NewServer
+    serverOpts := append(s.subsystems.readinessChecks(),
+        api.WithTracerProvider(tracerProvider),
+        api.WithShutdownSignal(s.notifier.Done()),
+        api.WithCleanupStatus(cleanup.Last),
+        api.WithStoreMetrics(storeMetrics),
+    )
     handler := api.NewServer(
         logger,
         cfg,
         commentStore,
+        serverOpts...,
     )
 
-    // Set up HTTP server
-    httpServer := &http.Server{
-        Addr:    net.JoinHostPort(*host, *port),
-        Handler: handler,
+    // Requests are counted so shutdown can report what it drained
+    s.httpServer = &http.Server{
+        Addr:    net.JoinHostPort(cfg.Host, cfg.Port),
+        Handler: s.requests.track(handler),
     }
+    return s, nil
+}
 
-    // Channel to signal when the server is ready
-    ready := make(chan struct{})
+// Start starts the subsystems and begins serving, returning once the
+// server accepts connections. The subsystems run until ctx is cancelled or
+// Shutdown is called.
+func (s *Server) Start(ctx context.Context) error {
+    logger := s.logger
+    build := version.Get()
+
+    if err := s.subsystems.start(ctx); err != nil {
+        s.stop()
+        return fmt.Errorf("starting subsystems: %w", err)
+    }
 
     // Create server listener manually so we can confirm it's ready
-    listener, err := net.Listen("tcp", httpServer.Addr)
-    if err != nil {
-        return fmt.Errorf("failed to create listener: %w", err)
+    if s.listener == nil {
+        listener, err := s.listen(ctx)
+        if err != nil {
+            s.stop()
+            return fmt.Errorf("failed to create listener: %w", err)
+        }
+        s.listener = listener
     }
+    addr := s.listener.Addr().String()
+
+    // Channel to signal when the server is ready
+    ready := make(chan struct{})
 
     // Start server in a goroutine
-    errChan := make(chan error, 1)
     go func() {
-        logger.Info(ctx, "server starting", "addr", httpServer.Addr)
+        logger.Info(ctx, "server starting",
+            "addr", addr,
+            "version", build.Version,
+            "commit", build.Commit,
+            "build_date", build.BuildDate,
+        )
 
         // Signal that we're ready to accept connections
         close(ready)
 
         // Serve using the listener
-        if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
-            errChan <- fmt.Errorf("error serving: %w", err)
+        if err := s.httpServer.Serve(s.listener); err != nil && err != http.ErrServerClosed {
+            s.serveErr <- fmt.Errorf("error serving: %w", err)
         }
-        close(errChan)
+        close(s.serveErr)
     }()
 
     // Wait for server to be ready or for an error
     select {
     case <-ready:
-        logger.Info(ctx, "server ready", "addr", httpServer.Addr)
-    case err := <-errChan:
+        logger.Info(ctx, "server ready", "addr", addr)
+        if s.onReady != nil {
+            s.onReady(s.listener.Addr())
+        }
+        return nil
+    case err := <-s.serveErr:
+        s.stop()
         return fmt.Errorf("server failed before becoming ready: %w", err)
     case <-time.After(5 * time.Second):
+        s.stop()
         return fmt.Errorf("timeout waiting for server to become ready")
     }
+}
 
-    // Wait for shutdown signal or error
-    select {
-    case err := <-errChan:
-        return err
-    case <-ctx.Done():
-        shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Addr returns the address the server listens on, or nil before Start.
+func (s *Server) Addr() net.Addr {
+    if s.listener == nil {
+        return nil
+    }
+    return s.listener.Addr()
+}
+
+// Listening while the address is in use is retried after listenBackoff,
+// doubling after each failure up to maxListenBackoff.
+const (
+    listenBackoff    = 100 * time.Millisecond
+    maxListenBackoff = 5 * time.Second
+)
+
+// listen listens on the configured Unix socket, or the host and port if
+// there is none. While the address is in use it retries up to
+// cfg.ListenRetries times with exponential backoff, or until ctx is done.
+func (s *Server) listen(ctx context.Context) (net.Listener, error) {
+    delay := listenBackoff
+    for attempt := 1; ; attempt++ {
+        listener, err := s.listenOnce()
+        if err == nil || !errors.Is(err, syscall.EADDRINUSE) || attempt > s.cfg.ListenRetries {
+            return listener, err
+        }
+        s.logger.Warn(ctx, "address in use, retrying listen",
+            "error", err,
+            "attempt", attempt,
+            "retries", s.cfg.ListenRetries,
+            "retry_in", delay.String(),
+        )
+        timer := time.NewTimer(delay)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return nil, err
+        case <-timer.C:
+        }
+        delay = min(delay*2, maxListenBackoff)
+    }
+}
+
+// listenOnce makes a single attempt to listen on the configured address.
+func (s *Server) listenOnce() (net.Listener, error) {
+    if s.cfg.Socket == "" {
+        return net.Listen("tcp", s.httpServer.Addr)
+    }
+    return listenUnix(s.cfg.Socket, s.cfg.SocketMode)
+}
+
+// listenUnix listens on a Unix socket at path with permissions mode,
+// replacing a socket left behind by a server that didn't shut down
+// cleanly. Closing the listener removes the socket file.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+    if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+        if err := os.Remove(path); err != nil {
+            return nil, fmt.Errorf("removing stale socket: %w", err)
+        }
+    }
+    listener, err := net.Listen("unix", path)
+    if err != nil {
+        return nil, err
+    }
+    if err := os.Chmod(path, mode); err != nil {
+        listener.Close()
+        return nil, fmt.Errorf("setting socket permissions: %w", err)
+    }
+    return listener, nil
+}
+
+// Shutdown stops the server gracefully: it tells long-lived handlers to
+// finish and answers new requests with 503 for the configured drain delay,
+// then stops taking connections and waits for in-flight requests until
+// ctx is done, closing whatever is left. The subsystems are stopped and
+// spans flushed after. Only the first call does anything; later ones
+// return its result.
+func (s *Server) Shutdown(ctx context.Context) error {
+    s.shutdownOnce.Do(func() {
+        s.shutdownErr = s.shutdown(ctx)
+        s.stop()
+    })
+    return s.shutdownErr
+}
+
+func (s *Server) shutdown(ctx context.Context) error {
+    logger := s.logger
+
+    // Stop reusing connections, tell long-lived handlers to finish and
+    // turn away new requests with 503, /readyz included. Listening on for
+    // the drain delay gives load balancers time to see it and stop routing
+    // here before connections are refused
+    s.httpServer.SetKeepAlivesEnabled(false)
+    s.notifier.notify()
+    if delay := s.cfg.ShutdownDrainDelay; delay > 0 {
+        logger.Info(ctx, "draining before shutdown", "delay", delay.String())
+        timer := time.NewTimer(delay)
+        select {
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+        }
+    }
+
+    // Then stop listening and wait for in-flight requests up to the
+    // deadline. How long the drain took shows how close it came to the
+    // timeout.
+    inFlightAtStart := s.requests.count()
+    fields := []interface{}{"in_flight", inFlightAtStart}
+    if deadline, ok := ctx.Deadline(); ok {
+        fields = append(fields, "timeout", time.Until(deadline).Round(time.Millisecond).String())
+    }
+    logger.Info(ctx, "shutting down server gracefully", fields...)
+    start := time.Now()
+
+    err := s.httpServer.Shutdown(ctx)
+    switch {
+    case errors.Is(err, context.DeadlineExceeded):
+        // Close the stragglers' connections so the process can exit
+        forced := s.requests.count()
+        if err := s.httpServer.Close(); err != nil {
+            logger.Error(ctx, "error closing server", "error", err)
+        }
+        logger.Warn(ctx, "shutdown timed out, closed remaining requests",
+            "drained", max(inFlightAtStart-forced, 0),
+            "force_closed", forced,
+            "duration", time.Since(start).Round(time.Millisecond).String(),
+        )
+    case err != nil:
+        return fmt.Errorf("error shutting down server: %w", err)
+    default:
+        logger.Info(ctx, "server stopped",
+            "drained", inFlightAtStart,
+            "duration", time.Since(start).Round(time.Millisecond).String(),
+        )
+    }
+    return nil
+}
+
+// stop stops the subsystems, flushes spans and, if New built it, closes
+// the logger, once.
+func (s *Server) stop() {
+    s.stopOnce.Do(func() {
+        ctx := context.Background()
+        s.subsystems.stop()
+        s.background.Wait()
+
+        flushCtx, cancel := context.WithTimeout(ctx, s.cfg.ShutdownTimeout)
         defer cancel()
+        if err := s.shutdownTracing(flushCtx); err != nil {
+            s.logger.Error(ctx, "error flushing spans", "error", err)
+        }
 
-        logger.Info(ctx, "shutting down server gracefully")
-        if err := httpServer.Shutdown(shutdownCtx); err != nil {
-            return fmt.Errorf("error shutting down server: %w", err)
+        if s.ownLogger {
+            closeLogger(ctx, s.logger)
         }
-        return nil
+    })
+}
+
+// newLogger builds the logger cfg describes, writing to w.
+func newLogger(w io.Writer, cfg *config.Config) *logging.Logger {
+    var opts []logging.Option
+    if cfg.LogBufferSize > 0 {
+        policy := logging.DropOldest
+        if cfg.LogOverflow == config.LogOverflowBlock {
+            policy = logging.Block
+        }
+        opts = append(opts, logging.WithBuffer(cfg.LogBufferSize, policy))
+    }
+    if !cfg.LogStackTraces {
+        opts = append(opts, logging.WithStackTraces(false))
+    }
+    return logging.NewLogger(w, opts...)
+}
+
+// closeLogger flushes and closes logger, first warning if its buffer
+// dropped entries.
+func closeLogger(ctx context.Context, logger *logging.Logger) {
+    if n := logger.Dropped(); n > 0 {
+        logger.Warn(ctx, "log buffer overflowed, entries dropped", "dropped", n)
     }
+    logger.Close()
 }
\ No newline at end of file
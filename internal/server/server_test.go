@@ -0,0 +1,23 @@
+// internal/server/server_test.go
+
+package server
+
+import (
+    "strings"
+    "testing"
+    "web-service/internal/config"
+)
+
+func TestNewValidatesConfig(t *testing.T) {
+    // A config built in code skips Load's defaults; New must refuse it
+    // rather than start a cleanup ticker with a zero interval
+    _, err := New(WithConfig(&config.Config{JWTSecret: "x", Environment: "development"}))
+    if err == nil {
+        t.Fatal("expected an error for a zero-value config")
+    }
+    for _, want := range []string{"CLEANUP_INTERVAL", "SHUTDOWN_TIMEOUT"} {
+        if !strings.Contains(err.Error(), want) {
+            t.Errorf("expected the error to mention %s, got %v", want, err)
+        }
+    }
+}
@@ -0,0 +1,47 @@
+// internal/server/shutdown.go
+
+package server
+
+import (
+    "net/http"
+    "sync"
+    "sync/atomic"
+)
+
+// shutdownNotifier tells long-lived handlers, such as streams, that
+// graceful shutdown has started so they can wind down before the drain
+// timeout. Handlers reach it through api.WithShutdownSignal.
+type shutdownNotifier struct {
+    once sync.Once
+    done chan struct{}
+}
+
+func newShutdownNotifier() *shutdownNotifier {
+    return &shutdownNotifier{done: make(chan struct{})}
+}
+
+// Done returns a channel that is closed when shutdown starts.
+func (n *shutdownNotifier) Done() <-chan struct{} {
+    return n.done
+}
+
+func (n *shutdownNotifier) notify() {
+    n.once.Do(func() { close(n.done) })
+}
+
+// inFlight counts the requests currently being handled.
+type inFlight struct {
+    n atomic.Int64
+}
+
+func (f *inFlight) track(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        f.n.Add(1)
+        defer f.n.Add(-1)
+        next.ServeHTTP(w, r)
+    })
+}
+
+func (f *inFlight) count() int64 {
+    return f.n.Load()
+}
@@ -0,0 +1,262 @@
+// internal/similarity/similarity.go
+
+// Package similarity detects near-duplicate comments. Content is reduced to
+// a 64-bit SimHash fingerprint, so comments that differ by a few characters
+// get fingerprints that differ in only a few bits, and an Index compares a
+// new comment against a bounded window of recent ones.
+package similarity
+
+import (
+    "container/list"
+    "hash/fnv"
+    "math/bits"
+    "strings"
+    "sync"
+    "unicode"
+)
+
+// shingleSize is the length in runes of the overlapping substrings hashed
+// into a fingerprint.
+const shingleSize = 4
+
+// MinLength is the shortest normalized content, in runes, that is
+// fingerprinted. Shorter comments ("thanks!", "+1") are too generic to
+// call duplicates.
+const MinLength = 16
+
+// DefaultThreshold separates near-duplicates from distinct comments: a few
+// edited characters typically leave similarity above 0.84, while unrelated
+// comments of similar length stay below 0.77.
+const DefaultThreshold = 0.8
+
+// Fingerprint is the SimHash of a comment's normalized content.
+type Fingerprint uint64
+
+// Similarity returns the fraction of bits a and b share, from 0 to 1.
+func Similarity(a, b Fingerprint) float64 {
+    return 1 - float64(bits.OnesCount64(uint64(a^b)))/64
+}
+
+// Normalize lower-cases content, drops punctuation and collapses runs of
+// whitespace, so trivial edits don't change the fingerprint.
+func Normalize(content string) string {
+    var b strings.Builder
+    space := false
+    for _, r := range strings.ToLower(content) {
+        switch {
+        case unicode.IsLetter(r) || unicode.IsNumber(r):
+            if space && b.Len() > 0 {
+                b.WriteByte(' ')
+            }
+            space = false
+            b.WriteRune(r)
+        case unicode.IsSpace(r):
+            space = true
+        }
+    }
+    return b.String()
+}
+
+// Compute returns the fingerprint of content, and false if its normalized
+// form is shorter than MinLength.
+func Compute(content string) (Fingerprint, bool) {
+    runes := []rune(Normalize(content))
+    if len(runes) < MinLength {
+        return 0, false
+    }
+
+    var weights [64]int
+    h := fnv.New64a()
+    for i := 0; i+shingleSize <= len(runes); i++ {
+        h.Reset()
+        h.Write([]byte(string(runes[i : i+shingleSize])))
+        sum := h.Sum64()
+        for bit := 0; bit < 64; bit++ {
+            if sum&(1<<bit) != 0 {
+                weights[bit]++
+            } else {
+                weights[bit]--
+            }
+        }
+    }
+
+    var fp Fingerprint
+    for bit, w := range weights {
+        if w > 0 {
+            fp |= 1 << bit
+        }
+    }
+    return fp, true
+}
+
+// Match is a recent comment similar to the one checked.
+type Match struct {
+    CommentID  string
+    UserID     string
+    Similarity float64
+}
+
+type entry struct {
+    fp        Fingerprint
+    commentID string
+    userID    string
+}
+
+// ring is a fixed-size buffer of the most recent entries.
+type ring struct {
+    entries []entry
+    next    int
+}
+
+func (r *ring) add(e entry, size int) {
+    if size <= 0 {
+        return
+    }
+    if len(r.entries) < size {
+        r.entries = append(r.entries, e)
+        return
+    }
+    r.entries[r.next] = e
+    r.next = (r.next + 1) % size
+}
+
+// removeUser drops userID's entries, keeping the rest oldest first, and
+// returns the IDs of the comments dropped.
+func (r *ring) removeUser(userID string) []string {
+    ordered := append(r.entries[r.next:len(r.entries):len(r.entries)], r.entries[:r.next]...)
+    var kept []entry
+    var removed []string
+    for _, e := range ordered {
+        if e.userID == userID {
+            removed = append(removed, e.commentID)
+            continue
+        }
+        kept = append(kept, e)
+    }
+    r.entries, r.next = kept, 0
+    return removed
+}
+
+// Index remembers the fingerprints of recent comments: the last Window
+// comments overall and the last UserWindow comments of each of the last
+// MaxUsers users to post. Memory is bounded by those sizes, and each check
+// compares against at most Window+UserWindow fingerprints.
+type Index struct {
+    threshold  float64
+    window     int
+    userWindow int
+    maxUsers   int
+
+    mu     sync.Mutex
+    global ring
+    users  map[string]*list.Element // of *userRing, most recent at front
+    lru    *list.List
+}
+
+type userRing struct {
+    userID string
+    ring
+}
+
+// Defaults for the per-user windows.
+const (
+    DefaultUserWindow = 20
+    DefaultMaxUsers   = 10000
+)
+
+// Option configures an Index.
+type Option func(*Index)
+
+// WithUserWindow sets how many recent comments are kept per user, and for
+// how many users.
+func WithUserWindow(size, maxUsers int) Option {
+    return func(ix *Index) {
+        ix.userWindow = size
+        ix.maxUsers = maxUsers
+    }
+}
+
+// NewIndex returns an index that reports matches at or above threshold
+// among the last window comments.
+func NewIndex(threshold float64, window int, opts ...Option) *Index {
+    ix := &Index{
+        threshold:  threshold,
+        window:     window,
+        userWindow: DefaultUserWindow,
+        maxUsers:   DefaultMaxUsers,
+        users:      make(map[string]*list.Element),
+        lru:        list.New(),
+    }
+    for _, opt := range opts {
+        opt(ix)
+    }
+    return ix
+}
+
+// Check returns the most similar recent comment to fp, if any reaches the
+// threshold. userID's own recent comments are checked even after they have
+// left the global window.
+func (ix *Index) Check(userID string, fp Fingerprint) (Match, bool) {
+    ix.mu.Lock()
+    defer ix.mu.Unlock()
+
+    var best Match
+    consider := func(entries []entry) {
+        for _, e := range entries {
+            if s := Similarity(fp, e.fp); s >= ix.threshold && s > best.Similarity {
+                best = Match{CommentID: e.commentID, UserID: e.userID, Similarity: s}
+            }
+        }
+    }
+    consider(ix.global.entries)
+    if el, ok := ix.users[userID]; ok {
+        consider(el.Value.(*userRing).entries)
+    }
+    return best, best.CommentID != ""
+}
+
+// Add records the fingerprint of a newly created comment.
+func (ix *Index) Add(userID, commentID string, fp Fingerprint) {
+    ix.mu.Lock()
+    defer ix.mu.Unlock()
+
+    e := entry{fp: fp, commentID: commentID, userID: userID}
+    ix.global.add(e, ix.window)
+
+    if ix.userWindow <= 0 || ix.maxUsers <= 0 {
+        return
+    }
+    el, ok := ix.users[userID]
+    if ok {
+        ix.lru.MoveToFront(el)
+    } else {
+        if ix.lru.Len() >= ix.maxUsers {
+            oldest := ix.lru.Back()
+            ix.lru.Remove(oldest)
+            delete(ix.users, oldest.Value.(*userRing).userID)
+        }
+        el = ix.lru.PushFront(&userRing{userID: userID})
+        ix.users[userID] = el
+    }
+    el.Value.(*userRing).add(e, ix.userWindow)
+}
+
+// Forget drops every fingerprint of userID's comments and returns how many
+// comments they were for.
+func (ix *Index) Forget(userID string) int {
+    ix.mu.Lock()
+    defer ix.mu.Unlock()
+
+    forgotten := make(map[string]bool)
+    for _, id := range ix.global.removeUser(userID) {
+        forgotten[id] = true
+    }
+    if el, ok := ix.users[userID]; ok {
+        for _, e := range el.Value.(*userRing).entries {
+            forgotten[e.commentID] = true
+        }
+        ix.lru.Remove(el)
+        delete(ix.users, userID)
+    }
+    return len(forgotten)
+}
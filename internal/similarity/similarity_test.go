@@ -0,0 +1,141 @@
+// internal/similarity/similarity_test.go
+
+package similarity
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "testing"
+)
+
+const threshold = DefaultThreshold
+
+func fingerprint(t *testing.T, content string) Fingerprint {
+    t.Helper()
+    fp, ok := Compute(content)
+    if !ok {
+        t.Fatalf("expected %q to be fingerprinted", content)
+    }
+    return fp
+}
+
+func TestSimilarity(t *testing.T) {
+    const spam = "Buy cheap watches at our online store, best prices guaranteed!"
+
+    tests := []struct {
+        name    string
+        a, b    string
+        similar bool
+    }{
+        {name: "identical", a: spam, b: spam, similar: true},
+        {name: "case and punctuation", a: spam, b: "BUY CHEAP WATCHES at our online store... best prices guaranteed", similar: true},
+        {name: "one character changed", a: spam, b: "Buy cheap watches at our online stor3, best prices guaranteed!", similar: true},
+        {name: "one character inserted", a: spam, b: "Buy cheap watchess at our online store, best prices guaranteed!", similar: true},
+        {name: "word swapped", a: spam, b: "Buy cheap watches at our online shop, best prices guaranteed!", similar: true},
+        {name: "distinct comments", a: spam, b: "I disagree with the author's point about interest rates entirely.", similar: false},
+        {name: "same topic", a: "The new release fixed the login bug I reported last week.", b: "The new release broke the search page I use every single day.", similar: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := Similarity(fingerprint(t, tt.a), fingerprint(t, tt.b))
+            if (s >= threshold) != tt.similar {
+                t.Errorf("similarity %.3f, want similar=%v", s, tt.similar)
+            }
+        })
+    }
+}
+
+func TestComputeSkipsShortContent(t *testing.T) {
+    for _, content := range []string{"", "thanks!", "+1 agreed"} {
+        if _, ok := Compute(content); ok {
+            t.Errorf("expected %q to be too short to fingerprint", content)
+        }
+    }
+}
+
+func TestIndex(t *testing.T) {
+    spam := fingerprint(t, "Buy cheap watches at our online store, best prices guaranteed!")
+    tweaked := fingerprint(t, "Buy cheap watches at our online stor3, best prices guaranteed!")
+
+    t.Run("global match", func(t *testing.T) {
+        ix := NewIndex(threshold, 10)
+        ix.Add("spammer", "c1", spam)
+
+        m, ok := ix.Check("other", tweaked)
+        if !ok || m.CommentID != "c1" || m.UserID != "spammer" {
+            t.Errorf("got %+v, %v; want a match on c1", m, ok)
+        }
+    })
+
+    t.Run("global window is bounded", func(t *testing.T) {
+        ix := NewIndex(threshold, 3, WithUserWindow(0, 0))
+        ix.Add("spammer", "c1", spam)
+        for i := 0; i < 3; i++ {
+            ix.Add("other", fmt.Sprint("filler", i), fingerprint(t, fmt.Sprintf("An unrelated comment number %d about gardening", i)))
+        }
+        if len(ix.global.entries) != 3 {
+            t.Errorf("expected 3 entries, got %d", len(ix.global.entries))
+        }
+        if m, ok := ix.Check("other", tweaked); ok {
+            t.Errorf("expected c1 to have left the window, matched %+v", m)
+        }
+    })
+
+    t.Run("user window outlives global window", func(t *testing.T) {
+        ix := NewIndex(threshold, 1)
+        ix.Add("spammer", "c1", spam)
+        ix.Add("other", "c2", fingerprint(t, "An unrelated comment about gardening tools"))
+
+        if _, ok := ix.Check("someone", tweaked); ok {
+            t.Error("expected no match for another user")
+        }
+        if m, ok := ix.Check("spammer", tweaked); !ok || m.CommentID != "c1" {
+            t.Errorf("got %+v, %v; want the spammer's own c1", m, ok)
+        }
+    })
+
+    t.Run("tracked users are bounded", func(t *testing.T) {
+        ix := NewIndex(threshold, 1, WithUserWindow(5, 2))
+        for _, user := range []string{"a", "b", "c"} {
+            ix.Add(user, user+"1", spam)
+        }
+        if len(ix.users) != 2 || ix.lru.Len() != 2 {
+            t.Errorf("expected 2 tracked users, got %d", len(ix.users))
+        }
+        if _, ok := ix.users["a"]; ok {
+            t.Error("expected the least recent user to be evicted")
+        }
+    })
+    t.Run("forget drops a user's fingerprints", func(t *testing.T) {
+        ix := NewIndex(threshold, 3)
+        ix.Add("spammer", "c1", spam)
+        for i := 0; i < 3; i++ {
+            ix.Add("other", fmt.Sprint("filler", i), fingerprint(t, fmt.Sprintf("An unrelated comment number %d about gardening", i)))
+        }
+        ix.Add("spammer", "c2", spam)
+
+        if n := ix.Forget("spammer"); n != 2 {
+            t.Errorf("expected 2 comments forgotten, got %d", n)
+        }
+        if m, ok := ix.Check("spammer", tweaked); ok {
+            t.Errorf("expected no match after forgetting, matched %+v", m)
+        }
+        if _, ok := ix.users["spammer"]; ok {
+            t.Error("expected the user's window to be dropped")
+        }
+
+        // The remaining entries still age out oldest first
+        ix.Add("other", "c3", spam)
+        ix.Add("other", "c4", spam)
+        var ids []string
+        for _, e := range ix.global.entries {
+            ids = append(ids, e.commentID)
+        }
+        sort.Strings(ids)
+        if strings.Join(ids, " ") != "c3 c4 filler2" {
+            t.Errorf("expected filler1 to age out first, got %v", ids)
+        }
+    })
+}
@@ -5,13 +5,28 @@ package storage
 import (
     "context"
     "errors"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/trace"
+    "slices"
+    "sort"
     "sync"
     "time"
+    "unsafe"
+    "web-service/internal/erasure"
     "web-service/internal/util"
 )
 
 var (
     ErrNotFound = errors.New("comment not found")
+
+    // ErrVersionConflict is returned by Update when the comment has
+    // changed since the version the caller expected.
+    ErrVersionConflict = errors.New("comment version conflict")
+
+    // ErrConflict is returned by Create when a comment with the new
+    // comment's ID already exists.
+    ErrConflict = errors.New("comment already exists")
 )
 
 type Comment struct {
@@ -20,53 +35,473 @@ type Comment struct {
     Author    string
     CreatedAt time.Time
     UserID    string    // Added to track who created the comment
+
+    // ExpiresAt, when non-zero, is when the comment stops being visible.
+    // Expired comments are hidden from every read at once and physically
+    // removed later by DeleteExpired.
+    ExpiresAt time.Time
+
+    // Attachments are URLs the comment links to, such as images. The store
+    // keeps its own copy, and each read returns a fresh one.
+    Attachments []string
+
+    // ReactionCount is filled in by read methods from the store's reaction
+    // sets; it is ignored on writes.
+    ReactionCount int
+
+    // Version starts at 1 and is incremented by every change to the
+    // comment, so a writer can tell whether it changed since it was read.
+    // It is set by the store and ignored on writes.
+    Version int64
 }
 
 type CommentStore struct {
     mu       sync.RWMutex
     comments map[string]Comment
+    // reactions maps comment ID to the set of user IDs that reacted to it
+    reactions map[string]map[string]struct{}
+    // flags holds moderation flags, dropped along with their comment
+    flags *FlagStore
+
+    // bytes is the approximate memory held by comments, maintained on
+    // every mutation so reading it never requires a scan.
+    bytes   int64
+    budget  int64
+    onEvict func(evicted int, bytes int64)
+
+    // tombstones remember recently deleted comments for tombstoneTTL so a
+    // repeated delete can be told apart from one that never existed.
+    tombstones   map[string]Tombstone
+    tombstoneTTL time.Duration
+
+    // now is the store's clock, replaceable in tests with WithClock.
+    now func() time.Time
+
+    // newID generates comment IDs, util.GenerateID unless set with
+    // WithIDGenerator.
+    newID func() string
+
+    // writes counts mutations, giving each write a position that
+    // consistency tokens can refer to, and modified is when the last one
+    // happened.
+    writes   uint64
+    modified time.Time
+
+    // faults, if set, injects errors and latency for chaos testing.
+    faults *FaultInjector
+
+    // tracer starts a child span for each traced operation.
+    tracer trace.Tracer
+
+    // subscribers receive an Event for each change; see Subscribe.
+    subscribers map[chan Event]struct{}
+}
+
+// Tombstone records the deletion of a comment.
+type Tombstone struct {
+    UserID    string
+    DeletedAt time.Time
+}
+
+// Option configures a CommentStore.
+type Option func(*CommentStore)
+
+// WithMemoryBudget caps the approximate memory held by the store. When a
+// write pushes usage over budget the oldest comments, other than the one
+// written, are evicted until usage drops below 90% of it, and onEvict (if
+// non-nil) is called with the number of comments removed and the resulting
+// usage. A budget <= 0 disables the cap.
+func WithMemoryBudget(budget int64, onEvict func(evicted int, bytes int64)) Option {
+    return func(s *CommentStore) {
+        s.budget = budget
+        s.onEvict = onEvict
+    }
+}
+
+// WithTombstones makes Delete keep a tombstone for each deleted comment for
+// ttl, retrievable with Deleted. A ttl <= 0 keeps no tombstones.
+func WithTombstones(ttl time.Duration) Option {
+    return func(s *CommentStore) {
+        s.tombstoneTTL = ttl
+    }
+}
+
+// WithClock replaces the clock used for creation times, expiry and
+// tombstones.
+func WithClock(now func() time.Time) Option {
+    return func(s *CommentStore) {
+        s.now = now
+    }
+}
+
+// WithIDGenerator sets the function generating comment IDs. Pass
+// util.GenerateSortableID for IDs that sort in creation order, which pair
+// well with paginating by ID.
+func WithIDGenerator(newID func() string) Option {
+    return func(s *CommentStore) {
+        s.newID = newID
+    }
+}
+
+// WithTracerProvider sets where the store's spans go, the global provider
+// unless set.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+    return func(s *CommentStore) {
+        s.tracer = tp.Tracer(tracerName)
+    }
+}
+
+// tracerName is the instrumentation name of the store's spans.
+const tracerName = "web-service/internal/storage"
+
+func NewCommentStore(opts ...Option) *CommentStore {
+    s := &CommentStore{
+        comments:    make(map[string]Comment),
+        reactions:   make(map[string]map[string]struct{}),
+        flags:       NewFlagStore(),
+        tombstones:  make(map[string]Tombstone),
+        now:         time.Now,
+        newID:       util.GenerateID,
+        tracer:      otel.Tracer(tracerName),
+        subscribers: make(map[chan Event]struct{}),
+    }
+    for _, opt := range opts {
+        opt(s)
+    }
+    s.flags.now = s.now
+    return s
+}
+
+// commentOverhead approximates the fixed cost of a map entry: the key's
+// string header plus the Comment struct itself.
+const commentOverhead = int64(unsafe.Sizeof("")) + int64(unsafe.Sizeof(Comment{}))
+
+// sizeOf returns the approximate number of bytes c holds in the store.
+func sizeOf(c Comment) int64 {
+    size := commentOverhead + int64(len(c.ID)*2+len(c.Content)+len(c.Author)+len(c.UserID))
+    for _, a := range c.Attachments {
+        size += int64(unsafe.Sizeof(a)) + int64(len(a))
+    }
+    return size
+}
+
+// reactionOverhead approximates the cost of one entry in a reaction set.
+const reactionOverhead = int64(unsafe.Sizeof(""))
+
+// put stores c, keeping the byte accounting in step. Callers must hold mu.
+func (s *CommentStore) put(c Comment) {
+    if old, exists := s.comments[c.ID]; exists {
+        s.bytes -= sizeOf(old)
+    }
+    c.ReactionCount = 0
+    c.Attachments = slices.Clone(c.Attachments)
+    s.comments[c.ID] = c
+    s.bytes += sizeOf(c)
+    s.touch()
+}
+
+// touch records a mutation. Callers must hold mu.
+func (s *CommentStore) touch() {
+    s.writes++
+    s.modified = s.now()
+}
+
+// remove deletes the comment with id, keeping the byte accounting in step.
+// Callers must hold mu.
+func (s *CommentStore) remove(id string) {
+    if old, exists := s.comments[id]; exists {
+        s.bytes -= sizeOf(old)
+        delete(s.comments, id)
+        s.touch()
+    }
+    for userID := range s.reactions[id] {
+        s.bytes -= reactionOverhead + int64(len(userID))
+    }
+    delete(s.reactions, id)
+    s.flags.removeComment(id)
+}
+
+// startSpan starts a child span of ctx for the store operation op.
+func (s *CommentStore) startSpan(ctx context.Context, op string) (context.Context, trace.Span) {
+    return s.tracer.Start(ctx, "CommentStore."+op)
+}
+
+// spanError records err on span and returns it.
+func spanError(span trace.Span, err error) error {
+    span.RecordError(err)
+    span.SetStatus(codes.Error, err.Error())
+    return err
+}
+
+// Flags returns the store's moderation flags.
+func (s *CommentStore) Flags() *FlagStore {
+    return s.flags
+}
+
+// stamp normalizes a time before it is stored: in UTC, without a monotonic
+// reading, truncated to the millisecond the API shows. A stored time then
+// reads back exactly as it was shown.
+func stamp(t time.Time) time.Time {
+    return t.UTC().Truncate(time.Millisecond)
+}
+
+// Expired reports whether c has an expiry time at or before now.
+func (c Comment) Expired(now time.Time) bool {
+    return !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt)
+}
+
+// lookup returns the comment with id if it exists and hasn't expired.
+// Callers must hold mu.
+func (s *CommentStore) lookup(id string) (Comment, bool) {
+    c, exists := s.comments[id]
+    if !exists || c.Expired(s.now()) {
+        return Comment{}, false
+    }
+    return c, true
+}
+
+// withReactions fills in c's reaction count, copying its attachments so
+// the caller can't change the stored comment through them. Callers must
+// hold mu.
+func (s *CommentStore) withReactions(c Comment) Comment {
+    c.Attachments = slices.Clone(c.Attachments)
+    c.ReactionCount = len(s.reactions[c.ID])
+    return c
+}
+
+// enforceBudget evicts the oldest comments while usage exceeds the budget,
+// publishing a deleted event for each that hadn't expired. The comment with
+// id keep, the one the triggering write stored, is never evicted: the write
+// succeeded, so its comment must still be there to read. Callers must hold
+// mu for writing.
+func (s *CommentStore) enforceBudget(keep string) {
+    if s.budget <= 0 || s.bytes <= s.budget {
+        return
+    }
+
+    oldest := make([]Comment, 0, len(s.comments))
+    for _, c := range s.comments {
+        if c.ID != keep {
+            oldest = append(oldest, c)
+        }
+    }
+    sort.Slice(oldest, func(i, j int) bool {
+        return oldest[i].CreatedAt.Before(oldest[j].CreatedAt)
+    })
+
+    now := s.now()
+    target := s.budget / 10 * 9
+    evicted := 0
+    for _, c := range oldest {
+        if s.bytes <= target {
+            break
+        }
+        // Readers skip expired comments already, so only live ones are
+        // news to subscribers
+        live := !c.Expired(now)
+        if live {
+            c = s.withReactions(c)
+        }
+        s.remove(c.ID)
+        evicted++
+        if live {
+            s.publish(Event{Type: EventDeleted, Comment: c})
+        }
+    }
+
+    if s.onEvict != nil {
+        s.onEvict(evicted, s.bytes)
+    }
+}
+
+// MemoryUsage returns the approximate number of bytes held by the store.
+func (s *CommentStore) MemoryUsage(ctx context.Context) (int64, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
+    }
+
+    return s.bytes, nil
+}
+
+// Ping reports whether the store can serve requests. The in-memory store
+// is always reachable, so this only confirms the lock can be taken before
+// ctx is done.
+func (s *CommentStore) Ping(ctx context.Context) error {
+    if err := s.inject(ctx, "Ping", ""); err != nil {
+        return err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    default:
+    }
+
+    return nil
 }
 
-func NewCommentStore() *CommentStore {
-    return &CommentStore{
-        comments: make(map[string]Comment),
+// Position returns the store's current write position, which increases
+// with every mutation. Reads from the in-memory store always reflect every
+// write it has accepted, so any position it returned is already satisfied.
+func (s *CommentStore) Position(ctx context.Context) (uint64, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
     }
+
+    return s.writes, nil
 }
 
+// Now returns the time by the store's clock, which stamps comments and
+// decides when they expire, so that callers working out lifetimes agree
+// with it.
+func (s *CommentStore) Now() time.Time {
+    return s.now()
+}
+
+// LastModified returns when the comments last changed: the later of the
+// last mutation, a flag being added or removed, and a comment expiring. It
+// is zero if nothing has changed since the store was created.
+func (s *CommentStore) LastModified(ctx context.Context) (time.Time, error) {
+    if err := s.inject(ctx, "LastModified", ""); err != nil {
+        return time.Time{}, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return time.Time{}, ctx.Err()
+    default:
+    }
+
+    modified := s.modified
+    if flagged := s.flags.lastModified(); flagged.After(modified) {
+        modified = flagged
+    }
+    now := s.now()
+    for _, c := range s.comments {
+        if c.Expired(now) && c.ExpiresAt.After(modified) {
+            modified = c.ExpiresAt
+        }
+    }
+    return modified, nil
+}
+
+// Create stores c as a new comment at version 1. It gets a generated ID
+// unless c.ID is set, as when importing comments, and an ID already in use,
+// even by an expired comment not yet removed, is refused with ErrConflict
+// rather than overwritten.
 func (s *CommentStore) Create(ctx context.Context, c Comment) (Comment, error) {
+    ctx, span := s.startSpan(ctx, "Create")
+    defer span.End()
+
+    if err := s.inject(ctx, "Create", ""); err != nil {
+        return Comment{}, spanError(span, err)
+    }
+
     s.mu.Lock()
     defer s.mu.Unlock()
 
     select {
     case <-ctx.Done():
-        return Comment{}, ctx.Err()
+        return Comment{}, spanError(span, ctx.Err())
     default:
     }
 
-    c.ID = util.GenerateID()
-    c.CreatedAt = time.Now()
-    s.comments[c.ID] = c
+    if c.ID == "" {
+        c.ID = s.newID()
+    }
+    if _, exists := s.comments[c.ID]; exists {
+        return Comment{}, spanError(span, ErrConflict)
+    }
+    c.CreatedAt = stamp(s.now())
+    c.ExpiresAt = stamp(c.ExpiresAt)
+    c.Version = 1
+    s.put(c)
+    s.publish(Event{Type: EventCreated, Comment: c})
+    s.enforceBudget(c.ID)
     return c, nil
 }
 
 func (s *CommentStore) List(ctx context.Context) ([]Comment, error) {
+    ctx, span := s.startSpan(ctx, "List")
+    defer span.End()
+
+    if err := s.inject(ctx, "List", ""); err != nil {
+        return nil, spanError(span, err)
+    }
+
     s.mu.RLock()
     defer s.mu.RUnlock()
 
     select {
     case <-ctx.Done():
-        return nil, ctx.Err()
+        return nil, spanError(span, ctx.Err())
     default:
     }
 
+    now := s.now()
     comments := make([]Comment, 0, len(s.comments))
     for _, c := range s.comments {
-        comments = append(comments, c)
+        if !c.Expired(now) {
+            comments = append(comments, s.withReactions(c))
+        }
     }
     return comments, nil
 }
 
+// Range calls fn for each comment until fn returns false. Unlike List it
+// does not copy the store into a new slice, so callers that transform
+// comments into another representation avoid an intermediate allocation.
+// fn runs under the store's read lock and must not call back into the store.
+func (s *CommentStore) Range(ctx context.Context, fn func(Comment) bool) error {
+    ctx, span := s.startSpan(ctx, "Range")
+    defer span.End()
+
+    if err := s.inject(ctx, "Range", ""); err != nil {
+        return spanError(span, err)
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return spanError(span, ctx.Err())
+    default:
+    }
+
+    now := s.now()
+    for _, c := range s.comments {
+        if c.Expired(now) {
+            continue
+        }
+        if !fn(s.withReactions(c)) {
+            break
+        }
+    }
+    return nil
+}
+
 func (s *CommentStore) Get(ctx context.Context, id string) (Comment, error) {
+    if err := s.inject(ctx, "Get", id); err != nil {
+        return Comment{}, err
+    }
+
     s.mu.RLock()
     defer s.mu.RUnlock()
 
@@ -76,14 +511,18 @@ func (s *CommentStore) Get(ctx context.Context, id string) (Comment, error) {
     default:
     }
 
-    comment, exists := s.comments[id]
+    comment, exists := s.lookup(id)
     if !exists {
         return Comment{}, ErrNotFound
     }
-    return comment, nil
+    return s.withReactions(comment), nil
 }
 
 func (s *CommentStore) Delete(ctx context.Context, id string) error {
+    if err := s.inject(ctx, "Delete", id); err != nil {
+        return err
+    }
+
     s.mu.Lock()
     defer s.mu.Unlock()
 
@@ -93,15 +532,75 @@ func (s *CommentStore) Delete(ctx context.Context, id string) error {
     default:
     }
 
-    if _, exists := s.comments[id]; !exists {
+    existing, exists := s.lookup(id)
+    if !exists {
         return ErrNotFound
     }
 
-    delete(s.comments, id)
+    s.remove(id)
+    if s.tombstoneTTL > 0 {
+        now := s.now()
+        for tid, t := range s.tombstones {
+            if now.Sub(t.DeletedAt) > s.tombstoneTTL {
+                delete(s.tombstones, tid)
+            }
+        }
+        s.tombstones[id] = Tombstone{UserID: existing.UserID, DeletedAt: stamp(now)}
+    }
+    s.publish(Event{Type: EventDeleted, Comment: existing})
     return nil
 }
 
-func (s *CommentStore) Update(ctx context.Context, id string, c Comment) (Comment, error) {
+// Deleted returns the tombstone for a comment deleted within the tombstone
+// TTL, or ErrNotFound if there is none.
+func (s *CommentStore) Deleted(ctx context.Context, id string) (Tombstone, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return Tombstone{}, ctx.Err()
+    default:
+    }
+
+    t, exists := s.tombstones[id]
+    if !exists || s.now().Sub(t.DeletedAt) > s.tombstoneTTL {
+        return Tombstone{}, ErrNotFound
+    }
+    return t, nil
+}
+
+// Tombstones returns the tombstones of comments deleted within the
+// tombstone TTL, keyed by comment ID.
+func (s *CommentStore) Tombstones(ctx context.Context) (map[string]Tombstone, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    tombstones := make(map[string]Tombstone, len(s.tombstones))
+    for id, t := range s.tombstones {
+        if now.Sub(t.DeletedAt) <= s.tombstoneTTL {
+            tombstones[id] = t
+        }
+    }
+    return tombstones, nil
+}
+
+// Update replaces the content, author and attachments of the comment with
+// id. If version is non-zero and the comment is no longer at that version,
+// nothing is changed and ErrVersionConflict is returned along with the
+// comment as it now is. A zero version updates whatever the version.
+func (s *CommentStore) Update(ctx context.Context, id string, version int64, c Comment) (Comment, error) {
+    if err := s.inject(ctx, "Update", id); err != nil {
+        return Comment{}, err
+    }
+
     s.mu.Lock()
     defer s.mu.Unlock()
 
@@ -111,23 +610,127 @@ func (s *CommentStore) Update(ctx context.Context, id string, c Comment) (Commen
     default:
     }
 
-    existing, exists := s.comments[id]
+    existing, exists := s.lookup(id)
     if !exists {
         return Comment{}, ErrNotFound
     }
+    if version != 0 && existing.Version != version {
+        return s.withReactions(existing), ErrVersionConflict
+    }
 
     // Preserve creation metadata
     c.ID = existing.ID
     c.CreatedAt = existing.CreatedAt
     c.UserID = existing.UserID // Prevent user ID changes
+    c.ExpiresAt = existing.ExpiresAt
+    c.Version = existing.Version + 1
 
-    s.comments[id] = c
+    s.put(c)
+    c = s.withReactions(c)
+    s.publish(Event{Type: EventUpdated, Comment: c})
+    s.enforceBudget(c.ID)
     return c, nil
 }
 
+// AddReaction records that userID reacted to the comment with id. Reacting
+// twice is not an error; the returned count is the comment's reaction count
+// afterwards.
+func (s *CommentStore) AddReaction(ctx context.Context, id, userID string) (int, error) {
+    if err := s.inject(ctx, "AddReaction", id); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
+    }
+
+    if _, exists := s.lookup(id); !exists {
+        return 0, ErrNotFound
+    }
+
+    users, ok := s.reactions[id]
+    if !ok {
+        users = make(map[string]struct{})
+        s.reactions[id] = users
+    }
+    if _, reacted := users[userID]; !reacted {
+        users[userID] = struct{}{}
+        s.bytes += reactionOverhead + int64(len(userID))
+        s.touch()
+    }
+    return len(users), nil
+}
+
+// RemoveReaction withdraws userID's reaction to the comment with id.
+// Removing a reaction that was never made is not an error.
+func (s *CommentStore) RemoveReaction(ctx context.Context, id, userID string) (int, error) {
+    if err := s.inject(ctx, "RemoveReaction", id); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
+    }
+
+    if _, exists := s.lookup(id); !exists {
+        return 0, ErrNotFound
+    }
+
+    users := s.reactions[id]
+    if _, reacted := users[userID]; reacted {
+        delete(users, userID)
+        s.bytes -= reactionOverhead + int64(len(userID))
+        s.touch()
+    }
+    if len(users) == 0 {
+        delete(s.reactions, id)
+    }
+    return len(users), nil
+}
+
+// ReactedTo returns the set of comment IDs userID has reacted to.
+func (s *CommentStore) ReactedTo(ctx context.Context, userID string) (map[string]bool, error) {
+    if err := s.inject(ctx, "ReactedTo", ""); err != nil {
+        return nil, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    default:
+    }
+
+    reacted := make(map[string]bool)
+    for id, users := range s.reactions {
+        if _, ok := users[userID]; ok {
+            if _, visible := s.lookup(id); visible {
+                reacted[id] = true
+            }
+        }
+    }
+    return reacted, nil
+}
+
 // Optional: Add methods for querying comments
 
 func (s *CommentStore) ListByUser(ctx context.Context, userID string) ([]Comment, error) {
+    if err := s.inject(ctx, "ListByUser", ""); err != nil {
+        return nil, err
+    }
+
     s.mu.RLock()
     defer s.mu.RUnlock()
 
@@ -137,55 +740,354 @@ func (s *CommentStore) ListByUser(ctx context.Context, userID string) ([]Comment
     default:
     }
 
+    now := s.now()
     var comments []Comment
     for _, c := range s.comments {
-        if c.UserID == userID {
-            comments = append(comments, c)
+        if c.UserID == userID && !c.Expired(now) {
+            comments = append(comments, s.withReactions(c))
         }
     }
     return comments, nil
 }
 
-func (s *CommentStore) DeleteByUser(ctx context.Context, userID string) error {
+// CountByUser returns the number of userID's comments, which is how many
+// DeleteByUser would remove.
+func (s *CommentStore) CountByUser(ctx context.Context, userID string) (int, error) {
+    if err := s.inject(ctx, "CountByUser", ""); err != nil {
+        return 0, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    count := 0
+    for _, c := range s.comments {
+        if c.UserID == userID && !c.Expired(now) {
+            count++
+        }
+    }
+    return count, nil
+}
+
+// DeleteByUser removes all of userID's comments and returns how many there
+// were. Expired comments are removed too but not counted, as they were
+// already gone to readers. No tombstones are kept.
+func (s *CommentStore) DeleteByUser(ctx context.Context, userID string) (int, error) {
+    if err := s.inject(ctx, "DeleteByUser", ""); err != nil {
+        return 0, err
+    }
+
     s.mu.Lock()
     defer s.mu.Unlock()
 
     select {
     case <-ctx.Done():
-        return ctx.Err()
+        return 0, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    count := 0
+    for id, c := range s.comments {
+        if c.UserID != userID {
+            continue
+        }
+        s.remove(id)
+        if !c.Expired(now) {
+            count++
+            s.publish(Event{Type: EventDeleted, Comment: c})
+        }
+    }
+    return count, nil
+}
+
+// EraseUser removes userID's comments and reactions. Tombstones of their
+// deleted comments are kept so repeated deletes still behave, but lose the
+// owner and so are anonymized.
+func (s *CommentStore) EraseUser(ctx context.Context, userID string) (erasure.Report, error) {
+    if err := s.inject(ctx, "EraseUser", ""); err != nil {
+        return erasure.Report{}, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return erasure.Report{}, ctx.Err()
     default:
     }
 
+    now := s.now()
+    var report erasure.Report
     for id, c := range s.comments {
         if c.UserID == userID {
-            delete(s.comments, id)
+            s.remove(id)
+            report.Removed++
+            if !c.Expired(now) {
+                s.publish(Event{Type: EventDeleted, Comment: c})
+            }
         }
     }
-    return nil
+    for _, users := range s.reactions {
+        if _, reacted := users[userID]; reacted {
+            delete(users, userID)
+            s.bytes -= reactionOverhead + int64(len(userID))
+            s.touch()
+            report.Removed++
+        }
+    }
+    for id, t := range s.tombstones {
+        if t.UserID == userID {
+            t.UserID = ""
+            s.tombstones[id] = t
+            report.Anonymized++
+        }
+    }
+    return report, nil
 }
 
-// Optional: Add a method to clean up old comments
-func (s *CommentStore) DeleteOlderThan(ctx context.Context, age time.Duration) error {
+// RenameAuthor sets the Author of every comment by userID to author in one
+// batch and returns how many comments were changed.
+func (s *CommentStore) RenameAuthor(ctx context.Context, userID, author string) (int, error) {
+    if err := s.inject(ctx, "RenameAuthor", ""); err != nil {
+        return 0, err
+    }
+
     s.mu.Lock()
     defer s.mu.Unlock()
 
     select {
     case <-ctx.Done():
-        return ctx.Err()
+        return 0, ctx.Err()
     default:
     }
 
-    cutoff := time.Now().Add(-age)
+    now := s.now()
+    updated := 0
+    for _, c := range s.comments {
+        if c.UserID == userID && c.Author != author && !c.Expired(now) {
+            c.Author = author
+            c.Version++
+            s.put(c)
+            updated++
+            s.publish(Event{Type: EventUpdated, Comment: s.withReactions(c)})
+        }
+    }
+    s.enforceBudget("")
+    return updated, nil
+}
+
+// DeleteOlderThan removes comments created more than age ago and returns
+// how many were removed.
+func (s *CommentStore) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+    if err := s.inject(ctx, "DeleteOlderThan", ""); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    cutoff := now.Add(-age)
+    deleted := 0
     for id, c := range s.comments {
         if c.CreatedAt.Before(cutoff) {
-            delete(s.comments, id)
+            s.remove(id)
+            deleted++
+            if !c.Expired(now) {
+                s.publish(Event{Type: EventDeleted, Comment: c})
+            }
         }
     }
-    return nil
+    return deleted, nil
+}
+
+// DeleteExpired physically removes expired comments, which reads already
+// skip, and returns how many were removed.
+func (s *CommentStore) DeleteExpired(ctx context.Context) (int, error) {
+    if err := s.inject(ctx, "DeleteExpired", ""); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    deleted := 0
+    for id, c := range s.comments {
+        if c.Expired(now) {
+            s.remove(id)
+            deleted++
+        }
+    }
+    return deleted, nil
+}
+
+// Search returns the comments matching f, newest first, skipping offset
+// matches and returning at most limit of them, along with the total number
+// of matches. A limit <= 0 returns every match after offset.
+func (s *CommentStore) Search(ctx context.Context, f Filter, offset, limit int) ([]Comment, int, error) {
+    if err := s.inject(ctx, "Search", ""); err != nil {
+        return nil, 0, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return nil, 0, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    var matches []Comment
+    for _, c := range s.comments {
+        if !c.Expired(now) && f.Match(c) {
+            matches = append(matches, s.withReactions(c))
+        }
+    }
+    sort.Slice(matches, func(i, j int) bool {
+        if matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+            return matches[i].ID < matches[j].ID
+        }
+        return matches[i].CreatedAt.After(matches[j].CreatedAt)
+    })
+
+    total := len(matches)
+    if offset >= total {
+        return []Comment{}, total, nil
+    }
+    matches = matches[offset:]
+    if limit > 0 && limit < len(matches) {
+        matches = matches[:limit]
+    }
+    return matches, total, nil
+}
+
+// Stats summarises the comments held by the store.
+type Stats struct {
+    Total    int
+    ByAuthor map[string]int
+    ByUser   map[string]int
+    Oldest   time.Time
+    Newest   time.Time
+}
+
+// Stats aggregates the store in a single pass under one read lock, so the
+// figures are consistent with each other.
+func (s *CommentStore) Stats(ctx context.Context) (Stats, error) {
+    if err := s.inject(ctx, "Stats", ""); err != nil {
+        return Stats{}, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return Stats{}, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    stats := Stats{
+        ByAuthor: make(map[string]int),
+        ByUser:   make(map[string]int),
+    }
+    for _, c := range s.comments {
+        if c.Expired(now) {
+            continue
+        }
+        stats.Total++
+        stats.ByAuthor[c.Author]++
+        stats.ByUser[c.UserID]++
+        if stats.Oldest.IsZero() || c.CreatedAt.Before(stats.Oldest) {
+            stats.Oldest = c.CreatedAt
+        }
+        if c.CreatedAt.After(stats.Newest) {
+            stats.Newest = c.CreatedAt
+        }
+    }
+    return stats, nil
+}
+
+// CountSince returns the number of comments created at or after t.
+func (s *CommentStore) CountSince(ctx context.Context, t time.Time) (int, error) {
+    if err := s.inject(ctx, "CountSince", ""); err != nil {
+        return 0, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    count := 0
+    for _, c := range s.comments {
+        if !c.Expired(now) && !c.CreatedAt.Before(t) {
+            count++
+        }
+    }
+    return count, nil
+}
+
+// CountByAuthor returns the number of comments under each author name.
+func (s *CommentStore) CountByAuthor(ctx context.Context) (map[string]int, error) {
+    if err := s.inject(ctx, "CountByAuthor", ""); err != nil {
+        return nil, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    default:
+    }
+
+    now := s.now()
+    counts := make(map[string]int)
+    for _, c := range s.comments {
+        if !c.Expired(now) {
+            counts[c.Author]++
+        }
+    }
+    return counts, nil
 }
 
 // Optional: Add a method to count comments
 func (s *CommentStore) Count(ctx context.Context) (int, error) {
+    if err := s.inject(ctx, "Count", ""); err != nil {
+        return 0, err
+    }
+
     s.mu.RLock()
     defer s.mu.RUnlock()
 
@@ -195,5 +1097,12 @@ func (s *CommentStore) Count(ctx context.Context) (int, error) {
     default:
     }
 
-    return len(s.comments), nil
-}
\ No newline at end of file
+    now := s.now()
+    count := 0
+    for _, c := range s.comments {
+        if !c.Expired(now) {
+            count++
+        }
+    }
+    return count, nil
+}
@@ -0,0 +1,80 @@
+// internal/storage/comments_bench_test.go
+
+package storage
+
+import (
+    "context"
+    "runtime"
+    "strings"
+    "testing"
+)
+
+// BenchmarkWriteAndList measures a steady write+list workload, reporting
+// GC pause time alongside allocations so the full-copy List can be compared
+// with the Range iterator:
+//
+//	go test -run=^$ -bench=WriteAndList -benchmem ./internal/storage
+func BenchmarkWriteAndList(b *testing.B) {
+    const seed = 50_000
+
+    type view struct {
+        ID, Content string
+    }
+
+    workloads := []struct {
+        name string
+        list func(ctx context.Context, s *CommentStore, buf []view) []view
+    }{
+        {
+            name: "List",
+            list: func(ctx context.Context, s *CommentStore, _ []view) []view {
+                comments, _ := s.List(ctx)
+                out := make([]view, len(comments))
+                for i, c := range comments {
+                    out[i] = view{ID: c.ID, Content: c.Content}
+                }
+                return out
+            },
+        },
+        {
+            name: "Range",
+            list: func(ctx context.Context, s *CommentStore, buf []view) []view {
+                buf = buf[:0]
+                s.Range(ctx, func(c Comment) bool {
+                    buf = append(buf, view{ID: c.ID, Content: c.Content})
+                    return true
+                })
+                return buf
+            },
+        },
+    }
+
+    content := strings.Repeat("x", 200)
+    for _, wl := range workloads {
+        b.Run(wl.name, func(b *testing.B) {
+            ctx := context.Background()
+            s := NewCommentStore()
+            for i := 0; i < seed; i++ {
+                s.Create(ctx, Comment{Content: content, Author: "bench", UserID: "bench"})
+            }
+
+            var buf []view
+            runtime.GC()
+            var before runtime.MemStats
+            runtime.ReadMemStats(&before)
+
+            b.ReportAllocs()
+            b.ResetTimer()
+            for i := 0; i < b.N; i++ {
+                s.Create(ctx, Comment{Content: content, Author: "bench", UserID: "bench"})
+                buf = wl.list(ctx, s, buf)
+            }
+            b.StopTimer()
+
+            var after runtime.MemStats
+            runtime.ReadMemStats(&after)
+            b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+            b.ReportMetric(float64(after.NumGC-before.NumGC), "gc-cycles")
+        })
+    }
+}
@@ -0,0 +1,642 @@
+// internal/storage/comments_test.go
+
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// fakeClock is a settable clock for WithClock.
+type fakeClock struct {
+    now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestCommentExpiry(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+    s := NewCommentStore(WithClock(clock.Now))
+
+    expiresAt := clock.now.Add(time.Hour)
+    ephemeral, err := s.Create(ctx, Comment{Content: "announcement", Author: "ops", UserID: "u1", ExpiresAt: expiresAt})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Create(ctx, Comment{Content: "permanent", Author: "ops", UserID: "u1"}); err != nil {
+        t.Fatal(err)
+    }
+
+    visible := func() int {
+        t.Helper()
+        n, err := s.Count(ctx)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return n
+    }
+
+    // Just before the boundary the comment is still readable
+    clock.now = expiresAt.Add(-time.Nanosecond)
+    if got := visible(); got != 2 {
+        t.Fatalf("expected 2 visible comments before expiry, got %d", got)
+    }
+    if _, err := s.Get(ctx, ephemeral.ID); err != nil {
+        t.Fatalf("expected comment before expiry, got %v", err)
+    }
+
+    // At the boundary every read path hides it, before any sweep
+    clock.now = expiresAt
+    if _, err := s.Get(ctx, ephemeral.ID); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Get: expected ErrNotFound, got %v", err)
+    }
+    if got := visible(); got != 1 {
+        t.Errorf("Count: expected 1, got %d", got)
+    }
+    if list, _ := s.List(ctx); len(list) != 1 {
+        t.Errorf("List: expected 1 comment, got %d", len(list))
+    }
+    if list, _ := s.ListByUser(ctx, "u1"); len(list) != 1 {
+        t.Errorf("ListByUser: expected 1 comment, got %d", len(list))
+    }
+    ranged := 0
+    s.Range(ctx, func(Comment) bool { ranged++; return true })
+    if ranged != 1 {
+        t.Errorf("Range: expected 1 comment, got %d", ranged)
+    }
+    if _, total, _ := s.Search(ctx, Filter{}, 0, 0); total != 1 {
+        t.Errorf("Search: expected 1 match, got %d", total)
+    }
+    if stats, _ := s.Stats(ctx); stats.Total != 1 || stats.ByAuthor["ops"] != 1 {
+        t.Errorf("Stats: expected 1 comment, got %+v", stats)
+    }
+    if _, err := s.Update(ctx, ephemeral.ID, 0, Comment{Content: "edit"}); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Update: expected ErrNotFound, got %v", err)
+    }
+    if _, err := s.AddReaction(ctx, ephemeral.ID, "u2"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("AddReaction: expected ErrNotFound, got %v", err)
+    }
+
+    // The sweeper physically removes it
+    before, _ := s.MemoryUsage(ctx)
+    n, err := s.DeleteExpired(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if n != 1 {
+        t.Errorf("DeleteExpired: expected 1 removed, got %d", n)
+    }
+    if after, _ := s.MemoryUsage(ctx); after >= before {
+        t.Errorf("expected memory usage to drop below %d, got %d", before, after)
+    }
+    if n, _ := s.DeleteExpired(ctx); n != 0 {
+        t.Errorf("second DeleteExpired: expected 0 removed, got %d", n)
+    }
+}
+
+func TestUpdateKeepsExpiry(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+    s := NewCommentStore(WithClock(clock.Now))
+
+    expiresAt := clock.now.Add(time.Hour)
+    c, err := s.Create(ctx, Comment{Content: "a", Author: "ops", ExpiresAt: expiresAt})
+    if err != nil {
+        t.Fatal(err)
+    }
+    updated, err := s.Update(ctx, c.ID, 0, Comment{Content: "b", Author: "ops"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !updated.ExpiresAt.Equal(expiresAt) {
+        t.Errorf("expected expiry %v to survive update, got %v", expiresAt, updated.ExpiresAt)
+    }
+}
+
+func TestUpdateVersion(t *testing.T) {
+    ctx := context.Background()
+    s := NewCommentStore()
+
+    c, err := s.Create(ctx, Comment{Content: "a", Author: "ops"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c.Version != 1 {
+        t.Fatalf("expected a new comment at version 1, got %d", c.Version)
+    }
+
+    // Two writers edit from the same read; the second is refused
+    first, err := s.Update(ctx, c.ID, c.Version, Comment{Content: "b", Author: "ops"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if first.Version != 2 {
+        t.Errorf("expected version 2 after update, got %d", first.Version)
+    }
+    current, err := s.Update(ctx, c.ID, c.Version, Comment{Content: "c", Author: "ops"})
+    if !errors.Is(err, ErrVersionConflict) {
+        t.Fatalf("expected ErrVersionConflict, got %v", err)
+    }
+    if current.Version != 2 || current.Content != "b" {
+        t.Errorf("expected the conflict to return version 2 with content b, got %d with %q", current.Version, current.Content)
+    }
+
+    // A zero version updates whatever the version
+    if updated, err := s.Update(ctx, c.ID, 0, Comment{Content: "d", Author: "ops"}); err != nil || updated.Version != 3 {
+        t.Errorf("expected unconditional update to version 3, got %d, %v", updated.Version, err)
+    }
+
+    if n, err := s.RenameAuthor(ctx, "", "admin"); err != nil || n != 1 {
+        t.Fatalf("expected 1 renamed, got %d, %v", n, err)
+    }
+    if got, _ := s.Get(ctx, c.ID); got.Version != 4 {
+        t.Errorf("expected rename to bump the version to 4, got %d", got.Version)
+    }
+}
+
+func TestAttachmentsAreCopied(t *testing.T) {
+    ctx := context.Background()
+    s := NewCommentStore()
+
+    attachments := []string{"https://example.com/a.png", "https://example.com/b.png"}
+    c, err := s.Create(ctx, Comment{Content: "pics", Author: "ops", Attachments: attachments})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Neither the caller's slice nor one read back reaches the store
+    attachments[0] = "https://evil.example/"
+    got, err := s.Get(ctx, c.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.Attachments[0] != "https://example.com/a.png" {
+        t.Errorf("expected the stored attachment unchanged, got %q", got.Attachments[0])
+    }
+    got.Attachments[1] = "https://evil.example/"
+    listed, err := s.List(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := []string{"https://example.com/a.png", "https://example.com/b.png"}; !slices.Equal(listed[0].Attachments, want) {
+        t.Errorf("expected %q, got %q", want, listed[0].Attachments)
+    }
+
+    // Updates replace the list, and an empty one clears it
+    updated, err := s.Update(ctx, c.ID, 0, Comment{Content: "pics", Author: "ops", Attachments: []string{"https://example.com/c.png"}})
+    if err != nil || !slices.Equal(updated.Attachments, []string{"https://example.com/c.png"}) {
+        t.Errorf("expected the attachments replaced, got %q, %v", updated.Attachments, err)
+    }
+    if cleared, err := s.Update(ctx, c.ID, 0, Comment{Content: "pics", Author: "ops"}); err != nil || len(cleared.Attachments) != 0 {
+        t.Errorf("expected the attachments cleared, got %q, %v", cleared.Attachments, err)
+    }
+}
+
+func TestEraseUser(t *testing.T) {
+    ctx := context.Background()
+    s := NewCommentStore(WithTombstones(time.Hour))
+
+    mine, err := s.Create(ctx, Comment{Content: "mine", Author: "Alice", UserID: "alice"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    deleted, err := s.Create(ctx, Comment{Content: "deleted", Author: "Alice", UserID: "alice"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    theirs, err := s.Create(ctx, Comment{Content: "theirs", Author: "Bob", UserID: "bob"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := s.Delete(ctx, deleted.ID); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.AddReaction(ctx, theirs.ID, "alice"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.AddReaction(ctx, theirs.ID, "bob"); err != nil {
+        t.Fatal(err)
+    }
+    before, err := s.MemoryUsage(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    report, err := s.EraseUser(ctx, "alice")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Removed != 2 || report.Anonymized != 1 {
+        t.Errorf("expected 2 removed and 1 anonymized, got %+v", report)
+    }
+    if _, err := s.Get(ctx, mine.ID); !errors.Is(err, ErrNotFound) {
+        t.Errorf("expected alice's comment gone, got %v", err)
+    }
+    c, err := s.Get(ctx, theirs.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c.ReactionCount != 1 {
+        t.Errorf("expected only bob's reaction left, got %d", c.ReactionCount)
+    }
+    if tomb, err := s.Deleted(ctx, deleted.ID); err != nil || tomb.UserID != "" {
+        t.Errorf("expected an anonymized tombstone, got %+v, %v", tomb, err)
+    }
+    if after, _ := s.MemoryUsage(ctx); after >= before {
+        t.Errorf("expected memory usage to drop from %d, got %d", before, after)
+    }
+
+    if report, err := s.EraseUser(ctx, "alice"); err != nil || report.Removed != 0 || report.Anonymized != 0 {
+        t.Errorf("expected an empty re-run, got %+v, %v", report, err)
+    }
+}
+
+func TestDeleteByUser(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+    s := NewCommentStore(WithClock(clock.Now))
+
+    expiring := clock.now.Add(time.Minute)
+    for _, c := range []Comment{
+        {Content: "one", Author: "Alice", UserID: "alice"},
+        {Content: "two", Author: "Alice", UserID: "alice"},
+        {Content: "expired", Author: "Alice", UserID: "alice", ExpiresAt: expiring},
+    } {
+        if _, err := s.Create(ctx, c); err != nil {
+            t.Fatal(err)
+        }
+    }
+    theirs, err := s.Create(ctx, Comment{Content: "theirs", Author: "Bob", UserID: "bob"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    clock.now = clock.now.Add(time.Hour)
+
+    if n, err := s.CountByUser(ctx, "alice"); err != nil || n != 2 {
+        t.Errorf("expected 2 counted, got %d, %v", n, err)
+    }
+    if n, err := s.DeleteByUser(ctx, "alice"); err != nil || n != 2 {
+        t.Errorf("expected 2 deleted, got %d, %v", n, err)
+    }
+    if comments, _ := s.ListByUser(ctx, "alice"); len(comments) != 0 {
+        t.Errorf("expected alice's comments gone, got %d", len(comments))
+    }
+    if _, err := s.Get(ctx, theirs.ID); err != nil {
+        t.Errorf("expected bob's comment kept, got %v", err)
+    }
+    if n, err := s.DeleteByUser(ctx, "alice"); err != nil || n != 0 {
+        t.Errorf("expected nothing left to delete, got %d, %v", n, err)
+    }
+}
+
+func TestWithIDGenerator(t *testing.T) {
+    ctx := context.Background()
+    n := 0
+    s := NewCommentStore(WithIDGenerator(func() string {
+        n++
+        return fmt.Sprintf("id-%d", n)
+    }))
+
+    c, err := s.Create(ctx, Comment{Content: "a", Author: "ops"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c.ID != "id-1" {
+        t.Errorf("expected ID from the generator, got %q", c.ID)
+    }
+    if _, err := s.Get(ctx, "id-1"); err != nil {
+        t.Errorf("expected comment stored under generated ID: %v", err)
+    }
+}
+
+func TestCreateWithID(t *testing.T) {
+    ctx := context.Background()
+    s := NewCommentStore(WithIDGenerator(func() string { return "generated" }))
+
+    c, err := s.Create(ctx, Comment{ID: "imported-1", Content: "a", Author: "ops"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c.ID != "imported-1" {
+        t.Errorf("expected the supplied ID kept, got %q", c.ID)
+    }
+
+    // Concurrent creates under one ID: exactly one wins, the rest conflict
+    var wg sync.WaitGroup
+    results := make(chan error, 8)
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            _, err := s.Create(ctx, Comment{ID: "imported-2", Content: fmt.Sprint(i), Author: "ops"})
+            results <- err
+        }(i)
+    }
+    wg.Wait()
+    close(results)
+    created := 0
+    for err := range results {
+        switch {
+        case err == nil:
+            created++
+        case !errors.Is(err, ErrConflict):
+            t.Errorf("expected ErrConflict, got %v", err)
+        }
+    }
+    if created != 1 {
+        t.Errorf("expected exactly one create to succeed, got %d", created)
+    }
+
+    // A generated ID that collides is refused too, not overwritten
+    if _, err := s.Create(ctx, Comment{Content: "first", Author: "ops"}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Create(ctx, Comment{Content: "second", Author: "ops"}); !errors.Is(err, ErrConflict) {
+        t.Errorf("expected ErrConflict for a colliding generated ID, got %v", err)
+    }
+    if got, err := s.Get(ctx, "generated"); err != nil || got.Content != "first" {
+        t.Errorf("expected the first comment kept, got %q, %v", got.Content, err)
+    }
+    if n, _ := s.Count(ctx); n != 3 {
+        t.Errorf("expected 3 comments, got %d", n)
+    }
+}
+
+func TestTombstones(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+    s := NewCommentStore(WithTombstones(time.Hour), WithClock(clock.Now))
+
+    var ids []string
+    for _, user := range []string{"alice", "bob"} {
+        c, err := s.Create(ctx, Comment{Content: "bye", Author: user, UserID: user})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if err := s.Delete(ctx, c.ID); err != nil {
+            t.Fatal(err)
+        }
+        ids = append(ids, c.ID)
+        clock.now = clock.now.Add(40 * time.Minute)
+    }
+
+    tombstones, err := s.Tombstones(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(tombstones) != 1 || tombstones[ids[1]].UserID != "bob" {
+        t.Errorf("expected only bob's unexpired tombstone, got %+v", tombstones)
+    }
+}
+
+func TestLastModified(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+    s := NewCommentStore(WithClock(clock.Now))
+
+    lastModified := func(t *testing.T) time.Time {
+        t.Helper()
+        modified, err := s.LastModified(ctx)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return modified
+    }
+
+    if modified := lastModified(t); !modified.IsZero() {
+        t.Errorf("expected zero for an untouched store, got %s", modified)
+    }
+
+    created := clock.now
+    c, err := s.Create(ctx, Comment{Content: "hi", Author: "alice", UserID: "alice", ExpiresAt: created.Add(time.Hour)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    clock.now = clock.now.Add(time.Minute)
+    if modified := lastModified(t); !modified.Equal(created) {
+        t.Errorf("expected the create at %s, got %s", created, modified)
+    }
+
+    flagged := clock.now
+    if _, _, err := s.Flags().Add(ctx, Flag{CommentID: c.ID, UserID: "bob"}); err != nil {
+        t.Fatal(err)
+    }
+    if modified := lastModified(t); !modified.Equal(flagged) {
+        t.Errorf("expected the flag at %s, got %s", flagged, modified)
+    }
+
+    clock.now = clock.now.Add(2 * time.Hour)
+    if modified := lastModified(t); !modified.Equal(c.ExpiresAt) {
+        t.Errorf("expected the expiry at %s, got %s", c.ExpiresAt, modified)
+    }
+
+    deleted := clock.now
+    if _, err := s.DeleteExpired(ctx); err != nil {
+        t.Fatal(err)
+    }
+    if modified := lastModified(t); !modified.Equal(deleted) {
+        t.Errorf("expected the delete at %s, got %s", deleted, modified)
+    }
+}
+
+func TestSubscribe(t *testing.T) {
+    ctx := context.Background()
+    s := NewCommentStore()
+
+    events, cancel := s.Subscribe(10)
+    c, err := s.Create(ctx, Comment{Content: "hello", Author: "Alice", UserID: "alice"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Update(ctx, c.ID, 0, Comment{Content: "hello again", Author: "Alice"}); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.Delete(ctx, c.ID); err != nil {
+        t.Fatal(err)
+    }
+
+    want := []struct {
+        typ     EventType
+        content string
+    }{
+        {EventCreated, "hello"},
+        {EventUpdated, "hello again"},
+        {EventDeleted, "hello again"},
+    }
+    for _, w := range want {
+        e := <-events
+        if e.Type != w.typ || e.Comment.ID != c.ID || e.Comment.Content != w.content {
+            t.Errorf("expected %s %q, got %s %q", w.typ, w.content, e.Type, e.Comment.Content)
+        }
+    }
+
+    cancel()
+    if _, ok := <-events; ok {
+        t.Error("expected the channel closed after cancel")
+    }
+    cancel()
+}
+
+func TestSubscribeBatchOperations(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+    s := NewCommentStore(WithClock(clock.Now))
+
+    create := func(userID string) Comment {
+        t.Helper()
+        c, err := s.Create(ctx, Comment{Content: "hello", Author: "Old", UserID: userID})
+        if err != nil {
+            t.Fatal(err)
+        }
+        return c
+    }
+    old := create("alice")
+    clock.now = clock.now.Add(48 * time.Hour)
+    renamed := create("bob")
+    erased := create("carol")
+
+    events, cancel := s.Subscribe(10)
+    defer cancel()
+
+    if _, err := s.DeleteOlderThan(ctx, 24*time.Hour); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.RenameAuthor(ctx, "bob", "New"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.EraseUser(ctx, "carol"); err != nil {
+        t.Fatal(err)
+    }
+
+    want := []struct {
+        typ    EventType
+        id     string
+        author string
+    }{
+        {EventDeleted, old.ID, "Old"},
+        {EventUpdated, renamed.ID, "New"},
+        {EventDeleted, erased.ID, "Old"},
+    }
+    for _, w := range want {
+        e := <-events
+        if e.Type != w.typ || e.Comment.ID != w.id || e.Comment.Author != w.author {
+            t.Errorf("expected %s of %s by %q, got %s of %s by %q", w.typ, w.id, w.author, e.Type, e.Comment.ID, e.Comment.Author)
+        }
+    }
+    select {
+    case e := <-events:
+        t.Errorf("unexpected event %+v", e)
+    default:
+    }
+}
+
+func TestSubscribeDropsSlowSubscriber(t *testing.T) {
+    ctx := context.Background()
+    s := NewCommentStore()
+
+    events, cancel := s.Subscribe(1)
+    defer cancel()
+    for i := 0; i < 3; i++ {
+        if _, err := s.Create(ctx, Comment{Content: "spam", Author: "Bob"}); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    if e, ok := <-events; !ok || e.Type != EventCreated {
+        t.Fatalf("expected the buffered event first, got %+v, %v", e, ok)
+    }
+    if _, ok := <-events; ok {
+        t.Error("expected the channel closed once the subscriber fell behind")
+    }
+}
+
+func TestCountSinceAndByAuthor(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+    s := NewCommentStore(WithClock(clock.Now))
+
+    for _, author := range []string{"Alice", "Bob", "Alice"} {
+        if _, err := s.Create(ctx, Comment{Content: "hi", Author: author}); err != nil {
+            t.Fatal(err)
+        }
+        clock.now = clock.now.Add(time.Hour)
+    }
+    if _, err := s.Create(ctx, Comment{Content: "gone", Author: "Carol", ExpiresAt: clock.now}); err != nil {
+        t.Fatal(err)
+    }
+
+    since := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+    if n, err := s.CountSince(ctx, since); err != nil || n != 2 {
+        t.Errorf("expected 2 comments since %s, got %d, %v", since, n, err)
+    }
+    counts, err := s.CountByAuthor(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(counts) != 2 || counts["Alice"] != 2 || counts["Bob"] != 1 {
+        t.Errorf("expected Alice 2 and Bob 1, got %v", counts)
+    }
+}
+
+func TestMemoryBudgetEviction(t *testing.T) {
+    ctx := context.Background()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+    var evictions []int
+    s := NewCommentStore(WithClock(clock.Now), WithMemoryBudget(3*sizeOf(Comment{ID: "c1", Content: "hello"}), func(evicted int, bytes int64) {
+        evictions = append(evictions, evicted)
+    }))
+
+    var ids []string
+    for i := 1; i <= 3; i++ {
+        c, err := s.Create(ctx, Comment{ID: fmt.Sprintf("c%d", i), Content: "hello"})
+        if err != nil {
+            t.Fatal(err)
+        }
+        ids = append(ids, c.ID)
+        clock.now = clock.now.Add(time.Minute)
+    }
+
+    events, cancel := s.Subscribe(10)
+    defer cancel()
+
+    // A comment bigger than the whole budget evicts everything else, but is
+    // kept itself
+    big, err := s.Create(ctx, Comment{ID: "big", Content: strings.Repeat("x", 1000)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Get(ctx, big.ID); err != nil {
+        t.Errorf("expected the new comment kept, got %v", err)
+    }
+    if n, _ := s.Count(ctx); n != 1 {
+        t.Errorf("expected only the new comment left, got %d", n)
+    }
+    if len(evictions) != 1 || evictions[0] != 3 {
+        t.Errorf("expected one eviction of 3 comments, got %v", evictions)
+    }
+
+    // The create is announced first, then each eviction, oldest first
+    want := append([]string{big.ID}, ids...)
+    for i, id := range want {
+        e := <-events
+        wantType := EventDeleted
+        if i == 0 {
+            wantType = EventCreated
+        }
+        if e.Type != wantType || e.Comment.ID != id {
+            t.Errorf("expected %s of %s, got %s of %s", wantType, id, e.Type, e.Comment.ID)
+        }
+    }
+    select {
+    case e := <-events:
+        t.Errorf("unexpected event %+v", e)
+    default:
+    }
+}
@@ -0,0 +1,64 @@
+// internal/storage/events.go
+
+package storage
+
+// EventType says how a comment changed.
+type EventType string
+
+const (
+    EventCreated EventType = "created"
+    EventUpdated EventType = "updated"
+    EventDeleted EventType = "deleted"
+)
+
+// Event is a change to a comment, delivered to subscribers. A deleted
+// event carries the comment as it was before deletion.
+type Event struct {
+    Type    EventType
+    Comment Comment
+}
+
+// Subscribe returns a channel receiving an Event for each comment created,
+// updated or deleted from now on, in the order the changes were made, and
+// a function that ends the subscription and closes the channel. Writers
+// never wait on subscribers: one that falls buffer events behind is
+// dropped and its channel closed, and should resubscribe and reread what
+// it missed. Batch operations such as RenameAuthor and EraseUser publish
+// an event per comment, and so does eviction under a memory budget, after
+// the event for the write that triggered it: an evicted comment is gone
+// for readers as surely as a deleted one. Comments removed once expired
+// produce no events, as readers already skip them.
+func (s *CommentStore) Subscribe(buffer int) (<-chan Event, func()) {
+    ch := make(chan Event, buffer)
+
+    s.mu.Lock()
+    s.subscribers[ch] = struct{}{}
+    s.mu.Unlock()
+
+    return ch, func() {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        s.unsubscribe(ch)
+    }
+}
+
+// publish delivers e to every subscriber, dropping those whose buffer is
+// full. Callers must hold mu, which keeps events in write order.
+func (s *CommentStore) publish(e Event) {
+    for ch := range s.subscribers {
+        select {
+        case ch <- e:
+        default:
+            s.unsubscribe(ch)
+        }
+    }
+}
+
+// unsubscribe removes and closes ch if it is still subscribed. Callers
+// must hold mu.
+func (s *CommentStore) unsubscribe(ch chan Event) {
+    if _, ok := s.subscribers[ch]; ok {
+        delete(s.subscribers, ch)
+        close(ch)
+    }
+}
@@ -0,0 +1,144 @@
+// internal/storage/faults.go
+
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "sync"
+    "time"
+)
+
+var (
+    // ErrUnavailable reports that the store could not serve a request.
+    // Callers should treat it as transient.
+    ErrUnavailable = errors.New("store unavailable")
+
+    // ErrInjected is returned by operations failed by a FaultInjector. It
+    // wraps ErrUnavailable.
+    ErrInjected = fmt.Errorf("injected fault: %w", ErrUnavailable)
+)
+
+// Fault describes misbehaviour to inject into store operations.
+type Fault struct {
+    // Method is the CommentStore method to affect, e.g. "List". Empty
+    // matches every method.
+    Method string
+    // IDs limits the fault to operations on these comment IDs. Empty
+    // matches any operation, including ones without an ID.
+    IDs []string
+    // Probability is the chance in (0, 1] that a matching operation is
+    // affected. Zero means always.
+    Probability float64
+    // Latency delays the operation, or until its context is done.
+    Latency time.Duration
+    // Fail makes the operation return ErrInjected after any latency.
+    Fail bool
+}
+
+func (f Fault) matches(method, id string) bool {
+    if f.Method != "" && f.Method != method {
+        return false
+    }
+    if len(f.IDs) == 0 {
+        return true
+    }
+    for _, fid := range f.IDs {
+        if fid == id {
+            return true
+        }
+    }
+    return false
+}
+
+// FaultInjector injects configured faults into a CommentStore's operations
+// for chaos testing. Faults can be replaced at any time. It must never be
+// enabled in production.
+type FaultInjector struct {
+    mu     sync.RWMutex
+    faults []Fault
+    rand   func() float64
+}
+
+// NewFaultInjector returns an injector with no faults configured.
+func NewFaultInjector() *FaultInjector {
+    return &FaultInjector{rand: rand.Float64}
+}
+
+// Set replaces the configured faults. An empty list clears them.
+func (fi *FaultInjector) Set(faults []Fault) error {
+    for i, f := range faults {
+        if f.Probability < 0 || f.Probability > 1 {
+            return fmt.Errorf("fault %d: probability must be between 0 and 1, got %v", i, f.Probability)
+        }
+        if f.Latency < 0 {
+            return fmt.Errorf("fault %d: latency must not be negative", i)
+        }
+    }
+
+    fi.mu.Lock()
+    defer fi.mu.Unlock()
+    fi.faults = append([]Fault(nil), faults...)
+    return nil
+}
+
+// Faults returns the configured faults.
+func (fi *FaultInjector) Faults() []Fault {
+    fi.mu.RLock()
+    defer fi.mu.RUnlock()
+    return append([]Fault{}, fi.faults...)
+}
+
+// inject applies every fault matching method and id: it sleeps for their
+// latency and returns ErrInjected if any of them fails the operation.
+func (fi *FaultInjector) inject(ctx context.Context, method, id string) error {
+    fi.mu.RLock()
+    var latency time.Duration
+    fail := false
+    for _, f := range fi.faults {
+        if !f.matches(method, id) || (f.Probability > 0 && fi.rand() >= f.Probability) {
+            continue
+        }
+        latency += f.Latency
+        fail = fail || f.Fail
+    }
+    fi.mu.RUnlock()
+
+    if latency > 0 {
+        timer := time.NewTimer(latency)
+        defer timer.Stop()
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-timer.C:
+        }
+    }
+    if fail {
+        return fmt.Errorf("%s: %w", method, ErrInjected)
+    }
+    return nil
+}
+
+// WithFaultInjector routes the store's operations through fi.
+func WithFaultInjector(fi *FaultInjector) Option {
+    return func(s *CommentStore) {
+        s.faults = fi
+    }
+}
+
+// FaultInjector returns the store's fault injector, or nil if it has none.
+func (s *CommentStore) FaultInjector() *FaultInjector {
+    return s.faults
+}
+
+// inject applies any configured faults for method on the comment with id.
+// It must be called before taking mu so injected latency doesn't block
+// other operations.
+func (s *CommentStore) inject(ctx context.Context, method, id string) error {
+    if s.faults == nil {
+        return nil
+    }
+    return s.faults.inject(ctx, method, id)
+}
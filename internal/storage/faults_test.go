@@ -0,0 +1,52 @@
+// internal/storage/faults_test.go
+
+package storage
+
+import (
+    "context"
+    "errors"
+    "testing"
+)
+
+func TestFaultInjector(t *testing.T) {
+    ctx := context.Background()
+    fi := NewFaultInjector()
+    s := NewCommentStore(WithFaultInjector(fi))
+
+    target, _ := s.Create(ctx, Comment{Content: "target"})
+    other, _ := s.Create(ctx, Comment{Content: "other"})
+
+    if err := fi.Set([]Fault{{Method: "Get", IDs: []string{target.ID}, Fail: true}}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Get(ctx, target.ID); !errors.Is(err, ErrUnavailable) {
+        t.Errorf("expected injected ErrUnavailable, got %v", err)
+    }
+    if _, err := s.Get(ctx, other.ID); err != nil {
+        t.Errorf("expected other IDs to be unaffected, got %v", err)
+    }
+    if _, err := s.List(ctx); err != nil {
+        t.Errorf("expected other methods to be unaffected, got %v", err)
+    }
+
+    // Probability is drawn per operation
+    draws := []float64{0.2, 0.8}
+    fi.rand = func() float64 {
+        d := draws[0]
+        draws = draws[1:]
+        return d
+    }
+    if err := fi.Set([]Fault{{Method: "Count", Probability: 0.5, Fail: true}}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Count(ctx); !errors.Is(err, ErrInjected) {
+        t.Errorf("draw below probability: expected ErrInjected, got %v", err)
+    }
+    if _, err := s.Count(ctx); err != nil {
+        t.Errorf("draw above probability: expected success, got %v", err)
+    }
+
+    if err := fi.Set([]Fault{{Probability: 1.5}}); err == nil {
+        t.Error("expected out-of-range probability to be rejected")
+    }
+}
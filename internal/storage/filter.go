@@ -0,0 +1,69 @@
+// internal/storage/filter.go
+
+package storage
+
+import (
+    "strings"
+    "time"
+)
+
+// FilterOp is the kind of a Filter node.
+type FilterOp int
+
+const (
+    FilterAll FilterOp = iota // matches every comment
+    FilterAnd
+    FilterOr
+    FilterNot
+    FilterContent // content contains Value, case-insensitively
+    FilterAuthor  // author equals Value, case-insensitively
+    FilterUser    // user ID equals Value
+    FilterBefore  // created before Time
+    FilterAfter   // created at or after Time
+)
+
+// Filter is a tree of conditions over comments. Leaf nodes compare a single
+// field; And, Or and Not combine Children. The zero Filter matches
+// everything.
+type Filter struct {
+    Op       FilterOp
+    Value    string
+    Time     time.Time
+    Children []Filter
+}
+
+// Match reports whether c satisfies the filter.
+func (f Filter) Match(c Comment) bool {
+    switch f.Op {
+    case FilterAll:
+        return true
+    case FilterAnd:
+        for _, child := range f.Children {
+            if !child.Match(c) {
+                return false
+            }
+        }
+        return true
+    case FilterOr:
+        for _, child := range f.Children {
+            if child.Match(c) {
+                return true
+            }
+        }
+        return false
+    case FilterNot:
+        return len(f.Children) == 1 && !f.Children[0].Match(c)
+    case FilterContent:
+        return strings.Contains(strings.ToLower(c.Content), strings.ToLower(f.Value))
+    case FilterAuthor:
+        return strings.EqualFold(c.Author, f.Value)
+    case FilterUser:
+        return c.UserID == f.Value
+    case FilterBefore:
+        return c.CreatedAt.Before(f.Time)
+    case FilterAfter:
+        return !c.CreatedAt.Before(f.Time)
+    default:
+        return false
+    }
+}
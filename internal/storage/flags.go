@@ -0,0 +1,173 @@
+// internal/storage/flags.go
+
+package storage
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "time"
+    "web-service/internal/erasure"
+)
+
+// Flag is a user's report of a comment for moderation.
+type Flag struct {
+    CommentID string
+    UserID    string
+    Reason    string
+    CreatedAt time.Time
+}
+
+// FlaggedComment summarizes the flags on one comment, oldest flag first.
+type FlaggedComment struct {
+    CommentID     string
+    Flags         []Flag
+    LastFlaggedAt time.Time
+}
+
+// FlagStore holds flags keyed by comment and reporter, so each user flags a
+// comment at most once. A CommentStore keeps its FlagStore in step, removing
+// a comment's flags whenever the comment is removed.
+type FlagStore struct {
+    mu sync.RWMutex
+    // flags maps comment ID to the flags on it by reporting user ID
+    flags map[string]map[string]Flag
+    now   func() time.Time
+    // modified is when a flag was last added or removed.
+    modified time.Time
+}
+
+func NewFlagStore() *FlagStore {
+    return &FlagStore{
+        flags: make(map[string]map[string]Flag),
+        now:   time.Now,
+    }
+}
+
+// Add records f, setting its creation time. If the user has already flagged
+// the comment it returns their existing flag unchanged and created false.
+func (s *FlagStore) Add(ctx context.Context, f Flag) (flag Flag, created bool, err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return Flag{}, false, ctx.Err()
+    default:
+    }
+
+    byUser := s.flags[f.CommentID]
+    if existing, ok := byUser[f.UserID]; ok {
+        return existing, false, nil
+    }
+    if byUser == nil {
+        byUser = make(map[string]Flag)
+        s.flags[f.CommentID] = byUser
+    }
+    f.CreatedAt = stamp(s.now())
+    byUser[f.UserID] = f
+    s.modified = f.CreatedAt
+    return f, true, nil
+}
+
+// Counts returns the number of flags on each flagged comment.
+func (s *FlagStore) Counts(ctx context.Context) (map[string]int, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    default:
+    }
+
+    counts := make(map[string]int, len(s.flags))
+    for commentID, byUser := range s.flags {
+        counts[commentID] = len(byUser)
+    }
+    return counts, nil
+}
+
+// List returns every flagged comment, most flagged first and most recently
+// flagged first among equals.
+func (s *FlagStore) List(ctx context.Context) ([]FlaggedComment, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    default:
+    }
+
+    flagged := make([]FlaggedComment, 0, len(s.flags))
+    for commentID, byUser := range s.flags {
+        fc := FlaggedComment{CommentID: commentID, Flags: make([]Flag, 0, len(byUser))}
+        for _, f := range byUser {
+            fc.Flags = append(fc.Flags, f)
+            if f.CreatedAt.After(fc.LastFlaggedAt) {
+                fc.LastFlaggedAt = f.CreatedAt
+            }
+        }
+        sort.Slice(fc.Flags, func(i, j int) bool {
+            if !fc.Flags[i].CreatedAt.Equal(fc.Flags[j].CreatedAt) {
+                return fc.Flags[i].CreatedAt.Before(fc.Flags[j].CreatedAt)
+            }
+            return fc.Flags[i].UserID < fc.Flags[j].UserID
+        })
+        flagged = append(flagged, fc)
+    }
+    sort.Slice(flagged, func(i, j int) bool {
+        if len(flagged[i].Flags) != len(flagged[j].Flags) {
+            return len(flagged[i].Flags) > len(flagged[j].Flags)
+        }
+        if !flagged[i].LastFlaggedAt.Equal(flagged[j].LastFlaggedAt) {
+            return flagged[i].LastFlaggedAt.After(flagged[j].LastFlaggedAt)
+        }
+        return flagged[i].CommentID < flagged[j].CommentID
+    })
+    return flagged, nil
+}
+
+// removeComment drops every flag on the comment with id.
+func (s *FlagStore) removeComment(id string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.flags[id]; ok {
+        delete(s.flags, id)
+        s.modified = s.now()
+    }
+}
+
+// lastModified returns when a flag was last added or removed.
+func (s *FlagStore) lastModified() time.Time {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.modified
+}
+
+// EraseUser removes the flags userID reported. Flags on the user's own
+// comments go with the comments.
+func (s *FlagStore) EraseUser(ctx context.Context, userID string) (erasure.Report, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return erasure.Report{}, ctx.Err()
+    default:
+    }
+
+    var report erasure.Report
+    for commentID, byUser := range s.flags {
+        if _, ok := byUser[userID]; ok {
+            delete(byUser, userID)
+            s.modified = s.now()
+            report.Removed++
+            if len(byUser) == 0 {
+                delete(s.flags, commentID)
+            }
+        }
+    }
+    return report, nil
+}
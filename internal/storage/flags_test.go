@@ -0,0 +1,61 @@
+// internal/storage/flags_test.go
+
+package storage
+
+import (
+    "context"
+    "testing"
+)
+
+func TestFlagStore(t *testing.T) {
+    ctx := context.Background()
+    s := NewCommentStore()
+    flags := s.Flags()
+
+    c, err := s.Create(ctx, Comment{Content: "a", Author: "Alice", UserID: "alice"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    other, err := s.Create(ctx, Comment{Content: "b", Author: "Alice", UserID: "alice"})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, created, err := flags.Add(ctx, Flag{CommentID: c.ID, UserID: "bob", Reason: "spam"}); err != nil || !created {
+        t.Fatalf("expected first flag to be created, got %v %v", created, err)
+    }
+    f, created, err := flags.Add(ctx, Flag{CommentID: c.ID, UserID: "bob", Reason: "other"})
+    if err != nil || created || f.Reason != "spam" {
+        t.Fatalf("expected repeat to return the original flag, got %+v %v %v", f, created, err)
+    }
+    for _, userID := range []string{"carol", "dave"} {
+        if _, _, err := flags.Add(ctx, Flag{CommentID: other.ID, UserID: userID}); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    list, err := flags.List(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(list) != 2 || list[0].CommentID != other.ID || len(list[0].Flags) != 2 || list[1].CommentID != c.ID {
+        t.Fatalf("expected most flagged comment first, got %+v", list)
+    }
+
+    // Erasing a reporter removes only their flags
+    report, err := flags.EraseUser(ctx, "carol")
+    if err != nil || report.Removed != 1 {
+        t.Fatalf("expected one flag erased, got %+v %v", report, err)
+    }
+
+    if err := s.Delete(ctx, c.ID); err != nil {
+        t.Fatal(err)
+    }
+    counts, err := flags.Counts(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(counts) != 1 || counts[other.ID] != 1 {
+        t.Errorf("expected only dave's flag on the remaining comment, got %v", counts)
+    }
+}
@@ -0,0 +1,189 @@
+// internal/storage/instrumented.go
+
+package storage
+
+import (
+    "context"
+    "time"
+    "web-service/internal/erasure"
+    "web-service/pkg/logging"
+)
+
+// InstrumentedStore wraps a CommentRepository, recording the calls made to
+// each method in its metrics and logging those slower than the metrics'
+// threshold at WARN, with the context of the request they slowed. Timing
+// from outside the store means injected latency and time spent waiting for
+// its lock both count. Methods that only read counters the store keeps,
+// such as Position, are passed through unmeasured.
+type InstrumentedStore struct {
+    inner   CommentRepository
+    logger  *logging.Logger
+    metrics *Metrics
+}
+
+var _ CommentRepository = (*InstrumentedStore)(nil)
+
+func NewInstrumentedStore(inner CommentRepository, logger *logging.Logger, metrics *Metrics) *InstrumentedStore {
+    return &InstrumentedStore{inner: inner, logger: logger, metrics: metrics}
+}
+
+// observe records a call to method that started at start and returned
+// *err.
+func (s *InstrumentedStore) observe(ctx context.Context, method string, start time.Time, err *error) {
+    took := time.Since(start)
+    if !s.metrics.record(method, took, *err) {
+        return
+    }
+
+    fields := []interface{}{
+        "method", method,
+        "duration_ms", took.Milliseconds(),
+        "threshold", s.metrics.slowThreshold.String(),
+    }
+    if *err != nil {
+        fields = append(fields, "error", *err)
+    }
+    s.logger.Warn(ctx, "slow store operation", fields...)
+}
+
+func (s *InstrumentedStore) Ping(ctx context.Context) (err error) {
+    defer s.observe(ctx, "Ping", time.Now(), &err)
+    return s.inner.Ping(ctx)
+}
+
+func (s *InstrumentedStore) LastModified(ctx context.Context) (_ time.Time, err error) {
+    defer s.observe(ctx, "LastModified", time.Now(), &err)
+    return s.inner.LastModified(ctx)
+}
+
+func (s *InstrumentedStore) Create(ctx context.Context, c Comment) (_ Comment, err error) {
+    defer s.observe(ctx, "Create", time.Now(), &err)
+    return s.inner.Create(ctx, c)
+}
+
+func (s *InstrumentedStore) List(ctx context.Context) (_ []Comment, err error) {
+    defer s.observe(ctx, "List", time.Now(), &err)
+    return s.inner.List(ctx)
+}
+
+func (s *InstrumentedStore) Range(ctx context.Context, fn func(Comment) bool) (err error) {
+    defer s.observe(ctx, "Range", time.Now(), &err)
+    return s.inner.Range(ctx, fn)
+}
+
+func (s *InstrumentedStore) Get(ctx context.Context, id string) (_ Comment, err error) {
+    defer s.observe(ctx, "Get", time.Now(), &err)
+    return s.inner.Get(ctx, id)
+}
+
+func (s *InstrumentedStore) Update(ctx context.Context, id string, version int64, c Comment) (_ Comment, err error) {
+    defer s.observe(ctx, "Update", time.Now(), &err)
+    return s.inner.Update(ctx, id, version, c)
+}
+
+func (s *InstrumentedStore) Delete(ctx context.Context, id string) (err error) {
+    defer s.observe(ctx, "Delete", time.Now(), &err)
+    return s.inner.Delete(ctx, id)
+}
+
+func (s *InstrumentedStore) AddReaction(ctx context.Context, id, userID string) (_ int, err error) {
+    defer s.observe(ctx, "AddReaction", time.Now(), &err)
+    return s.inner.AddReaction(ctx, id, userID)
+}
+
+func (s *InstrumentedStore) RemoveReaction(ctx context.Context, id, userID string) (_ int, err error) {
+    defer s.observe(ctx, "RemoveReaction", time.Now(), &err)
+    return s.inner.RemoveReaction(ctx, id, userID)
+}
+
+func (s *InstrumentedStore) ReactedTo(ctx context.Context, userID string) (_ map[string]bool, err error) {
+    defer s.observe(ctx, "ReactedTo", time.Now(), &err)
+    return s.inner.ReactedTo(ctx, userID)
+}
+
+func (s *InstrumentedStore) ListByUser(ctx context.Context, userID string) (_ []Comment, err error) {
+    defer s.observe(ctx, "ListByUser", time.Now(), &err)
+    return s.inner.ListByUser(ctx, userID)
+}
+
+func (s *InstrumentedStore) CountByUser(ctx context.Context, userID string) (_ int, err error) {
+    defer s.observe(ctx, "CountByUser", time.Now(), &err)
+    return s.inner.CountByUser(ctx, userID)
+}
+
+func (s *InstrumentedStore) DeleteByUser(ctx context.Context, userID string) (_ int, err error) {
+    defer s.observe(ctx, "DeleteByUser", time.Now(), &err)
+    return s.inner.DeleteByUser(ctx, userID)
+}
+
+func (s *InstrumentedStore) EraseUser(ctx context.Context, userID string) (_ erasure.Report, err error) {
+    defer s.observe(ctx, "EraseUser", time.Now(), &err)
+    return s.inner.EraseUser(ctx, userID)
+}
+
+func (s *InstrumentedStore) RenameAuthor(ctx context.Context, userID, author string) (_ int, err error) {
+    defer s.observe(ctx, "RenameAuthor", time.Now(), &err)
+    return s.inner.RenameAuthor(ctx, userID, author)
+}
+
+func (s *InstrumentedStore) DeleteOlderThan(ctx context.Context, age time.Duration) (_ int, err error) {
+    defer s.observe(ctx, "DeleteOlderThan", time.Now(), &err)
+    return s.inner.DeleteOlderThan(ctx, age)
+}
+
+func (s *InstrumentedStore) DeleteExpired(ctx context.Context) (_ int, err error) {
+    defer s.observe(ctx, "DeleteExpired", time.Now(), &err)
+    return s.inner.DeleteExpired(ctx)
+}
+
+func (s *InstrumentedStore) Search(ctx context.Context, f Filter, offset, limit int) (_ []Comment, _ int, err error) {
+    defer s.observe(ctx, "Search", time.Now(), &err)
+    return s.inner.Search(ctx, f, offset, limit)
+}
+
+func (s *InstrumentedStore) Stats(ctx context.Context) (_ Stats, err error) {
+    defer s.observe(ctx, "Stats", time.Now(), &err)
+    return s.inner.Stats(ctx)
+}
+
+func (s *InstrumentedStore) CountSince(ctx context.Context, t time.Time) (_ int, err error) {
+    defer s.observe(ctx, "CountSince", time.Now(), &err)
+    return s.inner.CountSince(ctx, t)
+}
+
+func (s *InstrumentedStore) Count(ctx context.Context) (_ int, err error) {
+    defer s.observe(ctx, "Count", time.Now(), &err)
+    return s.inner.Count(ctx)
+}
+
+func (s *InstrumentedStore) Now() time.Time {
+    return s.inner.Now()
+}
+
+func (s *InstrumentedStore) MemoryUsage(ctx context.Context) (int64, error) {
+    return s.inner.MemoryUsage(ctx)
+}
+
+func (s *InstrumentedStore) Position(ctx context.Context) (uint64, error) {
+    return s.inner.Position(ctx)
+}
+
+func (s *InstrumentedStore) Deleted(ctx context.Context, id string) (Tombstone, error) {
+    return s.inner.Deleted(ctx, id)
+}
+
+func (s *InstrumentedStore) Tombstones(ctx context.Context) (map[string]Tombstone, error) {
+    return s.inner.Tombstones(ctx)
+}
+
+func (s *InstrumentedStore) Subscribe(buffer int) (<-chan Event, func()) {
+    return s.inner.Subscribe(buffer)
+}
+
+func (s *InstrumentedStore) Flags() *FlagStore {
+    return s.inner.Flags()
+}
+
+func (s *InstrumentedStore) FaultInjector() *FaultInjector {
+    return s.inner.FaultInjector()
+}
\ No newline at end of file
@@ -0,0 +1,103 @@
+// internal/storage/instrumented_test.go
+
+package storage
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "testing"
+    "time"
+    "web-service/pkg/logging"
+)
+
+// slowRepository stands in for a backend with latency: Get takes delay,
+// and everything else is answered by the embedded repository.
+type slowRepository struct {
+    CommentRepository
+    delay time.Duration
+}
+
+func (r slowRepository) Get(ctx context.Context, id string) (Comment, error) {
+    time.Sleep(r.delay)
+    return r.CommentRepository.Get(ctx, id)
+}
+
+func TestInstrumentedStore(t *testing.T) {
+    ctx := context.Background()
+    fi := NewFaultInjector()
+    var logs bytes.Buffer
+    metrics := NewMetrics(10 * time.Millisecond)
+    s := NewInstrumentedStore(
+        slowRepository{CommentRepository: NewCommentStore(WithFaultInjector(fi)), delay: 20 * time.Millisecond},
+        logging.NewLogger(&logs),
+        metrics,
+    )
+
+    c, err := s.Create(ctx, Comment{ID: "first", Content: "hello"})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Misses and conflicts are answers, not failures
+    if err := fi.Set([]Fault{{Method: "Count", Fail: true}}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Create(ctx, Comment{ID: "first", Content: "again"}); !errors.Is(err, ErrConflict) {
+        t.Fatalf("expected ErrConflict, got %v", err)
+    }
+    if _, err := s.Get(ctx, c.ID); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("expected ErrNotFound, got %v", err)
+    }
+    if _, err := s.Count(ctx); !errors.Is(err, ErrInjected) {
+        t.Fatalf("expected ErrInjected, got %v", err)
+    }
+
+    stats := metrics.Operations()
+    if got := stats["Create"]; got.Calls != 2 || got.Errors != 0 || got.Slow != 0 {
+        t.Errorf("Create: expected 2 calls without errors, got %+v", got)
+    }
+    if got := stats["Get"]; got.Calls != 2 || got.Errors != 0 || got.Slow != 2 || got.Max < 20*time.Millisecond || got.Total < 40*time.Millisecond {
+        t.Errorf("Get: expected 2 slow calls taking at least 20ms, got %+v", got)
+    }
+    if got := stats["Count"]; got.Calls != 1 || got.Errors != 1 {
+        t.Errorf("Count: expected 1 failed call, got %+v", got)
+    }
+    if _, ok := stats["List"]; ok {
+        t.Error("expected no stats for a method never called")
+    }
+
+    // Each slow call is logged, the miss with its error
+    var slow []map[string]any
+    dec := json.NewDecoder(&logs)
+    for dec.More() {
+        var entry struct {
+            Message string         `json:"message"`
+            Fields  map[string]any `json:"fields"`
+        }
+        if err := dec.Decode(&entry); err != nil {
+            t.Fatal(err)
+        }
+        if entry.Message == "slow store operation" {
+            slow = append(slow, entry.Fields)
+        }
+    }
+    if len(slow) != 2 {
+        t.Fatalf("expected 2 slow operations logged, got %v", slow)
+    }
+    for _, fields := range slow {
+        if fields["method"] != "Get" || fields["threshold"] != "10ms" {
+            t.Errorf("expected a slow Get against 10ms, got %v", fields)
+        }
+    }
+    if _, ok := slow[0]["error"]; ok {
+        t.Errorf("expected no error on the found comment, got %v", slow[0])
+    }
+    if slow[1]["error"] == nil {
+        t.Errorf("expected the miss logged with its error, got %v", slow[1])
+    }
+}
\ No newline at end of file
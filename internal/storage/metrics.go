@@ -0,0 +1,78 @@
+// internal/storage/metrics.go
+
+package storage
+
+import (
+    "errors"
+    "sync"
+    "time"
+)
+
+// OperationStats summarises the calls made to one store method.
+type OperationStats struct {
+    Calls int64
+    // Errors counts calls that failed. ErrNotFound, ErrVersionConflict and
+    // ErrConflict are answers rather than failures and aren't counted.
+    Errors int64
+    // Slow counts calls that took longer than the slow threshold.
+    Slow  int64
+    Total time.Duration
+    Max   time.Duration
+}
+
+// Metrics records the calls made to each method of an InstrumentedStore.
+type Metrics struct {
+    mu            sync.Mutex
+    methods       map[string]*OperationStats
+    slowThreshold time.Duration
+}
+
+// NewMetrics returns empty metrics counting calls that take longer than
+// slowThreshold as slow. A threshold <= 0 counts none.
+func NewMetrics(slowThreshold time.Duration) *Metrics {
+    return &Metrics{
+        methods:       make(map[string]*OperationStats),
+        slowThreshold: slowThreshold,
+    }
+}
+
+// Operations returns the calls made to each method since the metrics were
+// created, keyed by method name.
+func (m *Metrics) Operations() map[string]OperationStats {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    stats := make(map[string]OperationStats, len(m.methods))
+    for method, st := range m.methods {
+        stats[method] = *st
+    }
+    return stats
+}
+
+// record counts a call to method that took took and returned err, and
+// reports whether it was slow.
+func (m *Metrics) record(method string, took time.Duration, err error) (slow bool) {
+    failed := err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrVersionConflict) && !errors.Is(err, ErrConflict)
+    slow = m.slowThreshold > 0 && took > m.slowThreshold
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    st, ok := m.methods[method]
+    if !ok {
+        st = &OperationStats{}
+        m.methods[method] = st
+    }
+    st.Calls++
+    st.Total += took
+    if took > st.Max {
+        st.Max = took
+    }
+    if failed {
+        st.Errors++
+    }
+    if slow {
+        st.Slow++
+    }
+    return slow
+}
\ No newline at end of file
@@ -0,0 +1,54 @@
+// internal/storage/repository.go
+
+package storage
+
+import (
+    "context"
+    "time"
+    "web-service/internal/erasure"
+)
+
+// CommentRepository is the comment storage the API and the server's
+// background jobs work against. CommentStore implements it, and
+// InstrumentedStore wraps one to measure it.
+type CommentRepository interface {
+    erasure.Eraser
+
+    Now() time.Time
+    Ping(ctx context.Context) error
+    MemoryUsage(ctx context.Context) (int64, error)
+    Position(ctx context.Context) (uint64, error)
+    LastModified(ctx context.Context) (time.Time, error)
+
+    Create(ctx context.Context, c Comment) (Comment, error)
+    List(ctx context.Context) ([]Comment, error)
+    Range(ctx context.Context, fn func(Comment) bool) error
+    Get(ctx context.Context, id string) (Comment, error)
+    Update(ctx context.Context, id string, version int64, c Comment) (Comment, error)
+    Delete(ctx context.Context, id string) error
+    Deleted(ctx context.Context, id string) (Tombstone, error)
+    Tombstones(ctx context.Context) (map[string]Tombstone, error)
+
+    AddReaction(ctx context.Context, id, userID string) (int, error)
+    RemoveReaction(ctx context.Context, id, userID string) (int, error)
+    ReactedTo(ctx context.Context, userID string) (map[string]bool, error)
+
+    ListByUser(ctx context.Context, userID string) ([]Comment, error)
+    CountByUser(ctx context.Context, userID string) (int, error)
+    DeleteByUser(ctx context.Context, userID string) (int, error)
+    RenameAuthor(ctx context.Context, userID, author string) (int, error)
+
+    DeleteOlderThan(ctx context.Context, age time.Duration) (int, error)
+    DeleteExpired(ctx context.Context) (int, error)
+
+    Search(ctx context.Context, f Filter, offset, limit int) ([]Comment, int, error)
+    Stats(ctx context.Context) (Stats, error)
+    CountSince(ctx context.Context, t time.Time) (int, error)
+    Count(ctx context.Context) (int, error)
+
+    Subscribe(buffer int) (<-chan Event, func())
+    Flags() *FlagStore
+    FaultInjector() *FaultInjector
+}
+
+var _ CommentRepository = (*CommentStore)(nil)
\ No newline at end of file
@@ -0,0 +1,110 @@
+// internal/storage/users.go
+
+package storage
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "web-service/internal/auth"
+    "web-service/internal/erasure"
+)
+
+var (
+    // ErrInvalidCredentials is returned by Authenticate for an unknown
+    // username or a wrong password, without saying which.
+    ErrInvalidCredentials = errors.New("invalid credentials")
+
+    ErrUserExists = errors.New("user already exists")
+)
+
+type User struct {
+    ID       string
+    Username string
+    Role     string
+    // PasswordHash is an auth.HashPassword hash.
+    PasswordHash string
+}
+
+// UserStore authenticates users logging in.
+type UserStore interface {
+    Authenticate(ctx context.Context, username, password string) (User, error)
+}
+
+// dummyHash is checked against when the username is unknown, so failed
+// logins take as long whether or not the user exists.
+const dummyHash = "pbkdf2-sha256$210000$QykgFG10/8I3P/Q3oDcNmg$d+yHYIco+DxJ96bGvlvTaBuT7X32MjE+Gko84gAt/bI"
+
+// MemoryUserStore is a UserStore holding users in memory, typically seeded
+// from configuration at startup.
+type MemoryUserStore struct {
+    mu    sync.RWMutex
+    users map[string]User
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+    return &MemoryUserStore{users: make(map[string]User)}
+}
+
+// Add stores u, rejecting a malformed password hash or a username already
+// taken.
+func (s *MemoryUserStore) Add(u User) error {
+    if err := auth.ValidatePasswordHash(u.PasswordHash); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, exists := s.users[u.Username]; exists {
+        return ErrUserExists
+    }
+    s.users[u.Username] = u
+    return nil
+}
+
+func (s *MemoryUserStore) Authenticate(ctx context.Context, username, password string) (User, error) {
+    s.mu.RLock()
+    u, exists := s.users[username]
+    s.mu.RUnlock()
+
+    select {
+    case <-ctx.Done():
+        return User{}, ctx.Err()
+    default:
+    }
+
+    hash := u.PasswordHash
+    if !exists {
+        hash = dummyHash
+    }
+    ok, err := auth.CheckPassword(hash, password)
+    if err != nil {
+        return User{}, err
+    }
+    if !ok || !exists {
+        return User{}, ErrInvalidCredentials
+    }
+    return u, nil
+}
+
+// EraseUser removes the user with ID userID.
+func (s *MemoryUserStore) EraseUser(ctx context.Context, userID string) (erasure.Report, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    select {
+    case <-ctx.Done():
+        return erasure.Report{}, ctx.Err()
+    default:
+    }
+
+    var report erasure.Report
+    for username, u := range s.users {
+        if u.ID == userID {
+            delete(s.users, username)
+            report.Removed++
+        }
+    }
+    return report, nil
+}
@@ -0,0 +1,69 @@
+// internal/storage/users_test.go
+
+package storage
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "web-service/internal/auth"
+)
+
+func TestMemoryUserStore(t *testing.T) {
+    hash, err := auth.HashPassword("s3cret")
+    if err != nil {
+        t.Fatal(err)
+    }
+    store := NewMemoryUserStore()
+    alice := User{ID: "u-alice", Username: "alice", Role: "admin", PasswordHash: hash}
+    if err := store.Add(alice); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := store.Add(alice); !errors.Is(err, ErrUserExists) {
+        t.Errorf("duplicate username: expected ErrUserExists, got %v", err)
+    }
+    if err := store.Add(User{Username: "bob", PasswordHash: "plaintext"}); !errors.Is(err, auth.ErrMalformedHash) {
+        t.Errorf("plaintext password: expected ErrMalformedHash, got %v", err)
+    }
+
+    ctx := context.Background()
+    u, err := store.Authenticate(ctx, "alice", "s3cret")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if u.ID != "u-alice" || u.Role != "admin" {
+        t.Errorf("got %+v, want alice", u)
+    }
+
+    for _, tc := range []struct{ username, password string }{
+        {"alice", "wrong"},
+        {"nobody", "s3cret"},
+    } {
+        if _, err := store.Authenticate(ctx, tc.username, tc.password); !errors.Is(err, ErrInvalidCredentials) {
+            t.Errorf("Authenticate(%q, %q): expected ErrInvalidCredentials, got %v", tc.username, tc.password, err)
+        }
+    }
+}
+
+func TestMemoryUserStoreEraseUser(t *testing.T) {
+    hash, err := auth.HashPassword("s3cret")
+    if err != nil {
+        t.Fatal(err)
+    }
+    store := NewMemoryUserStore()
+    if err := store.Add(User{ID: "u-alice", Username: "alice", PasswordHash: hash}); err != nil {
+        t.Fatal(err)
+    }
+
+    ctx := context.Background()
+    if report, err := store.EraseUser(ctx, "u-alice"); err != nil || report.Removed != 1 {
+        t.Fatalf("expected 1 removed, got %+v, %v", report, err)
+    }
+    if _, err := store.Authenticate(ctx, "alice", "s3cret"); !errors.Is(err, ErrInvalidCredentials) {
+        t.Errorf("expected erased user unable to log in, got %v", err)
+    }
+    if report, err := store.EraseUser(ctx, "u-alice"); err != nil || report.Removed != 0 {
+        t.Errorf("expected an empty re-run, got %+v, %v", report, err)
+    }
+}
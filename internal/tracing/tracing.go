@@ -0,0 +1,180 @@
+// internal/tracing/tracing.go
+
+// Package tracing traces requests with OpenTelemetry. Spans are exported
+// over OTLP/HTTP when an endpoint is configured; otherwise tracing is a
+// no-op, though incoming trace context still flows through to the logs.
+package tracing
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+    "go.opentelemetry.io/otel/trace/noop"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// Name is the instrumentation name tracers are created under.
+const Name = "web-service"
+
+// CloudTraceHeader is Google Cloud's trace header, of the form
+// TRACE_ID/SPAN_ID;o=OPTIONS, honored when a request has no traceparent.
+const CloudTraceHeader = "X-Cloud-Trace-Context"
+
+// NewProvider returns a tracer provider exporting to the OTLP/HTTP endpoint,
+// a URL such as http://collector:4318, or a no-op provider when endpoint is
+// empty. The returned shutdown flushes buffered spans.
+func NewProvider(ctx context.Context, endpoint, version string) (trace.TracerProvider, func(context.Context) error, error) {
+    if endpoint == "" {
+        return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+    }
+
+    exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+    if err != nil {
+        return nil, nil, fmt.Errorf("creating OTLP exporter: %w", err)
+    }
+    res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+        semconv.ServiceName(Name),
+        semconv.ServiceVersion(version),
+    ))
+    if err != nil {
+        return nil, nil, fmt.Errorf("building trace resource: %w", err)
+    }
+    tp := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(res),
+    )
+    return tp, tp.Shutdown, nil
+}
+
+// NewMiddleware starts a server span for each request, continuing the
+// caller's trace from its traceparent header or, failing that, its
+// X-Cloud-Trace-Context header. route names the span after the matched
+// route pattern rather than the raw path, keeping span names few.
+func NewMiddleware(tp trace.TracerProvider, route func(*http.Request) string) func(http.Handler) http.Handler {
+    tracer := tp.Tracer(Name)
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            // Unmatched requests share one span name rather than one per path
+            name := r.Method
+            attrs := []attribute.KeyValue{
+                semconv.HTTPRequestMethodKey.String(r.Method),
+                semconv.URLPath(r.URL.Path),
+            }
+            if pattern := route(r); pattern != "" {
+                name = pattern
+                attrs = append(attrs, semconv.HTTPRoute(routePath(pattern)))
+            }
+            ctx, span := tracer.Start(extract(r), name,
+                trace.WithSpanKind(trace.SpanKindServer),
+                trace.WithAttributes(attrs...),
+            )
+            defer span.End()
+
+            sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+            next.ServeHTTP(sw, r.WithContext(ctx))
+
+            span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+            if sw.status >= http.StatusInternalServerError {
+                span.SetStatus(codes.Error, http.StatusText(sw.status))
+            }
+        })
+    }
+}
+
+// routePath strips the method from a ServeMux pattern such as
+// "GET /api/v1/comments/{id}".
+func routePath(pattern string) string {
+    if _, path, ok := strings.Cut(pattern, " "); ok {
+        return path
+    }
+    return pattern
+}
+
+// RecordError marks the span in ctx as failed with err. It does nothing when
+// ctx carries no recording span.
+func RecordError(ctx context.Context, err error) {
+    span := trace.SpanFromContext(ctx)
+    span.RecordError(err)
+    span.SetStatus(codes.Error, err.Error())
+}
+
+// extract returns the request's context carrying the remote span context
+// the caller sent, if any.
+func extract(r *http.Request) context.Context {
+    ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+    if trace.SpanContextFromContext(ctx).IsValid() {
+        return ctx
+    }
+    if sc, ok := parseCloudTraceContext(r.Header.Get(CloudTraceHeader)); ok {
+        return trace.ContextWithRemoteSpanContext(ctx, sc)
+    }
+    return ctx
+}
+
+// parseCloudTraceContext parses an X-Cloud-Trace-Context value: a 32 hex
+// digit trace ID, then optionally a slash and a decimal span ID, then
+// optionally ";o=1" when the trace is sampled.
+func parseCloudTraceContext(h string) (trace.SpanContext, bool) {
+    if h == "" {
+        return trace.SpanContext{}, false
+    }
+    h, options, _ := strings.Cut(h, ";")
+    traceHex, spanDec, _ := strings.Cut(h, "/")
+
+    var cfg trace.SpanContextConfig
+    if len(traceHex) != 32 {
+        return trace.SpanContext{}, false
+    }
+    if _, err := hex.Decode(cfg.TraceID[:], []byte(traceHex)); err != nil {
+        return trace.SpanContext{}, false
+    }
+    if spanDec != "" {
+        id, err := strconv.ParseUint(spanDec, 10, 64)
+        if err != nil {
+            return trace.SpanContext{}, false
+        }
+        for i := 7; i >= 0; i-- {
+            cfg.SpanID[i] = byte(id)
+            id >>= 8
+        }
+    }
+    if options == "o=1" {
+        cfg.TraceFlags = trace.FlagsSampled
+    }
+    cfg.Remote = true
+
+    // A span context needs a span ID to be valid. The trace ID alone is
+    // still worth continuing, so stand in a random parent for a missing one
+    if !cfg.SpanID.IsValid() {
+        rand.Read(cfg.SpanID[:])
+    }
+    sc := trace.NewSpanContext(cfg)
+    return sc, sc.IsValid()
+}
+
+// statusWriter records the status code a handler writes.
+type statusWriter struct {
+    http.ResponseWriter
+    status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+    w.status = code
+    w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
@@ -0,0 +1,168 @@
+// internal/tracing/tracing_test.go
+
+package tracing
+
+import (
+    "context"
+    "errors"
+    "go.opentelemetry.io/otel/codes"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// serve runs one request through the middleware with handler behind it
+// and returns the span it recorded.
+func serve(t *testing.T, req *http.Request, handler http.HandlerFunc) sdktrace.ReadOnlySpan {
+    t.Helper()
+
+    recorder := tracetest.NewSpanRecorder()
+    tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+    route := func(*http.Request) string { return "GET /api/v1/comments/{id}" }
+    NewMiddleware(tp, route)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+    spans := recorder.Ended()
+    if len(spans) != 1 {
+        t.Fatalf("expected 1 span, got %d", len(spans))
+    }
+    return spans[0]
+}
+
+func TestMiddlewareSpan(t *testing.T) {
+    req := httptest.NewRequest(http.MethodGet, "/api/v1/comments/abc", nil)
+    span := serve(t, req, func(w http.ResponseWriter, r *http.Request) {
+        if !trace.SpanContextFromContext(r.Context()).IsValid() {
+            t.Error("expected the handler's context to carry the span")
+        }
+        w.WriteHeader(http.StatusNotFound)
+    })
+
+    if span.Name() != "GET /api/v1/comments/{id}" {
+        t.Errorf("expected span named after the route, got %q", span.Name())
+    }
+    if span.SpanKind() != trace.SpanKindServer {
+        t.Errorf("expected a server span, got %s", span.SpanKind())
+    }
+    want := map[string]any{
+        string(semconv.HTTPRouteKey):              "/api/v1/comments/{id}",
+        string(semconv.HTTPResponseStatusCodeKey): int64(http.StatusNotFound),
+    }
+    for _, attr := range span.Attributes() {
+        if v, ok := want[string(attr.Key)]; ok {
+            if attr.Value.AsInterface() != v {
+                t.Errorf("expected %s %v, got %v", attr.Key, v, attr.Value.AsInterface())
+            }
+            delete(want, string(attr.Key))
+        }
+    }
+    if len(want) > 0 {
+        t.Errorf("missing attributes %v", want)
+    }
+    if span.Status().Code == codes.Error {
+        t.Error("expected a 4xx response not to mark the span failed")
+    }
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+    req := httptest.NewRequest(http.MethodGet, "/api/v1/comments/abc", nil)
+    span := serve(t, req, func(w http.ResponseWriter, r *http.Request) {
+        RecordError(r.Context(), errors.New("store unreachable"))
+        w.WriteHeader(http.StatusInternalServerError)
+    })
+
+    if span.Status().Code != codes.Error {
+        t.Errorf("expected error status, got %v", span.Status())
+    }
+    if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+        t.Errorf("expected the error recorded as an exception event, got %v", span.Events())
+    }
+}
+
+func TestMiddlewarePropagation(t *testing.T) {
+    tests := []struct {
+        name        string
+        header      string
+        value       string
+        wantTrace   string
+        wantParent  string
+        wantSampled bool
+    }{
+        {
+            name:        "traceparent",
+            header:      "traceparent",
+            value:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+            wantTrace:   "4bf92f3577b34da6a3ce929d0e0e4736",
+            wantParent:  "00f067aa0ba902b7",
+            wantSampled: true,
+        },
+        {
+            name:        "cloud trace",
+            header:      CloudTraceHeader,
+            value:       "105445aa7843bc8bf206b12000100000/1;o=1",
+            wantTrace:   "105445aa7843bc8bf206b12000100000",
+            wantParent:  "0000000000000001",
+            wantSampled: true,
+        },
+        {
+            name:        "cloud trace without span",
+            header:      CloudTraceHeader,
+            value:       "105445aa7843bc8bf206b12000100000;o=1",
+            wantTrace:   "105445aa7843bc8bf206b12000100000",
+            wantSampled: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodGet, "/api/v1/comments/abc", nil)
+            req.Header.Set(tt.header, tt.value)
+            span := serve(t, req, func(http.ResponseWriter, *http.Request) {})
+
+            if got := span.SpanContext().TraceID().String(); got != tt.wantTrace {
+                t.Errorf("expected trace %s, got %s", tt.wantTrace, got)
+            }
+            if tt.wantParent != "" {
+                if got := span.Parent().SpanID().String(); got != tt.wantParent {
+                    t.Errorf("expected parent %s, got %s", tt.wantParent, got)
+                }
+            }
+            if !span.Parent().IsRemote() {
+                t.Error("expected a remote parent")
+            }
+            if got := span.Parent().IsSampled(); got != tt.wantSampled {
+                t.Errorf("expected parent sampled %v, got %v", tt.wantSampled, got)
+            }
+        })
+    }
+}
+
+func TestParseCloudTraceContextInvalid(t *testing.T) {
+    for _, h := range []string{
+        "",
+        "not-a-trace",
+        "105445aa7843bc8bf206b12000100000/notanumber",
+        "00000000000000000000000000000000/1",
+    } {
+        if sc, ok := parseCloudTraceContext(h); ok {
+            t.Errorf("%q: expected no span context, got %v", h, sc)
+        }
+    }
+}
+
+func TestNewProviderNoop(t *testing.T) {
+    tp, shutdown, err := NewProvider(context.Background(), "", "test")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer shutdown(context.Background())
+
+    _, span := tp.Tracer(Name).Start(context.Background(), "op")
+    defer span.End()
+    if span.IsRecording() {
+        t.Error("expected no spans recorded without an endpoint")
+    }
+}
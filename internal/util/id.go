@@ -10,12 +10,42 @@ import (
     "strings"
 )
 
-// GenerateID generates a URL-safe, base64 encoded UUID
+// GenerateID generates a URL-safe, base64 encoded UUID. The IDs are random,
+// so they don't sort by creation time; see GenerateSortableID.
 func GenerateID() string {
     id := uuid.New()
     return base64.RawURLEncoding.EncodeToString(id[:])
 }
 
+// crockford is Crockford's base32 alphabet, which sorts in ASCII order.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// GenerateSortableID generates a UUIDv7 encoded as 26 characters of
+// Crockford base32, in the style of a ULID. IDs start with their creation
+// time in milliseconds and increase within a process even within the same
+// millisecond, so they sort lexicographically in creation order. That makes
+// them usable as pagination cursors.
+func GenerateSortableID() string {
+    id := uuid.Must(uuid.NewV7())
+
+    // 128 bits take 26 five-bit characters; the first carries the top 3
+    // bits
+    var out [26]byte
+    var acc uint64
+    bits := 2
+    i := 0
+    for _, b := range id {
+        acc = acc<<8 | uint64(b)
+        bits += 8
+        for bits >= 5 {
+            bits -= 5
+            out[i] = crockford[(acc>>bits)&31]
+            i++
+        }
+    }
+    return string(out[:])
+}
+
 // GenerateSecureToken generates a cryptographically secure random token
 func GenerateSecureToken(length int) (string, error) {
     b := make([]byte, length)
@@ -23,4 +53,4 @@ func GenerateSecureToken(length int) (string, error) {
         return "", fmt.Errorf("failed to generate secure token: %w", err)
     }
     return strings.TrimRight(base64.URLEncoding.EncodeToString(b), "="), nil
-}
\ No newline at end of file
+}
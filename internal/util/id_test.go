@@ -0,0 +1,25 @@
+// internal/util/id_test.go
+
+package util
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestGenerateSortableID(t *testing.T) {
+    prev := ""
+    for i := 0; i < 1000; i++ {
+        id := GenerateSortableID()
+        if len(id) != 26 {
+            t.Fatalf("expected 26 characters, got %d in %q", len(id), id)
+        }
+        if strings.Trim(id, crockford) != "" {
+            t.Fatalf("expected only Crockford base32 characters, got %q", id)
+        }
+        if id <= prev {
+            t.Fatalf("expected %q to sort after %q", id, prev)
+        }
+        prev = id
+    }
+}
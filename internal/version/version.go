@@ -0,0 +1,57 @@
+// internal/version/version.go
+
+// Package version holds the build metadata of the running binary. The
+// values are injected at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X web-service/internal/version.Version=v1.2.0 \
+//	    -X web-service/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	    -X web-service/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+//	    ./cmd/server
+package version
+
+import (
+    "fmt"
+    "runtime/debug"
+)
+
+// Set via -ldflags -X. Commit and BuildDate fall back to the VCS stamp Go
+// embeds in binaries built from a checkout.
+var (
+    Version   = "dev"
+    Commit    = ""
+    BuildDate = ""
+)
+
+// Info describes the running build.
+type Info struct {
+    Version   string
+    Commit    string
+    BuildDate string
+}
+
+// Get returns the build metadata, with "unknown" for anything that was
+// neither injected nor stamped by the toolchain.
+func Get() Info {
+    info := Info{Version: Version, Commit: Commit, BuildDate: BuildDate}
+    if bi, ok := debug.ReadBuildInfo(); ok {
+        for _, s := range bi.Settings {
+            switch {
+            case s.Key == "vcs.revision" && info.Commit == "":
+                info.Commit = s.Value
+            case s.Key == "vcs.time" && info.BuildDate == "":
+                info.BuildDate = s.Value
+            }
+        }
+    }
+    if info.Commit == "" {
+        info.Commit = "unknown"
+    }
+    if info.BuildDate == "" {
+        info.BuildDate = "unknown"
+    }
+    return info
+}
+
+func (i Info) String() string {
+    return fmt.Sprintf("%s (commit %s, built %s)", i.Version, i.Commit, i.BuildDate)
+}
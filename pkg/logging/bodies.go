@@ -0,0 +1,185 @@
+// pkg/logging/bodies.go
+
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redactedFields name the body fields whose values are never logged,
+// matched case-insensitively.
+var redactedFields = map[string]bool{
+    "password":      true,
+    "token":         true,
+    "authorization": true,
+}
+
+const redacted = "[REDACTED]"
+
+// redactPattern finds redacted fields in bodies that can't be parsed, such
+// as truncated JSON or form data: a JSON member with a string or bare value,
+// or a form field.
+var redactPattern = regexp.MustCompile(`(?i)("(?:password|token|authorization)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)|\b(password|token|authorization)=[^&\s]*`)
+
+// NewBodyLoggingMiddleware logs the first limit bytes of each request and
+// response body at DEBUG, for diagnosing what clients send and receive.
+// Installing it is the opt-in, so its entries are written whatever the
+// logger's level, without lowering the level for everything else.
+// Passwords, tokens and authorization values are redacted first. The
+// request body is captured as the handler reads it and the response as it
+// is written, so neither is buffered ahead of the handler.
+func NewBodyLoggingMiddleware(logger *Logger, limit int, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        state := &bodyLogState{}
+        r = r.WithContext(context.WithValue(r.Context(), bodyLogKey, state))
+        reqBody := &bodyCapture{limit: limit}
+        if r.Body != nil && r.Body != http.NoBody {
+            r.Body = struct {
+                io.Reader
+                io.Closer
+            }{io.TeeReader(r.Body, reqBody), r.Body}
+        }
+        bw := &bodyWriter{ResponseWriter: w, body: &bodyCapture{limit: limit}}
+
+        next.ServeHTTP(bw, r)
+
+        reqLogged, respLogged := reqBody.String(), bw.body.String()
+        if state.redacted() {
+            reqLogged, respLogged = reqBody.redacted(), bw.body.redacted()
+        }
+        logger.write(r.Context(), DEBUG, 1, "http bodies",
+            "method", r.Method,
+            "path", r.URL.Path,
+            "request_body", reqLogged,
+            "response_body", respLogged,
+        )
+    })
+}
+
+// bodyLogState is what handlers have said about a request's body logging.
+type bodyLogState struct {
+    mu     sync.Mutex
+    redact bool
+}
+
+func (s *bodyLogState) redacted() bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.redact
+}
+
+// RedactBodies keeps the request's bodies out of body logging entirely,
+// logging only their sizes, for endpoints such as login whose whole
+// exchange is sensitive. Outside NewBodyLoggingMiddleware it does nothing.
+func RedactBodies(ctx context.Context) {
+    if state, ok := ctx.Value(bodyLogKey).(*bodyLogState); ok {
+        state.mu.Lock()
+        state.redact = true
+        state.mu.Unlock()
+    }
+}
+
+// bodyCapture keeps the first limit bytes written to it and counts the
+// rest.
+type bodyCapture struct {
+    buf   bytes.Buffer
+    limit int
+    total int
+}
+
+func (c *bodyCapture) Write(p []byte) (int, error) {
+    c.total += len(p)
+    if room := c.limit - c.buf.Len(); room > 0 {
+        c.buf.Write(p[:min(room, len(p))])
+    }
+    return len(p), nil
+}
+
+// String returns the captured body redacted, with a marker if it was
+// truncated.
+func (c *bodyCapture) String() string {
+    s := redactBody(c.buf.Bytes())
+    if c.total > c.buf.Len() {
+        s += fmt.Sprintf("...[truncated, %d bytes total]", c.total)
+    }
+    return s
+}
+
+// redacted describes the captured body by its size alone.
+func (c *bodyCapture) redacted() string {
+    return fmt.Sprintf("%s (%d bytes)", redacted, c.total)
+}
+
+// redactBody returns body with redacted fields' values replaced. Valid JSON
+// is redacted field by field at any depth; anything else falls back to
+// pattern matching.
+func redactBody(body []byte) string {
+    var v any
+    if err := json.Unmarshal(body, &v); err == nil {
+        if out, err := json.Marshal(redactValue(v)); err == nil {
+            return string(out)
+        }
+    }
+    return redactPattern.ReplaceAllStringFunc(string(body), func(m string) string {
+        if i := strings.IndexByte(m, '='); i >= 0 && !strings.HasPrefix(m, `"`) {
+            return m[:i+1] + redacted
+        }
+        sub := redactPattern.FindStringSubmatch(m)
+        return sub[1] + `"` + redacted + `"`
+    })
+}
+
+func redactValue(v any) any {
+    switch v := v.(type) {
+    case map[string]any:
+        for k, val := range v {
+            if redactedFields[strings.ToLower(k)] {
+                v[k] = redacted
+            } else {
+                v[k] = redactValue(val)
+            }
+        }
+    case []any:
+        for i, val := range v {
+            v[i] = redactValue(val)
+        }
+    }
+    return v
+}
+
+// bodyWriter captures the response body as it is written. It passes
+// flushes and hijacks through so streamed responses still stream and
+// upgraded connections still upgrade.
+type bodyWriter struct {
+    http.ResponseWriter
+    body *bodyCapture
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+    n, err := w.ResponseWriter.Write(p)
+    w.body.Write(p[:n])
+    return n, err
+}
+
+func (w *bodyWriter) Flush() {
+    http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *bodyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *bodyWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
@@ -0,0 +1,102 @@
+// pkg/logging/bodies_test.go
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+    tests := []struct {
+        name string
+        body string
+        want string
+    }{
+        {name: "json", body: `{"username":"mod","password":"hunter2"}`, want: `{"password":"[REDACTED]","username":"mod"}`},
+        {name: "nested and case-insensitive", body: `{"auth":{"Token":"abc","scopes":["read"]},"items":[{"PASSWORD":1}]}`,
+            want: `{"auth":{"Token":"[REDACTED]","scopes":["read"]},"items":[{"PASSWORD":"[REDACTED]"}]}`},
+        {name: "truncated json", body: `{"username":"mod","password":"hun`, want: `{"username":"mod","password":"[REDACTED]"`},
+        {name: "escaped quote", body: `{"token":"a\"b","x":1`, want: `{"token":"[REDACTED]","x":1`},
+        {name: "form", body: `username=mod&password=hunter2&authorization=Bearer+x`, want: `username=mod&password=[REDACTED]&authorization=[REDACTED]`},
+        {name: "nothing to redact", body: `{"content":"hello"}`, want: `{"content":"hello"}`},
+        {name: "not json", body: `hello`, want: `hello`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := redactBody([]byte(tt.body)); got != tt.want {
+                t.Errorf("expected %s, got %s", tt.want, got)
+            }
+        })
+    }
+}
+
+func TestBodyLoggingMiddleware(t *testing.T) {
+    // The logger is left at INFO: body logging has its own level check
+    var logs bytes.Buffer
+    logger := NewLogger(&logs)
+
+    var received string
+    handler := NewBodyLoggingMiddleware(logger, 16, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        data, err := io.ReadAll(r.Body)
+        if err != nil {
+            t.Fatal(err)
+        }
+        received = string(data)
+        w.Write([]byte("first "))
+        w.(http.Flusher).Flush()
+        w.Write([]byte("second and more"))
+    }))
+
+    body := strings.Repeat("x", 40)
+    rec := httptest.NewRecorder()
+    handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+    if received != body {
+        t.Errorf("expected the handler to read the whole body, got %q", received)
+    }
+    if rec.Body.String() != "first second and more" || !rec.Flushed {
+        t.Errorf("expected the response written and flushed, got %q flushed %v", rec.Body.String(), rec.Flushed)
+    }
+
+    out := logs.String()
+    if !strings.Contains(out, `"level":"DEBUG"`) || !strings.Contains(out, `bodies.go:`) {
+        t.Errorf("expected a DEBUG entry from the middleware, got %s", out)
+    }
+    if !strings.Contains(out, strings.Repeat("x", 16)+"...[truncated, 40 bytes total]") {
+        t.Errorf("expected the request body truncated to 16 bytes, got %s", out)
+    }
+    if !strings.Contains(out, "first second and...[truncated, 21 bytes total]") {
+        t.Errorf("expected the response body truncated to 16 bytes, got %s", out)
+    }
+}
+
+func TestRedactBodies(t *testing.T) {
+    var logs bytes.Buffer
+    logger := NewLogger(&logs)
+    logger.SetLevel(DEBUG)
+
+    handler := NewBodyLoggingMiddleware(logger, 64, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        RedactBodies(r.Context())
+        io.ReadAll(r.Body)
+        w.Write([]byte(`{"session":"secret"}`))
+    }))
+    handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"user":"ada"}`)))
+
+    out := logs.String()
+    if strings.Contains(out, "ada") || strings.Contains(out, "secret") {
+        t.Errorf("expected the bodies left out, got %s", out)
+    }
+    if !strings.Contains(out, "[REDACTED] (14 bytes)") || !strings.Contains(out, "[REDACTED] (20 bytes)") {
+        t.Errorf("expected the body sizes logged, got %s", out)
+    }
+
+    // Outside the middleware it does nothing
+    RedactBodies(context.Background())
+}
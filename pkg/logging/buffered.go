@@ -0,0 +1,167 @@
+// pkg/logging/buffered.go
+
+package logging
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what a buffered logger does with an entry when its
+// buffer is full.
+type OverflowPolicy int
+
+const (
+    // DropOldest discards the oldest buffered entry to make room, so
+    // logging never blocks the caller.
+    DropOldest OverflowPolicy = iota
+    // Block waits for the writer to catch up, so no entry is lost.
+    Block
+)
+
+// WithBuffer makes the logger write asynchronously: entries are queued in a
+// buffer of size entries and written by a background goroutine, so a slow
+// writer doesn't add latency to the goroutine logging. policy decides what
+// happens when the buffer is full. Close the logger to flush the buffer and
+// stop the goroutine. A size <= 0 leaves the logger synchronous.
+func WithBuffer(size int, policy OverflowPolicy) Option {
+    return func(l *Logger) {
+        if size > 0 {
+            l.async = newAsyncWriter(l.out, size, policy)
+        }
+    }
+}
+
+// asyncWriter queues entries for a background goroutine to write.
+type asyncWriter struct {
+    out     io.Writer
+    queue   chan []byte
+    policy  OverflowPolicy
+    dropped atomic.Uint64
+    done    chan struct{}
+
+    // closeMu is held for reading while an entry is queued, so Close
+    // can't close the queue under a sender.
+    closeMu sync.RWMutex
+    closed  bool
+
+    // pending counts entries queued but not yet written or dropped, for
+    // Flush to wait on.
+    mu      sync.Mutex
+    drained *sync.Cond
+    pending int
+}
+
+func newAsyncWriter(out io.Writer, size int, policy OverflowPolicy) *asyncWriter {
+    a := &asyncWriter{
+        out:    out,
+        queue:  make(chan []byte, size),
+        policy: policy,
+        done:   make(chan struct{}),
+    }
+    a.drained = sync.NewCond(&a.mu)
+    go a.run()
+    return a
+}
+
+func (a *asyncWriter) run() {
+    defer close(a.done)
+    for entry := range a.queue {
+        a.out.Write(entry)
+        a.settle()
+    }
+}
+
+// write queues entry, applying the overflow policy if the queue is full.
+// Once closed, it writes entry directly instead.
+func (a *asyncWriter) write(entry []byte) {
+    a.closeMu.RLock()
+    defer a.closeMu.RUnlock()
+    if a.closed {
+        // Keep order with what was queued before Close
+        <-a.done
+        a.out.Write(entry)
+        return
+    }
+
+    a.mu.Lock()
+    a.pending++
+    a.mu.Unlock()
+
+    if a.policy == Block {
+        a.queue <- entry
+        return
+    }
+    for {
+        select {
+        case a.queue <- entry:
+            return
+        default:
+        }
+        select {
+        case <-a.queue:
+            a.dropped.Add(1)
+            a.settle()
+        default:
+        }
+    }
+}
+
+// settle records that a queued entry was written or dropped.
+func (a *asyncWriter) settle() {
+    a.mu.Lock()
+    a.pending--
+    if a.pending == 0 {
+        a.drained.Broadcast()
+    }
+    a.mu.Unlock()
+}
+
+// flush waits until every entry queued so far is written or dropped.
+func (a *asyncWriter) flush() {
+    a.mu.Lock()
+    for a.pending > 0 {
+        a.drained.Wait()
+    }
+    a.mu.Unlock()
+}
+
+// close writes what is queued and stops the goroutine. Entries logged
+// afterwards are written synchronously.
+func (a *asyncWriter) close() {
+    a.closeMu.Lock()
+    if !a.closed {
+        a.closed = true
+        close(a.queue)
+    }
+    a.closeMu.Unlock()
+    <-a.done
+}
+
+// Flush waits until every entry logged so far has been written. It returns
+// at once for a synchronous logger.
+func (l *Logger) Flush() {
+    if l.async != nil {
+        l.async.flush()
+    }
+}
+
+// Close flushes a buffered logger and stops its background goroutine.
+// The logger stays usable, writing synchronously. Call it during shutdown
+// so the last entries aren't lost.
+func (l *Logger) Close() error {
+    if l.async != nil {
+        l.async.close()
+    }
+    return nil
+}
+
+// Dropped returns how many entries a buffered logger using DropOldest has
+// discarded because its buffer was full.
+func (l *Logger) Dropped() uint64 {
+    if l.async == nil {
+        return 0
+    }
+    return l.async.dropped.Load()
+}
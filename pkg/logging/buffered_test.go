@@ -0,0 +1,195 @@
+// pkg/logging/buffered_test.go
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter holds every write until its gate is opened, standing in for
+// a stalled stdout.
+type gatedWriter struct {
+    gate chan struct{}
+    mu   sync.Mutex
+    buf  bytes.Buffer
+}
+
+func newGatedWriter() *gatedWriter {
+    return &gatedWriter{gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+    <-w.gate
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.buf.Write(p)
+}
+
+// messages returns the messages of the entries written so far.
+func (w *gatedWriter) messages(t *testing.T) []string {
+    t.Helper()
+
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    var messages []string
+    dec := json.NewDecoder(bytes.NewReader(w.buf.Bytes()))
+    for dec.More() {
+        var entry struct {
+            Message string `json:"message"`
+        }
+        if err := dec.Decode(&entry); err != nil {
+            t.Fatal(err)
+        }
+        messages = append(messages, entry.Message)
+    }
+    return messages
+}
+
+func TestBufferedLoggerFlush(t *testing.T) {
+    out := newGatedWriter()
+    close(out.gate)
+    logger := NewLogger(out, WithBuffer(16, Block))
+    defer logger.Close()
+
+    for i := 0; i < 100; i++ {
+        logger.Info(context.Background(), fmt.Sprintf("entry %d", i))
+    }
+    logger.Flush()
+
+    messages := out.messages(t)
+    if len(messages) != 100 {
+        t.Fatalf("expected 100 entries after Flush, got %d", len(messages))
+    }
+    for i, msg := range messages {
+        if want := fmt.Sprintf("entry %d", i); msg != want {
+            t.Fatalf("expected entry %d to be %q, got %q", i, want, msg)
+        }
+    }
+    if n := logger.Dropped(); n != 0 {
+        t.Errorf("expected no entries dropped, got %d", n)
+    }
+}
+
+func TestBufferedLoggerDropOldest(t *testing.T) {
+    out := newGatedWriter()
+    logger := NewLogger(out, WithBuffer(4, DropOldest))
+
+    // With the writer stalled, logging must still return promptly
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for i := 0; i < 20; i++ {
+            logger.Info(context.Background(), fmt.Sprintf("entry %d", i))
+        }
+    }()
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("logging blocked on a stalled writer")
+    }
+
+    close(out.gate)
+    logger.Close()
+
+    messages := out.messages(t)
+    dropped := logger.Dropped()
+    if dropped == 0 {
+        t.Fatal("expected entries to be dropped")
+    }
+    if uint64(len(messages))+dropped != 20 {
+        t.Errorf("expected %d written and %d dropped to total 20", len(messages), dropped)
+    }
+    if last := messages[len(messages)-1]; last != "entry 19" {
+        t.Errorf("expected the newest entry kept, got %q last", last)
+    }
+}
+
+func TestBufferedLoggerBlock(t *testing.T) {
+    out := newGatedWriter()
+    logger := NewLogger(out, WithBuffer(2, Block))
+
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for i := 0; i < 10; i++ {
+            logger.Info(context.Background(), fmt.Sprintf("entry %d", i))
+        }
+    }()
+    select {
+    case <-done:
+        t.Fatal("expected logging to block while the buffer is full")
+    case <-time.After(50 * time.Millisecond):
+    }
+
+    close(out.gate)
+    <-done
+    logger.Close()
+
+    if messages := out.messages(t); len(messages) != 10 {
+        t.Errorf("expected all 10 entries written, got %d", len(messages))
+    }
+    if n := logger.Dropped(); n != 0 {
+        t.Errorf("expected no entries dropped, got %d", n)
+    }
+}
+
+func TestBufferedLoggerAfterClose(t *testing.T) {
+    out := newGatedWriter()
+    close(out.gate)
+    logger := NewLogger(out, WithBuffer(8, DropOldest))
+
+    logger.Info(context.Background(), "before close")
+    logger.Close()
+    logger.Info(context.Background(), "after close")
+    logger.Close()
+
+    messages := out.messages(t)
+    if len(messages) != 2 || messages[0] != "before close" || messages[1] != "after close" {
+        t.Errorf("expected both entries in order, got %v", messages)
+    }
+}
+
+// slowWriter takes delay over every write, like stdout under backpressure.
+type slowWriter struct {
+    delay time.Duration
+}
+
+func (w slowWriter) Write(p []byte) (int, error) {
+    time.Sleep(w.delay)
+    return len(p), nil
+}
+
+// BenchmarkLogSlowWriter measures the time an Info call takes on the
+// logging goroutine when the writer is slow, synchronous against buffered:
+//
+//	go test -run=^$ -bench=LogSlowWriter ./pkg/logging
+func BenchmarkLogSlowWriter(b *testing.B) {
+    out := slowWriter{delay: 100 * time.Microsecond}
+    loggers := []struct {
+        name string
+        opts []Option
+    }{
+        {name: "Sync"},
+        {name: "Buffered", opts: []Option{WithBuffer(1024, DropOldest)}},
+    }
+
+    for _, lg := range loggers {
+        b.Run(lg.name, func(b *testing.B) {
+            logger := NewLogger(out, lg.opts...)
+            ctx := context.Background()
+            b.ResetTimer()
+            for i := 0; i < b.N; i++ {
+                logger.Info(ctx, "request completed", "status", 200, "path", "/api/v1/comments")
+            }
+            b.StopTimer()
+            b.ReportMetric(float64(logger.Dropped()), "dropped")
+            logger.Close()
+        })
+    }
+}
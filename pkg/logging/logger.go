@@ -3,13 +3,22 @@
 package logging
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go.opentelemetry.io/otel/trace"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -37,9 +46,95 @@ func (l Level) String() string {
     }
 }
 
+type contextKey string
+
+const (
+    requestIDKey  contextKey = "request_id"
+    accessInfoKey contextKey = "access_info"
+    bodyLogKey    contextKey = "body_log"
+)
+
+// RequestIDHeader carries the request ID in both directions: an incoming
+// value is honored when valid, and the chosen ID is always echoed back.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
+// RequestIDFromContext returns the request ID set by the logging middleware.
+func RequestIDFromContext(ctx context.Context) string {
+    if id, ok := ctx.Value(requestIDKey).(string); ok {
+        return id
+    }
+    return ""
+}
+
+// accessInfo collects what inner handlers learn about a request, such as
+// who made it, for the completion entry the middleware logs once they
+// return.
+type accessInfo struct {
+    mu     sync.Mutex
+    userID string
+}
+
+// SetUserID records the authenticated user on the request's access log
+// entry. Auth runs inside the logging middleware, so its context isn't
+// otherwise visible there. Outside the middleware it does nothing.
+func SetUserID(ctx context.Context, userID string) {
+    if info, ok := ctx.Value(accessInfoKey).(*accessInfo); ok {
+        info.mu.Lock()
+        info.userID = userID
+        info.mu.Unlock()
+    }
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to
+// adopt: non-empty, bounded and limited to URL-safe characters.
+func validRequestID(id string) bool {
+    if id == "" || len(id) > maxRequestIDLength {
+        return false
+    }
+    for _, r := range id {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+        case r == '-', r == '_', r == '.', r == ':':
+        default:
+            return false
+        }
+    }
+    return true
+}
+
+// Logger writes JSON log entries. Loggers derived with With or
+// WithCallerSkip share the original's output, level and settings.
 type Logger struct {
-    out    io.Writer
-    level  Level
+    *output
+
+    // fields are key/value pairs added to every entry, bound with With.
+    // They are never modified, only copied, so derived loggers can be
+    // used concurrently.
+    fields []interface{}
+
+    // callerSkip is how many frames above the Debug/Info/Warn/Error call
+    // the reported caller is.
+    callerSkip int
+}
+
+// output is the state shared by a logger and those derived from it.
+type output struct {
+    out   io.Writer
+    level Level
+
+    // mu serialises writes so concurrent goroutines don't interleave
+    // entries or race on writers that aren't goroutine-safe.
+    mu sync.Mutex
+
+    // async writes entries in the background when the logger is buffered
+    // (see WithBuffer), and is nil when it writes synchronously.
+    async *asyncWriter
+
+    // stackTraces adds a stack trace to ERROR entries.
+    stackTraces bool
 }
 
 type logEntry struct {
@@ -49,27 +144,109 @@ type logEntry struct {
     Caller     string                 `json:"caller,omitempty"`
     Fields     map[string]interface{} `json:"fields,omitempty"`
     StackTrace string                 `json:"stack_trace,omitempty"`
+    // Goroutine is the ID of the goroutine that logged an entry carrying a
+    // stack trace, to match it with other traces and profiles.
+    Goroutine uint64 `json:"goroutine,omitempty"`
+}
+
+// Option configures a Logger.
+type Option func(*Logger)
+
+// WithStackTraces sets whether ERROR entries carry a stack trace, which
+// they do by default. Other entries can ask for one with Stack.
+func WithStackTraces(enabled bool) Option {
+    return func(l *Logger) {
+        l.stackTraces = enabled
+    }
 }
 
-func NewLogger(out io.Writer) *Logger {
+// NewLogger returns a logger writing JSON entries to out, or to stdout if
+// out is nil. It writes synchronously unless configured with WithBuffer.
+func NewLogger(out io.Writer, opts ...Option) *Logger {
     if out == nil {
         out = os.Stdout
     }
-    return &Logger{
-        out:   out,
-        level: INFO,
+    l := &Logger{output: &output{
+        out:         out,
+        level:       INFO,
+        stackTraces: true,
+    }}
+    for _, opt := range opts {
+        opt(l)
     }
+    return l
 }
 
 func (l *Logger) SetLevel(level Level) {
     l.level = level
 }
 
+// WithCallerSkip returns a logger that reports the caller skip frames
+// further up the stack, for helpers that wrap the logger so entries point at
+// the helper's caller rather than the helper.
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+    return &Logger{output: l.output, fields: l.fields, callerSkip: l.callerSkip + skip}
+}
+
+// With returns a logger that adds fields, alternating keys and values, to
+// every entry after any bound already. Fields passed to a log call take
+// precedence over bound fields with the same key.
+func (l *Logger) With(fields ...interface{}) *Logger {
+    bound := make([]interface{}, 0, len(l.fields)+len(fields))
+    bound = append(append(bound, l.fields...), fields...)
+    return &Logger{output: l.output, fields: bound, callerSkip: l.callerSkip}
+}
+
+// maxStackTraceBytes bounds the stack trace added to ERROR entries.
+const maxStackTraceBytes = 64 << 10
+
+// Stack, passed among a log call's fields in place of a key, adds a stack
+// trace to that entry at any level, whether or not ERROR entries get one
+// automatically:
+//
+//	logger.Warn(ctx, "retrying", "attempt", n, logging.Stack)
+//
+// Bound with With, it adds one to every entry of the derived logger.
+var Stack = stackField{}
+
+type stackField struct{}
+
+// goroutineID returns the ID of the goroutine whose stack is trace, from
+// its "goroutine N [status]:" header, or 0 if it has none.
+func goroutineID(trace string) uint64 {
+    header, _, _ := strings.Cut(trace, " [")
+    id, err := strconv.ParseUint(strings.TrimPrefix(header, "goroutine "), 10, 64)
+    if err != nil {
+        return 0
+    }
+    return id
+}
+
+// stackTrace returns the calling goroutine's stack, growing the buffer
+// until it fits or reaches maxStackTraceBytes.
+func stackTrace() string {
+    buf := make([]byte, 2048)
+    for {
+        n := runtime.Stack(buf, false)
+        if n < len(buf) || len(buf) >= maxStackTraceBytes {
+            return string(buf[:n])
+        }
+        buf = make([]byte, 2*len(buf))
+    }
+}
+
 func (l *Logger) log(ctx context.Context, level Level, msg string, fields ...interface{}) {
     if level < l.level {
         return
     }
+    // The frames above are log, Debug/Info/Warn/Error and whatever wraps
+    // the logger
+    l.write(ctx, level, 3, msg, fields...)
+}
 
+// write writes an entry at level whatever the logger's level, reporting
+// the caller skip frames up the stack from write.
+func (l *Logger) write(ctx context.Context, level Level, skip int, msg string, fields ...interface{}) {
     entry := logEntry{
         Time:    time.Now(),
         Level:   level.String(),
@@ -78,40 +255,99 @@ func (l *Logger) log(ctx context.Context, level Level, msg string, fields ...int
     }
 
     // Add caller information
-    if pc, file, line, ok := runtime.Caller(2); ok {
-        if fn := runtime.FuncForPC(pc); fn != nil {
-            entry.Caller = fmt.Sprintf("%s:%d", file, line)
-        }
+    if _, file, line, ok := runtime.Caller(skip + l.callerSkip); ok {
+        entry.Caller = fmt.Sprintf("%s:%d", file, line)
     }
 
     // Add context values if any
     if ctx != nil {
-        if requestID, ok := ctx.Value("request_id").(string); ok {
+        if requestID := RequestIDFromContext(ctx); requestID != "" {
             entry.Fields["request_id"] = requestID
         }
         if userID, ok := ctx.Value("user_id").(string); ok {
             entry.Fields["user_id"] = userID
         }
-    }
-
-    // Add additional fields
-    for i := 0; i < len(fields)-1; i += 2 {
-        if key, ok := fields[i].(string); ok {
-            entry.Fields[key] = fields[i+1]
+        // Tie the entry to the request's trace, if it has one
+        if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+            entry.Fields["trace_id"] = sc.TraceID().String()
+            entry.Fields["span_id"] = sc.SpanID().String()
         }
     }
 
-    // Add stack trace for errors
-    if level == ERROR {
-        buf := make([]byte, 1024)
-        n := runtime.Stack(buf, false)
-        entry.StackTrace = string(buf[:n])
+    // Add bound fields, then the call's own so they win on conflict
+    boundStack := addFields(entry.Fields, l.fields)
+    callStack := addFields(entry.Fields, fields)
+
+    // Add stack trace for errors, unless disabled, or when asked for
+    if (level == ERROR && l.stackTraces) || boundStack || callStack {
+        entry.StackTrace = stackTrace()
+        entry.Goroutine = goroutineID(entry.StackTrace)
     }
 
     // Encode and write the log entry
     if data, err := json.Marshal(entry); err == nil {
+        if l.async != nil {
+            l.async.write(append(data, '\n'))
+            return
+        }
+        l.mu.Lock()
         l.out.Write(append(data, '\n'))
+        l.mu.Unlock()
+    }
+}
+
+// badKey is the key malformed fields are recorded under: a value where a
+// key was expected, or a key with no value after it.
+const badKey = "!BADKEY"
+
+// addFields adds fields, alternating keys and values, to dst. As with
+// log/slog, an argument that should be a key but isn't a string is
+// recorded under badKey and the next argument is read as a key, and a
+// trailing key with no value is recorded under badKey too, so mistakes
+// show in the output rather than vanishing. Several malformed arguments
+// are listed together. Errors are recorded as their message. It reports
+// whether Stack was among the keys.
+func addFields(dst map[string]interface{}, fields []interface{}) (stack bool) {
+    for i := 0; i < len(fields); i++ {
+        if _, ok := fields[i].(stackField); ok {
+            stack = true
+            continue
+        }
+        key, ok := fields[i].(string)
+        if !ok || i == len(fields)-1 {
+            addBadField(dst, fields[i])
+            continue
+        }
+        i++
+        dst[key] = fieldValue(fields[i])
     }
+    return stack
+}
+
+func addBadField(dst map[string]interface{}, v interface{}) {
+    v = fieldValue(v)
+    prev, ok := dst[badKey]
+    if !ok {
+        dst[badKey] = v
+        return
+    }
+    if list, ok := prev.(badFields); ok {
+        dst[badKey] = append(list, v)
+        return
+    }
+    dst[badKey] = badFields{prev, v}
+}
+
+// badFields lists the malformed arguments of one entry.
+type badFields []interface{}
+
+// fieldValue returns v as it should be logged: errors, which would
+// otherwise marshal as an empty object, become their message.
+func fieldValue(v interface{}) interface{} {
+    if err, ok := v.(error); ok && err != nil {
+        return err.Error()
+    }
+    return v
 }
 
 func (l *Logger) Debug(ctx context.Context, msg string, fields ...interface{}) {
@@ -130,19 +366,68 @@ func (l *Logger) Error(ctx context.Context, msg string, fields ...interface{}) {
     l.log(ctx, ERROR, msg, fields...)
 }
 
+// middlewareConfig holds what NewLoggingMiddleware takes from the
+// service embedding it.
+type middlewareConfig struct {
+    newRequestID func() string
+    clientIP     func(*http.Request) string
+}
+
+// MiddlewareOption configures NewLoggingMiddleware.
+type MiddlewareOption func(*middlewareConfig)
+
+// WithRequestIDGenerator sets how request IDs are generated for requests
+// that don't carry a valid one. The default is a random 128-bit ID.
+func WithRequestIDGenerator(generate func() string) MiddlewareOption {
+    return func(c *middlewareConfig) {
+        c.newRequestID = generate
+    }
+}
+
+// WithClientIP sets how the client IP logged for a request is found, for
+// services behind proxies. The default is the request's RemoteAddr.
+func WithClientIP(clientIP func(*http.Request) string) MiddlewareOption {
+    return func(c *middlewareConfig) {
+        c.clientIP = clientIP
+    }
+}
+
+// remoteAddr returns the address of r's direct peer.
+func remoteAddr(r *http.Request) string {
+    return r.RemoteAddr
+}
+
+// newRequestID returns 16 random bytes as unpadded URL-safe base64.
+func newRequestID() string {
+    var b [16]byte
+    rand.Read(b[:])
+    return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
 // Middleware to add request ID to context
-func NewLoggingMiddleware(logger *Logger, next http.Handler) http.Handler {
+func NewLoggingMiddleware(logger *Logger, next http.Handler, opts ...MiddlewareOption) http.Handler {
+    config := middlewareConfig{newRequestID: newRequestID, clientIP: remoteAddr}
+    for _, opt := range opts {
+        opt(&config)
+    }
+
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        // Generate request ID
-        requestID := fmt.Sprintf("%d", time.Now().UnixNano())
+        // Reuse the caller's request ID if valid, otherwise generate one
+        requestID := r.Header.Get(RequestIDHeader)
+        if !validRequestID(requestID) {
+            requestID = config.newRequestID()
+        }
+        w.Header().Set(RequestIDHeader, requestID)
 
         // Create new context with request ID
-        ctx := context.WithValue(r.Context(), "request_id", requestID)
+        ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+        info := &accessInfo{}
+        ctx = context.WithValue(ctx, accessInfoKey, info)
 
         // Create response writer wrapper to capture status code
         wrw := &responseWriter{
             ResponseWriter: w,
-            status:        http.StatusOK,
+            status:         http.StatusOK,
         }
 
         // Log request
@@ -150,7 +435,7 @@ func NewLoggingMiddleware(logger *Logger, next http.Handler) http.Handler {
             "method", r.Method,
             "path", r.URL.Path,
             "request_id", requestID,
-            "remote_addr", r.RemoteAddr,
+            "client_ip", config.clientIP(r),
         )
 
         startTime := time.Now()
@@ -158,41 +443,66 @@ func NewLoggingMiddleware(logger *Logger, next http.Handler) http.Handler {
         // Call next handler
         next.ServeHTTP(wrw, r.WithContext(ctx))
 
-        // Log response
-        logger.Info(ctx, "request completed",
+        // Log response. A request whose context was cancelled before the
+        // handler finished was abandoned by the client
+        fields := []interface{}{
             "method", r.Method,
             "path", r.URL.Path,
             "status", wrw.status,
+            "bytes", wrw.bytes,
             "duration_ms", time.Since(startTime).Milliseconds(),
             "request_id", requestID,
-        )
+            "user_agent", r.UserAgent(),
+            "aborted", errors.Is(ctx.Err(), context.Canceled),
+        }
+        info.mu.Lock()
+        if info.userID != "" {
+            fields = append(fields, "user_id", info.userID)
+        }
+        info.mu.Unlock()
+        logger.Info(ctx, "request completed", fields...)
     })
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture the status code and
+// count the bytes of body written
 type responseWriter struct {
     http.ResponseWriter
-    status int
+    status      int
+    bytes       int64
+    wroteHeader bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-    rw.status = code
+    rw.status, rw.wroteHeader = code, true
     rw.ResponseWriter.WriteHeader(code)
 }
 
-// Function to add trace ID to context
-func NewGoogleTraceIDMiddleware(logger *Logger, next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        traceID := r.Header.Get("X-Cloud-Trace-Context")
-        if traceID == "" {
-            traceID = fmt.Sprintf("trace-%d", time.Now().UnixNano())
-        }
+func (rw *responseWriter) Write(b []byte) (int, error) {
+    rw.wroteHeader = true
+    n, err := rw.ResponseWriter.Write(b)
+    rw.bytes += int64(n)
+    return n, err
+}
 
-        ctx := context.WithValue(r.Context(), "trace_id", traceID)
-        logger.Debug(ctx, "trace context added",
-            "trace_id", traceID,
-        )
+// Flush passes flushes through so streaming handlers that assert
+// http.Flusher still stream.
+func (rw *responseWriter) Flush() {
+    http.NewResponseController(rw.ResponseWriter).Flush()
+}
 
-        next.ServeHTTP(w, r.WithContext(ctx))
-    })
-}
\ No newline at end of file
+// Hijack passes hijacks through so connections can be taken over, as for
+// WebSocket upgrades. A hijacked request is logged with status 101 unless
+// the handler wrote another, since its response no longer goes through rw.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+    if err == nil && !rw.wroteHeader {
+        rw.status = http.StatusSwitchingProtocols
+    }
+    return conn, buf, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+    return rw.ResponseWriter
+}
@@ -0,0 +1,600 @@
+// pkg/logging/logger_test.go
+
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// completionFields returns the fields of the "request completed" entry.
+func completionFields(t *testing.T, logs *bytes.Buffer) map[string]any {
+    t.Helper()
+
+    dec := json.NewDecoder(logs)
+    for dec.More() {
+        var entry struct {
+            Message string         `json:"message"`
+            Fields  map[string]any `json:"fields"`
+        }
+        if err := dec.Decode(&entry); err != nil {
+            t.Fatal(err)
+        }
+        if entry.Message == "request completed" {
+            return entry.Fields
+        }
+    }
+    t.Fatal("no request completed entry")
+    return nil
+}
+
+func TestLoggingMiddlewareAccessFields(t *testing.T) {
+    var logs bytes.Buffer
+    handler := NewLoggingMiddleware(NewLogger(&logs), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        SetUserID(r.Context(), "alice")
+        w.WriteHeader(http.StatusCreated)
+        w.Write([]byte("hello "))
+        w.Write([]byte("world"))
+    }))
+
+    req := httptest.NewRequest(http.MethodPost, "/api/v1/comments", nil)
+    req.Header.Set("User-Agent", "test-client/1.0")
+    handler.ServeHTTP(httptest.NewRecorder(), req)
+
+    fields := completionFields(t, &logs)
+    want := map[string]any{
+        "status":     float64(http.StatusCreated),
+        "bytes":      float64(len("hello world")),
+        "user_agent": "test-client/1.0",
+        "user_id":    "alice",
+        "aborted":    false,
+    }
+    for key, value := range want {
+        if fields[key] != value {
+            t.Errorf("expected %s %v, got %v", key, value, fields[key])
+        }
+    }
+}
+
+func TestLoggingMiddlewareRequestIDGenerator(t *testing.T) {
+    tests := []struct {
+        name  string
+        opts  []MiddlewareOption
+        check func(id string) bool
+    }{
+        {name: "default", check: validRequestID},
+        {name: "injected", opts: []MiddlewareOption{WithRequestIDGenerator(func() string { return "req-1" })}, check: func(id string) bool { return id == "req-1" }},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            handler := NewLoggingMiddleware(NewLogger(io.Discard), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), tt.opts...)
+            rec := httptest.NewRecorder()
+            handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+            if id := rec.Header().Get(RequestIDHeader); !tt.check(id) {
+                t.Errorf("unexpected request ID %q", id)
+            }
+        })
+    }
+}
+
+func TestLoggingMiddlewareClientIP(t *testing.T) {
+    tests := []struct {
+        name string
+        opts []MiddlewareOption
+        want string
+    }{
+        {name: "default", want: "192.0.2.1:1234"},
+        {name: "injected", opts: []MiddlewareOption{WithClientIP(func(*http.Request) string { return "203.0.113.7" })}, want: "203.0.113.7"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var logs bytes.Buffer
+            handler := NewLoggingMiddleware(NewLogger(&logs), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), tt.opts...)
+            handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+            var entry struct {
+                Message string         `json:"message"`
+                Fields  map[string]any `json:"fields"`
+            }
+            if err := json.NewDecoder(&logs).Decode(&entry); err != nil {
+                t.Fatal(err)
+            }
+            if entry.Message != "request started" || entry.Fields["client_ip"] != tt.want {
+                t.Errorf("expected request started with client_ip %q, got %q with %v", tt.want, entry.Message, entry.Fields["client_ip"])
+            }
+        })
+    }
+}
+
+func TestLoggingMiddlewareStreams(t *testing.T) {
+    var logs bytes.Buffer
+    logger := NewLogger(&logs)
+    logger.SetLevel(DEBUG)
+
+    // Streaming handlers flush through both the access and body loggers,
+    // whether they assert http.Flusher or use a ResponseController
+    var flushes []bool
+    rec := httptest.NewRecorder()
+    handler := NewLoggingMiddleware(logger, NewBodyLoggingMiddleware(logger, 64, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("data: one\n\n"))
+        w.(http.Flusher).Flush()
+        flushes = append(flushes, rec.Flushed)
+        rec.Flushed = false
+        w.Write([]byte("data: two\n\n"))
+        if err := http.NewResponseController(w).Flush(); err != nil {
+            t.Errorf("flushing: %v", err)
+        }
+        flushes = append(flushes, rec.Flushed)
+    })))
+    handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/comments/stream", nil))
+
+    if len(flushes) != 2 || !flushes[0] || !flushes[1] {
+        t.Errorf("expected both flushes to reach the client, got %v", flushes)
+    }
+    if fields := completionFields(t, &logs); fields["bytes"] != float64(len("data: one\n\ndata: two\n\n")) {
+        t.Errorf("expected every streamed byte counted, got %v", fields["bytes"])
+    }
+}
+
+func TestLoggingMiddlewareHijacks(t *testing.T) {
+    var logs bytes.Buffer
+    logger := NewLogger(&logs)
+    logger.SetLevel(DEBUG)
+
+    // An upgrading handler takes the connection over through both the
+    // access and body loggers
+    done := make(chan struct{})
+    handler := NewLoggingMiddleware(logger, NewBodyLoggingMiddleware(logger, 64, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        hj, ok := w.(http.Hijacker)
+        if !ok {
+            t.Error("expected the wrapped writer to be an http.Hijacker")
+            return
+        }
+        conn, buf, err := hj.Hijack()
+        if err != nil {
+            t.Errorf("hijacking: %v", err)
+            return
+        }
+        defer conn.Close()
+        buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\nhello")
+        buf.Flush()
+    })))
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        defer close(done)
+        handler.ServeHTTP(w, r)
+    }))
+    defer srv.Close()
+
+    conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+    io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+    br := bufio.NewReader(conn)
+    resp, err := http.ReadResponse(br, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if resp.StatusCode != http.StatusSwitchingProtocols {
+        t.Errorf("expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+    }
+    if body, _ := io.ReadAll(br); string(body) != "hello" {
+        t.Errorf("expected the upgraded connection to carry %q, got %q", "hello", body)
+    }
+    <-done
+
+    if fields := completionFields(t, &logs); fields["status"] != float64(http.StatusSwitchingProtocols) {
+        t.Errorf("expected a hijacked request logged with status 101, got %v", fields["status"])
+    }
+}
+
+func TestLoggingMiddlewareAnonymousAndAborted(t *testing.T) {
+    var logs bytes.Buffer
+    ctx, cancel := context.WithCancel(context.Background())
+    handler := NewLoggingMiddleware(NewLogger(&logs), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        // The client goes away mid-request
+        cancel()
+        <-r.Context().Done()
+    }))
+
+    req := httptest.NewRequest(http.MethodGet, "/healthz", nil).WithContext(ctx)
+    handler.ServeHTTP(httptest.NewRecorder(), req)
+
+    fields := completionFields(t, &logs)
+    if fields["aborted"] != true {
+        t.Errorf("expected aborted true, got %v", fields["aborted"])
+    }
+    if _, ok := fields["user_id"]; ok {
+        t.Errorf("expected no user_id for an unauthenticated request, got %v", fields["user_id"])
+    }
+    if fields["bytes"] != float64(0) {
+        t.Errorf("expected bytes 0, got %v", fields["bytes"])
+    }
+}
+
+func TestLogTraceFields(t *testing.T) {
+    traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+    spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+    ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+        TraceID: traceID,
+        SpanID:  spanID,
+    }))
+
+    var logs bytes.Buffer
+    NewLogger(&logs).Info(ctx, "hello")
+
+    var entry struct {
+        Fields map[string]any `json:"fields"`
+    }
+    if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+        t.Fatal(err)
+    }
+    if entry.Fields["trace_id"] != traceID.String() || entry.Fields["span_id"] != spanID.String() {
+        t.Errorf("expected trace and span IDs, got %v", entry.Fields)
+    }
+}
+
+// callSite returns the file:line of its caller, offset by lines, to compare
+// against an entry's caller.
+func callSite(t *testing.T, lines int) string {
+    t.Helper()
+
+    _, file, line, ok := runtime.Caller(1)
+    if !ok {
+        t.Fatal("no caller")
+    }
+    return fmt.Sprintf("%s:%d", file, line+lines)
+}
+
+// decodeEntry decodes the single entry in logs.
+func decodeEntry(t *testing.T, logs *bytes.Buffer) (caller, stackTrace string) {
+    t.Helper()
+
+    var entry struct {
+        Caller     string `json:"caller"`
+        StackTrace string `json:"stack_trace"`
+    }
+    if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+        t.Fatal(err)
+    }
+    return entry.Caller, entry.StackTrace
+}
+
+func TestLogCallerInHandler(t *testing.T) {
+    var logs bytes.Buffer
+    logger := NewLogger(&logs)
+    var want string
+    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        want = callSite(t, 1)
+        logger.Warn(r.Context(), "from the handler")
+    })
+    handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if caller, _ := decodeEntry(t, &logs); caller != want {
+        t.Errorf("expected caller %s, got %s", want, caller)
+    }
+}
+
+func TestLogCallerSkip(t *testing.T) {
+    var logs bytes.Buffer
+    logger := NewLogger(&logs)
+
+    // warn stands in for a helper wrapping the logger
+    warn := func(msg string) {
+        logger.WithCallerSkip(1).Warn(context.Background(), msg)
+    }
+    want := callSite(t, 1)
+    warn("from the helper's caller")
+
+    if caller, _ := decodeEntry(t, &logs); caller != want {
+        t.Errorf("expected caller %s, got %s", want, caller)
+    }
+}
+
+// logAtDepth logs an error from depth nested calls below its caller.
+func logAtDepth(logger *Logger, depth int) {
+    if depth > 0 {
+        logAtDepth(logger, depth-1)
+        return
+    }
+    logger.Error(context.Background(), "deep failure")
+}
+
+func TestLogStackTrace(t *testing.T) {
+    var logs bytes.Buffer
+    logAtDepth(NewLogger(&logs), 50)
+
+    // A deep stack is captured whole, down to the test
+    _, stack := decodeEntry(t, &logs)
+    if !strings.Contains(stack, "TestLogStackTrace") {
+        t.Errorf("expected the stack trace to reach the test, got %d bytes", len(stack))
+    }
+
+    logs.Reset()
+    logAtDepth(NewLogger(&logs, WithStackTraces(false)), 0)
+    if _, stack := decodeEntry(t, &logs); stack != "" {
+        t.Errorf("expected no stack trace when disabled, got %d bytes", len(stack))
+    }
+}
+
+func TestLogStack(t *testing.T) {
+    var logs bytes.Buffer
+    ctx := context.Background()
+
+    decode := func() (fields map[string]any, stack string, goroutine uint64) {
+        t.Helper()
+        var entry struct {
+            Fields     map[string]any `json:"fields"`
+            StackTrace string         `json:"stack_trace"`
+            Goroutine  uint64         `json:"goroutine"`
+        }
+        if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+            t.Fatal(err)
+        }
+        logs.Reset()
+        return entry.Fields, entry.StackTrace, entry.Goroutine
+    }
+
+    tests := []struct {
+        name      string
+        log       func(*Logger)
+        wantStack bool
+    }{
+        {"requested on a warning", func(l *Logger) { l.Warn(ctx, "retrying", "attempt", 2, Stack) }, true},
+        {"requested between fields", func(l *Logger) { l.Info(ctx, "retrying", Stack, "attempt", 2) }, true},
+        {"bound with With", func(l *Logger) { l.With(Stack).Info(ctx, "retrying", "attempt", 2) }, true},
+        {"not requested", func(l *Logger) { l.Warn(ctx, "retrying", "attempt", 2) }, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            // Requests are honoured even with automatic traces off
+            tt.log(NewLogger(&logs, WithStackTraces(false)))
+            fields, stack, goroutine := decode()
+            if fields["attempt"] != float64(2) || len(fields) != 1 {
+                t.Errorf("expected only the attempt field, got %v", fields)
+            }
+            if got := strings.Contains(stack, "TestLogStack"); got != tt.wantStack {
+                t.Errorf("expected stack trace %v, got %q", tt.wantStack, stack)
+            }
+            if (goroutine != 0) != tt.wantStack {
+                t.Errorf("expected a goroutine ID only with a stack trace, got %d", goroutine)
+            }
+        })
+    }
+
+    // Automatic traces carry the goroutine ID too
+    NewLogger(&logs).Error(ctx, "failed")
+    if _, stack, goroutine := decode(); goroutine == 0 || !strings.HasPrefix(stack, fmt.Sprintf("goroutine %d [", goroutine)) {
+        t.Errorf("expected the goroutine ID from the trace, got %d for %.40q", goroutine, stack)
+    }
+}
+
+func TestLoggerWith(t *testing.T) {
+    var logs bytes.Buffer
+    base := NewLogger(&logs)
+    child := base.With("comment_id", "c1", "user_id", "alice")
+    grandchild := child.With("user_id", "bob", "react", true)
+
+    decodeFields := func() map[string]any {
+        t.Helper()
+        var entry struct {
+            Fields map[string]any `json:"fields"`
+        }
+        if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+            t.Fatal(err)
+        }
+        logs.Reset()
+        return entry.Fields
+    }
+
+    tests := []struct {
+        name   string
+        log    func()
+        want   map[string]any
+        absent []string
+    }{
+        {
+            name: "bound fields merge with the call's",
+            log:  func() { child.Info(context.Background(), "hello", "error", "boom") },
+            want: map[string]any{"comment_id": "c1", "user_id": "alice", "error": "boom"},
+        },
+        {
+            name: "call fields win on conflict",
+            log:  func() { child.Info(context.Background(), "hello", "user_id", "carol") },
+            want: map[string]any{"comment_id": "c1", "user_id": "carol"},
+        },
+        {
+            name: "nested With overrides its parent",
+            log:  func() { grandchild.Info(context.Background(), "hello") },
+            want: map[string]any{"comment_id": "c1", "user_id": "bob", "react": true},
+        },
+        {
+            name:   "parent is unchanged",
+            log:    func() { base.Info(context.Background(), "hello") },
+            absent: []string{"comment_id", "user_id"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tt.log()
+            fields := decodeFields()
+            for key, value := range tt.want {
+                if fields[key] != value {
+                    t.Errorf("expected %s %v, got %v", key, value, fields[key])
+                }
+            }
+            for _, key := range tt.absent {
+                if _, ok := fields[key]; ok {
+                    t.Errorf("expected no %s, got %v", key, fields[key])
+                }
+            }
+        })
+    }
+
+    // Child loggers share the parent's level
+    base.SetLevel(WARN)
+    child.Info(context.Background(), "filtered")
+    if logs.Len() != 0 {
+        t.Errorf("expected the child to follow the parent's level, got %s", logs.String())
+    }
+}
+
+func TestLoggerWithConcurrent(t *testing.T) {
+    var logs syncWriter
+    base := NewLogger(&logs).With("service", "comments")
+
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            l := base.With("worker", i)
+            for j := 0; j < 50; j++ {
+                l.Info(context.Background(), "working", "step", j)
+            }
+        }(i)
+    }
+    wg.Wait()
+
+    dec := json.NewDecoder(bytes.NewReader(logs.Bytes()))
+    entries := 0
+    for dec.More() {
+        var entry struct {
+            Fields map[string]any `json:"fields"`
+        }
+        if err := dec.Decode(&entry); err != nil {
+            t.Fatal(err)
+        }
+        if entry.Fields["service"] != "comments" || entry.Fields["worker"] == nil {
+            t.Errorf("expected bound fields on every entry, got %v", entry.Fields)
+        }
+        entries++
+    }
+    if entries != 8*50 {
+        t.Errorf("expected %d entries, got %d", 8*50, entries)
+    }
+}
+
+// syncWriter is a bytes.Buffer safe to read after concurrent writes.
+type syncWriter struct {
+    mu  sync.Mutex
+    buf bytes.Buffer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.buf.Write(p)
+}
+
+func (w *syncWriter) Bytes() []byte {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.buf.Bytes()
+}
+
+func TestLogMalformedFields(t *testing.T) {
+    tests := []struct {
+        name   string
+        bound  []interface{}
+        fields []interface{}
+        want   map[string]any
+    }{
+        {
+            name:   "well formed",
+            fields: []interface{}{"a", 1, "b", "two"},
+            want:   map[string]any{"a": float64(1), "b": "two"},
+        },
+        {
+            name:   "trailing key without a value",
+            fields: []interface{}{"a", 1, "orphan"},
+            want:   map[string]any{"a": float64(1), badKey: "orphan"},
+        },
+        {
+            name:   "lone value",
+            fields: []interface{}{42},
+            want:   map[string]any{badKey: float64(42)},
+        },
+        {
+            name:   "non-string key is kept and the next argument read as a key",
+            fields: []interface{}{7, "a", 1},
+            want:   map[string]any{badKey: float64(7), "a": float64(1)},
+        },
+        {
+            name:   "value missing mid-list shifts the rest",
+            fields: []interface{}{"a", "b", 2},
+            want:   map[string]any{"a": "b", badKey: float64(2)},
+        },
+        {
+            name:   "several malformed arguments are listed together",
+            fields: []interface{}{1, 2, "a", "x", "orphan"},
+            want:   map[string]any{badKey: []any{float64(1), float64(2), "orphan"}, "a": "x"},
+        },
+        {
+            name:   "nil keys",
+            fields: []interface{}{nil, "a", 1, nil},
+            want:   map[string]any{badKey: []any{nil, nil}, "a": float64(1)},
+        },
+        {
+            name:   "error value",
+            fields: []interface{}{"error", errors.New("boom")},
+            want:   map[string]any{"error": "boom"},
+        },
+        {
+            name:   "wrapped error value",
+            fields: []interface{}{"error", fmt.Errorf("saving: %w", errors.New("boom"))},
+            want:   map[string]any{"error": "saving: boom"},
+        },
+        {
+            name:   "nil error value",
+            fields: []interface{}{"error", error(nil)},
+            want:   map[string]any{"error": nil},
+        },
+        {
+            name:   "error as a key",
+            fields: []interface{}{errors.New("boom")},
+            want:   map[string]any{badKey: "boom"},
+        },
+        {
+            name:   "malformed bound and call fields combine",
+            bound:  []interface{}{"a", 1, "orphan"},
+            fields: []interface{}{"b", 2, 3},
+            want:   map[string]any{"a": float64(1), "b": float64(2), badKey: []any{"orphan", float64(3)}},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var logs bytes.Buffer
+            NewLogger(&logs).With(tt.bound...).Info(context.Background(), "hello", tt.fields...)
+
+            var entry struct {
+                Fields map[string]any `json:"fields"`
+            }
+            if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(entry.Fields, tt.want) {
+                t.Errorf("expected fields %v, got %v", tt.want, entry.Fields)
+            }
+        })
+    }
+}
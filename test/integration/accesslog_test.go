@@ -0,0 +1,51 @@
+// test/integration/accesslog_test.go
+
+package integration
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "web-service/internal/api"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+func TestAccessLog(t *testing.T) {
+    t.Parallel()
+
+    var logs syncBuffer
+    srv := httptest.NewServer(api.NewServer(logging.NewLogger(&logs), testConfig(), storage.NewCommentStore()))
+    t.Cleanup(srv.Close)
+
+    req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Authorization", "Bearer "+issueToken(t, "alice", "user"))
+    req.Header.Set("User-Agent", "access-log-test/1.0")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+
+    fields := findLogFields(t, logs.String(), "request completed")
+    if fields == nil {
+        t.Fatalf("no request completed log line in:\n%s", logs.String())
+    }
+    // The auth middleware runs inside the logging middleware, yet the
+    // entry still names the authenticated user
+    if fields["user_id"] != "alice" {
+        t.Errorf("expected user_id alice, got %v", fields["user_id"])
+    }
+    if fields["user_agent"] != "access-log-test/1.0" {
+        t.Errorf("expected user_agent access-log-test/1.0, got %v", fields["user_agent"])
+    }
+    if n, _ := fields["bytes"].(float64); n <= 0 {
+        t.Errorf("expected bytes written, got %v", fields["bytes"])
+    }
+    if fields["aborted"] != false {
+        t.Errorf("expected aborted false, got %v", fields["aborted"])
+    }
+}
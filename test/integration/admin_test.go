@@ -0,0 +1,203 @@
+// test/integration/admin_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "sync"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+)
+
+func TestAdminListComments(t *testing.T) {
+    t.Parallel()
+
+    var mu sync.Mutex
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    setNow := func(t time.Time) {
+        mu.Lock()
+        defer mu.Unlock()
+        now = t
+    }
+    clock := storage.WithClock(func() time.Time {
+        mu.Lock()
+        defer mu.Unlock()
+        return now
+    })
+    srv, aliceToken := newTestServer(t, "alice", clock, storage.WithTombstones(time.Hour))
+    bobToken := issueToken(t, "bob", "user")
+    adminToken := issueToken(t, "moderator", "admin")
+
+    createComment(t, srv, aliceToken, "first", "Alice")
+    setNow(now.Add(time.Minute))
+    createComment(t, srv, bobToken, "second", "Bob")
+    setNow(now.Add(time.Minute))
+    createComment(t, srv, aliceToken, "third", "Ally")
+    gone := createComment(t, srv, bobToken, "regretted", "Bob")
+    if resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+gone, bobToken, ""); resp.StatusCode != http.StatusNoContent {
+        t.Fatalf("deleting comment: expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+    }
+
+    list := func(t *testing.T, token string, params url.Values) *http.Response {
+        t.Helper()
+        return doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/comments?"+params.Encode(), token, "")
+    }
+
+    t.Run("non-admin is forbidden", func(t *testing.T) {
+        resp := list(t, aliceToken, nil)
+        if resp.StatusCode != http.StatusForbidden {
+            t.Errorf("expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+        }
+    })
+
+    t.Run("invalid time", func(t *testing.T) {
+        resp := list(t, adminToken, url.Values{"after": {"yesterday"}})
+        if resp.StatusCode != http.StatusBadRequest {
+            t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+    })
+
+    tests := []struct {
+        name        string
+        params      url.Values
+        wantContent []string
+        wantDeleted []string
+    }{
+        {name: "everything", wantContent: []string{"third", "second", "first"}, wantDeleted: []string{gone}},
+        {name: "by user", params: url.Values{"user": {"alice"}}, wantContent: []string{"third", "first"}},
+        {name: "by author", params: url.Values{"author": {"bob"}}, wantContent: []string{"second"}},
+        {name: "after", params: url.Values{"after": {"2024-01-01T00:01:00Z"}}, wantContent: []string{"third", "second"}, wantDeleted: []string{gone}},
+        {name: "before", params: url.Values{"before": {"2024-01-01T00:01:00Z"}}, wantContent: []string{"first"}},
+        {name: "user and time", params: url.Values{"user": {"bob"}, "after": {"2024-01-01T00:02:00Z"}}, wantDeleted: []string{gone}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resp := list(t, adminToken, tt.params)
+            if resp.StatusCode != http.StatusOK {
+                t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+            }
+            var body struct {
+                Comments []struct {
+                    Content string `json:"content"`
+                    UserID  string `json:"user_id"`
+                } `json:"comments"`
+                Deleted []struct {
+                    ID     string `json:"id"`
+                    UserID string `json:"user_id"`
+                } `json:"deleted"`
+                Total int `json:"total"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                t.Fatal(err)
+            }
+
+            if body.Total != len(tt.wantContent) || len(body.Comments) != len(tt.wantContent) {
+                t.Fatalf("expected %v, got %+v", tt.wantContent, body.Comments)
+            }
+            for i, c := range body.Comments {
+                if c.Content != tt.wantContent[i] || c.UserID == "" {
+                    t.Errorf("comment %d: expected %q with a user ID, got %+v", i, tt.wantContent[i], c)
+                }
+            }
+            if len(body.Deleted) != len(tt.wantDeleted) {
+                t.Fatalf("expected deleted %v, got %+v", tt.wantDeleted, body.Deleted)
+            }
+            for i, d := range body.Deleted {
+                if d.ID != tt.wantDeleted[i] || d.UserID != "bob" {
+                    t.Errorf("deleted %d: expected %s by bob, got %+v", i, tt.wantDeleted[i], d)
+                }
+            }
+        })
+    }
+}
+
+func TestAdminDeleteUserComments(t *testing.T) {
+    t.Parallel()
+
+    srv, aliceToken := newTestServer(t, "alice")
+    bobToken := issueToken(t, "bob", "user")
+    adminToken := issueToken(t, "moderator", "admin")
+
+    alice := []string{
+        createComment(t, srv, aliceToken, "first", "Alice"),
+        createComment(t, srv, aliceToken, "second", "Alice"),
+    }
+    bob := createComment(t, srv, bobToken, "mine", "Bob")
+
+    deleteComments := func(t *testing.T, token, query string) *http.Response {
+        t.Helper()
+        return doRequest(t, http.MethodDelete, srv.URL+"/api/v1/admin/users/alice/comments"+query, token, "")
+    }
+    deleted := func(t *testing.T, resp *http.Response) (int, bool) {
+        t.Helper()
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var body struct {
+            UserID  string `json:"user_id"`
+            Deleted int    `json:"deleted"`
+            DryRun  bool   `json:"dry_run"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        if body.UserID != "alice" {
+            t.Errorf("expected user_id alice, got %q", body.UserID)
+        }
+        return body.Deleted, body.DryRun
+    }
+    exists := func(t *testing.T, id string) bool {
+        t.Helper()
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+id, adminToken, "")
+        return resp.StatusCode == http.StatusOK
+    }
+
+    t.Run("needs the admin role", func(t *testing.T) {
+        if resp := deleteComments(t, "", ""); resp.StatusCode != http.StatusUnauthorized {
+            t.Errorf("without a token: expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+        }
+        if resp := deleteComments(t, aliceToken, ""); resp.StatusCode != http.StatusForbidden {
+            t.Errorf("as the user: expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+        }
+        if !exists(t, alice[0]) {
+            t.Error("expected alice's comments kept after forbidden requests")
+        }
+    })
+
+    t.Run("invalid dry_run", func(t *testing.T) {
+        if resp := deleteComments(t, adminToken, "?dry_run=maybe"); resp.StatusCode != http.StatusBadRequest {
+            t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+    })
+
+    n, dryRun := deleted(t, deleteComments(t, adminToken, "?dry_run=true"))
+    if n != 2 || !dryRun {
+        t.Errorf("dry run: expected 2 counted, got %d (dry_run %v)", n, dryRun)
+    }
+    for _, id := range alice {
+        if !exists(t, id) {
+            t.Errorf("dry run: expected comment %s kept", id)
+        }
+    }
+
+    n, dryRun = deleted(t, deleteComments(t, adminToken, ""))
+    if n != 2 || dryRun {
+        t.Errorf("expected 2 deleted, got %d (dry_run %v)", n, dryRun)
+    }
+    for _, id := range alice {
+        if exists(t, id) {
+            t.Errorf("expected comment %s deleted", id)
+        }
+    }
+    if !exists(t, bob) {
+        t.Error("expected bob's comment untouched")
+    }
+
+    if n, _ := deleted(t, deleteComments(t, adminToken, "")); n != 0 {
+        t.Errorf("repeat: expected 0 deleted, got %d", n)
+    }
+}
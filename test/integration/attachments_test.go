@@ -0,0 +1,101 @@
+// test/integration/attachments_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "slices"
+    "testing"
+)
+
+func TestCommentAttachments(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.CommentMaxAttachments = 2
+    cfg.CommentMaxAttachmentLength = 100
+    srv := startServer(t, cfg)
+    token := issueToken(t, "photographer", "user")
+
+    type comment struct {
+        ID          string   `json:"id"`
+        Attachments []string `json:"attachments"`
+    }
+    decode := func(t *testing.T, resp *http.Response, status int) comment {
+        t.Helper()
+        if resp.StatusCode != status {
+            t.Fatalf("expected status %d, got %d", status, resp.StatusCode)
+        }
+        var c comment
+        if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+            t.Fatal(err)
+        }
+        return c
+    }
+
+    attachments := []string{"https://example.com/cat.png", "http://example.com/dog.jpg"}
+    created := decode(t, doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", token,
+        `{"content":"pets","author":"Alice","attachments":["https://example.com/cat.png","http://example.com/dog.jpg"]}`), http.StatusCreated)
+    if !slices.Equal(created.Attachments, attachments) {
+        t.Errorf("expected attachments %q echoed, got %q", attachments, created.Attachments)
+    }
+    url := srv.URL + "/api/v1/comments/" + created.ID
+
+    t.Run("get", func(t *testing.T) {
+        got := decode(t, doRequest(t, http.MethodGet, url, token, ""), http.StatusOK)
+        if !slices.Equal(got.Attachments, attachments) {
+            t.Errorf("expected attachments %q, got %q", attachments, got.Attachments)
+        }
+    })
+
+    t.Run("replace", func(t *testing.T) {
+        got := decode(t, doRequest(t, http.MethodPut, url, token, `{"content":"pets","author":"Alice","attachments":["https://example.com/fish.gif"]}`), http.StatusOK)
+        if want := []string{"https://example.com/fish.gif"}; !slices.Equal(got.Attachments, want) {
+            t.Errorf("expected attachments %q, got %q", want, got.Attachments)
+        }
+    })
+
+    t.Run("clear", func(t *testing.T) {
+        decode(t, doRequest(t, http.MethodPut, url, token, `{"content":"pets","author":"Alice"}`), http.StatusOK)
+        got := decode(t, doRequest(t, http.MethodGet, url, token, ""), http.StatusOK)
+        if len(got.Attachments) != 0 {
+            t.Errorf("expected no attachments, got %q", got.Attachments)
+        }
+    })
+
+    t.Run("invalid", func(t *testing.T) {
+        tests := []struct {
+            name string
+            body string
+            want []string
+        }{
+            {"too many", `{"content":"pets","author":"Alice","attachments":["https://a.example","https://b.example","https://c.example"]}`, []string{"attachments"}},
+            {"not absolute", `{"content":"pets","author":"Alice","attachments":["https://a.example","cat.png"]}`, []string{"attachments[1]"}},
+            {"not http", `{"content":"pets","author":"Alice","attachments":["javascript:alert(1)"]}`, []string{"attachments[0]"}},
+        }
+        for _, tt := range tests {
+            t.Run(tt.name, func(t *testing.T) {
+                resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", token, tt.body)
+                if resp.StatusCode != http.StatusBadRequest {
+                    t.Fatalf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+                }
+                var body struct {
+                    InvalidParams []struct {
+                        Name string `json:"name"`
+                    } `json:"invalid-params"`
+                }
+                if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                    t.Fatal(err)
+                }
+                var names []string
+                for _, param := range body.InvalidParams {
+                    names = append(names, param.Name)
+                }
+                if !slices.Equal(names, tt.want) {
+                    t.Errorf("expected problems with %q, got %q", tt.want, names)
+                }
+            })
+        }
+    })
+}
\ No newline at end of file
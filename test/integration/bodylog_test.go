@@ -0,0 +1,100 @@
+// test/integration/bodylog_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "web-service/internal/api"
+    "web-service/internal/auth"
+    "web-service/internal/config"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+func TestBodyLoggingRedactsLogin(t *testing.T) {
+    t.Parallel()
+
+    const password = "correct-horse-battery-staple"
+    hash, err := auth.HashPassword(password)
+    if err != nil {
+        t.Fatal(err)
+    }
+    cfg := testConfig()
+    cfg.Users = []config.UserSeed{{Username: "mod", Role: "admin", PasswordHash: hash}}
+    cfg.LogHTTPBodies = true
+
+    var logs syncBuffer
+    logger := logging.NewLogger(&logs)
+    logger.SetLevel(logging.DEBUG)
+    srv := httptest.NewServer(api.NewServer(logger, cfg, storage.NewCommentStore()))
+    t.Cleanup(srv.Close)
+
+    body, _ := json.Marshal(map[string]string{"username": "mod", "password": password})
+    resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/login", "", string(body))
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    var out struct {
+        Token string `json:"token"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+        t.Fatal(err)
+    }
+
+    if strings.Contains(logs.String(), password) {
+        t.Fatalf("password logged:\n%s", logs.String())
+    }
+    if strings.Contains(logs.String(), out.Token) {
+        t.Fatalf("token logged:\n%s", logs.String())
+    }
+
+    fields := findLogFields(t, logs.String(), "http bodies")
+    if fields == nil {
+        t.Fatalf("no http bodies log line in:\n%s", logs.String())
+    }
+    want := fmt.Sprintf("[REDACTED] (%d bytes)", len(body))
+    if fields["request_body"] != want {
+        t.Errorf("expected the request body redacted to %q, got %v", want, fields["request_body"])
+    }
+    if respBody, _ := fields["response_body"].(string); !strings.HasPrefix(respBody, "[REDACTED] (") {
+        t.Errorf("expected the response body redacted, got %v", fields["response_body"])
+    }
+}
+
+func TestBodyLoggingOtherRoutes(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.LogHTTPBodies = true
+
+    // Bodies are logged with the logger left at INFO
+    var logs syncBuffer
+    srv := httptest.NewServer(api.NewServer(logging.NewLogger(&logs), cfg, storage.NewCommentStore()))
+    t.Cleanup(srv.Close)
+
+    createComment(t, srv, issueToken(t, "alice", "user"), "hello", "Alice")
+    fields := findLogFields(t, logs.String(), "http bodies")
+    if reqBody, _ := fields["request_body"].(string); !strings.Contains(reqBody, `"content":"hello"`) {
+        t.Errorf("expected the comment body logged, got %v", fields["request_body"])
+    }
+}
+
+func TestBodyLoggingOff(t *testing.T) {
+    t.Parallel()
+
+    var logs syncBuffer
+    logger := logging.NewLogger(&logs)
+    logger.SetLevel(logging.DEBUG)
+    srv := httptest.NewServer(api.NewServer(logger, testConfig(), storage.NewCommentStore()))
+    t.Cleanup(srv.Close)
+
+    createComment(t, srv, issueToken(t, "alice", "user"), "hello", "Alice")
+    if fields := findLogFields(t, logs.String(), "http bodies"); fields != nil {
+        t.Errorf("expected no bodies logged, got %v", fields)
+    }
+}
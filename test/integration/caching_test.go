@@ -0,0 +1,83 @@
+// test/integration/caching_test.go
+
+package integration
+
+import (
+    "net/http"
+    "sync"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+)
+
+func TestCommentListCaching(t *testing.T) {
+    t.Parallel()
+
+    var mu sync.Mutex
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    advance := func(d time.Duration) {
+        mu.Lock()
+        defer mu.Unlock()
+        now = now.Add(d)
+    }
+    clock := storage.WithClock(func() time.Time {
+        mu.Lock()
+        defer mu.Unlock()
+        return now
+    })
+    cfg := testConfig()
+    cfg.CommentListMaxAge = 30 * time.Second
+    srv := startServer(t, cfg, clock)
+    token := issueToken(t, "cacher", "user")
+
+    list := func(t *testing.T, since string) *http.Response {
+        t.Helper()
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments", nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        if since != "" {
+            req.Header.Set("If-Modified-Since", since)
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp
+    }
+
+    createComment(t, srv, token, "first", "Cacher")
+    resp := list(t, "")
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    if cc := resp.Header.Get("Cache-Control"); cc != "private, max-age=30" {
+        t.Errorf("expected Cache-Control private, max-age=30, got %q", cc)
+    }
+    modified := resp.Header.Get("Last-Modified")
+    if modified != "Mon, 01 Jan 2024 00:00:00 GMT" {
+        t.Fatalf("expected Last-Modified of the create, got %q", modified)
+    }
+
+    if resp := list(t, modified); resp.StatusCode != http.StatusNotModified {
+        t.Fatalf("unchanged list: expected status %d, got %d", http.StatusNotModified, resp.StatusCode)
+    }
+
+    advance(time.Minute)
+    id := createComment(t, srv, token, "second", "Cacher")
+    resp = list(t, modified)
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("after create: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    modified = resp.Header.Get("Last-Modified")
+
+    advance(time.Minute)
+    if resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+id, token, ""); resp.StatusCode != http.StatusNoContent {
+        t.Fatalf("deleting comment: expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+    }
+    if resp := list(t, modified); resp.StatusCode != http.StatusOK {
+        t.Errorf("after delete: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+}
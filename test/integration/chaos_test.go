@@ -0,0 +1,88 @@
+// test/integration/chaos_test.go
+
+package integration
+
+import (
+    "net/http"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+)
+
+func TestChaosStorageFaults(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.RequestTimeout = 100 * time.Millisecond
+    srv := startServer(t, cfg, storage.WithFaultInjector(storage.NewFaultInjector()))
+
+    user := issueToken(t, "chaos-user", "user")
+    admin := issueToken(t, "chaos-admin", "admin")
+    id := createComment(t, srv, user, "steady", "chaos")
+
+    setFaults := func(t *testing.T, body string) {
+        t.Helper()
+        resp := doRequest(t, http.MethodPut, srv.URL+"/api/v1/admin/faults", admin, body)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("setting faults: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+    }
+
+    t.Run("users cannot configure faults", func(t *testing.T) {
+        resp := doRequest(t, http.MethodPut, srv.URL+"/api/v1/admin/faults", user, `{"faults":[]}`)
+        if resp.StatusCode != http.StatusForbidden {
+            t.Errorf("expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+        }
+    })
+
+    t.Run("list latency exceeds request timeout", func(t *testing.T) {
+        // The list endpoint reads through Range
+        setFaults(t, `{"faults":[{"method":"Range","latency":"2s"}]}`)
+        defer setFaults(t, `{"faults":[]}`)
+
+        start := time.Now()
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", user, "")
+        if resp.StatusCode != http.StatusServiceUnavailable {
+            t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+        }
+        if elapsed := time.Since(start); elapsed > time.Second {
+            t.Errorf("expected the timeout to cut the request short, took %s", elapsed)
+        }
+    })
+
+    t.Run("failure on one comment", func(t *testing.T) {
+        other := createComment(t, srv, user, "unaffected", "chaos")
+        setFaults(t, `{"faults":[{"method":"Get","ids":["`+id+`"],"fail":true}]}`)
+        defer setFaults(t, `{"faults":[]}`)
+
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+id, user, "")
+        if resp.StatusCode != http.StatusServiceUnavailable {
+            t.Errorf("faulty comment: expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+        }
+        if resp.Header.Get("Retry-After") == "" {
+            t.Error("expected Retry-After on 503")
+        }
+        if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+other, user, ""); resp.StatusCode != http.StatusOK {
+            t.Errorf("other comment: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+    })
+
+    t.Run("readiness reflects store failures", func(t *testing.T) {
+        setFaults(t, `{"faults":[{"method":"Ping","fail":true}]}`)
+        defer setFaults(t, `{"faults":[]}`)
+
+        if resp := doRequest(t, http.MethodGet, srv.URL+"/readyz", "", ""); resp.StatusCode != http.StatusServiceUnavailable {
+            t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+        }
+    })
+}
+
+func TestFaultEndpointDisabledByDefault(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "no-chaos")
+    admin := issueToken(t, "admin", "admin")
+    if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/faults", admin, ""); resp.StatusCode != http.StatusNotFound {
+        t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+    }
+}
@@ -0,0 +1,64 @@
+// test/integration/consistency_test.go
+
+package integration
+
+import (
+    "net/http"
+    "testing"
+)
+
+func TestConsistencyToken(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "writer")
+
+    create := func() string {
+        t.Helper()
+        resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", token, `{"content":"c","author":"a"}`)
+        if resp.StatusCode != http.StatusCreated {
+            t.Fatalf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+        }
+        ct := resp.Header.Get("X-Consistency-Token")
+        if ct == "" {
+            t.Fatal("expected X-Consistency-Token on write")
+        }
+        return ct
+    }
+
+    first, second := create(), create()
+    if first == second {
+        t.Errorf("expected tokens to advance, got %q twice", first)
+    }
+
+    read := func(ct string) *http.Response {
+        t.Helper()
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments", nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        req.Header.Set("X-Consistency-Token", ct)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp
+    }
+
+    if resp := read(second); resp.StatusCode != http.StatusOK {
+        t.Errorf("echoed token: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    if resp := read("not a token"); resp.StatusCode != http.StatusBadRequest {
+        t.Errorf("malformed token: expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+    }
+    if resp := read(""); resp.Header.Get("X-Consistency-Token") != "" {
+        t.Error("expected no token on reads")
+    }
+
+    // Failed writes don't advance anything and carry no token
+    resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/missing", token, "")
+    if resp.StatusCode != http.StatusNotFound || resp.Header.Get("X-Consistency-Token") != "" {
+        t.Errorf("failed write: got %d with token %q", resp.StatusCode, resp.Header.Get("X-Consistency-Token"))
+    }
+}
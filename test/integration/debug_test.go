@@ -0,0 +1,91 @@
+// test/integration/debug_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "web-service/internal/api"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+    t.Parallel()
+
+    paths := []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline"}
+    admin := issueToken(t, "ops", "admin")
+
+    t.Run("disabled", func(t *testing.T) {
+        srv := startServer(t, testConfig())
+        for _, path := range paths {
+            resp := doRequest(t, http.MethodGet, srv.URL+path, admin, "")
+            if resp.StatusCode != http.StatusNotFound {
+                t.Errorf("%s: expected status %d, got %d", path, http.StatusNotFound, resp.StatusCode)
+            }
+        }
+    })
+
+    // Store operations are reported when the store is instrumented, as
+    // the server does
+    cfg := testConfig()
+    cfg.DebugEndpoints = true
+    metrics := storage.NewMetrics(0)
+    store := storage.NewInstrumentedStore(storage.NewCommentStore(), logging.NewLogger(io.Discard), metrics)
+    srv := httptest.NewServer(api.NewServer(logging.NewLogger(io.Discard), cfg, store, api.WithStoreMetrics(metrics)))
+    t.Cleanup(srv.Close)
+
+    t.Run("admin only", func(t *testing.T) {
+        user := issueToken(t, "someone", "user")
+        for _, path := range paths {
+            resp := doRequest(t, http.MethodGet, srv.URL+path, user, "")
+            if resp.StatusCode != http.StatusForbidden {
+                t.Errorf("%s: expected status %d, got %d", path, http.StatusForbidden, resp.StatusCode)
+            }
+            resp = doRequest(t, http.MethodGet, srv.URL+path, "", "")
+            if resp.StatusCode != http.StatusUnauthorized {
+                t.Errorf("%s: expected status %d without a token, got %d", path, http.StatusUnauthorized, resp.StatusCode)
+            }
+        }
+    })
+
+    t.Run("enabled", func(t *testing.T) {
+        for _, path := range paths {
+            resp := doRequest(t, http.MethodGet, srv.URL+path, admin, "")
+            if resp.StatusCode != http.StatusOK {
+                t.Errorf("%s: expected status %d, got %d", path, http.StatusOK, resp.StatusCode)
+            }
+        }
+    })
+
+    t.Run("runtime stats", func(t *testing.T) {
+        token := issueToken(t, "writer", "user")
+        createComment(t, srv, token, "hello", "Alice")
+        createComment(t, srv, token, "world", "Alice")
+
+        resp := doRequest(t, http.MethodGet, srv.URL+"/debug/vars", admin, "")
+        var stats struct {
+            Goroutines      int    `json:"goroutines"`
+            HeapAllocBytes  uint64 `json:"heap_alloc_bytes"`
+            Comments        int    `json:"comments"`
+            StoreOperations map[string]struct {
+                Calls int64 `json:"calls"`
+            } `json:"store_operations"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+            t.Fatal(err)
+        }
+        if stats.Comments != 2 {
+            t.Errorf("expected 2 comments, got %d", stats.Comments)
+        }
+        if stats.Goroutines == 0 || stats.HeapAllocBytes == 0 {
+            t.Errorf("expected runtime figures, got %+v", stats)
+        }
+        if stats.StoreOperations["Create"].Calls != 2 {
+            t.Errorf("expected 2 store Create calls, got %+v", stats.StoreOperations)
+        }
+    })
+}
\ No newline at end of file
@@ -0,0 +1,101 @@
+// test/integration/delete_test.go
+
+package integration
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+    "web-service/internal/api"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+func TestIdempotentDelete(t *testing.T) {
+    t.Parallel()
+
+    const window = 200 * time.Millisecond
+    srv, token := newTestServer(t, "deleter", storage.WithTombstones(window))
+
+    deleteComment := func(t *testing.T, id string) *http.Response {
+        t.Helper()
+        return doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+id, token, "")
+    }
+
+    t.Run("delete twice", func(t *testing.T) {
+        id := createComment(t, srv, token, "short lived", "deleter")
+
+        first := deleteComment(t, id)
+        if first.StatusCode != http.StatusNoContent || first.Header.Get("X-Already-Deleted") != "" {
+            t.Errorf("first delete: got %d with X-Already-Deleted %q", first.StatusCode, first.Header.Get("X-Already-Deleted"))
+        }
+
+        second := deleteComment(t, id)
+        if second.StatusCode != http.StatusNoContent || second.Header.Get("X-Already-Deleted") != "true" {
+            t.Errorf("second delete: got %d with X-Already-Deleted %q", second.StatusCode, second.Header.Get("X-Already-Deleted"))
+        }
+    })
+
+    t.Run("delete never existed", func(t *testing.T) {
+        if resp := deleteComment(t, "never-existed"); resp.StatusCode != http.StatusNotFound {
+            t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+        }
+    })
+
+    t.Run("another user's tombstone", func(t *testing.T) {
+        id := createComment(t, srv, token, "mine", "deleter")
+        deleteComment(t, id)
+
+        other := issueToken(t, "someone-else", "user")
+        resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+id, other, "")
+        if resp.StatusCode != http.StatusNotFound {
+            t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+        }
+    })
+
+    t.Run("tombstone expiry", func(t *testing.T) {
+        id := createComment(t, srv, token, "expiring", "deleter")
+        deleteComment(t, id)
+
+        time.Sleep(window + 50*time.Millisecond)
+        if resp := deleteComment(t, id); resp.StatusCode != http.StatusNotFound {
+            t.Errorf("expected status %d after expiry, got %d", http.StatusNotFound, resp.StatusCode)
+        }
+    })
+}
+
+func TestAdminDelete(t *testing.T) {
+    t.Parallel()
+
+    var logs syncBuffer
+    srv := httptest.NewServer(api.NewServer(logging.NewLogger(&logs), testConfig(), storage.NewCommentStore()))
+    t.Cleanup(srv.Close)
+    ownerToken := issueToken(t, "owner", "user")
+    otherToken := issueToken(t, "bystander", "user")
+    adminToken := issueToken(t, "moderator", "admin")
+
+    id := createComment(t, srv, ownerToken, "needs moderating", "owner")
+
+    if resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+id, otherToken, ""); resp.StatusCode != http.StatusForbidden {
+        t.Fatalf("non-admin delete: expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+    }
+    if findLogFields(t, logs.String(), "admin deleted another user's comment") != nil {
+        t.Fatalf("expected no audit entry for a refused delete:\n%s", logs.String())
+    }
+
+    if resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+id, adminToken, ""); resp.StatusCode != http.StatusNoContent {
+        t.Fatalf("admin delete: expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+    }
+    if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+id, ownerToken, ""); resp.StatusCode != http.StatusNotFound {
+        t.Errorf("expected the comment gone, got status %d", resp.StatusCode)
+    }
+
+    fields := findLogFields(t, logs.String(), "admin deleted another user's comment")
+    if fields == nil {
+        t.Fatalf("no audit entry in:\n%s", logs.String())
+    }
+    if fields["comment_id"] != id || fields["user_id"] != "moderator" || fields["owner_id"] != "owner" {
+        t.Errorf("unexpected audit fields: %v", fields)
+    }
+}
@@ -0,0 +1,135 @@
+// test/integration/erasure_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "web-service/internal/storage"
+)
+
+type erasureReceipt struct {
+    ID     string `json:"id"`
+    Stores []struct {
+        Store      string `json:"store"`
+        Removed    int    `json:"removed"`
+        Anonymized int    `json:"anonymized"`
+    } `json:"stores"`
+    Removed    int    `json:"removed"`
+    Anonymized int    `json:"anonymized"`
+    Subject    string `json:"subject"`
+    Signature  string `json:"signature"`
+}
+
+func TestEraseUser(t *testing.T) {
+    t.Parallel()
+
+    faults := storage.NewFaultInjector()
+    srv := startServer(t, testConfig(), storage.WithFaultInjector(faults))
+    alice := issueToken(t, "alice", "user")
+    bob := issueToken(t, "bob", "user")
+    admin := issueToken(t, "admin", "admin")
+
+    createComment(t, srv, alice, "First comment from Alice", "Alice")
+    createComment(t, srv, alice, "Second comment from Alice", "Alice")
+    bobs := createComment(t, srv, bob, "A comment from Bob", "Bob")
+    if resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments/"+bobs+"/reactions", alice, ""); resp.StatusCode != http.StatusOK {
+        t.Fatalf("reacting: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+
+    erase := func(t *testing.T, token string) (*http.Response, erasureReceipt) {
+        t.Helper()
+        resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/admin/users/alice/erase", token, "")
+        var receipt erasureReceipt
+        if resp.StatusCode == http.StatusOK {
+            if err := json.NewDecoder(resp.Body).Decode(&receipt); err != nil {
+                t.Fatal(err)
+            }
+        }
+        return resp, receipt
+    }
+
+    t.Run("admin only", func(t *testing.T) {
+        if resp, _ := erase(t, alice); resp.StatusCode != http.StatusForbidden {
+            t.Errorf("expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+        }
+    })
+
+    t.Run("failed store is reported and resumed", func(t *testing.T) {
+        if err := faults.Set([]storage.Fault{{Method: "EraseUser", Fail: true}}); err != nil {
+            t.Fatal(err)
+        }
+        resp, _ := erase(t, admin)
+        faults.Set(nil)
+        if resp.StatusCode != http.StatusServiceUnavailable {
+            t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+        }
+        var body struct {
+            InvalidParams []struct {
+                Name string `json:"name"`
+            } `json:"invalid-params"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        if len(body.InvalidParams) != 1 || body.InvalidParams[0].Name != "comments" {
+            t.Errorf("expected the comments store named in invalid-params, got %+v", body.InvalidParams)
+        }
+    })
+
+    var receipt erasureReceipt
+    t.Run("erase", func(t *testing.T) {
+        var resp *http.Response
+        resp, receipt = erase(t, admin)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        // Two comments and one reaction
+        if receipt.Removed != 3 {
+            t.Errorf("expected 3 records removed, got %d", receipt.Removed)
+        }
+        if receipt.Subject == "" || receipt.Subject == "alice" || receipt.Signature == "" {
+            t.Errorf("expected an opaque subject and a signature, got %+v", receipt)
+        }
+
+        var comments []struct {
+            UserID        string `json:"user_id"`
+            ReactionCount int    `json:"reaction_count"`
+        }
+        resp = doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", bob, "")
+        if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+            t.Fatal(err)
+        }
+        if len(comments) != 1 || comments[0].UserID != "bob" || comments[0].ReactionCount != 0 {
+            t.Errorf("expected only bob's unreacted comment left, got %+v", comments)
+        }
+    })
+
+    t.Run("receipt is retrievable", func(t *testing.T) {
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/erasures/"+receipt.ID, admin, "")
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var got erasureReceipt
+        if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+            t.Fatal(err)
+        }
+        if got.Signature != receipt.Signature || got.Removed != receipt.Removed {
+            t.Errorf("expected the original receipt, got %+v", got)
+        }
+        if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/erasures/unknown", admin, ""); resp.StatusCode != http.StatusNotFound {
+            t.Errorf("unknown receipt: expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+        }
+    })
+
+    t.Run("re-run is idempotent", func(t *testing.T) {
+        resp, again := erase(t, admin)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        if again.Removed != 0 || again.Anonymized != 0 || again.Subject != receipt.Subject {
+            t.Errorf("expected an empty receipt for the same subject, got %+v", again)
+        }
+    })
+}
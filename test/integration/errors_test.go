@@ -0,0 +1,119 @@
+// test/integration/errors_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "erring")
+
+    tests := []struct {
+        name        string
+        method      string
+        path        string
+        token       string
+        body        string
+        wantStatus  int
+        wantCode    string
+        wantDetails []string
+    }{
+        {name: "missing token", method: http.MethodGet, path: "/api/v1/comments", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+        {name: "invalid token", method: http.MethodGet, path: "/api/v1/comments", token: "garbage", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+        {name: "not found", method: http.MethodGet, path: "/api/v1/comments/missing", token: token, wantStatus: http.StatusNotFound, wantCode: "not_found"},
+        {name: "malformed body", method: http.MethodPost, path: "/api/v1/comments", token: token, body: `{`, wantStatus: http.StatusBadRequest, wantCode: "invalid_request", wantDetails: []string{"body"}},
+        {name: "wrong type", method: http.MethodPost, path: "/api/v1/comments", token: token, body: `{"content": 123, "author": "Alice"}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_request", wantDetails: []string{"content"}},
+        {name: "unknown field", method: http.MethodPost, path: "/api/v1/comments", token: token, body: `{"contnet": "hi", "author": "Alice"}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_request", wantDetails: []string{"contnet"}},
+        {name: "validation problems", method: http.MethodPost, path: "/api/v1/comments", token: token, body: `{"content": ""}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_request", wantDetails: []string{"author", "content"}},
+        {name: "admin only", method: http.MethodGet, path: "/api/v1/admin/comments/search", token: token, wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resp := doRequest(t, tt.method, srv.URL+tt.path, tt.token, tt.body)
+            if resp.StatusCode != tt.wantStatus {
+                t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+            }
+            if ct := resp.Header.Get("Content-Type"); ct != "application/problem+json" {
+                t.Errorf("expected Content-Type application/problem+json, got %q", ct)
+            }
+
+            var body struct {
+                Type          string `json:"type"`
+                Title         string `json:"title"`
+                Status        int    `json:"status"`
+                Detail        string `json:"detail"`
+                Code          string `json:"code"`
+                RequestID     string `json:"request_id"`
+                InvalidParams []struct {
+                    Name   string `json:"name"`
+                    Reason string `json:"reason"`
+                } `json:"invalid-params"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                t.Fatal(err)
+            }
+            if body.Code != tt.wantCode {
+                t.Errorf("expected code %q, got %q", tt.wantCode, body.Code)
+            }
+            if body.Type != "urn:web-service:problem:"+tt.wantCode || body.Status != tt.wantStatus || body.Title != http.StatusText(tt.wantStatus) {
+                t.Errorf("expected type, title and status for %s, got %+v", tt.wantCode, body)
+            }
+            if body.Detail == "" || body.RequestID == "" {
+                t.Errorf("expected a detail and request ID, got %+v", body)
+            }
+            fields := make(map[string]bool)
+            for _, param := range body.InvalidParams {
+                if param.Reason == "" {
+                    t.Errorf("expected a reason for %s", param.Name)
+                }
+                fields[param.Name] = true
+            }
+            if len(fields) != len(tt.wantDetails) {
+                t.Errorf("expected invalid params %v, got %+v", tt.wantDetails, body.InvalidParams)
+            }
+            for _, field := range tt.wantDetails {
+                if !fields[field] {
+                    t.Errorf("expected a problem for %s, got %+v", field, body.InvalidParams)
+                }
+            }
+        })
+    }
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "formposter")
+
+    req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/comments", strings.NewReader("content=hi&author=Alice"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusUnsupportedMediaType {
+        t.Fatalf("expected status %d, got %d", http.StatusUnsupportedMediaType, resp.StatusCode)
+    }
+    var body struct {
+        Code string `json:"code"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        t.Fatal(err)
+    }
+    if body.Code != "unsupported_media_type" {
+        t.Errorf("expected code unsupported_media_type, got %q", body.Code)
+    }
+}
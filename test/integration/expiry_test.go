@@ -0,0 +1,95 @@
+// test/integration/expiry_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+)
+
+func TestCommentExpiry(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.CommentMaxTTL = 48 * time.Hour
+    cfg.CommentTTLAdminThreshold = time.Hour
+    srv := startServer(t, cfg)
+
+    user := issueToken(t, "announcer", "user")
+    admin := issueToken(t, "moderator", "admin")
+
+    tests := []struct {
+        name       string
+        token      string
+        body       string
+        wantStatus int
+        wantExpiry bool
+    }{
+        {name: "no lifetime", token: user, body: `{"content":"c","author":"a"}`, wantStatus: http.StatusCreated},
+        {name: "ttl", token: user, body: `{"content":"c","author":"a","ttl":60}`, wantStatus: http.StatusCreated, wantExpiry: true},
+        {name: "expires_at", token: user, body: `{"content":"c","author":"a","expires_at":"` + time.Now().Add(30*time.Minute).Format(time.RFC3339) + `"}`, wantStatus: http.StatusCreated, wantExpiry: true},
+        {name: "beyond threshold as user", token: user, body: `{"content":"c","author":"a","ttl":7200}`, wantStatus: http.StatusForbidden},
+        {name: "beyond threshold as admin", token: admin, body: `{"content":"c","author":"a","ttl":7200}`, wantStatus: http.StatusCreated, wantExpiry: true},
+        {name: "beyond maximum", token: admin, body: `{"content":"c","author":"a","ttl":259200}`, wantStatus: http.StatusBadRequest},
+        {name: "in the past", token: user, body: `{"content":"c","author":"a","expires_at":"2000-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", tt.token, tt.body)
+            if resp.StatusCode != tt.wantStatus {
+                t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+            }
+            if resp.StatusCode != http.StatusCreated {
+                return
+            }
+
+            var created struct {
+                ExpiresAt *time.Time `json:"expires_at"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+                t.Fatal(err)
+            }
+            if got := created.ExpiresAt != nil; got != tt.wantExpiry {
+                t.Errorf("expected expires_at present=%v, got %v", tt.wantExpiry, created.ExpiresAt)
+            }
+        })
+    }
+}
+
+func TestCommentExpiryStoreClock(t *testing.T) {
+    t.Parallel()
+
+    // The store's clock, not the wall clock, decides what is in the
+    // future and how long a lifetime is
+    now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+    cfg := testConfig()
+    cfg.CommentMaxTTL = 48 * time.Hour
+    srv := startServer(t, cfg, storage.WithClock(func() time.Time { return now }))
+    token := issueToken(t, "announcer", "user")
+
+    expiresAt := func(t time.Time) string {
+        return `{"content":"c","author":"a","expires_at":"` + t.Format(time.RFC3339) + `"}`
+    }
+    tests := []struct {
+        name       string
+        body       string
+        wantStatus int
+    }{
+        {name: "future by the store", body: expiresAt(now.Add(time.Hour)), wantStatus: http.StatusCreated},
+        {name: "past by the store", body: expiresAt(now.Add(-time.Hour)), wantStatus: http.StatusBadRequest},
+        {name: "beyond maximum by the store", body: expiresAt(time.Now().Add(time.Hour)), wantStatus: http.StatusBadRequest},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", token, tt.body)
+            if resp.StatusCode != tt.wantStatus {
+                t.Errorf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+            }
+        })
+    }
+}
@@ -0,0 +1,105 @@
+// test/integration/fieldcase_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+)
+
+func TestFieldCase(t *testing.T) {
+    t.Parallel()
+
+    tests := []struct {
+        name      string
+        fieldCase string
+        body      string
+        wantKeys  []string
+        avoidKeys []string
+    }{
+        {
+            name:      "default is snake",
+            body:      `{"content":"snake body","author":"sam"}`,
+            wantKeys:  []string{"created_at", "user_id", "reaction_count", "viewer_has_reacted"},
+            avoidKeys: []string{"createdAt", "userId"},
+        },
+        {
+            name:      "explicit snake",
+            fieldCase: "snake",
+            body:      `{"content":"snake body","author":"sam"}`,
+            wantKeys:  []string{"created_at", "user_id"},
+            avoidKeys: []string{"createdAt"},
+        },
+        {
+            name:      "camel",
+            fieldCase: "camel",
+            body:      `{"content":"camel body","author":"cam"}`,
+            wantKeys:  []string{"createdAt", "userId", "reactionCount", "viewerHasReacted"},
+            avoidKeys: []string{"created_at", "user_id"},
+        },
+    }
+
+    for _, tt := range tests {
+        tt := tt
+        t.Run(tt.name, func(t *testing.T) {
+            t.Parallel()
+
+            srv, token := newTestServer(t, "case-tester")
+
+            send := func(method, body string) map[string]json.RawMessage {
+                t.Helper()
+                req, err := http.NewRequest(method, srv.URL+"/api/v1/comments", strings.NewReader(body))
+                if err != nil {
+                    t.Fatal(err)
+                }
+                req.Header.Set("Authorization", "Bearer "+token)
+                req.Header.Set("Content-Type", "application/json")
+                if tt.fieldCase != "" {
+                    req.Header.Set("X-Field-Case", tt.fieldCase)
+                }
+                resp, err := http.DefaultClient.Do(req)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                defer resp.Body.Close()
+
+                if method == http.MethodPost {
+                    if resp.StatusCode != http.StatusCreated {
+                        t.Fatalf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+                    }
+                    var obj map[string]json.RawMessage
+                    if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+                        t.Fatal(err)
+                    }
+                    return obj
+                }
+                var list []map[string]json.RawMessage
+                if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+                    t.Fatal(err)
+                }
+                if len(list) != 1 {
+                    t.Fatalf("expected 1 comment, got %d", len(list))
+                }
+                return list[0]
+            }
+
+            for _, obj := range []map[string]json.RawMessage{
+                send(http.MethodPost, tt.body),
+                send(http.MethodGet, ""),
+            } {
+                for _, k := range tt.wantKeys {
+                    if _, ok := obj[k]; !ok {
+                        t.Errorf("expected key %q in %v", k, obj)
+                    }
+                }
+                for _, k := range tt.avoidKeys {
+                    if _, ok := obj[k]; ok {
+                        t.Errorf("unexpected key %q in %v", k, obj)
+                    }
+                }
+            }
+        })
+    }
+}
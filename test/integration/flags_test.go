@@ -0,0 +1,145 @@
+// test/integration/flags_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+)
+
+func TestFlags(t *testing.T) {
+    t.Parallel()
+
+    srv, alice := newTestServer(t, "alice")
+    bob := issueToken(t, "bob", "user")
+    carol := issueToken(t, "carol", "user")
+    admin := issueToken(t, "mod", "admin")
+    commentID := createComment(t, srv, alice, "flag me", "alice")
+    flagsURL := srv.URL + "/api/v1/comments/" + commentID + "/flags"
+
+    type flag struct {
+        UserID    string `json:"user_id"`
+        Reason    string `json:"reason"`
+        CreatedAt string `json:"created_at"`
+    }
+    decodeFlag := func(t *testing.T, resp *http.Response, wantStatus int) flag {
+        t.Helper()
+        if resp.StatusCode != wantStatus {
+            t.Fatalf("expected status %d, got %d", wantStatus, resp.StatusCode)
+        }
+        var f flag
+        if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+            t.Fatal(err)
+        }
+        return f
+    }
+
+    first := decodeFlag(t, doRequest(t, http.MethodPost, flagsURL, bob, `{"reason":"spam"}`), http.StatusCreated)
+    if first.UserID != "bob" || first.Reason != "spam" {
+        t.Errorf("unexpected flag %+v", first)
+    }
+    // Flagging again is idempotent and keeps the original flag
+    again := decodeFlag(t, doRequest(t, http.MethodPost, flagsURL, bob, `{"reason":"changed my mind"}`), http.StatusOK)
+    if again != first {
+        t.Errorf("expected repeat to return %+v, got %+v", first, again)
+    }
+    // The reason is optional
+    decodeFlag(t, doRequest(t, http.MethodPost, flagsURL, carol, ""), http.StatusCreated)
+
+    resp := doRequest(t, http.MethodPost, flagsURL, carol, `{"reason":"`+strings.Repeat("x", 501)+`"}`)
+    if resp.StatusCode != http.StatusBadRequest {
+        t.Errorf("expected status %d for long reason, got %d", http.StatusBadRequest, resp.StatusCode)
+    }
+    resp = doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments/missing/flags", bob, "")
+    if resp.StatusCode != http.StatusNotFound {
+        t.Errorf("expected status %d for missing comment, got %d", http.StatusNotFound, resp.StatusCode)
+    }
+
+    t.Run("flag count only for admins", func(t *testing.T) {
+        for _, tt := range []struct {
+            token string
+            want  any
+        }{
+            {alice, nil},
+            {admin, float64(2)},
+        } {
+            var got map[string]any
+            resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+commentID, tt.token, "")
+            if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+                t.Fatal(err)
+            }
+            if got["flag_count"] != tt.want {
+                t.Errorf("expected flag_count %v, got %v", tt.want, got["flag_count"])
+            }
+        }
+    })
+
+    t.Run("listing is admin only", func(t *testing.T) {
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/flags", bob, "")
+        if resp.StatusCode != http.StatusForbidden {
+            t.Errorf("expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+        }
+
+        resp = doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/flags", admin, "")
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var list struct {
+            Comments []struct {
+                Comment struct {
+                    ID string `json:"id"`
+                } `json:"comment"`
+                FlagCount int    `json:"flag_count"`
+                Flags     []flag `json:"flags"`
+            } `json:"comments"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+            t.Fatal(err)
+        }
+        if len(list.Comments) != 1 || list.Comments[0].Comment.ID != commentID || list.Comments[0].FlagCount != 2 {
+            t.Fatalf("unexpected flag listing %+v", list)
+        }
+        if f := list.Comments[0].Flags; len(f) != 2 || f[0].UserID != "bob" || f[1].UserID != "carol" {
+            t.Errorf("expected bob's flag then carol's, got %+v", f)
+        }
+    })
+
+    t.Run("deleting the comment removes its flags", func(t *testing.T) {
+        resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+commentID, alice, "")
+        if resp.StatusCode != http.StatusNoContent {
+            t.Fatalf("expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+        }
+        resp = doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/flags", admin, "")
+        var list struct {
+            Comments []any `json:"comments"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+            t.Fatal(err)
+        }
+        if len(list.Comments) != 0 {
+            t.Errorf("expected no flagged comments, got %v", list.Comments)
+        }
+    })
+}
+
+func TestFlagReasonWhitespace(t *testing.T) {
+    t.Parallel()
+
+    srv, alice := newTestServer(t, "alice")
+    bob := issueToken(t, "bob", "user")
+    commentID := createComment(t, srv, alice, "flag me", "alice")
+
+    resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments/"+commentID+"/flags", bob, `{"reason":" \t\n "}`)
+    if resp.StatusCode != http.StatusCreated {
+        t.Fatalf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+    }
+    var f map[string]any
+    if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+        t.Fatal(err)
+    }
+    if reason, ok := f["reason"]; ok {
+        t.Errorf("expected a whitespace-only reason to be dropped, got %q", reason)
+    }
+}
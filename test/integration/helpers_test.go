@@ -0,0 +1,147 @@
+// test/integration/helpers_test.go
+
+package integration
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+    "web-service/internal/api"
+    "web-service/internal/auth"
+    "web-service/internal/config"
+    "web-service/internal/server"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// testSecret signs the tokens accepted by servers from newTestServer.
+const testSecret = "test-secret"
+
+// newTestServer starts the API on an httptest server backed by a fresh
+// in-memory store built with opts, and returns it with a valid user token
+// for userID.
+func newTestServer(t *testing.T, userID string, opts ...storage.Option) (*httptest.Server, string) {
+    t.Helper()
+
+    srv := startServer(t, testConfig(), opts...)
+    return srv, issueToken(t, userID, "user")
+}
+
+// testConfig returns the configuration newTestServer uses, for tests that
+// need to adjust it before calling startServer.
+func testConfig() *config.Config {
+    return &config.Config{
+        JWTSecret:   testSecret,
+        JWTExpiry:   time.Hour,
+        JWTLeeway:   auth.DefaultLeeway,
+        DatabaseURL: "memory://",
+        Environment: "test",
+    }
+}
+
+// startServer starts the API with cfg on an httptest server backed by a
+// fresh in-memory store built with opts.
+func startServer(t *testing.T, cfg *config.Config, opts ...storage.Option) *httptest.Server {
+    t.Helper()
+
+    srv := httptest.NewServer(api.NewServer(logging.NewLogger(io.Discard), cfg, storage.NewCommentStore(opts...)))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// runServer runs the command-line server with args on a port the system
+// chooses, logging to w, until ctx is done. It returns the server's base
+// URL once it is serving, and a channel receiving what Run returns.
+func runServer(t *testing.T, ctx context.Context, w io.Writer, args []string, getenv func(string) string) (string, <-chan error) {
+    t.Helper()
+
+    ready := make(chan net.Addr, 1)
+    runErr := make(chan error, 1)
+    go func() {
+        runErr <- server.Run(ctx, w, append(args, "--port", "0"), getenv, server.WithReady(func(addr net.Addr) {
+            ready <- addr
+        }))
+    }()
+
+    select {
+    case addr := <-ready:
+        return "http://" + addr.String(), runErr
+    case err := <-runErr:
+        t.Fatalf("Run returned before serving: %v", err)
+    case <-time.After(5 * time.Second):
+        t.Fatal("server did not start serving")
+    }
+    return "", nil
+}
+
+// issueToken mints a token for userID with role, valid for an hour.
+func issueToken(t *testing.T, userID, role string) string {
+    t.Helper()
+
+    token, err := auth.NewJWTManager(testSecret, time.Hour).GenerateToken(userID, role)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return token
+}
+
+// createComment posts a comment as the holder of token and returns its ID.
+func createComment(t *testing.T, srv *httptest.Server, token, content, author string) string {
+    t.Helper()
+
+    body := fmt.Sprintf(`{"content":%q,"author":%q}`, content, author)
+    req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/comments", strings.NewReader(body))
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated {
+        t.Fatalf("creating comment: expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+    }
+
+    var created struct {
+        ID string `json:"id"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+        t.Fatal(err)
+    }
+    return created.ID
+}
+
+// doRequest sends an authenticated request with an optional JSON body and
+// closes the response body when the test ends.
+func doRequest(t *testing.T, method, url, token, body string) *http.Response {
+    t.Helper()
+
+    req, err := http.NewRequest(method, url, strings.NewReader(body))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if token != "" {
+        req.Header.Set("Authorization", "Bearer "+token)
+    }
+    if body != "" {
+        req.Header.Set("Content-Type", "application/json")
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { resp.Body.Close() })
+    return resp
+}
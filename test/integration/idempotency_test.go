@@ -0,0 +1,105 @@
+// test/integration/idempotency_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.IdempotencyKeyTTL = time.Hour
+    srv := startServer(t, cfg)
+    alice := issueToken(t, "alice", "user")
+    bob := issueToken(t, "bob", "user")
+
+    post := func(t *testing.T, token, key, body string) (*http.Response, string) {
+        t.Helper()
+        req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/comments", strings.NewReader(body))
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        req.Header.Set("Content-Type", "application/json")
+        if key != "" {
+            req.Header.Set("Idempotency-Key", key)
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+        var created struct {
+            ID string `json:"id"`
+        }
+        json.NewDecoder(resp.Body).Decode(&created)
+        return resp, created.ID
+    }
+    countComments := func(t *testing.T) int {
+        t.Helper()
+        var comments []json.RawMessage
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", alice, "")
+        if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+            t.Fatal(err)
+        }
+        return len(comments)
+    }
+
+    body := `{"content":"Posted over a flaky network","author":"Alice"}`
+
+    first, id := post(t, alice, "retry-1", body)
+    if first.StatusCode != http.StatusCreated {
+        t.Fatalf("expected status %d, got %d", http.StatusCreated, first.StatusCode)
+    }
+
+    t.Run("retry returns the original comment", func(t *testing.T) {
+        resp, retryID := post(t, alice, "retry-1", body)
+        if resp.StatusCode != http.StatusCreated {
+            t.Fatalf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+        }
+        if retryID != id {
+            t.Errorf("expected comment %s, got %s", id, retryID)
+        }
+        if resp.Header.Get("Idempotent-Replayed") != "true" {
+            t.Error("expected Idempotent-Replayed header on retry")
+        }
+        if n := countComments(t); n != 1 {
+            t.Errorf("expected 1 comment, got %d", n)
+        }
+    })
+
+    t.Run("key reused for a different comment", func(t *testing.T) {
+        resp, _ := post(t, alice, "retry-1", `{"content":"Something else","author":"Alice"}`)
+        if resp.StatusCode != http.StatusUnprocessableEntity {
+            t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+        }
+    })
+
+    t.Run("keys are per user", func(t *testing.T) {
+        resp, otherID := post(t, bob, "retry-1", body)
+        if resp.StatusCode != http.StatusCreated || otherID == id {
+            t.Errorf("expected a new comment for another user, got status %d and id %s", resp.StatusCode, otherID)
+        }
+    })
+
+    t.Run("without a key every post creates", func(t *testing.T) {
+        _, a := post(t, alice, "", body)
+        _, b := post(t, alice, "", body)
+        if a == b {
+            t.Error("expected distinct comments without a key")
+        }
+    })
+
+    t.Run("oversized key", func(t *testing.T) {
+        resp, _ := post(t, alice, strings.Repeat("k", 256), body)
+        if resp.StatusCode != http.StatusBadRequest {
+            t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+    })
+}
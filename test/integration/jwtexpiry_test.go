@@ -0,0 +1,45 @@
+// test/integration/jwtexpiry_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestConfiguredJWTExpiry(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.JWTExpiry = 2 * time.Second
+    cfg.JWTLeeway = 0
+    cfg.Environment = "development" // for the test/test123 login
+    srv := startServer(t, cfg)
+
+    resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/login", "", `{"username":"test","password":"test123"}`)
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("login: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    var login struct {
+        Token     string `json:"token"`
+        ExpiresIn int64  `json:"expires_in"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+        t.Fatal(err)
+    }
+    if login.ExpiresIn != 2 {
+        t.Errorf("expected expires_in 2, got %d", login.ExpiresIn)
+    }
+
+    if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", login.Token, ""); resp.StatusCode != http.StatusOK {
+        t.Fatalf("fresh token: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+
+    // exp has one-second resolution, so wait a full second past it
+    time.Sleep(3 * time.Second)
+    if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", login.Token, ""); resp.StatusCode != http.StatusUnauthorized {
+        t.Errorf("expired token: expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+    }
+}
@@ -0,0 +1,96 @@
+// test/integration/listen_test.go
+
+package integration
+
+import (
+    "context"
+    "io"
+    "net"
+    "strings"
+    "testing"
+    "time"
+    "web-service/internal/server"
+)
+
+func TestListenRetries(t *testing.T) {
+    t.Parallel()
+
+    env := map[string]string{
+        "JWT_SECRET":  testSecret,
+        "ENVIRONMENT": "test",
+    }
+    getenv := func(key string) string { return env[key] }
+
+    // busy occupies a local port until the test ends or it is closed.
+    busy := func(t *testing.T) (net.Listener, string) {
+        t.Helper()
+        l, err := net.Listen("tcp", "127.0.0.1:0")
+        if err != nil {
+            t.Fatal(err)
+        }
+        t.Cleanup(func() { l.Close() })
+        _, port, _ := net.SplitHostPort(l.Addr().String())
+        return l, port
+    }
+
+    t.Run("fails fast by default", func(t *testing.T) {
+        _, port := busy(t)
+        err := server.Run(context.Background(), io.Discard, []string{"server", "--host", "127.0.0.1", "--port", port}, getenv)
+        if err == nil || !strings.Contains(err.Error(), "address already in use") {
+            t.Errorf("expected address in use error, got %v", err)
+        }
+    })
+
+    t.Run("gives up after the retries", func(t *testing.T) {
+        _, port := busy(t)
+        var logs syncBuffer
+        err := server.Run(context.Background(), &logs, []string{"server", "--host", "127.0.0.1", "--port", port, "--listen-retries", "2"}, getenv)
+        if err == nil || !strings.Contains(err.Error(), "address already in use") {
+            t.Errorf("expected address in use error, got %v", err)
+        }
+        if n := strings.Count(logs.String(), "address in use, retrying listen"); n != 2 {
+            t.Errorf("expected 2 retries logged, got %d:\n%s", n, logs.String())
+        }
+    })
+
+    t.Run("listens once the address is free", func(t *testing.T) {
+        blocker, port := busy(t)
+        ctx, cancel := context.WithCancel(context.Background())
+        defer cancel()
+
+        var logs syncBuffer
+        ready := make(chan net.Addr, 1)
+        runErr := make(chan error, 1)
+        go func() {
+            runErr <- server.Run(ctx, &logs, []string{"server", "--host", "127.0.0.1", "--port", port, "--listen-retries", "10"}, getenv,
+                server.WithReady(func(addr net.Addr) { ready <- addr }))
+        }()
+
+        if err := waitFor(5*time.Second, func() bool {
+            return strings.Contains(logs.String(), "address in use, retrying listen")
+        }); err != nil {
+            t.Fatal(err)
+        }
+        fields := findLogFields(t, logs.String(), "address in use, retrying listen")
+        if fields["attempt"] != float64(1) || fields["retry_in"] == "" {
+            t.Errorf("expected the first attempt and its backoff logged, got %v", fields)
+        }
+        blocker.Close()
+
+        select {
+        case addr := <-ready:
+            if _, got, _ := net.SplitHostPort(addr.String()); got != port {
+                t.Errorf("expected to listen on port %s, got %s", port, got)
+            }
+        case err := <-runErr:
+            t.Fatalf("Run returned before serving: %v", err)
+        case <-time.After(10 * time.Second):
+            t.Fatal("server did not start serving")
+        }
+
+        cancel()
+        if err := <-runErr; err != nil {
+            t.Errorf("Run returned %v", err)
+        }
+    })
+}
@@ -0,0 +1,110 @@
+// test/integration/lockout_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "time"
+    "web-service/internal/auth"
+    "web-service/internal/config"
+)
+
+func TestLoginLockout(t *testing.T) {
+    t.Parallel()
+
+    hash, err := auth.HashPassword("hunter2")
+    if err != nil {
+        t.Fatal(err)
+    }
+    cfg := testConfig()
+    cfg.Users = []config.UserSeed{
+        {Username: "mod", Role: "admin", PasswordHash: hash},
+        {Username: "other", Role: "user", PasswordHash: hash},
+    }
+    cfg.LoginMaxFailures = 3
+    cfg.LoginMaxFailuresPerIP = 5
+    cfg.LoginFailureWindow = time.Minute
+    cfg.LoginLockout = time.Minute
+    srv := startServer(t, cfg)
+
+    login := func(username, password string) *http.Response {
+        body, _ := json.Marshal(map[string]string{"username": username, "password": password})
+        return doRequest(t, http.MethodPost, srv.URL+"/api/v1/login", "", string(body))
+    }
+
+    for i := 0; i < 3; i++ {
+        if resp := login("mod", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+            t.Fatalf("failure %d: expected status %d, got %d", i+1, http.StatusUnauthorized, resp.StatusCode)
+        }
+    }
+
+    resp := login("mod", "hunter2")
+    if resp.StatusCode != http.StatusTooManyRequests {
+        t.Fatalf("expected status %d for locked username, got %d", http.StatusTooManyRequests, resp.StatusCode)
+    }
+    if got := resp.Header.Get("Retry-After"); got != "60" {
+        t.Errorf("expected Retry-After 60, got %q", got)
+    }
+    var body struct {
+        Code string `json:"code"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        t.Fatal(err)
+    }
+    if body.Code != "rate_limited" {
+        t.Errorf("expected code rate_limited, got %q", body.Code)
+    }
+
+    // Another username from the same IP still works until the per-IP
+    // limit is reached. A success clears the username's failures but not
+    // the IP's, which already stands at 3.
+    if resp := login("other", "hunter2"); resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d for other user, got %d", http.StatusOK, resp.StatusCode)
+    }
+    login("usera", "wrong")
+    if resp := login("other", "hunter2"); resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d below the per-IP limit, got %d", http.StatusOK, resp.StatusCode)
+    }
+    login("userb", "wrong")
+    if resp := login("other", "hunter2"); resp.StatusCode != http.StatusTooManyRequests {
+        t.Errorf("expected status %d for locked IP, got %d", http.StatusTooManyRequests, resp.StatusCode)
+    }
+}
+
+func TestLoginSuccessAtLimit(t *testing.T) {
+    t.Parallel()
+
+    hash, err := auth.HashPassword("hunter2")
+    if err != nil {
+        t.Fatal(err)
+    }
+    cfg := testConfig()
+    cfg.Users = []config.UserSeed{{Username: "mod", Role: "admin", PasswordHash: hash}}
+    cfg.LoginMaxFailures = 2
+    cfg.LoginFailureWindow = time.Minute
+    cfg.LoginLockout = time.Minute
+    srv := startServer(t, cfg)
+
+    login := func(password string) int {
+        body, _ := json.Marshal(map[string]string{"username": "mod", "password": password})
+        return doRequest(t, http.MethodPost, srv.URL+"/api/v1/login", "", string(body)).StatusCode
+    }
+
+    // The right password on the attempt that reaches the limit logs in,
+    // and resets the username's count
+    login("wrong")
+    if status := login("hunter2"); status != http.StatusOK {
+        t.Fatalf("expected status %d at the limit, got %d", http.StatusOK, status)
+    }
+    if status := login("wrong"); status != http.StatusUnauthorized {
+        t.Fatalf("expected status %d after the reset, got %d", http.StatusUnauthorized, status)
+    }
+    if status := login("wrong"); status != http.StatusUnauthorized {
+        t.Fatalf("expected status %d for the locking failure, got %d", http.StatusUnauthorized, status)
+    }
+    if status := login("hunter2"); status != http.StatusTooManyRequests {
+        t.Errorf("expected status %d once locked, got %d", http.StatusTooManyRequests, status)
+    }
+}
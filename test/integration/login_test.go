@@ -0,0 +1,68 @@
+// test/integration/login_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "web-service/internal/auth"
+    "web-service/internal/config"
+)
+
+func TestLoginUserStore(t *testing.T) {
+    t.Parallel()
+
+    hash, err := auth.HashPassword("hunter2")
+    if err != nil {
+        t.Fatal(err)
+    }
+    cfg := testConfig()
+    cfg.Users = []config.UserSeed{{Username: "mod", Role: "admin", PasswordHash: hash}}
+    srv := startServer(t, cfg)
+
+    login := func(t *testing.T, username, password string) (*http.Response, string) {
+        t.Helper()
+        body, _ := json.Marshal(map[string]string{"username": username, "password": password})
+        resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/login", "", string(body))
+        var out struct {
+            Token string `json:"token"`
+        }
+        if resp.StatusCode == http.StatusOK {
+            if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+                t.Fatal(err)
+            }
+        }
+        return resp, out.Token
+    }
+
+    t.Run("seeded user", func(t *testing.T) {
+        resp, token := login(t, "mod", "hunter2")
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+
+        claims, err := auth.NewJWTManager(testSecret, 0).ValidateToken(token)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if claims.UserID != "mod" || claims.Role != "admin" {
+            t.Errorf("expected user mod with role admin, got %s with %s", claims.UserID, claims.Role)
+        }
+        if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/admin/comments/search", token, ""); resp.StatusCode != http.StatusOK {
+            t.Errorf("admin search: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+    })
+
+    t.Run("wrong password", func(t *testing.T) {
+        if resp, _ := login(t, "mod", "hunter3"); resp.StatusCode != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+        }
+    })
+
+    t.Run("development user outside development", func(t *testing.T) {
+        if resp, _ := login(t, "test", "test123"); resp.StatusCode != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+        }
+    })
+}
@@ -6,12 +6,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 	"testing"
 	"time"
-	"web-service/internal/server"
 )
 
 
@@ -23,21 +20,21 @@ func TestCommentAPI(t *testing.T) {
         name         string
         args         []string
         envVars      map[string]string
-        setupFunc    func(t *testing.T)
-        request      func(t *testing.T) (*http.Response, error)
+        setupFunc    func(t *testing.T, base string)
+        request      func(t *testing.T, base string) (*http.Response, error)
         validateFunc func(t *testing.T, resp *http.Response)
     }{
         {
             name: "health check endpoint",
-            args: []string{"server", "--port", "8081"},
+            args: []string{"server"},
             envVars: map[string]string{
 				"JWT_SECRET":   "test-secret",
 				"DATABASE_URL": "memory://test",
 				"ENVIRONMENT":  "test",
 			},
-            request: func(t *testing.T) (*http.Response, error) {
+            request: func(t *testing.T, base string) (*http.Response, error) {
                 t.Log("Making health check request...")
-                return http.Get("http://localhost:8081/healthz")
+                return http.Get(base+"/healthz")
             },
             validateFunc: func(t *testing.T, resp *http.Response) {
                 t.Logf("Validating health check response with status code: %d", resp.StatusCode)
@@ -66,13 +63,13 @@ func TestCommentAPI(t *testing.T) {
         },
         {
             name: "create comment successfully",
-            args: []string{"server", "--port", "8082"},
+            args: []string{"server"},
             envVars: map[string]string{
 				"JWT_SECRET":   "test-secret",
 				"DATABASE_URL": "memory://test",
-				"ENVIRONMENT":  "test",
+				"ENVIRONMENT":  "development",
 			},
-            request: func(t *testing.T) (*http.Response, error) {
+            request: func(t *testing.T, base string) (*http.Response, error) {
                 t.Log("Making create comment request...")
                 comment := struct {
                     Content string `json:"content"`
@@ -87,7 +84,7 @@ func TestCommentAPI(t *testing.T) {
                     t.Fatal(err)
                 }
 
-                req, err := http.NewRequest(http.MethodPost, "http://localhost:8082/api/v1/comments", &buf)
+                req, err := http.NewRequest(http.MethodPost, base+"/api/v1/comments", &buf)
                 if err != nil {
                     t.Fatal(err)
                 }
@@ -106,7 +103,7 @@ func TestCommentAPI(t *testing.T) {
                     t.Fatal(err)
                 }
 
-                loginResp, err := http.Post("http://localhost:8082/api/v1/login", "application/json", &loginBuf)
+                loginResp, err := http.Post(base+"/api/v1/login", "application/json", &loginBuf)
                 if err != nil {
                     t.Fatal(err)
                 }
@@ -164,13 +161,13 @@ func TestCommentAPI(t *testing.T) {
         },
         {
             name: "list comments",
-            args: []string{"server", "--port", "8083"},
+            args: []string{"server"},
             envVars: map[string]string{
 				"JWT_SECRET":   "test-secret",
 				"DATABASE_URL": "memory://test",
-				"ENVIRONMENT":  "test",
+				"ENVIRONMENT":  "development",
 			},
-            setupFunc: func(t *testing.T) {
+            setupFunc: func(t *testing.T, base string) {
                 // Create a test comment first
                 comment := struct {
                     Content string `json:"content"`
@@ -199,7 +196,7 @@ func TestCommentAPI(t *testing.T) {
                     t.Fatal(err)
                 }
 
-                loginResp, err := http.Post("http://localhost:8083/api/v1/login", "application/json", &loginBuf)
+                loginResp, err := http.Post(base+"/api/v1/login", "application/json", &loginBuf)
                 if err != nil {
                     t.Fatal(err)
                 }
@@ -212,7 +209,7 @@ func TestCommentAPI(t *testing.T) {
                     t.Fatal(err)
                 }
 
-                req, err := http.NewRequest(http.MethodPost, "http://localhost:8083/api/v1/comments", &buf)
+                req, err := http.NewRequest(http.MethodPost, base+"/api/v1/comments", &buf)
                 if err != nil {
                     t.Fatal(err)
                 }
@@ -229,7 +226,7 @@ func TestCommentAPI(t *testing.T) {
                     t.Fatalf("failed to create setup comment: status %d", resp.StatusCode)
                 }
             },
-            request: func(t *testing.T) (*http.Response, error) {
+            request: func(t *testing.T, base string) (*http.Response, error) {
                 t.Log("Making list comments request...")
 
                 // Login to get token
@@ -246,7 +243,7 @@ func TestCommentAPI(t *testing.T) {
                     t.Fatal(err)
                 }
 
-                loginResp, err := http.Post("http://localhost:8083/api/v1/login", "application/json", &loginBuf)
+                loginResp, err := http.Post(base+"/api/v1/login", "application/json", &loginBuf)
                 if err != nil {
                     t.Fatal(err)
                 }
@@ -259,7 +256,7 @@ func TestCommentAPI(t *testing.T) {
                     t.Fatal(err)
                 }
 
-                req, err := http.NewRequest(http.MethodGet, "http://localhost:8083/api/v1/comments", nil)
+                req, err := http.NewRequest(http.MethodGet, base+"/api/v1/comments", nil)
                 if err != nil {
                     t.Fatal(err)
                 }
@@ -319,7 +316,9 @@ func TestCommentAPI(t *testing.T) {
                 cancel()
             })
 
-            var stdout, stderr bytes.Buffer
+            // The server logs to stdout as the test reads it on failure
+            var stdout syncBuffer
+            var stderr bytes.Buffer
 
             getenv := func(key string) string {
                 val := tt.envVars[key]
@@ -327,47 +326,16 @@ func TestCommentAPI(t *testing.T) {
                 return val
             }
 
-            // Start the server
-            serverErrCh := make(chan error, 1)
-            go func() {
-                t.Log("Starting server...")
-                if err := server.Run(ctx, &stdout, tt.args, getenv); err != nil {
-                    select {
-                    case <-ctx.Done():
-                        // Expected error from shutdown
-                        return
-                    default:
-                        serverErrCh <- err
-                    }
-                }
-            }()
-
-            // Wait for server to be ready
-            endpoint := fmt.Sprintf("http://localhost:%s/healthz", tt.args[2])
-            t.Logf("Waiting for server to be ready at %s", endpoint)
-            if err := waitForReady(ctx, 5*time.Second, endpoint); err != nil {
-                t.Logf("Server stdout:\n%s", stdout.String())
-                t.Logf("Server stderr:\n%s", stderr.String())
-                t.Fatalf("Server failed to become ready: %v", err)
-            }
-
-            // Check for any server errors
-            select {
-            case err := <-serverErrCh:
-                if err != nil {
-                    t.Fatalf("Server error: %v", err)
-                }
-            default:
-                // Server still running, proceed with test
-            }
+            // Start the server on a free port
+            base, _ := runServer(t, ctx, &stdout, tt.args, getenv)
 
             if tt.setupFunc != nil {
                 t.Log("Running setup function...")
-                tt.setupFunc(t)
+                tt.setupFunc(t, base)
             }
 
             t.Log("Making request...")
-            resp, err := tt.request(t)
+            resp, err := tt.request(t, base)
             if err != nil {
                 t.Fatalf("Request failed: %v", err)
             }
@@ -383,45 +351,3 @@ func TestCommentAPI(t *testing.T) {
         })
     }
 }
-
-func waitForReady(ctx context.Context, timeout time.Duration, endpoint string) error {
-    client := http.Client{
-        Timeout: 1 * time.Second,
-    }
-    startTime := time.Now()
-
-    for {
-        req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-        if err != nil {
-            return fmt.Errorf("failed to create request: %w", err)
-        }
-
-        resp, err := client.Do(req)
-        if err != nil {
-            select {
-            case <-ctx.Done():
-                return ctx.Err()
-            case <-time.After(250 * time.Millisecond):
-                continue
-            }
-        }
-
-        if resp != nil {
-            io.Copy(io.Discard, resp.Body)
-            resp.Body.Close()
-            if resp.StatusCode == http.StatusOK {
-                return nil
-            }
-        }
-
-        if time.Since(startTime) >= timeout {
-            return fmt.Errorf("timeout waiting for endpoint %s", endpoint)
-        }
-
-        select {
-        case <-ctx.Done():
-            return ctx.Err()
-        case <-time.After(250 * time.Millisecond):
-        }
-    }
-}
\ No newline at end of file
@@ -0,0 +1,278 @@
+// test/integration/negotiation_test.go
+
+package integration
+
+import (
+    "bytes"
+    "encoding/json"
+    "encoding/xml"
+    "io"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestContentNegotiation(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "negotiator")
+
+    tests := []struct {
+        name       string
+        method     string
+        path       string
+        body       string
+        accept     string
+        wantStatus int
+        wantType   string
+        validate   func(t *testing.T, body []byte)
+    }{
+        {
+            name:       "healthz defaults to json",
+            method:     http.MethodGet,
+            path:       "/healthz",
+            wantStatus: http.StatusOK,
+            wantType:   "application/json",
+        },
+        {
+            name:       "healthz wildcard is json",
+            method:     http.MethodGet,
+            path:       "/healthz",
+            accept:     "*/*",
+            wantStatus: http.StatusOK,
+            wantType:   "application/json",
+        },
+        {
+            name:       "healthz as xml",
+            method:     http.MethodGet,
+            path:       "/healthz",
+            accept:     "application/xml",
+            wantStatus: http.StatusOK,
+            wantType:   "application/xml",
+            validate: func(t *testing.T, body []byte) {
+                if !bytes.Contains(body, []byte(`<entry key="status">ok</entry>`)) {
+                    t.Errorf("expected status entry in %s", body)
+                }
+            },
+        },
+        {
+            name:       "create comment as xml",
+            method:     http.MethodPost,
+            path:       "/api/v1/comments",
+            body:       `{"content":"xml please","author":"tester"}`,
+            accept:     "application/xml",
+            wantStatus: http.StatusCreated,
+            wantType:   "application/xml",
+            validate: func(t *testing.T, body []byte) {
+                var c struct {
+                    XMLName xml.Name `xml:"comment"`
+                    Content string   `xml:"content"`
+                }
+                if err := xml.Unmarshal(body, &c); err != nil {
+                    t.Fatalf("decoding xml: %v", err)
+                }
+                if c.Content != "xml please" {
+                    t.Errorf("expected content %q, got %q", "xml please", c.Content)
+                }
+            },
+        },
+        {
+            name:       "list comments prefers higher quality",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments",
+            accept:     "application/json;q=0.5, application/xml",
+            wantStatus: http.StatusOK,
+            wantType:   "application/xml",
+            validate: func(t *testing.T, body []byte) {
+                if !bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(body, []byte(xml.Header))), []byte("<items")) {
+                    t.Errorf("expected <items> root, got %s", body)
+                }
+            },
+        },
+        {
+            name:       "list comments as json",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments",
+            accept:     "application/json",
+            wantStatus: http.StatusOK,
+            wantType:   "application/json",
+            validate: func(t *testing.T, body []byte) {
+                var list []json.RawMessage
+                if err := json.Unmarshal(body, &list); err != nil {
+                    t.Fatalf("decoding json: %v", err)
+                }
+            },
+        },
+        {
+            name:       "stats as xml",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments/stats",
+            accept:     "application/xml",
+            wantStatus: http.StatusOK,
+            wantType:   "application/xml",
+            validate: func(t *testing.T, body []byte) {
+                if !bytes.Contains(body, []byte("<by_author>")) {
+                    t.Errorf("expected by_author element in %s", body)
+                }
+            },
+        },
+        {
+            name:       "unsupported type is not acceptable",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments",
+            accept:     "text/html",
+            wantStatus: http.StatusNotAcceptable,
+            wantType:   "application/problem+json",
+            validate: func(t *testing.T, body []byte) {
+                var resp struct {
+                    Code   string `json:"code"`
+                    Detail string `json:"detail"`
+                }
+                if err := json.Unmarshal(body, &resp); err != nil {
+                    t.Fatalf("decoding json: %v", err)
+                }
+                if resp.Code != "not_acceptable" || resp.Detail == "" {
+                    t.Errorf("unexpected error body: %+v", resp)
+                }
+            },
+        },
+        {
+            name:       "excluded json is not acceptable",
+            method:     http.MethodGet,
+            path:       "/healthz",
+            accept:     "application/json;q=0",
+            wantStatus: http.StatusNotAcceptable,
+            wantType:   "application/problem+json",
+        },
+    }
+
+    for _, tt := range tests {
+        tt := tt
+        t.Run(tt.name, func(t *testing.T) {
+            t.Parallel()
+
+            req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+            if err != nil {
+                t.Fatal(err)
+            }
+            req.Header.Set("Authorization", "Bearer "+token)
+            req.Header.Set("Content-Type", "application/json")
+            if tt.accept != "" {
+                req.Header.Set("Accept", tt.accept)
+            }
+
+            resp, err := http.DefaultClient.Do(req)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer resp.Body.Close()
+
+            body, err := io.ReadAll(resp.Body)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            if resp.StatusCode != tt.wantStatus {
+                t.Errorf("expected status %d, got %d: %s", tt.wantStatus, resp.StatusCode, body)
+            }
+            if got := resp.Header.Get("Content-Type"); got != tt.wantType {
+                t.Errorf("expected Content-Type %q, got %q", tt.wantType, got)
+            }
+            if tt.validate != nil {
+                tt.validate(t, body)
+            }
+        })
+    }
+}
+
+func TestNotAcceptableWriteChangesNothing(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "plaintext")
+
+    req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/comments", strings.NewReader(`{"content":"hi","author":"Alice"}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Accept", "text/plain")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusNotAcceptable {
+        t.Fatalf("expected status %d, got %d", http.StatusNotAcceptable, resp.StatusCode)
+    }
+
+    // The comment must not have been stored behind the 406
+    resp = doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", token, "")
+    if got := resp.Header.Get("X-Total-Count"); got != "0" {
+        t.Errorf("expected no comments after a 406, got X-Total-Count %q", got)
+    }
+}
+
+func TestNotAcceptableBeforeCaching(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "cacher")
+    createComment(t, srv, token, "cached", "Alice")
+
+    // Unchanged since the client's copy, but the client can't read any
+    // copy we could send: 406, without the list's caching headers
+    req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Accept", "text/html")
+    req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusNotAcceptable {
+        t.Fatalf("expected status %d, got %d", http.StatusNotAcceptable, resp.StatusCode)
+    }
+    for _, header := range []string{"Cache-Control", "Last-Modified"} {
+        if got := resp.Header.Get(header); got != "" {
+            t.Errorf("expected no %s on a 406, got %q", header, got)
+        }
+    }
+}
+
+func TestNoContentRoutesSkipNegotiation(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "deleter")
+    id := createComment(t, srv, token, "doomed", "Alice")
+
+    del := func(token string) int {
+        req, err := http.NewRequest(http.MethodDelete, srv.URL+"/api/v1/comments/"+id, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        req.Header.Set("Accept", "text/plain")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+
+    // A delete answers with no body, so Accept has nothing to refuse, and
+    // a missing token is still reported as such
+    if status := del(""); status != http.StatusUnauthorized {
+        t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, status)
+    }
+    if status := del(token); status != http.StatusNoContent {
+        t.Errorf("expected status %d, got %d", http.StatusNoContent, status)
+    }
+}
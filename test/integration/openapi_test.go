@@ -0,0 +1,63 @@
+// test/integration/openapi_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "spec-reader")
+
+    // The spec is public, and JSON whatever the client asks for
+    req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/openapi.json", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Accept", "application/xml")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+        t.Errorf("expected Content-Type application/json, got %q", ct)
+    }
+
+    var spec struct {
+        OpenAPI    string                    `json:"openapi"`
+        Paths      map[string]map[string]any `json:"paths"`
+        Components struct {
+            Schemas map[string]any `json:"schemas"`
+        } `json:"components"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+        t.Fatal(err)
+    }
+    if spec.OpenAPI == "" {
+        t.Error("missing openapi version")
+    }
+    for path, method := range map[string]string{
+        "/api/v1/login":         "post",
+        "/api/v1/comments":      "post",
+        "/api/v1/comments/{id}": "get",
+        "/healthz":              "get",
+    } {
+        if _, ok := spec.Paths[path][method]; !ok {
+            t.Errorf("spec has no %s %s", method, path)
+        }
+    }
+    for _, name := range []string{"Comment", "CreateCommentRequest", "LoginRequest", "LoginResponse", "Problem"} {
+        if _, ok := spec.Components.Schemas[name]; !ok {
+            t.Errorf("spec has no %s schema", name)
+        }
+    }
+}
@@ -0,0 +1,120 @@
+// test/integration/pagination_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+)
+
+func TestListPagination(t *testing.T) {
+    t.Parallel()
+
+    // Tick the clock on every read so creation order is unambiguous
+    var mu sync.Mutex
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    clock := storage.WithClock(func() time.Time {
+        mu.Lock()
+        defer mu.Unlock()
+        now = now.Add(time.Second)
+        return now
+    })
+    srv, token := newTestServer(t, "pager", clock)
+
+    var ids []string
+    for i := 0; i < 5; i++ {
+        ids = append(ids, createComment(t, srv, token, fmt.Sprintf("comment %d", i), "Alice"))
+    }
+
+    list := func(t *testing.T, query string) (*http.Response, []string) {
+        t.Helper()
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments"+query, token, "")
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var comments []struct {
+            ID string `json:"id"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+            t.Fatal(err)
+        }
+        got := make([]string, len(comments))
+        for i, c := range comments {
+            got[i] = c.ID
+        }
+        return resp, got
+    }
+
+    tests := []struct {
+        name     string
+        query    string
+        wantIDs  []string
+        wantLink string
+    }{
+        {
+            name:     "first page",
+            query:    "?limit=2",
+            wantIDs:  ids[0:2],
+            wantLink: `</api/v1/comments?limit=2&offset=0>; rel="first", </api/v1/comments?limit=2&offset=2>; rel="next", </api/v1/comments?limit=2&offset=4>; rel="last"`,
+        },
+        {
+            name:     "middle page",
+            query:    "?limit=2&offset=2",
+            wantIDs:  ids[2:4],
+            wantLink: `</api/v1/comments?limit=2&offset=0>; rel="first", </api/v1/comments?limit=2&offset=0>; rel="prev", </api/v1/comments?limit=2&offset=4>; rel="next", </api/v1/comments?limit=2&offset=4>; rel="last"`,
+        },
+        {
+            name:     "last page",
+            query:    "?limit=2&offset=4",
+            wantIDs:  ids[4:],
+            wantLink: `</api/v1/comments?limit=2&offset=0>; rel="first", </api/v1/comments?limit=2&offset=2>; rel="prev", </api/v1/comments?limit=2&offset=4>; rel="last"`,
+        },
+        {
+            name:     "past the end",
+            query:    "?limit=2&offset=10",
+            wantIDs:  []string{},
+            wantLink: `</api/v1/comments?limit=2&offset=0>; rel="first", </api/v1/comments?limit=2&offset=8>; rel="prev", </api/v1/comments?limit=2&offset=4>; rel="last"`,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resp, got := list(t, tt.query)
+            if strings.Join(got, ",") != strings.Join(tt.wantIDs, ",") {
+                t.Errorf("expected comments %v, got %v", tt.wantIDs, got)
+            }
+            if total := resp.Header.Get("X-Total-Count"); total != "5" {
+                t.Errorf("expected X-Total-Count 5, got %q", total)
+            }
+            if link := resp.Header.Get("Link"); link != tt.wantLink {
+                t.Errorf("expected Link\n%s\ngot\n%s", tt.wantLink, link)
+            }
+        })
+    }
+
+    t.Run("unpaginated", func(t *testing.T) {
+        resp, got := list(t, "")
+        if len(got) != 5 {
+            t.Errorf("expected all 5 comments, got %d", len(got))
+        }
+        if total := resp.Header.Get("X-Total-Count"); total != "5" {
+            t.Errorf("expected X-Total-Count 5, got %q", total)
+        }
+        if link := resp.Header.Get("Link"); link != "" {
+            t.Errorf("expected no Link header, got %q", link)
+        }
+    })
+
+    t.Run("invalid limit", func(t *testing.T) {
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments?limit=0", token, "")
+        if resp.StatusCode != http.StatusBadRequest {
+            t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+    })
+}
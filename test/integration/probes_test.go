@@ -0,0 +1,72 @@
+// test/integration/probes_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "runtime"
+    "testing"
+)
+
+func TestProbes(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "kubelet")
+
+    // Probes are unauthenticated
+    for _, path := range []string{"/livez", "/healthz", "/readyz"} {
+        t.Run(path, func(t *testing.T) {
+            resp := doRequest(t, http.MethodGet, srv.URL+path, "", "")
+            if resp.StatusCode != http.StatusOK {
+                t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+            }
+        })
+    }
+}
+
+func TestHealthDetail(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "oncall")
+    admin := issueToken(t, "ops", "admin")
+    createComment(t, srv, token, "hello", "Alice")
+
+    // The detail shows internals, so it is for admins only
+    if resp := doRequest(t, http.MethodGet, srv.URL+"/health/detail", "", ""); resp.StatusCode != http.StatusUnauthorized {
+        t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, resp.StatusCode)
+    }
+    if resp := doRequest(t, http.MethodGet, srv.URL+"/health/detail", token, ""); resp.StatusCode != http.StatusForbidden {
+        t.Errorf("expected status %d for a user, got %d", http.StatusForbidden, resp.StatusCode)
+    }
+
+    resp := doRequest(t, http.MethodGet, srv.URL+"/health/detail", admin, "")
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    var detail struct {
+        Status        string `json:"status"`
+        UptimeSeconds *int64 `json:"uptime_seconds"`
+        Comments      int    `json:"comments"`
+        GoVersion     string `json:"go_version"`
+        Goroutines    int    `json:"goroutines"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&detail); err != nil {
+        t.Fatal(err)
+    }
+    if detail.Status != "ok" || detail.UptimeSeconds == nil || detail.Comments != 1 {
+        t.Errorf("expected ok with uptime and 1 comment, got %+v", detail)
+    }
+    if detail.GoVersion != runtime.Version() || detail.Goroutines == 0 {
+        t.Errorf("expected Go %s and a goroutine count, got %+v", runtime.Version(), detail)
+    }
+
+    // /healthz stays lean for load balancers
+    var basic map[string]any
+    if err := json.NewDecoder(doRequest(t, http.MethodGet, srv.URL+"/healthz", "", "").Body).Decode(&basic); err != nil {
+        t.Fatal(err)
+    }
+    if len(basic) != 2 {
+        t.Errorf("expected only status and time from /healthz, got %v", basic)
+    }
+}
@@ -0,0 +1,156 @@
+// test/integration/problem_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "encoding/xml"
+    "net/http"
+    "testing"
+    "web-service/internal/config"
+)
+
+func TestProblemDetails(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "problematic")
+
+    t.Run("validation", func(t *testing.T) {
+        resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", token, `{"content": "", "author": "Alice"}`)
+        if resp.StatusCode != http.StatusBadRequest {
+            t.Fatalf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+        if ct := resp.Header.Get("Content-Type"); ct != "application/problem+json" {
+            t.Errorf("expected Content-Type application/problem+json, got %q", ct)
+        }
+
+        var body map[string]any
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        for key, want := range map[string]any{
+            "type":   "urn:web-service:problem:invalid_request",
+            "title":  "Bad Request",
+            "status": float64(http.StatusBadRequest),
+            "code":   "invalid_request",
+        } {
+            if body[key] != want {
+                t.Errorf("expected %s %v, got %v", key, want, body[key])
+            }
+        }
+        params, _ := body["invalid-params"].([]any)
+        if len(params) != 1 {
+            t.Fatalf("expected one invalid param, got %v", body["invalid-params"])
+        }
+        if param := params[0].(map[string]any); param["name"] != "content" || param["reason"] != "content is required" {
+            t.Errorf("expected content to be required, got %v", param)
+        }
+    })
+
+    t.Run("auth", func(t *testing.T) {
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", "", "")
+        if resp.StatusCode != http.StatusUnauthorized {
+            t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+        }
+        if ct := resp.Header.Get("Content-Type"); ct != "application/problem+json" {
+            t.Errorf("expected Content-Type application/problem+json, got %q", ct)
+        }
+
+        var body map[string]any
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        if body["type"] != "urn:web-service:problem:unauthorized" || body["title"] != "Unauthorized" || body["status"] != float64(http.StatusUnauthorized) {
+            t.Errorf("unexpected problem: %v", body)
+        }
+        if _, ok := body["invalid-params"]; ok {
+            t.Errorf("expected no invalid params, got %v", body["invalid-params"])
+        }
+    })
+
+    t.Run("xml", func(t *testing.T) {
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments/missing", nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        req.Header.Set("Accept", "application/xml")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+
+        if resp.StatusCode != http.StatusNotFound {
+            t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+        }
+        if ct := resp.Header.Get("Content-Type"); ct != "application/problem+xml" {
+            t.Errorf("expected Content-Type application/problem+xml, got %q", ct)
+        }
+        var body struct {
+            XMLName xml.Name `xml:"urn:ietf:rfc:7807 problem"`
+            Type    string   `xml:"type"`
+            Status  int      `xml:"status"`
+        }
+        if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        if body.Type != "urn:web-service:problem:not_found" || body.Status != http.StatusNotFound {
+            t.Errorf("unexpected problem: %+v", body)
+        }
+    })
+}
+
+func TestLegacyErrorFormat(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.ErrorFormat = config.ErrorFormatLegacy
+    srv := startServer(t, cfg)
+    token := issueToken(t, "oldclient", "user")
+
+    tests := []struct {
+        name        string
+        token       string
+        body        string
+        wantStatus  int
+        wantCode    string
+        wantDetails string
+    }{
+        {name: "validation", token: token, body: `{"content": "", "author": "Alice"}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_request", wantDetails: "content"},
+        {name: "auth", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            method := http.MethodGet
+            if tt.body != "" {
+                method = http.MethodPost
+            }
+            resp := doRequest(t, method, srv.URL+"/api/v1/comments", tt.token, tt.body)
+            if resp.StatusCode != tt.wantStatus {
+                t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+            }
+            if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+                t.Errorf("expected Content-Type application/json, got %q", ct)
+            }
+
+            var body struct {
+                Error struct {
+                    Code    string              `json:"code"`
+                    Message string              `json:"message"`
+                    Details map[string][]string `json:"details"`
+                } `json:"error"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                t.Fatal(err)
+            }
+            if body.Error.Code != tt.wantCode || body.Error.Message == "" {
+                t.Errorf("expected code %q with a message, got %+v", tt.wantCode, body.Error)
+            }
+            if tt.wantDetails != "" && len(body.Error.Details[tt.wantDetails]) == 0 {
+                t.Errorf("expected a problem for %s, got %v", tt.wantDetails, body.Error.Details)
+            }
+        })
+    }
+}
\ No newline at end of file
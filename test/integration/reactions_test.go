@@ -0,0 +1,63 @@
+// test/integration/reactions_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+)
+
+func TestReactions(t *testing.T) {
+    t.Parallel()
+
+    srv, alice := newTestServer(t, "alice")
+    bob := issueToken(t, "bob", "user")
+    commentID := createComment(t, srv, alice, "react to me", "alice")
+    reactionsURL := srv.URL + "/api/v1/comments/" + commentID + "/reactions"
+
+    type reaction struct {
+        ReactionCount    int  `json:"reaction_count"`
+        ViewerHasReacted bool `json:"viewer_has_reacted"`
+    }
+    expectReaction := func(t *testing.T, resp *http.Response, want reaction) {
+        t.Helper()
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var got reaction
+        if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+            t.Fatal(err)
+        }
+        if got != want {
+            t.Errorf("expected %+v, got %+v", want, got)
+        }
+    }
+
+    expectReaction(t, doRequest(t, http.MethodPost, reactionsURL, alice, ""), reaction{1, true})
+    // Reacting again is idempotent
+    expectReaction(t, doRequest(t, http.MethodPost, reactionsURL, alice, ""), reaction{1, true})
+    expectReaction(t, doRequest(t, http.MethodPost, reactionsURL, bob, ""), reaction{2, true})
+
+    // The comment itself reflects the count and the viewer's own reaction
+    resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+commentID, alice, "")
+    expectReaction(t, resp, reaction{2, true})
+
+    expectReaction(t, doRequest(t, http.MethodDelete, reactionsURL, alice, ""), reaction{1, false})
+    // Withdrawing twice is idempotent too
+    expectReaction(t, doRequest(t, http.MethodDelete, reactionsURL, alice, ""), reaction{1, false})
+
+    resp = doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", alice, "")
+    var list []reaction
+    if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+        t.Fatal(err)
+    }
+    if len(list) != 1 || list[0] != (reaction{1, false}) {
+        t.Errorf("unexpected list reactions: %+v", list)
+    }
+
+    resp = doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments/missing/reactions", alice, "")
+    if resp.StatusCode != http.StatusNotFound {
+        t.Errorf("expected status %d for missing comment, got %d", http.StatusNotFound, resp.StatusCode)
+    }
+}
@@ -0,0 +1,163 @@
+// test/integration/rectify_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+)
+
+func TestAuthorNameRectification(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "unused")
+
+    rename := func(t *testing.T, token, body string) *http.Response {
+        t.Helper()
+        return doRequest(t, http.MethodPost, srv.URL+"/api/v1/me/author-name", token, body)
+    }
+    authorOf := func(t *testing.T, token, id string) string {
+        t.Helper()
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+id, token, "")
+        var c struct {
+            Author string `json:"author"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+            t.Fatal(err)
+        }
+        return c.Author
+    }
+
+    t.Run("rewrite comments", func(t *testing.T) {
+        token := issueToken(t, "renamer", "user")
+        first := createComment(t, srv, token, "one", "Old Name")
+        second := createComment(t, srv, token, "two", "Old Name")
+
+        other := issueToken(t, "bystander", "user")
+        theirs := createComment(t, srv, other, "three", "Old Name")
+
+        resp := rename(t, token, `{"author_name": "New Name", "rewrite_comments": true}`)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var body struct {
+            AuthorName      string `json:"author_name"`
+            CommentsUpdated int    `json:"comments_updated"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        if body.AuthorName != "New Name" || body.CommentsUpdated != 2 {
+            t.Errorf("got %+v, want New Name with 2 comments updated", body)
+        }
+
+        for _, id := range []string{first, second} {
+            if got := authorOf(t, token, id); got != "New Name" {
+                t.Errorf("comment %s: expected author %q, got %q", id, "New Name", got)
+            }
+        }
+        if got := authorOf(t, other, theirs); got != "Old Name" {
+            t.Errorf("another user's comment was renamed to %q", got)
+        }
+    })
+
+    t.Run("history left intact", func(t *testing.T) {
+        token := issueToken(t, "keeper", "user")
+        id := createComment(t, srv, token, "kept", "Old Name")
+
+        resp := rename(t, token, `{"author_name": "New Name"}`)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        if got := authorOf(t, token, id); got != "Old Name" {
+            t.Errorf("expected author to stay %q, got %q", "Old Name", got)
+        }
+        // Nothing was rewritten, so the day's change is still available
+        if resp := rename(t, token, `{"author_name": "New Name", "rewrite_comments": true}`); resp.StatusCode != http.StatusOK {
+            t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+    })
+
+    t.Run("once per day", func(t *testing.T) {
+        token := issueToken(t, "fickle", "user")
+
+        if resp := rename(t, token, `{"author_name": "First", "rewrite_comments": true}`); resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        resp := rename(t, token, `{"author_name": "Second", "rewrite_comments": true}`)
+        if resp.StatusCode != http.StatusTooManyRequests {
+            t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
+        }
+        if resp.Header.Get("Retry-After") == "" {
+            t.Error("expected a Retry-After header")
+        }
+    })
+
+    t.Run("invalid name", func(t *testing.T) {
+        token := issueToken(t, "blank", "user")
+        if resp := rename(t, token, `{"author_name": "  ", "rewrite_comments": true}`); resp.StatusCode != http.StatusBadRequest {
+            t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+        // A rejected request doesn't use up the day's change.
+        if resp := rename(t, token, `{"author_name": "Valid", "rewrite_comments": true}`); resp.StatusCode != http.StatusOK {
+            t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+    })
+
+    t.Run("decomposed name", func(t *testing.T) {
+        token := issueToken(t, "accented", "user")
+        id := createComment(t, srv, token, "bonjour", "Jose")
+
+        // "e" followed by a combining acute accent is stored as "é"
+        resp := rename(t, token, `{"author_name": "Jose\u0301", "rewrite_comments": true}`)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var body struct {
+            AuthorName string `json:"author_name"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        if body.AuthorName != "José" {
+            t.Errorf("expected author_name %q in NFC, got %q", "José", body.AuthorName)
+        }
+        if got := authorOf(t, token, id); got != "José" {
+            t.Errorf("expected author %q in NFC, got %q", "José", got)
+        }
+    })
+
+    t.Run("unauthenticated", func(t *testing.T) {
+        if resp := rename(t, "", `{"author_name": "Anyone"}`); resp.StatusCode != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+        }
+    })
+}
+
+func TestAuthorNameLength(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.CommentMaxAuthorLength = 5
+    srv := startServer(t, cfg)
+
+    tests := []struct {
+        name       string
+        authorName string
+        want       int
+    }{
+        {name: "at the limit", authorName: "Jósef", want: http.StatusOK},
+        {name: "over the limit", authorName: "Josefa", want: http.StatusBadRequest},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            token := issueToken(t, tt.name, "user")
+            resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/me/author-name", token, `{"author_name": "`+tt.authorName+`", "rewrite_comments": true}`)
+            if resp.StatusCode != tt.want {
+                t.Errorf("expected status %d, got %d", tt.want, resp.StatusCode)
+            }
+        })
+    }
+}
@@ -0,0 +1,70 @@
+// test/integration/requestid_test.go
+
+package integration
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+)
+
+func TestRequestID(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "tracer")
+
+    get := func(t *testing.T, path, requestID string) *http.Response {
+        t.Helper()
+        req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if requestID != "" {
+            req.Header.Set("X-Request-ID", requestID)
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        t.Cleanup(func() { resp.Body.Close() })
+        return resp
+    }
+
+    t.Run("valid incoming ID is echoed", func(t *testing.T) {
+        resp := get(t, "/healthz", "client-abc_123.4")
+        if got := resp.Header.Get("X-Request-ID"); got != "client-abc_123.4" {
+            t.Errorf("expected echoed request ID, got %q", got)
+        }
+    })
+
+    t.Run("invalid incoming ID is replaced", func(t *testing.T) {
+        for _, bad := range []string{"has spaces", "<script>", strings.Repeat("a", 129)} {
+            resp := get(t, "/healthz", bad)
+            got := resp.Header.Get("X-Request-ID")
+            if got == "" || got == bad {
+                t.Errorf("expected a generated ID for %q, got %q", bad, got)
+            }
+        }
+    })
+
+    t.Run("generated IDs are unique", func(t *testing.T) {
+        seen := make(map[string]bool)
+        for i := 0; i < 20; i++ {
+            id := get(t, "/healthz", "").Header.Get("X-Request-ID")
+            if id == "" || seen[id] {
+                t.Fatalf("expected unique non-empty IDs, got %q again", id)
+            }
+            seen[id] = true
+        }
+    })
+
+    t.Run("unauthenticated responses carry an ID", func(t *testing.T) {
+        resp := get(t, "/api/v1/comments", "")
+        if resp.StatusCode != http.StatusUnauthorized {
+            t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+        }
+        if resp.Header.Get("X-Request-ID") == "" {
+            t.Error("expected X-Request-ID on 401 response")
+        }
+    })
+}
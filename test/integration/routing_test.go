@@ -0,0 +1,192 @@
+// test/integration/routing_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "slices"
+    "strings"
+    "testing"
+)
+
+func TestRouting(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "router")
+
+    // Seed a comment so the {id} routes have something to find
+    commentID := createComment(t, srv, token, "routed", "router")
+
+    tests := []struct {
+        name       string
+        method     string
+        path       string
+        wantStatus int
+        wantAllow  string
+        wantCode   string
+    }{
+        {
+            name:       "get existing comment",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments/" + commentID,
+            wantStatus: http.StatusOK,
+        },
+        {
+            name:       "get missing comment",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments/does-not-exist",
+            wantStatus: http.StatusNotFound,
+        },
+        {
+            name:       "nested path is not a comment",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments/abc/def/ghi",
+            wantStatus: http.StatusNotFound,
+            wantCode:   "not_found",
+        },
+        {
+            name:       "trailing slash without id",
+            method:     http.MethodGet,
+            path:       "/api/v1/comments/",
+            wantStatus: http.StatusNotFound,
+            wantCode:   "not_found",
+        },
+        {
+            name:       "unknown route",
+            method:     http.MethodGet,
+            path:       "/api/v1/nothing-here",
+            wantStatus: http.StatusNotFound,
+            wantCode:   "not_found",
+        },
+        {
+            name:       "unsupported method on collection",
+            method:     http.MethodDelete,
+            path:       "/api/v1/comments",
+            wantStatus: http.StatusMethodNotAllowed,
+            wantAllow:  "GET, HEAD, OPTIONS, POST",
+            wantCode:   "method_not_allowed",
+        },
+        {
+            name:       "unsupported method on comment",
+            method:     http.MethodPost,
+            path:       "/api/v1/comments/" + commentID,
+            wantStatus: http.StatusMethodNotAllowed,
+            wantAllow:  "DELETE, GET, HEAD, OPTIONS, PUT",
+            wantCode:   "method_not_allowed",
+        },
+        {
+            name:       "login requires post",
+            method:     http.MethodGet,
+            path:       "/api/v1/login",
+            wantStatus: http.StatusMethodNotAllowed,
+            wantAllow:  "OPTIONS, POST",
+            wantCode:   "method_not_allowed",
+        },
+    }
+
+    for _, tt := range tests {
+        tt := tt
+        t.Run(tt.name, func(t *testing.T) {
+            t.Parallel()
+
+            req, err := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+            if err != nil {
+                t.Fatal(err)
+            }
+            req.Header.Set("Authorization", "Bearer "+token)
+
+            resp, err := http.DefaultClient.Do(req)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer resp.Body.Close()
+
+            if resp.StatusCode != tt.wantStatus {
+                t.Errorf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+            }
+            if tt.wantAllow != "" {
+                if got := resp.Header.Get("Allow"); got != tt.wantAllow {
+                    t.Errorf("expected Allow %q, got %q", tt.wantAllow, got)
+                }
+            }
+            if tt.wantCode != "" {
+                var body struct {
+                    Code string `json:"code"`
+                }
+                if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                    t.Fatal(err)
+                }
+                if body.Code != tt.wantCode {
+                    t.Errorf("expected code %q, got %q", tt.wantCode, body.Code)
+                }
+            }
+        })
+    }
+}
+
+func TestPreflight(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "preflight")
+
+    tests := []struct {
+        name       string
+        path       string
+        wantStatus int
+        wantAllow  string
+    }{
+        {name: "collection", path: "/api/v1/comments", wantStatus: http.StatusOK, wantAllow: "GET, HEAD, OPTIONS, POST"},
+        {name: "comment", path: "/api/v1/comments/abc", wantStatus: http.StatusOK, wantAllow: "DELETE, GET, HEAD, OPTIONS, PUT"},
+        {name: "login", path: "/api/v1/login", wantStatus: http.StatusOK, wantAllow: "OPTIONS, POST"},
+        {name: "no such route", path: "/api/v1/nothing", wantStatus: http.StatusNotFound},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            // Preflights carry no credentials
+            resp := doRequest(t, http.MethodOptions, srv.URL+tt.path, "", "")
+            if resp.StatusCode != tt.wantStatus {
+                t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+            }
+            if got := resp.Header.Get("Allow"); got != tt.wantAllow {
+                t.Errorf("expected Allow %q, got %q", tt.wantAllow, got)
+            }
+            if got := resp.Header.Get("Access-Control-Allow-Methods"); got != tt.wantAllow {
+                t.Errorf("expected Access-Control-Allow-Methods %q, got %q", tt.wantAllow, got)
+            }
+        })
+    }
+}
+
+func TestCORSHeaders(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "cors")
+
+    resp := doRequest(t, http.MethodOptions, srv.URL+"/api/v1/comments", "", "")
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+
+    tests := []struct {
+        header string
+        want   []string
+    }{
+        // Request headers the API reads, so browsers may send them
+        {header: "Access-Control-Allow-Headers", want: []string{"X-Consistency-Token", "Idempotency-Key", "If-Match", "If-Modified-Since"}},
+        // Response headers the API sets, so browser scripts may read them
+        {header: "Access-Control-Expose-Headers", want: []string{"X-Consistency-Token", "ETag", "Last-Modified", "X-Already-Deleted", "Idempotent-Replayed", "Retry-After"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.header, func(t *testing.T) {
+            got := strings.Split(resp.Header.Get(tt.header), ", ")
+            for _, name := range tt.want {
+                if !slices.Contains(got, name) {
+                    t.Errorf("expected %s to list %s, got %q", tt.header, name, got)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,112 @@
+// test/integration/sanitize_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "web-service/internal/config"
+)
+
+func TestContentSanitization(t *testing.T) {
+    t.Parallel()
+
+    const content = `*Nice* post! <script>alert("pwned")</script><b onclick="steal()">really</b>`
+
+    tests := []struct {
+        policy      string
+        wantContent string
+    }{
+        {policy: config.ContentPolicyText, wantContent: content},
+        {policy: config.ContentPolicyAllowlist, wantContent: "*Nice* post! <b>really</b>"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.policy, func(t *testing.T) {
+            t.Parallel()
+
+            cfg := testConfig()
+            cfg.ContentPolicy = tt.policy
+            srv := startServer(t, cfg)
+            token := issueToken(t, "writer", "user")
+            id := createComment(t, srv, token, content, "Writer")
+
+            get := func(t *testing.T, query string) (status int, comment struct {
+                Content     string `json:"content"`
+                ContentHTML string `json:"content_html"`
+            }) {
+                t.Helper()
+                resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+id+query, token, "")
+                if resp.StatusCode == http.StatusOK {
+                    if err := json.NewDecoder(resp.Body).Decode(&comment); err != nil {
+                        t.Fatal(err)
+                    }
+                }
+                return resp.StatusCode, comment
+            }
+
+            _, stored := get(t, "")
+            if stored.Content != tt.wantContent {
+                t.Errorf("expected stored content %q, got %q", tt.wantContent, stored.Content)
+            }
+            if stored.ContentHTML != "" {
+                t.Errorf("expected no content_html without render, got %q", stored.ContentHTML)
+            }
+
+            _, rendered := get(t, "?render=html")
+            if rendered.Content != tt.wantContent {
+                t.Errorf("expected raw content alongside the rendering, got %q", rendered.Content)
+            }
+            if !strings.Contains(rendered.ContentHTML, "<em>Nice</em>") {
+                t.Errorf("expected emphasis rendered, got %q", rendered.ContentHTML)
+            }
+            if strings.Contains(rendered.ContentHTML, "<script") || strings.Contains(rendered.ContentHTML, `onclick="`) {
+                t.Errorf("expected script neutralized, got %q", rendered.ContentHTML)
+            }
+
+            if status, _ := get(t, "?render=pdf"); status != http.StatusBadRequest {
+                t.Errorf("unknown render: expected status %d, got %d", http.StatusBadRequest, status)
+            }
+        })
+    }
+}
+
+func TestPlainTextRoundTrip(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "mathematician")
+
+    tests := []struct {
+        content  string
+        wantHTML string
+    }{
+        {content: "if a<b then c", wantHTML: "<p>if a&lt;b then c</p>"},
+        {content: "use <T any> generics", wantHTML: "<p>use &lt;T any&gt; generics</p>"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.content, func(t *testing.T) {
+            id := createComment(t, srv, token, tt.content, "Ada")
+
+            resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+id+"?render=html", token, "")
+            if resp.StatusCode != http.StatusOK {
+                t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+            }
+            var comment struct {
+                Content     string `json:"content"`
+                ContentHTML string `json:"content_html"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&comment); err != nil {
+                t.Fatal(err)
+            }
+            if comment.Content != tt.content {
+                t.Errorf("expected content %q kept as written, got %q", tt.content, comment.Content)
+            }
+            if comment.ContentHTML != tt.wantHTML {
+                t.Errorf("expected content_html %q, got %q", tt.wantHTML, comment.ContentHTML)
+            }
+        })
+    }
+}
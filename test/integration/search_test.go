@@ -0,0 +1,103 @@
+// test/integration/search_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "testing"
+)
+
+func TestAdminSearch(t *testing.T) {
+    t.Parallel()
+
+    srv, userToken := newTestServer(t, "searcher")
+    adminToken := issueToken(t, "moderator", "admin")
+
+    createComment(t, srv, userToken, "buy cheap watches now", "bob")
+    createComment(t, srv, userToken, "a thoughtful reply", "alice")
+    createComment(t, srv, userToken, "watches are great", "alice")
+
+    search := func(t *testing.T, token, q string, extra url.Values) *http.Response {
+        t.Helper()
+
+        params := url.Values{"q": {q}}
+        for k, v := range extra {
+            params[k] = v
+        }
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/admin/comments/search?"+params.Encode(), nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        t.Cleanup(func() { resp.Body.Close() })
+        return resp
+    }
+
+    t.Run("non-admin is forbidden", func(t *testing.T) {
+        resp := search(t, userToken, "watches", nil)
+        if resp.StatusCode != http.StatusForbidden {
+            t.Errorf("expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+        }
+    })
+
+    t.Run("syntax error reports position", func(t *testing.T) {
+        resp := search(t, adminToken, `author:bob "unterminated`, nil)
+        if resp.StatusCode != http.StatusBadRequest {
+            t.Fatalf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+        var body struct {
+            Code     string   `json:"code"`
+            Position int      `json:"position"`
+            Expected []string `json:"expected"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        if body.Code != "invalid_request" || body.Position != 11 || len(body.Expected) == 0 {
+            t.Errorf("unexpected syntax error body: %+v", body)
+        }
+    })
+
+    tests := []struct {
+        name      string
+        q         string
+        extra     url.Values
+        wantTotal int
+        wantCount int
+    }{
+        {name: "empty query matches all", q: "", wantTotal: 3, wantCount: 3},
+        {name: "bare word", q: "watches", wantTotal: 2, wantCount: 2},
+        {name: "field and negation", q: "watches -author:bob", wantTotal: 1, wantCount: 1},
+        {name: "phrase", q: `"thoughtful reply"`, wantTotal: 1, wantCount: 1},
+        {name: "or group", q: "author:bob OR reply", wantTotal: 2, wantCount: 2},
+        {name: "date range", q: "before:2000-01-01", wantTotal: 0, wantCount: 0},
+        {name: "paginated", q: "author:alice", extra: url.Values{"limit": {"1"}}, wantTotal: 2, wantCount: 1},
+    }
+
+    for _, tt := range tests {
+        tt := tt
+        t.Run(tt.name, func(t *testing.T) {
+            resp := search(t, adminToken, tt.q, tt.extra)
+            if resp.StatusCode != http.StatusOK {
+                t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+            }
+            var body struct {
+                Comments []json.RawMessage `json:"comments"`
+                Total    int               `json:"total"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                t.Fatal(err)
+            }
+            if body.Total != tt.wantTotal || len(body.Comments) != tt.wantCount {
+                t.Errorf("expected total %d and %d comments, got %d and %d",
+                    tt.wantTotal, tt.wantCount, body.Total, len(body.Comments))
+            }
+        })
+    }
+}
@@ -0,0 +1,75 @@
+// test/integration/security_test.go
+
+package integration
+
+import (
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "web-service/internal/api"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "scanner")
+
+    tests := []struct {
+        name       string
+        path       string
+        token      string
+        wantStatus int
+    }{
+        {name: "success", path: "/api/v1/comments", token: token, wantStatus: http.StatusOK},
+        {name: "unauthorized", path: "/api/v1/comments", wantStatus: http.StatusUnauthorized},
+        {name: "not found", path: "/nowhere", token: token, wantStatus: http.StatusNotFound},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            resp := doRequest(t, http.MethodGet, srv.URL+tt.path, tt.token, "")
+            if resp.StatusCode != tt.wantStatus {
+                t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+            }
+            want := map[string]string{
+                "X-Content-Type-Options": "nosniff",
+                "X-Frame-Options":        "DENY",
+                "Referrer-Policy":        "no-referrer",
+            }
+            for header, value := range want {
+                if got := resp.Header.Get(header); got != value {
+                    t.Errorf("%s: expected %q, got %q", header, value, got)
+                }
+            }
+            if got := resp.Header.Get("Strict-Transport-Security"); got != "" {
+                t.Errorf("expected no HSTS over plain HTTP, got %q", got)
+            }
+        })
+    }
+}
+
+func TestSecurityHeadersOverTLS(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.SecurityFrameOptions = "SAMEORIGIN"
+    cfg.SecurityHSTS = "max-age=300"
+    srv := httptest.NewTLSServer(api.NewServer(logging.NewLogger(io.Discard), cfg, storage.NewCommentStore()))
+    t.Cleanup(srv.Close)
+
+    resp, err := srv.Client().Get(srv.URL + "/healthz")
+    if err != nil {
+        t.Fatal(err)
+    }
+    resp.Body.Close()
+
+    if got := resp.Header.Get("Strict-Transport-Security"); got != "max-age=300" {
+        t.Errorf("expected configured HSTS, got %q", got)
+    }
+    if got := resp.Header.Get("X-Frame-Options"); got != "SAMEORIGIN" {
+        t.Errorf("expected configured X-Frame-Options, got %q", got)
+    }
+}
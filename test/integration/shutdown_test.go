@@ -0,0 +1,207 @@
+// test/integration/shutdown_test.go
+
+package integration
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+    "web-service/internal/auth"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+    t.Parallel()
+
+    tests := []struct {
+        name            string
+        latency         string
+        shutdownTimeout string
+        wantCompleted   bool
+        wantMessage     string
+        wantField       string
+    }{
+        {name: "drains in-flight request", latency: "500ms", shutdownTimeout: "5s", wantCompleted: true, wantMessage: "server stopped", wantField: "drained"},
+        {name: "force closes after timeout", latency: "10s", shutdownTimeout: "200ms", wantCompleted: false, wantMessage: "shutdown timed out, closed remaining requests", wantField: "force_closed"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            t.Parallel()
+
+            env := map[string]string{
+                "JWT_SECRET":       testSecret,
+                "ENVIRONMENT":      "test",
+                "FAULT_INJECTION":  "true",
+                "SHUTDOWN_TIMEOUT": tt.shutdownTimeout,
+            }
+            ctx, cancel := context.WithCancel(context.Background())
+            defer cancel()
+
+            // Force-closed handlers may still log as the test reads
+            var logs syncBuffer
+            base, runErr := runServer(t, ctx, &logs, []string{"server"}, func(key string) string { return env[key] })
+
+            token, err := auth.NewJWTManager(testSecret, time.Hour,
+                auth.WithIssuer("web-service"), auth.WithAudience("web-service"),
+            ).GenerateToken("admin", "admin")
+            if err != nil {
+                t.Fatal(err)
+            }
+            faults := fmt.Sprintf(`{"faults":[{"method":"Range","latency":%q}]}`, tt.latency)
+            if resp := doRequest(t, http.MethodPut, base+"/api/v1/admin/faults", token, faults); resp.StatusCode != http.StatusOK {
+                t.Fatalf("setting faults: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+            }
+
+            // Hold a slow request open across the shutdown
+            slow := make(chan error, 1)
+            go func() {
+                req, err := http.NewRequest(http.MethodGet, base+"/api/v1/comments", nil)
+                if err != nil {
+                    slow <- err
+                    return
+                }
+                req.Header.Set("Authorization", "Bearer "+token)
+                resp, err := http.DefaultClient.Do(req)
+                if err != nil {
+                    slow <- err
+                    return
+                }
+                resp.Body.Close()
+                if resp.StatusCode != http.StatusOK {
+                    err = fmt.Errorf("status %d", resp.StatusCode)
+                }
+                slow <- err
+            }()
+            time.Sleep(100 * time.Millisecond)
+
+            start := time.Now()
+            cancel()
+            select {
+            case err := <-runErr:
+                if err != nil {
+                    t.Fatalf("Run returned %v", err)
+                }
+            case <-time.After(5 * time.Second):
+                t.Fatal("server did not stop")
+            }
+            if !tt.wantCompleted && time.Since(start) > 2*time.Second {
+                t.Errorf("forced shutdown took %s", time.Since(start))
+            }
+
+            if err := <-slow; (err == nil) != tt.wantCompleted {
+                t.Errorf("slow request: got error %v, want completed %v", err, tt.wantCompleted)
+            }
+
+            fields := findLogFields(t, logs.String(), tt.wantMessage)
+            if fields == nil {
+                t.Fatalf("no %q log line in:\n%s", tt.wantMessage, logs.String())
+            }
+            if n, _ := fields[tt.wantField].(float64); n != 1 {
+                t.Errorf("expected %s 1, got %v", tt.wantField, fields[tt.wantField])
+            }
+            if d, _ := fields["duration"].(string); d == "" {
+                t.Errorf("expected a shutdown duration, got %v", fields["duration"])
+            }
+        })
+    }
+}
+
+func TestShutdownDrainDelay(t *testing.T) {
+    t.Parallel()
+
+    env := map[string]string{
+        "JWT_SECRET":           testSecret,
+        "ENVIRONMENT":          "test",
+        "SHUTDOWN_DRAIN_DELAY": "1s",
+    }
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    var logs syncBuffer
+    base, runErr := runServer(t, ctx, &logs, []string{"server"}, func(key string) string { return env[key] })
+    if resp := doRequest(t, http.MethodGet, base+"/readyz", "", ""); resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d before shutdown, got %d", http.StatusOK, resp.StatusCode)
+    }
+
+    // While draining the server still accepts connections, but fails
+    // readiness and turns every request away
+    start := time.Now()
+    cancel()
+    deadline := time.Now().Add(500 * time.Millisecond)
+    for {
+        resp := doRequest(t, http.MethodGet, base+"/readyz", "", "")
+        if resp.StatusCode == http.StatusServiceUnavailable {
+            if got := resp.Header.Get("Retry-After"); got == "" {
+                t.Error("expected Retry-After on the draining response")
+            }
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("expected status %d while draining, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    if resp := doRequest(t, http.MethodGet, base+"/livez", "", ""); resp.StatusCode != http.StatusServiceUnavailable {
+        t.Errorf("expected status %d for other routes while draining, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+    }
+
+    select {
+    case err := <-runErr:
+        if err != nil {
+            t.Fatalf("Run returned %v", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("server did not stop")
+    }
+    if took := time.Since(start); took < time.Second {
+        t.Errorf("expected the server to drain for 1s, stopped after %s", took)
+    }
+    if findLogFields(t, logs.String(), "draining before shutdown") == nil {
+        t.Errorf("no draining log line in:\n%s", logs.String())
+    }
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads.
+type syncBuffer struct {
+    mu  sync.Mutex
+    buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.String()
+}
+
+// findLogFields returns the fields of the first log line with message.
+func findLogFields(t *testing.T, logs, message string) map[string]any {
+    t.Helper()
+
+    scanner := bufio.NewScanner(strings.NewReader(logs))
+    for scanner.Scan() {
+        var entry struct {
+            Message string         `json:"message"`
+            Fields  map[string]any `json:"fields"`
+        }
+        if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+            continue
+        }
+        if entry.Message == message {
+            return entry.Fields
+        }
+    }
+    return nil
+}
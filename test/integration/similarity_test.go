@@ -0,0 +1,86 @@
+// test/integration/similarity_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "testing"
+)
+
+func TestNearDuplicateDetection(t *testing.T) {
+    t.Parallel()
+
+    const spam = "Buy cheap watches at our online store, best prices guaranteed!"
+
+    post := func(t *testing.T, url, token, content string) *http.Response {
+        t.Helper()
+        return doRequest(t, http.MethodPost, url+"/api/v1/comments", token, fmt.Sprintf(`{"content":%q,"author":"a"}`, content))
+    }
+
+    t.Run("reject", func(t *testing.T) {
+        cfg := testConfig()
+        cfg.SimilarityAction = "reject"
+        cfg.SimilarityThreshold = 0.8
+        cfg.SimilarityWindow = 100
+        srv := startServer(t, cfg)
+        spammer := issueToken(t, "spammer", "user")
+        other := issueToken(t, "other", "user")
+
+        createComment(t, srv, spammer, spam, "a")
+
+        tests := []struct {
+            name       string
+            token      string
+            content    string
+            wantStatus int
+        }{
+            {name: "one character changed", token: spammer, content: "Buy cheap watches at our online stor3, best prices guaranteed!", wantStatus: http.StatusConflict},
+            {name: "same spam from another user", token: other, content: "buy cheap watches at our online store - best prices guaranteed", wantStatus: http.StatusConflict},
+            {name: "distinct comment", token: spammer, content: "I disagree with the author's point about interest rates entirely.", wantStatus: http.StatusCreated},
+            {name: "short comment", token: other, content: "thanks!", wantStatus: http.StatusCreated},
+            {name: "short comment repeated", token: other, content: "thanks!", wantStatus: http.StatusCreated},
+        }
+        for _, tt := range tests {
+            t.Run(tt.name, func(t *testing.T) {
+                resp := post(t, srv.URL, tt.token, tt.content)
+                if resp.StatusCode != tt.wantStatus {
+                    t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+                }
+                if tt.wantStatus != http.StatusConflict {
+                    return
+                }
+                var body struct {
+                    Code string `json:"code"`
+                }
+                if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                    t.Fatal(err)
+                }
+                if body.Code != "duplicate_content" {
+                    t.Errorf("expected code duplicate_content, got %q", body.Code)
+                }
+            })
+        }
+    })
+
+    t.Run("flag", func(t *testing.T) {
+        cfg := testConfig()
+        cfg.SimilarityAction = "flag"
+        cfg.SimilarityThreshold = 0.8
+        cfg.SimilarityWindow = 100
+        srv := startServer(t, cfg)
+        token := issueToken(t, "spammer", "user")
+
+        createComment(t, srv, token, spam, "a")
+        if resp := post(t, srv.URL, token, "Buy cheap watches at our online stor3, best prices guaranteed!"); resp.StatusCode != http.StatusCreated {
+            t.Errorf("expected flagged comment to be created, got %d", resp.StatusCode)
+        }
+    })
+
+    t.Run("off", func(t *testing.T) {
+        srv, token := newTestServer(t, "spammer")
+        createComment(t, srv, token, spam, "a")
+        createComment(t, srv, token, spam, "a")
+    })
+}
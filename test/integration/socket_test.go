@@ -0,0 +1,99 @@
+// test/integration/socket_test.go
+
+package integration
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+    "web-service/internal/server"
+)
+
+func TestUnixSocket(t *testing.T) {
+    t.Parallel()
+
+    env := map[string]string{
+        "JWT_SECRET":  testSecret,
+        "ENVIRONMENT": "test",
+    }
+    getenv := func(key string) string { return env[key] }
+
+    t.Run("serves on the socket", func(t *testing.T) {
+        socket := filepath.Join(t.TempDir(), "web.sock")
+        ctx, cancel := context.WithCancel(context.Background())
+        defer cancel()
+
+        runErr := make(chan error, 1)
+        go func() {
+            runErr <- server.Run(ctx, io.Discard, []string{"server", "--socket", socket, "--socket-mode", "0600"}, getenv)
+        }()
+
+        client := &http.Client{
+            Timeout: time.Second,
+            Transport: &http.Transport{
+                DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+                    var d net.Dialer
+                    return d.DialContext(ctx, "unix", socket)
+                },
+            },
+        }
+        if err := waitFor(5*time.Second, func() bool {
+            resp, err := client.Get("http://unix/livez")
+            if err != nil {
+                return false
+            }
+            resp.Body.Close()
+            return resp.StatusCode == http.StatusOK
+        }); err != nil {
+            t.Fatal(err)
+        }
+
+        fi, err := os.Stat(socket)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if perm := fi.Mode().Perm(); perm != 0o600 {
+            t.Errorf("expected socket permissions 0600, got %#o", perm)
+        }
+
+        cancel()
+        select {
+        case err := <-runErr:
+            if err != nil {
+                t.Fatalf("Run returned %v", err)
+            }
+        case <-time.After(5 * time.Second):
+            t.Fatal("server did not stop")
+        }
+        if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+            t.Errorf("expected the socket to be removed on shutdown, got %v", err)
+        }
+    })
+
+    t.Run("excludes host and port", func(t *testing.T) {
+        socket := filepath.Join(t.TempDir(), "web.sock")
+        err := server.Run(context.Background(), io.Discard, []string{"server", "--socket", socket, "--port", "8099"}, getenv)
+        if err == nil || !strings.Contains(err.Error(), "--socket") {
+            t.Errorf("expected an error combining --socket and --port, got %v", err)
+        }
+    })
+}
+
+// waitFor polls ready until it reports true or timeout passes.
+func waitFor(timeout time.Duration, ready func() bool) error {
+    deadline := time.Now().Add(timeout)
+    for !ready() {
+        if time.Now().After(deadline) {
+            return errors.New("timed out waiting for the server")
+        }
+        time.Sleep(50 * time.Millisecond)
+    }
+    return nil
+}
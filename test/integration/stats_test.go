@@ -0,0 +1,155 @@
+// test/integration/stats_test.go
+
+package integration
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "testing"
+    "time"
+    "web-service/internal/auth"
+)
+
+func TestCommentStats(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "statistician")
+
+    fetch := func(t *testing.T) (resp struct {
+        Total           int            `json:"total"`
+        Last24h         int            `json:"last_24h"`
+        Last7d          int            `json:"last_7d"`
+        DistinctAuthors int            `json:"distinct_authors"`
+        Mine            int            `json:"mine"`
+        ByAuthor        map[string]int `json:"by_author"`
+        ByUser          map[string]int `json:"by_user"`
+        Oldest          *time.Time     `json:"oldest"`
+        Newest          *time.Time     `json:"newest"`
+    }) {
+        t.Helper()
+
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments/stats", nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+
+        r, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer r.Body.Close()
+        if r.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, r.StatusCode)
+        }
+        if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+            t.Fatal(err)
+        }
+        return resp
+    }
+
+    empty := fetch(t)
+    if empty.Total != 0 || empty.Oldest != nil || empty.Newest != nil {
+        t.Errorf("expected empty stats, got %+v", empty)
+    }
+
+    createComment(t, srv, token, "first", "alice")
+    createComment(t, srv, token, "second", "alice")
+    createComment(t, srv, token, "third", "bob")
+
+    stats := fetch(t)
+    if stats.Total != 3 {
+        t.Errorf("expected total 3, got %d", stats.Total)
+    }
+    if stats.ByAuthor["alice"] != 2 || stats.ByAuthor["bob"] != 1 {
+        t.Errorf("unexpected per-author counts: %v", stats.ByAuthor)
+    }
+    if stats.ByUser["statistician"] != 3 {
+        t.Errorf("unexpected per-user counts: %v", stats.ByUser)
+    }
+    if stats.Last24h != 3 || stats.Last7d != 3 || stats.DistinctAuthors != 2 || stats.Mine != 3 {
+        t.Errorf("expected 3 in each window, 2 authors and 3 of mine, got %+v", stats)
+    }
+    if stats.Oldest == nil || stats.Newest == nil || stats.Newest.Before(*stats.Oldest) {
+        t.Errorf("expected oldest <= newest, got %v and %v", stats.Oldest, stats.Newest)
+    }
+}
+
+func TestCommentStatsCache(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.StatsCacheTTL = time.Hour
+    srv := startServer(t, cfg)
+    token := issueToken(t, "dashboard", "user")
+
+    total := func(t *testing.T) int {
+        t.Helper()
+
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/stats", token, "")
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var stats struct {
+            Total int `json:"total"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+            t.Fatal(err)
+        }
+        return stats.Total
+    }
+
+    createComment(t, srv, token, "counted", "alice")
+    if got := total(t); got != 1 {
+        t.Fatalf("expected total 1, got %d", got)
+    }
+    createComment(t, srv, token, "not yet counted", "alice")
+    if got := total(t); got != 1 {
+        t.Errorf("expected the cached total 1 within the TTL, got %d", got)
+    }
+}
+
+func TestCommentStatsCleanup(t *testing.T) {
+    t.Parallel()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    env := map[string]string{
+        "JWT_SECRET":        testSecret,
+        "ENVIRONMENT":       "test",
+        "CLEANUP_INTERVAL":  "10ms",
+        "COMMENT_RETENTION": "1h",
+    }
+    base, _ := runServer(t, ctx, io.Discard, []string{"server"}, func(key string) string { return env[key] })
+    token, err := auth.NewJWTManager(testSecret, time.Hour,
+        auth.WithIssuer("web-service"), auth.WithAudience("web-service"),
+    ).GenerateToken("statistician", "user")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var body struct {
+        Cleanup *struct {
+            LastRun time.Time `json:"last_run"`
+            Expired int       `json:"expired"`
+            Purged  int       `json:"purged"`
+        } `json:"cleanup"`
+    }
+    if err := waitFor(5*time.Second, func() bool {
+        resp := doRequest(t, http.MethodGet, base+"/api/v1/comments/stats", token, "")
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+            t.Fatal(err)
+        }
+        return body.Cleanup != nil
+    }); err != nil {
+        t.Fatal(err)
+    }
+    if body.Cleanup.LastRun.IsZero() || body.Cleanup.Expired != 0 || body.Cleanup.Purged != 0 {
+        t.Errorf("expected an empty run with its time, got %+v", body.Cleanup)
+    }
+}
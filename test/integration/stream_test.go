@@ -0,0 +1,164 @@
+// test/integration/stream_test.go
+
+package integration
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+    "web-service/internal/api"
+    "web-service/internal/storage"
+    "web-service/pkg/logging"
+)
+
+// streamEvent is one server-sent event, with an empty name if it had none.
+type streamEvent struct {
+    name string
+    data map[string]any
+}
+
+// openStream connects to the event stream at path as the holder of token
+// and returns its events, read in the background until the stream ends.
+func openStream(t *testing.T, srv *httptest.Server, path, token string) <-chan streamEvent {
+    t.Helper()
+
+    req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { resp.Body.Close() })
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+        t.Fatalf("expected Content-Type text/event-stream, got %q", ct)
+    }
+    if resp.Header.Get("X-Accel-Buffering") != "no" {
+        t.Errorf("expected proxy buffering disabled, got X-Accel-Buffering %q", resp.Header.Get("X-Accel-Buffering"))
+    }
+
+    events := make(chan streamEvent, 16)
+    go func() {
+        defer close(events)
+        scanner := bufio.NewScanner(resp.Body)
+        var e streamEvent
+        for scanner.Scan() {
+            line := scanner.Text()
+            switch {
+            case strings.HasPrefix(line, "event: "):
+                e.name = strings.TrimPrefix(line, "event: ")
+            case strings.HasPrefix(line, "data: "):
+                json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.data)
+            case line == "" && (e.name != "" || e.data != nil):
+                events <- e
+                e = streamEvent{}
+            }
+        }
+    }()
+    return events
+}
+
+func nextEvent(t *testing.T, events <-chan streamEvent) streamEvent {
+    t.Helper()
+
+    select {
+    case e, ok := <-events:
+        if !ok {
+            t.Fatal("stream ended early")
+        }
+        return e
+    case <-time.After(5 * time.Second):
+        t.Fatal("timed out waiting for an event")
+    }
+    return streamEvent{}
+}
+
+func TestCommentStream(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "watcher")
+
+    t.Run("requires a token", func(t *testing.T) {
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/stream", "", "")
+        if resp.StatusCode != http.StatusUnauthorized {
+            t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+        }
+    })
+
+    events := openStream(t, srv, "/api/v1/comments/stream", token)
+
+    id := createComment(t, srv, token, "live", "Alice")
+    if e := nextEvent(t, events); e.name != "created" || e.data["id"] != id || e.data["content"] != "live" {
+        t.Errorf("expected created event for %s, got %+v", id, e)
+    }
+
+    resp := doRequest(t, http.MethodPut, srv.URL+"/api/v1/comments/"+id, token, `{"content":"edited","author":"Alice"}`)
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("updating comment: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+    if e := nextEvent(t, events); e.name != "updated" || e.data["content"] != "edited" {
+        t.Errorf("expected updated event, got %+v", e)
+    }
+
+    resp = doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+id, token, "")
+    if resp.StatusCode != http.StatusNoContent {
+        t.Fatalf("deleting comment: expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+    }
+    if e := nextEvent(t, events); e.name != "deleted" || e.data["id"] != id || e.data["content"] != nil {
+        t.Errorf("expected deleted event carrying only the ID, got %+v", e)
+    }
+}
+
+func TestCommentStreamEndsOnShutdown(t *testing.T) {
+    t.Parallel()
+
+    shutdown := make(chan struct{})
+    srv := httptest.NewServer(api.NewServer(logging.NewLogger(io.Discard), testConfig(), storage.NewCommentStore(), api.WithShutdownSignal(shutdown)))
+    t.Cleanup(srv.Close)
+
+    events := openStream(t, srv, "/api/v1/comments/stream", issueToken(t, "watcher", "user"))
+    close(shutdown)
+
+    select {
+    case e, ok := <-events:
+        if ok {
+            t.Errorf("expected the stream to end, got %+v", e)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("stream still open after shutdown")
+    }
+}
+
+func TestNewCommentEvents(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "dashboard")
+
+    if resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/events", "", ""); resp.StatusCode != http.StatusUnauthorized {
+        t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, resp.StatusCode)
+    }
+
+    events := openStream(t, srv, "/api/v1/comments/events", token)
+
+    first := createComment(t, srv, token, "first", "Alice")
+    if resp := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/comments/"+first, token, ""); resp.StatusCode != http.StatusNoContent {
+        t.Fatalf("deleting comment: expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+    }
+    second := createComment(t, srv, token, "second", "Bob")
+
+    for _, want := range []string{first, second} {
+        if e := nextEvent(t, events); e.name != "" || e.data["id"] != want {
+            t.Errorf("expected an unnamed event for %s, got %+v", want, e)
+        }
+    }
+}
@@ -0,0 +1,118 @@
+// test/integration/timestamps_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "encoding/xml"
+    "net/http"
+    "regexp"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+)
+
+var timestampPattern = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z$`)
+
+func TestTimestampFormat(t *testing.T) {
+    t.Parallel()
+
+    // A clock in another zone with sub-millisecond noise
+    created := time.Date(2024, 3, 1, 17, 30, 0, 123456789, time.FixedZone("UTC+5", 5*60*60))
+    clock := storage.WithClock(func() time.Time { return created })
+    srv, token := newTestServer(t, "alice", clock)
+
+    const want = "2024-03-01T12:30:00.123Z"
+    resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", token, `{"content":"hello","author":"Alice","ttl":3600}`)
+    if resp.StatusCode != http.StatusCreated {
+        t.Fatalf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+    }
+    var body struct {
+        ID        string `json:"id"`
+        CreatedAt string `json:"created_at"`
+        ExpiresAt string `json:"expires_at"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        t.Fatal(err)
+    }
+    if body.CreatedAt != want {
+        t.Errorf("expected created_at %s, got %s", want, body.CreatedAt)
+    }
+    // expires_at is set from the request's ttl against the wall clock
+    if !timestampPattern.MatchString(body.ExpiresAt) {
+        t.Errorf("expected expires_at in UTC with milliseconds, got %s", body.ExpiresAt)
+    }
+
+    t.Run("get shows the same time", func(t *testing.T) {
+        var got struct {
+            CreatedAt string `json:"created_at"`
+            ExpiresAt string `json:"expires_at"`
+        }
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments/"+body.ID, token, "")
+        if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+            t.Fatal(err)
+        }
+        if got.CreatedAt != body.CreatedAt || got.ExpiresAt != body.ExpiresAt {
+            t.Errorf("expected %s and %s, got %s and %s", body.CreatedAt, body.ExpiresAt, got.CreatedAt, got.ExpiresAt)
+        }
+    })
+
+    t.Run("list shows the same time", func(t *testing.T) {
+        var got []struct {
+            CreatedAt string `json:"created_at"`
+        }
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/comments", token, "")
+        if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+            t.Fatal(err)
+        }
+        if len(got) != 1 || got[0].CreatedAt != body.CreatedAt {
+            t.Errorf("expected %s, got %+v", body.CreatedAt, got)
+        }
+    })
+
+    t.Run("camelCase", func(t *testing.T) {
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments/"+body.ID, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        req.Header.Set("X-Field-Case", "camel")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+        var got struct {
+            CreatedAt string `json:"createdAt"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+            t.Fatal(err)
+        }
+        if got.CreatedAt != body.CreatedAt {
+            t.Errorf("expected %s, got %s", body.CreatedAt, got.CreatedAt)
+        }
+    })
+
+    t.Run("xml", func(t *testing.T) {
+        req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/comments/"+body.ID, nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        req.Header.Set("Accept", "application/xml")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+        var got struct {
+            CreatedAt string `xml:"created_at"`
+        }
+        if err := xml.NewDecoder(resp.Body).Decode(&got); err != nil {
+            t.Fatal(err)
+        }
+        if got.CreatedAt != body.CreatedAt {
+            t.Errorf("expected %s, got %s", body.CreatedAt, got.CreatedAt)
+        }
+    })
+}
@@ -0,0 +1,109 @@
+// test/integration/users_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "slices"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+    "web-service/internal/storage"
+)
+
+func TestUserComments(t *testing.T) {
+    t.Parallel()
+
+    // Tick the clock on every read so creation order is unambiguous
+    var mu sync.Mutex
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    clock := storage.WithClock(func() time.Time {
+        mu.Lock()
+        defer mu.Unlock()
+        now = now.Add(time.Second)
+        return now
+    })
+    srv, alice := newTestServer(t, "alice", clock)
+    bob := issueToken(t, "bob", "user")
+
+    var aliceIDs []string
+    for i := 0; i < 3; i++ {
+        aliceIDs = append(aliceIDs, createComment(t, srv, alice, fmt.Sprintf("alice %d", i), "Alice"))
+    }
+    createComment(t, srv, bob, "bob's", "Bob")
+
+    list := func(t *testing.T, token, path string) (*http.Response, []string) {
+        t.Helper()
+        resp := doRequest(t, http.MethodGet, srv.URL+path, token, "")
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        var comments []struct {
+            ID string `json:"id"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+            t.Fatal(err)
+        }
+        ids := make([]string, len(comments))
+        for i, c := range comments {
+            ids[i] = c.ID
+        }
+        return resp, ids
+    }
+
+    t.Run("another user's", func(t *testing.T) {
+        resp, ids := list(t, bob, "/api/v1/users/alice/comments?limit=10")
+        if !slices.Equal(ids, aliceIDs) {
+            t.Errorf("expected %v oldest first, got %v", aliceIDs, ids)
+        }
+        if got := resp.Header.Get("X-Total-Count"); got != "3" {
+            t.Errorf("expected X-Total-Count 3, got %q", got)
+        }
+    })
+
+    t.Run("me", func(t *testing.T) {
+        _, ids := list(t, alice, "/api/v1/users/me/comments")
+        slices.Sort(ids)
+        want := slices.Clone(aliceIDs)
+        slices.Sort(want)
+        if !slices.Equal(ids, want) {
+            t.Errorf("expected %v, got %v", want, ids)
+        }
+    })
+
+    t.Run("paginated", func(t *testing.T) {
+        resp, ids := list(t, alice, "/api/v1/users/me/comments?limit=1&offset=1")
+        if !slices.Equal(ids, aliceIDs[1:2]) {
+            t.Errorf("expected %v, got %v", aliceIDs[1:2], ids)
+        }
+        if link := resp.Header.Get("Link"); !strings.Contains(link, `/api/v1/users/me/comments?limit=1&offset=2>; rel="next"`) {
+            t.Errorf("expected a next link, got %q", link)
+        }
+    })
+
+    t.Run("unknown user", func(t *testing.T) {
+        resp, ids := list(t, alice, "/api/v1/users/nobody/comments")
+        if len(ids) != 0 || resp.Header.Get("X-Total-Count") != "0" {
+            t.Errorf("expected an empty list, got %v with total %q", ids, resp.Header.Get("X-Total-Count"))
+        }
+    })
+
+    t.Run("requires authentication", func(t *testing.T) {
+        for _, path := range []string{"/api/v1/users/alice/comments", "/api/v1/users/me/comments"} {
+            resp := doRequest(t, http.MethodGet, srv.URL+path, "", "")
+            if resp.StatusCode != http.StatusUnauthorized {
+                t.Errorf("%s: expected status %d, got %d", path, http.StatusUnauthorized, resp.StatusCode)
+            }
+        }
+    })
+
+    t.Run("invalid limit", func(t *testing.T) {
+        resp := doRequest(t, http.MethodGet, srv.URL+"/api/v1/users/alice/comments?limit=0", alice, "")
+        if resp.StatusCode != http.StatusBadRequest {
+            t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+        }
+    })
+}
\ No newline at end of file
@@ -0,0 +1,79 @@
+// test/integration/validation_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "slices"
+    "strings"
+    "testing"
+)
+
+func TestCommentLengthLimits(t *testing.T) {
+    t.Parallel()
+
+    cfg := testConfig()
+    cfg.CommentMinLength = 3
+    cfg.CommentMaxLength = 20
+    cfg.CommentMaxAuthorLength = 8
+    srv := startServer(t, cfg)
+    token := issueToken(t, "limited", "user")
+
+    tests := []struct {
+        name    string
+        content string
+        author  string
+        want    map[string][]string
+    }{
+        {name: "within limits", content: "just right", author: "Alice"},
+        {name: "short content", content: "ok", author: "Alice", want: map[string][]string{"content": {"content must be at least 3 characters"}}},
+        {name: "long content", content: strings.Repeat("x", 21), author: "Alice", want: map[string][]string{"content": {"content must be at most 20 characters"}}},
+        {name: "long author", content: "just right", author: "Alexandria", want: map[string][]string{"author": {"author must be at most 8 characters"}}},
+        {name: "empty content", content: "", author: "Alice", want: map[string][]string{"content": {"content is required"}}},
+        {name: "whitespace content", content: "   ", author: "Alice", want: map[string][]string{"content": {"content is required"}}},
+        {name: "blank and long", content: strings.Repeat(" ", 21), author: "Alice", want: map[string][]string{"content": {"content is required", "content must be at most 20 characters"}}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            body := fmt.Sprintf(`{"content":%q,"author":%q}`, tt.content, tt.author)
+            resp := doRequest(t, http.MethodPost, srv.URL+"/api/v1/comments", token, body)
+            if tt.want == nil {
+                if resp.StatusCode != http.StatusCreated {
+                    t.Errorf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+                }
+                return
+            }
+            if resp.StatusCode != http.StatusBadRequest {
+                t.Fatalf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+            }
+            var out struct {
+                InvalidParams []struct {
+                    Name   string `json:"name"`
+                    Reason string `json:"reason"`
+                } `json:"invalid-params"`
+            }
+            if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+                t.Fatal(err)
+            }
+            got := make(map[string][]string)
+            for _, param := range out.InvalidParams {
+                got[param.Name] = append(got[param.Name], param.Reason)
+            }
+            for field, msgs := range tt.want {
+                if got := got[field]; !slices.Equal(got, msgs) {
+                    t.Errorf("%s: expected %q, got %q", field, msgs, got)
+                }
+            }
+        })
+    }
+
+    // Updates are held to the same limits
+    id := createComment(t, srv, token, "original", "Alice")
+    resp := doRequest(t, http.MethodPut, srv.URL+"/api/v1/comments/"+id, token, `{"content":"`+strings.Repeat("y", 21)+`","author":"Alice"}`)
+    if resp.StatusCode != http.StatusBadRequest {
+        t.Errorf("update: expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+    }
+}
@@ -0,0 +1,35 @@
+// test/integration/version_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+    "web-service/internal/version"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+    t.Parallel()
+
+    srv, _ := newTestServer(t, "operator")
+
+    // The endpoint is public, like /healthz
+    resp := doRequest(t, http.MethodGet, srv.URL+"/version", "", "")
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+    }
+
+    var got struct {
+        Version   string `json:"version"`
+        Commit    string `json:"commit"`
+        BuildDate string `json:"build_date"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+        t.Fatal(err)
+    }
+    want := version.Get()
+    if got.Version != want.Version || got.Commit != want.Commit || got.BuildDate != want.BuildDate {
+        t.Errorf("expected %+v, got %+v", want, got)
+    }
+}
@@ -0,0 +1,138 @@
+// test/integration/versions_test.go
+
+package integration
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+)
+
+func TestCommentVersions(t *testing.T) {
+    t.Parallel()
+
+    srv, token := newTestServer(t, "alice")
+    id := createComment(t, srv, token, "first draft", "Alice")
+    url := srv.URL + "/api/v1/comments/" + id
+
+    put := func(t *testing.T, ifMatch, body string) (*http.Response, map[string]any) {
+        t.Helper()
+        req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(body))
+        if err != nil {
+            t.Fatal(err)
+        }
+        req.Header.Set("Authorization", "Bearer "+token)
+        req.Header.Set("Content-Type", "application/json")
+        if ifMatch != "" {
+            req.Header.Set("If-Match", ifMatch)
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+        var out map[string]any
+        if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+            t.Fatal(err)
+        }
+        return resp, out
+    }
+
+    resp := doRequest(t, http.MethodGet, url, token, "")
+    if etag := resp.Header.Get("ETag"); etag != `"1"` {
+        t.Fatalf(`expected ETag "1" on a new comment, got %q`, etag)
+    }
+
+    t.Run("interleaved updates", func(t *testing.T) {
+        // Two clients edit from version 1 at once; exactly one wins
+        statuses := make(chan int, 2)
+        var wg sync.WaitGroup
+        for _, content := range []string{"edit one", "edit two"} {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader(`{"content":"`+content+`","author":"Alice","version":1}`))
+                req.Header.Set("Authorization", "Bearer "+token)
+                req.Header.Set("Content-Type", "application/json")
+                resp, err := http.DefaultClient.Do(req)
+                if err != nil {
+                    statuses <- 0
+                    return
+                }
+                resp.Body.Close()
+                statuses <- resp.StatusCode
+            }()
+        }
+        wg.Wait()
+        close(statuses)
+
+        counts := make(map[int]int)
+        for status := range statuses {
+            counts[status]++
+        }
+        if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != 1 {
+            t.Errorf("expected one 200 and one 409, got %v", counts)
+        }
+    })
+
+    t.Run("stale If-Match", func(t *testing.T) {
+        resp, out := put(t, `"1"`, `{"content":"late edit","author":"Alice"}`)
+        if resp.StatusCode != http.StatusConflict {
+            t.Fatalf("expected status %d, got %d", http.StatusConflict, resp.StatusCode)
+        }
+        if out["code"] != "version_conflict" || out["current_version"] != float64(2) {
+            t.Errorf("expected version_conflict with current_version 2, got %v", out)
+        }
+        if etag := resp.Header.Get("ETag"); etag != `"2"` {
+            t.Errorf(`expected ETag "2", got %q`, etag)
+        }
+    })
+
+    t.Run("current If-Match", func(t *testing.T) {
+        resp, out := put(t, `"2"`, `{"content":"third draft","author":"Alice"}`)
+        if resp.StatusCode != http.StatusOK {
+            t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+        }
+        if out["version"] != float64(3) || resp.Header.Get("ETag") != `"3"` {
+            t.Errorf(`expected version 3 and ETag "3", got %v and %q`, out["version"], resp.Header.Get("ETag"))
+        }
+    })
+
+    t.Run("without a version", func(t *testing.T) {
+        resp, out := put(t, "", `{"content":"fourth draft","author":"Alice"}`)
+        if resp.StatusCode != http.StatusOK || out["version"] != float64(4) {
+            t.Errorf("expected status %d at version 4, got %d at %v", http.StatusOK, resp.StatusCode, out["version"])
+        }
+    })
+
+    t.Run("invalid", func(t *testing.T) {
+        tests := []struct {
+            name    string
+            ifMatch string
+            body    string
+            field   string
+            reason  string
+        }{
+            {"disagreeing versions", `"4"`, `{"content":"x","author":"Alice","version":3}`, "version", ""},
+            {"malformed If-Match", `W/"4"`, `{"content":"x","author":"Alice"}`, "If-Match", ""},
+            {"negative version", "", `{"content":"x","author":"Alice","version":-1}`, "version", "version must not be negative"},
+        }
+        for _, tt := range tests {
+            t.Run(tt.name, func(t *testing.T) {
+                resp, out := put(t, tt.ifMatch, tt.body)
+                if resp.StatusCode != http.StatusBadRequest {
+                    t.Fatalf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+                }
+                params, _ := out["invalid-params"].([]any)
+                if len(params) != 1 || params[0].(map[string]any)["name"] != tt.field {
+                    t.Errorf("expected a problem with %s, got %v", tt.field, params)
+                }
+                if tt.reason != "" && len(params) == 1 && params[0].(map[string]any)["reason"] != tt.reason {
+                    t.Errorf("expected reason %q, got %v", tt.reason, params[0])
+                }
+            })
+        }
+    })
+}
\ No newline at end of file